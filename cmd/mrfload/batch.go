@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+
+	"github.com/gyeh/pricestats/internal/batch"
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/model"
+)
+
+var batchOpts struct {
+	dir      string
+	glob     string
+	manifest string
+	failFast bool
+}
+
+// batchEntries returns the files selected by --dir, --glob or --manifest,
+// or nil for a single-file ingest.
+func batchEntries(cmd *cobra.Command) ([]batch.Entry, error) {
+	var sources []string
+	for _, name := range []string{"dir", "glob", "manifest"} {
+		if cmd.Flags().Changed(name) {
+			sources = append(sources, "--"+name)
+		}
+	}
+	switch {
+	case len(sources) == 0:
+		return nil, nil
+	case len(sources) > 1:
+		return nil, fmt.Errorf("%s and %s are mutually exclusive", sources[0], sources[1])
+	case cmd.Flags().Changed("file"):
+		return nil, fmt.Errorf("--file cannot be combined with %s", sources[0])
+	}
+
+	switch {
+	case batchOpts.dir != "":
+		return batch.FromDir(batchOpts.dir)
+	case batchOpts.glob != "":
+		return batch.FromGlob(batchOpts.glob)
+	default:
+		return batch.LoadManifest(batchOpts.manifest)
+	}
+}
+
+// poolConnsPerRun is how many pool connections one ingest.Run holds at
+// once: one for its current statement or transaction (a staging chunk
+// commits both COPYs in one transaction, and publishing is one transaction)
+// and one for the heartbeat that ticks meanwhile. The advisory lock session
+// of each Run is an extra connection outside the pool.
+const poolConnsPerRun = 2
+
+// runIngestBatch ingests every entry, prints a per-file table and returns
+// the aggregate exit code: Cancelled if interrupted by a signal, else
+// BatchFailure if any file failed (or was not run), else PartialSuccess if
+// any file was partial, else Success.
+func runIngestBatch(ctx context.Context, log zerolog.Logger, entries []batch.Entry) int {
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		return exitcode.UsageError
+	}
+
+	// Size the pool for the workers unless set explicitly. The ANALYZE after
+	// the batch runs once the workers are done.
+	maxConns := cfg.MaxConns
+	if maxConns == 0 && cfg.Parallel > 1 {
+		maxConns = int32(poolConnsPerRun * cfg.Parallel)
+	}
+	pool, err := db.NewPool(ctx, cfg.DSN, maxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		return exitcode.DBConnError
+	}
+	defer pool.Close()
+
+	results := batch.Run(ctx, pool, log, cfg, entries, batch.Options{Parallel: cfg.Parallel, FailFast: batchOpts.failFast})
+
+	code := exitcode.Success
+	var failed, partial int
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tOUTCOME\tREAD\tSTAGED\tREJECTED\tSERVING\tDURATION\tERROR")
+	for _, r := range results {
+		name := filepath.Base(r.Entry.File)
+		switch {
+		case !r.Started():
+			failed++
+			fmt.Fprintf(tw, "%s\tnot run\t\t\t\t\t\t\n", name)
+			continue
+		case r.Err != nil:
+			failed++
+		case r.Summary.Outcome == model.OutcomePartial:
+			partial++
+		}
+
+		outcome, errText := "", ""
+		var s model.IngestSummary
+		if r.Summary != nil {
+			s = *r.Summary
+			outcome = string(s.Outcome)
+		}
+		if r.Err != nil {
+			outcome = fmt.Sprintf("failed (exit %d)", pipelineExitCode(r.Err))
+			errText = r.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%.1fs\t%s\n",
+			name, outcome, s.RowsRead, s.RowsStaged, s.RowsRejected, s.RowsInsertedServing,
+			s.DurationTotal.Seconds(), errText)
+	}
+	tw.Flush()
+
+	fmt.Printf("\nBatch complete: %d files, %d failed, %d partial\n", len(results), failed, partial)
+	switch {
+	case ctx.Err() != nil:
+		code = exitcode.Cancelled
+	case failed > 0:
+		code = exitcode.BatchFailure
+	case partial > 0:
+		code = exitcode.PartialSuccess
+	}
+	return code
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/logging"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the effective configuration",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the merged configuration (defaults < file < profile < env < flags) with secrets masked",
+	RunE:  runConfigPrint,
+}
+
+func init() {
+	configCmd.AddCommand(configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigPrint(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+
+	out, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		log.Error().Err(err).Msg("marshal config failed")
+		os.Exit(exitcode.UsageError)
+	}
+	if configFile != "" {
+		fmt.Printf("# config: %s\n", configFile)
+	}
+	if cfg.Profile != "" {
+		fmt.Printf("# profile: %s\n", cfg.Profile)
+	}
+	fmt.Print(string(out))
+	return nil
+}
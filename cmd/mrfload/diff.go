@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/diff"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/ingest"
+	"github.com/gyeh/pricestats/internal/logging"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare two loaded versions of a hospital's MRF",
+	Long: `Compares the serving rows of two loaded (transformed, partial or active)
+files of the same hospital. Items are keyed by (code type, code, setting,
+payer, plan, modifiers); their gross charge, negotiated dollar amount and
+negotiated percentage are compared separately. Reports items added, removed
+and repriced, with absolute and percent deltas of the negotiated dollar
+amount for payer rows and of the gross charge otherwise, flags changed
+negotiated percentages, and summarizes per code type and payer.`,
+	RunE: runDiff,
+}
+
+var diffOpts struct {
+	from, to    int64
+	output      string
+	limit       int32
+	summaryOnly bool
+}
+
+func init() {
+	f := diffCmd.Flags()
+	f.Int64Var(&diffOpts.from, "from", 0, "Older MRF file ID (ingest.mrf_files.mrf_file_id) (required)")
+	f.Int64Var(&diffOpts.to, "to", 0, "Newer MRF file ID (required)")
+	f.StringVarP(&diffOpts.output, "output", "o", "table", "Output format: table, json or csv")
+	f.Int32Var(&diffOpts.limit, "limit", 0, "Maximum items to list (0 lists all); the summary covers every item")
+	f.BoolVar(&diffOpts.summaryOnly, "summary", false, "Only print the per code type and payer summary")
+	_ = diffCmd.MarkFlagRequired("from")
+	_ = diffCmd.MarkFlagRequired("to")
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		os.Exit(exitcode.UsageError)
+	}
+	format, err := diff.ParseFormat(diffOpts.output)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid --output")
+		os.Exit(exitcode.UsageError)
+	}
+	if diffOpts.from == diffOpts.to || diffOpts.limit < 0 {
+		log.Error().Msg("--from and --to must differ and --limit must not be negative")
+		os.Exit(exitcode.UsageError)
+	}
+
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	defer pool.Close()
+
+	r, err := diff.Compare(ctx, sqlcgen.New(pool), diffOpts.from, diffOpts.to,
+		diff.Options{Limit: diffOpts.limit, SummaryOnly: diffOpts.summaryOnly})
+	if err != nil {
+		log.Error().Err(err).Msg("diff failed")
+		if errors.Is(err, ingest.ErrFileNotFound) || errors.Is(err, diff.ErrDifferentHospitals) ||
+			errors.Is(err, diff.ErrNotLoaded) {
+			os.Exit(exitcode.UsageError)
+		}
+		os.Exit(exitcode.DBConnError)
+	}
+	if err := diff.Write(os.Stdout, format, r, diffOpts.summaryOnly); err != nil {
+		log.Error().Err(err).Msg("write diff failed")
+		os.Exit(exitcode.ValidationError)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/ingest"
+	"github.com/gyeh/pricestats/internal/logging"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+var filesCmd = &cobra.Command{
+	Use:   "files",
+	Short: "Inspect and manage loaded MRF file versions",
+}
+
+var filesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List loaded files with serving row counts per code type",
+	Args:  cobra.NoArgs,
+	RunE:  runFilesList,
+}
+
+var filesShowCmd = &cobra.Command{
+	Use:   "show <file-id>",
+	Short: "Show one file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runFilesShow,
+}
+
+var filesActivateCmd = &cobra.Command{
+	Use:   "activate <file-id>",
+	Short: "Make a file its hospital's active version (e.g. roll back to an earlier one)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runFilesActivate,
+}
+
+var filesDeleteCmd = &cobra.Command{
+	Use:   "delete <file-id>",
+	Short: "Delete a non-active file with its serving, staging and rejected rows",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runFilesDelete,
+}
+
+var filesOpts struct {
+	hospital string
+	status   string
+	active   bool
+	output   string
+}
+
+func init() {
+	lf := filesListCmd.Flags()
+	lf.StringVar(&filesOpts.hospital, "hospital", "", "Only files of hospitals whose name contains this (case-insensitive)")
+	lf.StringVar(&filesOpts.status, "status", "", "Only files with this status (e.g. active, partial, failed)")
+	lf.BoolVar(&filesOpts.active, "active", false, "Only active (--active) or inactive (--active=false) files")
+	for _, c := range []*cobra.Command{filesListCmd, filesShowCmd} {
+		c.Flags().StringVarP(&filesOpts.output, "output", "o", "table", "Output format: table or json")
+	}
+	for _, c := range []*cobra.Command{filesActivateCmd, filesDeleteCmd} {
+		c.Flags().DurationVar(&cfg.LockTimeout, "lock-timeout", 0, "How long to wait for an ingest of the same hospital (0 = fail at once)")
+	}
+	filesCmd.AddCommand(filesListCmd, filesShowCmd, filesActivateCmd, filesDeleteCmd)
+	rootCmd.AddCommand(filesCmd)
+}
+
+// filesPool checks the DSN and output flag and connects.
+func filesPool(ctx context.Context, log zerolog.Logger) *pgxpool.Pool {
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		os.Exit(exitcode.UsageError)
+	}
+	if filesOpts.output != "table" && filesOpts.output != "json" {
+		log.Error().Str("output", filesOpts.output).Msg("--output must be table or json")
+		os.Exit(exitcode.UsageError)
+	}
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	return pool
+}
+
+// fileIDArg parses the <file-id> argument.
+func fileIDArg(log zerolog.Logger, arg string) int64 {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id <= 0 {
+		log.Error().Str("file_id", arg).Msg("file ID must be a positive integer")
+		os.Exit(exitcode.UsageError)
+	}
+	return id
+}
+
+func runFilesList(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+	pool := filesPool(ctx, log)
+	defer pool.Close()
+
+	var filter ingest.FileFilter
+	if filesOpts.hospital != "" {
+		filter.Hospital = &filesOpts.hospital
+	}
+	if filesOpts.status != "" {
+		filter.Status = &filesOpts.status
+	}
+	if cmd.Flags().Changed("active") {
+		filter.IsActive = &filesOpts.active
+	}
+	files, err := ingest.ListFiles(ctx, sqlcgen.New(pool), filter)
+	if err != nil {
+		log.Error().Err(err).Msg("list files failed")
+		os.Exit(exitcode.DBConnError)
+	}
+
+	if filesOpts.output == "json" {
+		printJSON(log, files)
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tHOSPITAL\tFILE\tSTATUS\tACTIVE\tIMPORTED\tSTAGED\tREJECTED\tSERVING")
+	for _, f := range files {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%v\t%s\t%s\t%s\t%s\n",
+			f.MRFFileID, f.HospitalName, f.FileName, f.Status, f.IsActive,
+			f.ImportedAt.Format(time.DateTime), optInt(f.RowsStaged), optInt(f.RowsRejected), servingCounts(f.ServingRows))
+	}
+	tw.Flush()
+	return nil
+}
+
+func runFilesShow(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+	id := fileIDArg(log, args[0])
+	pool := filesPool(ctx, log)
+	defer pool.Close()
+
+	f, err := ingest.GetFile(ctx, sqlcgen.New(pool), id)
+	if err != nil {
+		log.Error().Err(err).Msg("show file failed")
+		os.Exit(filesExitCode(err))
+	}
+	if filesOpts.output == "json" {
+		printJSON(log, f)
+		return nil
+	}
+
+	var total int64
+	for _, n := range f.ServingRows {
+		total += n
+	}
+	fmt.Printf("File ID:         %d\n", f.MRFFileID)
+	fmt.Printf("Hospital:        %s (%d)\n", f.HospitalName, f.HospitalID)
+	fmt.Printf("File:            %s\n", f.FileName)
+	fmt.Printf("SHA-256:         %s\n", f.FileSHA256)
+	fmt.Printf("Version:         %s\n", deref(f.Version))
+	if f.LastUpdatedOn != nil {
+		fmt.Printf("Last updated on: %s\n", f.LastUpdatedOn.Format(time.DateOnly))
+	}
+	fmt.Printf("Status:          %s\n", f.Status)
+	fmt.Printf("Active:          %v\n", f.IsActive)
+	fmt.Printf("Imported at:     %s\n", f.ImportedAt.Format(time.RFC3339))
+	if f.IngestBatchID != nil {
+		fmt.Printf("Ingest batch:    %s\n", f.IngestBatchID)
+	}
+	fmt.Printf("Rows read:       %s\n", optInt(f.RowsRead))
+	fmt.Printf("Rows staged:     %s\n", optInt(f.RowsStaged))
+	fmt.Printf("Rows rejected:   %s\n", optInt(f.RowsRejected))
+	fmt.Printf("Serving rows:    %d\n", total)
+	for _, ct := range sortedKeys(f.ServingRows) {
+		fmt.Printf("  %-12s %10d\n", ct, f.ServingRows[ct])
+	}
+	return nil
+}
+
+func runFilesActivate(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+	id := fileIDArg(log, args[0])
+	pool := filesPool(ctx, log)
+	defer pool.Close()
+
+	if err := ingest.ActivateFile(ctx, pool, log, id, cfg.LockTimeout); err != nil {
+		log.Error().Err(err).Msg("activate failed")
+		os.Exit(filesExitCode(err))
+	}
+	fmt.Printf("File %d is now the active version\n", id)
+	return nil
+}
+
+func runFilesDelete(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+	id := fileIDArg(log, args[0])
+	pool := filesPool(ctx, log)
+	defer pool.Close()
+
+	if err := ingest.DeleteFile(ctx, pool, log, id, cfg.LockTimeout); err != nil {
+		log.Error().Err(err).Msg("delete failed")
+		os.Exit(filesExitCode(err))
+	}
+	fmt.Printf("File %d deleted\n", id)
+	return nil
+}
+
+// filesExitCode maps a files subcommand error to the process exit code.
+func filesExitCode(err error) int {
+	var locked *ingest.LockedError
+	var pgErr interface{ SQLState() string }
+	switch {
+	case errors.Is(err, ingest.ErrFileNotFound):
+		return exitcode.UsageError
+	case errors.As(err, &locked):
+		return exitcode.Locked
+	case errors.As(err, &pgErr):
+		return exitcode.DBConnError
+	default:
+		return exitcode.ValidationError
+	}
+}
+
+func printJSON(log zerolog.Logger, v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Error().Err(err).Msg("encode JSON failed")
+		os.Exit(exitcode.UsageError)
+	}
+}
+
+func optInt(n *int64) string {
+	if n == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*n, 10)
+}
+
+// servingCounts formats per-code-type row counts as "CPT=10 HCPCS=3".
+func servingCounts(m map[string]int64) string {
+	if len(m) == 0 {
+		return "-"
+	}
+	parts := make([]string, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		parts = append(parts, fmt.Sprintf("%s=%d", k, m[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
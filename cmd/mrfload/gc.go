@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/ingest"
+	"github.com/gyeh/pricestats/internal/logging"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Reap files stuck by crashed ingests and orphaned staging batches",
+	RunE:  runGC,
+}
+
+var gcOpts ingest.GCOptions
+
+func init() {
+	f := gcCmd.Flags()
+	f.BoolVar(&gcOpts.DryRun, "dry-run", false, "Report what would be reaped without changing anything")
+	f.DurationVar(&gcOpts.StaleAfter, "stale-after", time.Hour, "Reap a running file after this long without a heartbeat")
+	f.DurationVar(&gcOpts.OrphanAfter, "orphan-after", 24*time.Hour, "Delete staging batches of failed, cancelled or superseded runs idle this long")
+	rootCmd.AddCommand(gcCmd)
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		os.Exit(exitcode.UsageError)
+	}
+	if gcOpts.StaleAfter <= 0 || gcOpts.OrphanAfter < 0 {
+		log.Error().Msg("--stale-after must be positive and --orphan-after not negative")
+		os.Exit(exitcode.UsageError)
+	}
+
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	defer pool.Close()
+
+	report, err := ingest.GC(ctx, pool, log, gcOpts)
+	if report != nil {
+		printGCReport(report, gcOpts.DryRun)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("gc failed")
+		os.Exit(exitcode.TransformError)
+	}
+	return nil
+}
+
+func printGCReport(r *ingest.GCReport, dryRun bool) {
+	verb := "Reaped"
+	if dryRun {
+		verb = "Would reap"
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s %d stale files:\n", verb, len(r.Files))
+	if len(r.Files) > 0 {
+		fmt.Fprintln(tw, "FILE_ID\tFILE\tSTATUS\tLAST_SEEN\tSTAGING_ROWS\tSKIPPED")
+		for _, f := range r.Files {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\t%s\n",
+				f.MRFFileID, f.FileName, f.Status, f.LastSeen.Format(time.RFC3339), f.StagingRows, f.Skipped)
+		}
+	}
+	fmt.Fprintf(tw, "\n%s %d orphaned staging batches:\n", verb, len(r.Batches))
+	if len(r.Batches) > 0 {
+		fmt.Fprintln(tw, "FILE_ID\tBATCH\tFILE_STATUS\tLAST_SEEN\tSTAGING_ROWS")
+		for _, b := range r.Batches {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\n",
+				b.MRFFileID, b.IngestBatchID, b.FileStatus, b.LastSeen.Format(time.RFC3339), b.StagingRows)
+		}
+	}
+	tw.Flush()
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/ingest"
+	"github.com/gyeh/pricestats/internal/logging"
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show how a code's rates changed across a hospital's file versions",
+	Long: `Prints the price timeline of a code at the hospitals whose name matches
+--hospital: one block per item (code type, code, setting, payer, plan,
+modifiers) with each period's prices, dated by the files' last_updated_on.
+History is recorded as versions are activated (--activate-version or
+files activate); activating an older version rolls the timeline back to
+it. --rebuild replays the hospital's loaded files up to its active
+version to recreate it, e.g. for versions loaded before history was kept.`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+var historyOpts struct {
+	hospital string
+	code     string
+	codeType string
+	payer    string
+	output   string
+	rebuild  bool
+}
+
+func init() {
+	f := historyCmd.Flags()
+	f.StringVar(&historyOpts.hospital, "hospital", "", "Hospitals whose name contains this (case-insensitive) (required)")
+	f.StringVar(&historyOpts.code, "code", "", "Billing code, e.g. 99213 (required unless --rebuild)")
+	f.StringVar(&historyOpts.codeType, "code-type", "", "Only this code type, e.g. CPT")
+	f.StringVar(&historyOpts.payer, "payer", "", "Only payers whose name contains this (case-insensitive)")
+	f.StringVarP(&historyOpts.output, "output", "o", "table", "Output format: table or json")
+	f.BoolVar(&historyOpts.rebuild, "rebuild", false, "Recreate the hospitals' history from their loaded files first")
+	f.DurationVar(&cfg.LockTimeout, "lock-timeout", 0, "With --rebuild, how long to wait for an ingest of the same hospital (0 = fail at once)")
+	_ = historyCmd.MarkFlagRequired("hospital")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		os.Exit(exitcode.UsageError)
+	}
+	if historyOpts.code == "" && !historyOpts.rebuild {
+		log.Error().Msg("--code is required")
+		os.Exit(exitcode.UsageError)
+	}
+	if historyOpts.output != "table" && historyOpts.output != "json" {
+		log.Error().Str("output", historyOpts.output).Msg("--output must be table or json")
+		os.Exit(exitcode.UsageError)
+	}
+	filter := ingest.HistoryFilter{Hospital: historyOpts.hospital, Code: historyOpts.code}
+	if historyOpts.codeType != "" {
+		ct, ok := model.CodeTypeByName(strings.ToUpper(historyOpts.codeType))
+		if !ok {
+			log.Error().Str("code_type", historyOpts.codeType).Msg("unknown code type")
+			os.Exit(exitcode.UsageError)
+		}
+		filter.CodeType = &ct.Name
+	}
+	if historyOpts.payer != "" {
+		filter.Payer = &historyOpts.payer
+	}
+
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	defer pool.Close()
+
+	if historyOpts.rebuild {
+		n, err := ingest.RebuildHistory(ctx, pool, log, historyOpts.hospital, cfg.LockTimeout)
+		if err != nil {
+			log.Error().Err(err).Msg("rebuild history failed")
+			var locked *ingest.LockedError
+			if errors.As(err, &locked) {
+				os.Exit(exitcode.Locked)
+			}
+			os.Exit(exitcode.DBConnError)
+		}
+		log.Info().Int("files", n).Msg("price history rebuilt")
+		if historyOpts.code == "" {
+			return nil
+		}
+	}
+
+	periods, err := ingest.ListHistory(ctx, sqlcgen.New(pool), filter)
+	if err != nil {
+		log.Error().Err(err).Msg("list history failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	if historyOpts.output == "json" {
+		printJSON(log, periods)
+		return nil
+	}
+	printHistory(periods)
+	return nil
+}
+
+// printHistory prints one table per item with a row per period. CHANGE is
+// the rate's move from the previous period: the negotiated dollar amount,
+// or the gross charge for the hospital's own charges.
+func printHistory(periods []ingest.PricePeriod) {
+	if len(periods) == 0 {
+		fmt.Println("No price history")
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	var item string
+	var prev *int64
+	for _, p := range periods {
+		key := strings.Join([]string{p.Hospital, p.CodeType, p.Code, deref(p.Setting), deref(p.Payer), deref(p.Plan), deref(p.Modifiers)}, "\x1f")
+		if key != item {
+			tw.Flush()
+			if item != "" {
+				fmt.Println()
+			}
+			item, prev = key, nil
+			payer := "(gross)"
+			if p.Payer != nil {
+				payer = *p.Payer
+				if p.Plan != nil {
+					payer += " / " + *p.Plan
+				}
+			}
+			fmt.Printf("%s  %s %s  %s  %s", p.Hospital, p.CodeType, p.Code, p.Description, payer)
+			if p.Setting != nil {
+				fmt.Printf("  [%s]", *p.Setting)
+			}
+			if p.Modifiers != nil {
+				fmt.Printf("  modifiers %s", *p.Modifiers)
+			}
+			fmt.Println()
+			fmt.Fprintln(tw, "  FROM\tTO\tNEGOTIATED\tPCT\tESTIMATED\tGROSS\tCASH\tCHANGE\tFILES")
+		}
+		to := "current"
+		if p.ValidTo != nil {
+			to = p.ValidTo.Format(time.DateOnly)
+		}
+		pct := "-"
+		if p.NegotiatedPercentageBps != nil {
+			pct = fmt.Sprintf("%.2f%%", float64(*p.NegotiatedPercentageBps)/100)
+		}
+		rate := p.NegotiatedDollarCents
+		if p.Payer == nil {
+			rate = p.GrossChargeCents
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d-%d\n",
+			p.ValidFrom.Format(time.DateOnly), to, dollars(p.NegotiatedDollarCents), pct,
+			dollars(p.EstimatedAmountCents), dollars(p.GrossChargeCents), dollars(p.DiscountedCashCents),
+			rateChange(prev, rate), p.FirstMRFFileID, p.LastMRFFileID)
+		prev = rate
+	}
+	tw.Flush()
+}
+
+// rateChange formats the percent move from prev to cur, or "-" when either
+// is missing or prev is zero.
+func rateChange(prev, cur *int64) string {
+	if prev == nil || cur == nil || *prev == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.2f%%", float64(*cur-*prev)*100/float64(*prev))
+}
+
+// dollars formats cents as a dollar amount.
+func dollars(c *int64) string {
+	if c == nil {
+		return "-"
+	}
+	n, sign := *c, ""
+	if n < 0 {
+		n, sign = -n, "-"
+	}
+	return fmt.Sprintf("%s$%d.%02d", sign, n/100, n%100)
+}
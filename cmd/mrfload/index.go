@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/index"
+	"github.com/gyeh/pricestats/internal/logging"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Rank hospitals by a market-basket price index",
+	Long: `Prices the market basket (market_basket: in the config file) against every
+hospital's active version and ranks each hospital and payer by a weighted
+price index: the weighted mean of price / market median price * 100 over
+the basket items the hospital prices, so 100 is the market median. Payers
+are compared with payers and hospitals' gross charges with gross charges.
+Coverage is the share of basket items and weight priced; unpriced items are
+listed as missing, and entries under --min-coverage are left unranked.
+
+Results are stored per file version under the basket's name, so
+'mrfload index trend' can follow a hospital across versions.`,
+	Args: cobra.NoArgs,
+	RunE: runIndex,
+}
+
+var indexTrendCmd = &cobra.Command{
+	Use:   "trend",
+	Short: "Show a hospital's stored index results across file versions",
+	Args:  cobra.NoArgs,
+	RunE:  runIndexTrend,
+}
+
+var indexOpts struct {
+	minCoverage float64
+	payer       string
+	output      string
+	dryRun      bool
+	hospital    string
+}
+
+func init() {
+	f := indexCmd.Flags()
+	f.Float64Var(&indexOpts.minCoverage, "min-coverage", 0.5, "Share of the basket weight (0-1) an entry must price to be ranked")
+	f.StringVar(&indexOpts.payer, "payer", "", "Only rank payers whose name contains this (case-insensitive)")
+	f.StringVarP(&indexOpts.output, "output", "o", "table", "Output format: table, json or csv")
+	f.BoolVar(&indexOpts.dryRun, "dry-run", false, "Print the ranking without storing it")
+	tf := indexTrendCmd.Flags()
+	tf.StringVar(&indexOpts.hospital, "hospital", "", "Hospitals whose name contains this (case-insensitive) (required)")
+	tf.StringVar(&indexOpts.payer, "payer", "", "Only payers whose name contains this (case-insensitive)")
+	tf.StringVarP(&indexOpts.output, "output", "o", "table", "Output format: table or json")
+	_ = indexTrendCmd.MarkFlagRequired("hospital")
+	indexCmd.AddCommand(indexTrendCmd)
+	rootCmd.AddCommand(indexCmd)
+}
+
+func runIndex(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		os.Exit(exitcode.UsageError)
+	}
+	if len(cfg.MarketBasket.Items) == 0 {
+		log.Error().Msg("no market basket: set market_basket: in the config file")
+		os.Exit(exitcode.UsageError)
+	}
+	format, err := index.ParseFormat(indexOpts.output)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid --output")
+		os.Exit(exitcode.UsageError)
+	}
+	if indexOpts.minCoverage < 0 || indexOpts.minCoverage > 1 {
+		log.Error().Msg("--min-coverage must be between 0 and 1")
+		os.Exit(exitcode.UsageError)
+	}
+
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	defer pool.Close()
+
+	res, err := index.Compute(ctx, pool, log, cfg.MarketBasket, index.Options{
+		MinCoverage: indexOpts.minCoverage,
+		Store:       !indexOpts.dryRun,
+		Payer:       optFlag(indexOpts.payer),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("index failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	if err := index.Write(os.Stdout, format, res); err != nil {
+		log.Error().Err(err).Msg("write results failed")
+		os.Exit(exitcode.ValidationError)
+	}
+	return nil
+}
+
+func runIndexTrend(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		os.Exit(exitcode.UsageError)
+	}
+	if cfg.MarketBasket.Name == "" {
+		log.Error().Msg("no market basket: set market_basket: in the config file")
+		os.Exit(exitcode.UsageError)
+	}
+	if indexOpts.output != "table" && indexOpts.output != "json" {
+		log.Error().Str("output", indexOpts.output).Msg("--output must be table or json")
+		os.Exit(exitcode.UsageError)
+	}
+
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	defer pool.Close()
+
+	points, err := index.Trend(ctx, sqlcgen.New(pool), cfg.MarketBasket.Name, indexOpts.hospital, optFlag(indexOpts.payer))
+	if err != nil {
+		log.Error().Err(err).Msg("index trend failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	if indexOpts.output == "json" {
+		printJSON(log, points)
+		return nil
+	}
+	if err := index.WriteTrend(os.Stdout, points); err != nil {
+		log.Error().Err(err).Msg("write results failed")
+		os.Exit(exitcode.ValidationError)
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -11,37 +13,53 @@ import (
 	"github.com/gyeh/pricestats/internal/exitcode"
 	"github.com/gyeh/pricestats/internal/ingest"
 	"github.com/gyeh/pricestats/internal/logging"
+	"github.com/gyeh/pricestats/internal/model"
 )
 
 var ingestCmd = &cobra.Command{
 	Use:   "ingest",
-	Short: "Ingest a Parquet file into the database",
+	Short: "Ingest an MRF file (Parquet, CMS JSON or CMS CSV) into the database",
 	RunE:  runIngest,
 }
 
-var configFile string
-
 func init() {
 	f := ingestCmd.Flags()
-	f.StringVar(&cfg.FilePath, "file", "", "Path to Parquet file (required)")
-	f.StringVar(&configFile, "config", "", "Path to YAML config file (optional)")
+	f.StringVar(&cfg.FilePath, "file", "", "Path to MRF file: Parquet, CMS JSON or CMS CSV (required; or file: in the config)")
 	f.BoolVar(&cfg.ActivateVersion, "activate-version", false, "Mark this file version as active")
 	f.BoolVar(&cfg.Force, "force", false, "Re-import even if file SHA already exists")
+	f.BoolVar(&cfg.Resume, "resume", false, "Resume an interrupted ingest of the file, skipping staging when its rows are intact")
 	f.BoolVar(&cfg.KeepStaging, "keep-staging", false, "Keep staging rows after transform")
 	f.BoolVar(&cfg.IncludePayerPrices, "include-payer-prices", false, "Include payer/plan names and negotiated price fields (excluded by default)")
-	_ = ingestCmd.MarkFlagRequired("file")
+	f.Int64Var(&cfg.RejectPolicy.TolerateRejects, "tolerate-rejects", 0, "Problem rows (rejects + codeless rows) still reported as full success (0 = none; with no limit set, problem rows never make a run partial)")
+	f.Float64Var(&cfg.RejectPolicy.TolerateRejectPct, "tolerate-reject-pct", 0, "Percentage of rows read still reported as full success (0 = none)")
+	f.Int64Var(&cfg.RejectPolicy.MaxRejects, "max-rejects", 0, "Fail and roll back when problem rows exceed this count (0 = no limit)")
+	f.Float64Var(&cfg.RejectPolicy.MaxRejectPct, "max-reject-pct", 0, "Fail and roll back when problem rows exceed this percentage of rows read (0 = no limit)")
+	f.IntVar(&cfg.BatchSize, "batch-size", 1024, "Source rows read per batch")
+	f.IntVar(&cfg.ChunkRows, "chunk-rows", 20_000, "Source rows committed per staging transaction (checkpoint interval); two chunks are buffered in memory at ~1-2 KB per row")
+	f.IntVar(&cfg.CopyRetries, "copy-retries", 5, "Retries of a staging chunk after a connection error")
+	f.DurationVar(&cfg.CopyRetryBackoff, "copy-retry-backoff", time.Second, "Wait before the first staging retry, doubling per retry")
+	f.StringVar(&cfg.HospitalName, "hospital-name", "", "Resolve the hospital by this name instead of the one published in the file")
+	f.StringVar(&batchOpts.dir, "dir", "", "Ingest every .parquet, .json and .csv file in this directory")
+	f.StringVar(&batchOpts.glob, "glob", "", "Ingest every file matching this glob pattern (quote it)")
+	f.StringVar(&batchOpts.manifest, "manifest", "", "Ingest the files listed in this YAML manifest")
+	f.BoolVar(&batchOpts.failFast, "fail-fast", false, "Stop a batch ingest at the first failed file")
+	f.IntVar(&cfg.Parallel, "parallel", 1, "Files ingested at once in a batch ingest; each holds up to 2 pool connections plus 1 lock session connection outside the pool")
+	f.DurationVar(&cfg.LockTimeout, "lock-timeout", 0, "How long to wait for another ingest of the same hospital (0 = fail at once)")
 	rootCmd.AddCommand(ingestCmd)
 }
 
 func runIngest(cmd *cobra.Command, args []string) error {
 	log := logging.Setup(cfg.LogFormat)
-	ctx := context.Background()
+	ctx, stop := signalContext(log)
+	defer stop()
 
-	if configFile != "" {
-		if err := cfg.LoadFromFile(configFile); err != nil {
-			log.Error().Err(err).Msg("config file load failed")
-			os.Exit(exitcode.UsageError)
-		}
+	entries, err := batchEntries(cmd)
+	if err != nil {
+		log.Error().Err(err).Msg("batch input failed")
+		os.Exit(exitcode.UsageError)
+	}
+	if entries != nil {
+		os.Exit(runIngestBatch(ctx, log, entries))
 	}
 
 	if err := cfg.ValidateWithDSN(); err != nil {
@@ -49,7 +67,7 @@ func runIngest(cmd *cobra.Command, args []string) error {
 		os.Exit(exitcode.UsageError)
 	}
 
-	pool, err := db.NewPool(ctx, cfg.DSN)
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
 	if err != nil {
 		log.Error().Err(err).Msg("database connection failed")
 		os.Exit(exitcode.DBConnError)
@@ -58,22 +76,58 @@ func runIngest(cmd *cobra.Command, args []string) error {
 
 	summary, err := ingest.Run(ctx, pool, log, &cfg)
 	if err != nil {
-		if pe, ok := err.(*ingest.PipelineError); ok {
+		var locked *ingest.LockedError
+		if errors.Is(err, context.Canceled) {
+			log.Warn().Err(err).Msg("ingest cancelled")
+		} else if errors.As(err, &locked) {
+			log.Error().Str("holder_batch", locked.HolderBatchID).Int32("holder_pid", locked.HolderPID).
+				Msg(locked.Error())
+		} else if pe, ok := err.(*ingest.PipelineError); ok {
 			log.Error().Err(pe.Err).Str("phase", pe.Phase).Msg("ingest failed")
-			switch pe.Phase {
-			case "preflight":
-				os.Exit(exitcode.ValidationError)
-			case "stage":
-				os.Exit(exitcode.CopyError)
-			default:
-				os.Exit(exitcode.TransformError)
-			}
+		} else {
+			log.Error().Err(err).Msg("ingest failed")
 		}
-		log.Error().Err(err).Msg("ingest failed")
-		os.Exit(exitcode.TransformError)
+		os.Exit(pipelineExitCode(err))
 	}
 
 	fmt.Printf("Ingest complete: %d rows staged, %d rows in serving table (%.1fs)\n",
 		summary.RowsStaged, summary.RowsInsertedServing, summary.DurationTotal.Seconds())
+	if summary.Resumed {
+		fmt.Printf("Resumed: reused the staging rows of batch %s\n", summary.IngestBatchID)
+	}
+	if summary.RowsFilteredOut > 0 {
+		fmt.Printf("Left out by code_types: %d rows\n", summary.RowsFilteredOut)
+	}
+	if summary.Outcome == model.OutcomeSuccess && summary.OutcomeReason != "" {
+		fmt.Printf("Problem rows: %s (see `mrfload rejects --file-id %d`)\n", summary.OutcomeReason, summary.MRFFileID)
+	}
+	if summary.Outcome == model.OutcomePartial {
+		fmt.Printf("Partial success: %s (%d rejected, %d without codes; see `mrfload rejects --file-id %d`)\n",
+			summary.OutcomeReason, summary.RowsRejected, summary.RowsWithoutCodes, summary.MRFFileID)
+		os.Exit(exitcode.PartialSuccess)
+	}
 	return nil
 }
+
+// pipelineExitCode maps an ingest.Run error to the process exit code.
+func pipelineExitCode(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return exitcode.Cancelled
+	}
+	var locked *ingest.LockedError
+	if errors.As(err, &locked) {
+		return exitcode.Locked
+	}
+	pe, ok := err.(*ingest.PipelineError)
+	if !ok {
+		return exitcode.TransformError
+	}
+	switch pe.Phase {
+	case "preflight", "policy":
+		return exitcode.ValidationError
+	case "stage":
+		return exitcode.CopyError
+	default:
+		return exitcode.TransformError
+	}
+}
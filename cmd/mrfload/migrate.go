@@ -30,7 +30,7 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 		os.Exit(exitcode.UsageError)
 	}
 
-	pool, err := db.NewPool(ctx, cfg.DSN)
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
 	if err != nil {
 		log.Error().Err(err).Msg("database connection failed")
 		os.Exit(exitcode.DBConnError)
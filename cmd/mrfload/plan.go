@@ -10,8 +10,8 @@ import (
 	"github.com/gyeh/pricestats/internal/exitcode"
 	"github.com/gyeh/pricestats/internal/logging"
 	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/mrfread"
 	"github.com/gyeh/pricestats/internal/normalize"
-	"github.com/gyeh/pricestats/internal/parquetread"
 )
 
 var planCmd = &cobra.Command{
@@ -21,8 +21,7 @@ var planCmd = &cobra.Command{
 }
 
 func init() {
-	planCmd.Flags().StringVar(&cfg.FilePath, "file", "", "Path to Parquet file (required)")
-	_ = planCmd.MarkFlagRequired("file")
+	planCmd.Flags().StringVar(&cfg.FilePath, "file", "", "Path to MRF file: Parquet, CMS JSON or CMS CSV (required; or file: in the config)")
 	rootCmd.AddCommand(planCmd)
 }
 
@@ -48,23 +47,24 @@ func runPlan(cmd *cobra.Command, args []string) error {
 	}
 
 	// Open and validate
-	reader, err := parquetread.Open(cfg.FilePath)
+	reader, format, err := mrfread.Open(cfg.FilePath)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to open parquet file")
+		log.Error().Err(err).Msg("failed to open MRF file")
 		os.Exit(exitcode.ValidationError)
 	}
 	defer reader.Close()
 
-	if err := parquetread.ValidateSchema(reader.Schema()); err != nil {
+	if err := reader.Validate(); err != nil {
 		log.Error().Err(err).Msg("schema validation failed")
 		os.Exit(exitcode.ValidationError)
 	}
 
+	// numRows is -1 for streaming formats that don't know their length up front
 	numRows := reader.NumRows()
 
 	// Sample rows to estimate code explosion
 	sampleSize := int64(1000)
-	if sampleSize > numRows {
+	if numRows >= 0 && sampleSize > numRows {
 		sampleSize = numRows
 	}
 
@@ -98,9 +98,14 @@ func runPlan(cmd *cobra.Command, args []string) error {
 	// Print report
 	fmt.Println("=== mrfload plan ===")
 	fmt.Printf("File:       %s\n", cfg.FilePath)
+	fmt.Printf("Format:     %s\n", format)
 	fmt.Printf("SHA-256:    %s\n", sha)
 	fmt.Printf("Size:       %d bytes\n", stat.Size())
-	fmt.Printf("Total rows: %d\n", numRows)
+	if numRows >= 0 {
+		fmt.Printf("Total rows: %d\n", numRows)
+	} else {
+		fmt.Println("Total rows: unknown (streaming format)")
+	}
 	fmt.Printf("Hospital:   %s\n", hospitalName)
 	fmt.Printf("Sampled:    %d rows\n", sampled)
 	fmt.Println()
@@ -109,13 +114,20 @@ func runPlan(cmd *cobra.Command, args []string) error {
 	totalExploded := int64(0)
 	for _, ct := range model.AllCodeTypes {
 		count := codeCounts[ct.Name]
-		if count > 0 {
-			projected := count * numRows / sampled
-			totalExploded += projected
-			fmt.Printf("  %-10s %6d sampled → ~%d projected serving rows\n", ct.Name, count, projected)
+		if count == 0 {
+			continue
+		}
+		if numRows < 0 {
+			fmt.Printf("  %-10s %6d sampled\n", ct.Name, count)
+			continue
 		}
+		projected := count * numRows / sampled
+		totalExploded += projected
+		fmt.Printf("  %-10s %6d sampled → ~%d projected serving rows\n", ct.Name, count, projected)
+	}
+	if numRows >= 0 {
+		fmt.Printf("\nEstimated total serving rows: ~%d\n", totalExploded)
 	}
-	fmt.Printf("\nEstimated total serving rows: ~%d\n", totalExploded)
 	fmt.Println("Schema validation: OK")
 
 	return nil
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/ingest"
+	"github.com/gyeh/pricestats/internal/logging"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete superseded file versions per the retention policy",
+	Long: `Deletes the files selected by the retention policy (retention: in the config
+file, or the flags below). Superseded versions, the activatable ones imported
+before their hospital's active version, are deleted beyond the newest
+--keep-versions per hospital or when imported more than --max-age-days ago.
+Failed and cancelled attempts are deleted when imported more than
+--failed-max-age-days ago. The active version of a hospital, and versions
+loaded after it without being activated, are never deleted.`,
+	RunE: runPrune,
+}
+
+var pruneDryRun bool
+
+func init() {
+	f := pruneCmd.Flags()
+	f.BoolVar(&pruneDryRun, "dry-run", false, "Report what would be deleted without changing anything")
+	f.IntVar(&cfg.Retention.KeepVersions, "keep-versions", 0, "Superseded versions to keep per hospital, newest first (0 = no limit)")
+	f.IntVar(&cfg.Retention.MaxAgeDays, "max-age-days", 0, "Delete superseded versions imported more than this many days ago (0 = no limit)")
+	f.IntVar(&cfg.Retention.FailedMaxAgeDays, "failed-max-age-days", 0, "Delete failed and cancelled attempts imported more than this many days ago (0 = no limit)")
+	f.IntVar(&cfg.Retention.DeleteBatchRows, "delete-batch-rows", 0, "Serving rows removed per DELETE statement (0 = 50000)")
+	f.BoolVar(&cfg.Retention.Vacuum, "vacuum", false, "VACUUM (ANALYZE) the partitions rows were deleted from")
+	f.DurationVar(&cfg.LockTimeout, "lock-timeout", 0, "How long to wait for an ingest of the same hospital (0 = fail at once)")
+	rootCmd.AddCommand(pruneCmd)
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		os.Exit(exitcode.UsageError)
+	}
+	if !cfg.Retention.Enabled() {
+		log.Error().Msg("no retention rule set: give --keep-versions, --max-age-days or --failed-max-age-days, or retention: in the config file")
+		os.Exit(exitcode.UsageError)
+	}
+
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	defer pool.Close()
+
+	report, err := ingest.Prune(ctx, pool, log, cfg.Retention, cfg.LockTimeout, pruneDryRun)
+	if report != nil {
+		printPruneReport(report, pruneDryRun)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("prune failed")
+		os.Exit(filesExitCode(err))
+	}
+	return nil
+}
+
+func printPruneReport(r *ingest.PruneReport, dryRun bool) {
+	verb := "Deleted"
+	if dryRun {
+		verb = "Would delete"
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s %d superseded versions and failed attempts:\n", verb, len(r.Files))
+	if len(r.Files) > 0 {
+		fmt.Fprintln(tw, "FILE_ID\tHOSPITAL\tFILE\tSTATUS\tIMPORTED_AT\tREASON\tSERVING_ROWS\tSKIPPED")
+		for _, f := range r.Files {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				f.MRFFileID, f.HospitalName, f.FileName, f.Status, f.ImportedAt.Format(time.RFC3339),
+				f.Reason, servingCounts(f.ServingRows), f.Skipped)
+		}
+	}
+	fmt.Fprintf(tw, "\n%s %d serving rows\n", verb, r.ServingRowsDeleted)
+	for _, p := range r.Vacuumed {
+		fmt.Fprintf(tw, "Vacuumed %s\n", p)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/logging"
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/prices"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+var queryCmd = &cobra.Command{
+	Use:   "query [code...]",
+	Short: "Look up active-version prices of billing codes",
+	Long: `Looks up the prices of one or more codes of a code type in the active
+version of every hospital's file, cheapest first per code. A row's price is
+its negotiated dollar amount, or its gross charge when it has none. Codes
+come from the arguments and/or --codes-file (one per line, # comments), so
+a market basket can be looked up in one call; --limit applies to each code.`,
+	RunE: runQuery,
+}
+
+var queryOpts struct {
+	codeType  string
+	codesFile string
+	hospital  string
+	state     string
+	payer     string
+	plan      string
+	setting   string
+	output    string
+	limit     int32
+}
+
+func init() {
+	f := queryCmd.Flags()
+	f.StringVar(&queryOpts.codeType, "code-type", "", "Code type, e.g. CPT, HCPCS, MS-DRG (required)")
+	f.StringVar(&queryOpts.codesFile, "codes-file", "", "File of codes to look up, one per line")
+	f.StringVar(&queryOpts.hospital, "hospital", "", "Only hospitals whose name contains this (case-insensitive)")
+	f.StringVar(&queryOpts.state, "state", "", "Only hospitals licensed in this state, e.g. CA")
+	f.StringVar(&queryOpts.payer, "payer", "", "Only payers whose name contains this (case-insensitive)")
+	f.StringVar(&queryOpts.plan, "plan", "", "Only plans whose name contains this (case-insensitive)")
+	f.StringVar(&queryOpts.setting, "setting", "", "Only this setting: inpatient or outpatient (rows for both match either)")
+	f.StringVarP(&queryOpts.output, "output", "o", "table", "Output format: table, json or csv")
+	f.Int32Var(&queryOpts.limit, "limit", 100, "Maximum rows per code (0 returns all)")
+	_ = queryCmd.MarkFlagRequired("code-type")
+	rootCmd.AddCommand(queryCmd)
+}
+
+func runQuery(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		os.Exit(exitcode.UsageError)
+	}
+	format, err := prices.ParseFormat(queryOpts.output)
+	if err != nil {
+		log.Error().Err(err).Msg("invalid --output")
+		os.Exit(exitcode.UsageError)
+	}
+	if _, ok := model.CodeTypeByName(strings.ToUpper(queryOpts.codeType)); !ok {
+		log.Error().Str("code_type", queryOpts.codeType).Msg("unknown code type")
+		os.Exit(exitcode.UsageError)
+	}
+	if queryOpts.limit < 0 {
+		log.Error().Msg("--limit must not be negative")
+		os.Exit(exitcode.UsageError)
+	}
+
+	filter := prices.Filter{
+		CodeType: queryOpts.codeType,
+		Codes:    args,
+		Hospital: optFlag(queryOpts.hospital),
+		State:    optFlag(queryOpts.state),
+		Payer:    optFlag(queryOpts.payer),
+		Plan:     optFlag(queryOpts.plan),
+		Setting:  optFlag(queryOpts.setting),
+		Limit:    queryOpts.limit,
+	}
+	if queryOpts.codesFile != "" {
+		f, err := os.Open(queryOpts.codesFile)
+		if err != nil {
+			log.Error().Err(err).Msg("open codes file failed")
+			os.Exit(exitcode.UsageError)
+		}
+		codes, err := prices.ReadCodes(f)
+		f.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("read codes file failed")
+			os.Exit(exitcode.UsageError)
+		}
+		filter.Codes = append(filter.Codes, codes...)
+	}
+	if len(filter.Codes) == 0 {
+		log.Error().Msg("give at least one code as an argument or in --codes-file")
+		os.Exit(exitcode.UsageError)
+	}
+
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	defer pool.Close()
+
+	rows, truncated, err := prices.Lookup(ctx, sqlcgen.New(pool), filter)
+	if err != nil {
+		log.Error().Err(err).Msg("query failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	if len(truncated) > 0 {
+		log.Warn().Strs("codes", truncated).Int32("limit", queryOpts.limit).
+			Msg("some codes have more rows than --limit; raise it or pass 0 to see them all")
+	}
+	if err := prices.Write(os.Stdout, format, rows); err != nil {
+		log.Error().Err(err).Msg("write results failed")
+		os.Exit(exitcode.ValidationError)
+	}
+	return nil
+}
+
+// optFlag maps an unset string flag to nil.
+func optFlag(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/logging"
+	"github.com/gyeh/pricestats/internal/rejects"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+var rejectsCmd = &cobra.Command{
+	Use:   "rejects",
+	Short: "List, summarize and export rows quarantined during ingest",
+	RunE:  runRejects,
+}
+
+var rejectsOpts struct {
+	fileID       int64
+	reason       string
+	limit        int32
+	exportPath   string
+	exportFormat string
+}
+
+func init() {
+	f := rejectsCmd.Flags()
+	f.Int64Var(&rejectsOpts.fileID, "file-id", 0, "MRF file ID (ingest.mrf_files.mrf_file_id) (required)")
+	f.StringVar(&rejectsOpts.reason, "reason", "", "Only show rows with this reason code")
+	f.Int32Var(&rejectsOpts.limit, "limit", 50, "Maximum rows to list (0 lists all)")
+	f.StringVar(&rejectsOpts.exportPath, "export", "", "Write all matching rows to this file instead of listing them")
+	f.StringVar(&rejectsOpts.exportFormat, "format", "", "Export format: parquet or csv (default: from --export extension)")
+	_ = rejectsCmd.MarkFlagRequired("file-id")
+	rootCmd.AddCommand(rejectsCmd)
+}
+
+func runRejects(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		os.Exit(exitcode.UsageError)
+	}
+
+	var exportFormat rejects.Format
+	if rejectsOpts.exportPath != "" {
+		var err error
+		if exportFormat, err = rejects.FormatForPath(rejectsOpts.exportPath, rejectsOpts.exportFormat); err != nil {
+			log.Error().Err(err).Msg("invalid export format")
+			os.Exit(exitcode.UsageError)
+		}
+	}
+
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	defer pool.Close()
+	q := sqlcgen.New(pool)
+
+	params := sqlcgen.ListRejectedRowsParams{MrfFileID: rejectsOpts.fileID}
+	if rejectsOpts.reason != "" {
+		params.ReasonCode = &rejectsOpts.reason
+	}
+
+	if rejectsOpts.exportPath != "" {
+		rows, err := q.ListRejectedRows(ctx, params)
+		if err != nil {
+			log.Error().Err(err).Msg("list rejected rows failed")
+			os.Exit(exitcode.DBConnError)
+		}
+		out, err := rejects.FromDB(rows)
+		if err != nil {
+			log.Error().Err(err).Msg("decode rejected rows failed")
+			os.Exit(exitcode.ValidationError)
+		}
+		f, err := os.Create(rejectsOpts.exportPath)
+		if err != nil {
+			log.Error().Err(err).Msg("create export file failed")
+			os.Exit(exitcode.UsageError)
+		}
+		if err := rejects.Write(f, exportFormat, out); err != nil {
+			f.Close()
+			log.Error().Err(err).Msg("export failed")
+			os.Exit(exitcode.ValidationError)
+		}
+		if err := f.Close(); err != nil {
+			log.Error().Err(err).Msg("close export file failed")
+			os.Exit(exitcode.ValidationError)
+		}
+		fmt.Printf("Exported %d rejected rows to %s (%s)\n", len(out), rejectsOpts.exportPath, exportFormat)
+		return nil
+	}
+
+	groups, err := q.CountRejectsByReason(ctx, rejectsOpts.fileID)
+	if err != nil {
+		log.Error().Err(err).Msg("count rejected rows failed")
+		os.Exit(exitcode.DBConnError)
+	}
+
+	var total int64
+	for _, g := range groups {
+		total += g.RowCount
+	}
+	fmt.Printf("File ID:        %d\n", rejectsOpts.fileID)
+	fmt.Printf("Rejected rows:  %d\n", total)
+	if total == 0 {
+		return nil
+	}
+
+	fmt.Println("\nBy reason:")
+	for _, g := range groups {
+		fmt.Printf("  %-22s %-28s %10d\n", g.ReasonCode, g.FieldName, g.RowCount)
+	}
+
+	if rejectsOpts.limit > 0 {
+		params.RowLimit = &rejectsOpts.limit
+	}
+	rows, err := q.ListRejectedRows(ctx, params)
+	if err != nil {
+		log.Error().Err(err).Msg("list rejected rows failed")
+		os.Exit(exitcode.DBConnError)
+	}
+
+	fmt.Println("\nRows:")
+	fmt.Printf("  %10s  %-22s %-28s %s\n", "ROW", "REASON", "FIELD", "DETAIL")
+	for _, r := range rows {
+		fmt.Printf("  %10d  %-22s %-28s %s\n", r.SourceRowNumber, r.ReasonCode, deref(r.FieldName), deref(r.Detail))
+	}
+	return nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
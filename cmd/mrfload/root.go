@@ -6,16 +6,31 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/gyeh/pricestats/internal/config"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/logging"
 )
 
-var cfg config.Config
+var cfg = config.Defaults()
+
+// configFile and profile select the YAML config; both also come from
+// MRFLOAD_CONFIG / MRFLOAD_PROFILE.
+var configFile, profile string
 
 var rootCmd = &cobra.Command{
 	Use:   "mrfload",
-	Short: "Hospital MRF Parquet → Postgres bulk loader",
-	Long:  "Reads hospital MRF Parquet files and bulk-loads them into Supabase/Postgres via the COPY protocol.",
+	Short: "Hospital MRF → Postgres bulk loader",
+	Long:  "Reads hospital MRF files (Parquet, CMS JSON or CMS CSV) and bulk-loads them into Supabase/Postgres via the COPY protocol.",
+
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := loadConfig(cmd); err != nil {
+			log := logging.Setup(cfg.LogFormat)
+			log.Error().Err(err).Msg("config load failed")
+			os.Exit(exitcode.UsageError)
+		}
+	},
 }
 
 func init() {
@@ -26,6 +41,47 @@ func init() {
 	}
 
 	pf := rootCmd.PersistentFlags()
-	pf.StringVar(&cfg.DSN, "dsn", os.Getenv("DATABASE_URL"), "Postgres connection string (or set SUPABASE_DB_URL)")
+	pf.StringVar(&cfg.DSN, "dsn", "", "Postgres connection string (or set DATABASE_URL / MRFLOAD_DSN)")
 	pf.StringVar(&cfg.LogFormat, "log-format", "text", "Log format: text or json")
+	pf.Int32Var(&cfg.MaxConns, "max-conns", 0, "Maximum database connections (0 = pgx default)")
+	pf.StringVar(&configFile, "config", "", "Path to YAML config file (optional)")
+	pf.StringVar(&profile, "profile", "", "Config file profile to apply, e.g. dev or prod")
+}
+
+// loadConfig builds the effective configuration in cfg. Flags were already
+// parsed into cfg, so their values are saved, cfg is rebuilt from defaults,
+// the config file (and profile) and the environment, and the flags given
+// explicitly on the command line are applied again on top.
+func loadConfig(cmd *cobra.Command) error {
+	explicit := map[*pflag.Flag]string{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		explicit[f] = f.Value.String()
+	})
+
+	if configFile == "" {
+		configFile = os.Getenv(config.EnvPrefix + "CONFIG")
+	}
+	if profile == "" {
+		profile = os.Getenv(config.EnvPrefix + "PROFILE")
+	}
+	if profile != "" && configFile == "" {
+		return fmt.Errorf("--profile %q requires --config", profile)
+	}
+
+	cfg = config.Defaults()
+	cfg.Profile = profile
+	if configFile != "" {
+		if err := cfg.LoadFromFile(configFile); err != nil {
+			return err
+		}
+	}
+	if err := cfg.LoadEnv(os.LookupEnv); err != nil {
+		return fmt.Errorf("environment: %w", err)
+	}
+	for f, v := range explicit {
+		if err := f.Value.Set(v); err != nil {
+			return fmt.Errorf("--%s: %w", f.Name, err)
+		}
+	}
+	return cfg.Check()
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gyeh/pricestats/internal/api"
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/logging"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the loaded prices as a JSON HTTP API",
+	Long: `Serves a read-only JSON API over the loaded data:
+
+  GET /v1/codes/{type}/{code}/prices  a code's prices, cheapest first
+  GET /v1/hospitals                   hospitals with an active version
+  GET /v1/hospitals/{id}/files        a hospital's file versions
+  GET /v1/payers                      payers with active-version rates
+
+Prices and payers come from each hospital's active version only. Lists
+are paged with ?limit= and the previous page's next_cursor as ?cursor=.
+SIGINT or SIGTERM stops accepting connections and drains open requests.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+var serveOpts struct {
+	addr            string
+	shutdownTimeout time.Duration
+}
+
+func init() {
+	f := serveCmd.Flags()
+	f.StringVar(&serveOpts.addr, "addr", ":8080", "Address to listen on")
+	f.DurationVar(&serveOpts.shutdownTimeout, "shutdown-timeout", 10*time.Second, "How long to drain open requests on shutdown")
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx, stop := signalContext(log)
+	defer stop()
+
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		os.Exit(exitcode.UsageError)
+	}
+
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	defer pool.Close()
+
+	srv := &http.Server{
+		Addr:              serveOpts.addr,
+		Handler:           api.New(pool, log),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	log.Info().Str("addr", serveOpts.addr).Msg("serving")
+
+	select {
+	case err := <-errc:
+		log.Error().Err(err).Msg("server failed")
+		os.Exit(exitcode.UsageError)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveOpts.shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Warn().Err(err).Msg("shutdown did not drain every request")
+	}
+	log.Info().Msg("server stopped")
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gyeh/pricestats/internal/exitcode"
+)
+
+// signalContext returns a context cancelled by the first SIGINT or SIGTERM,
+// giving a running ingest the chance to unwind and clean up. A second signal
+// exits at once with exitcode.Cancelled. stop releases the signal handler.
+func signalContext(log zerolog.Logger) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-sigs:
+			log.Warn().Str("signal", sig.String()).Msg("cancelling; signal again to exit immediately")
+			cancel()
+		case <-done:
+			return
+		}
+		select {
+		case sig := <-sigs:
+			log.Error().Str("signal", sig.String()).Msg("forced exit")
+			os.Exit(exitcode.Cancelled)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigs)
+		close(done)
+		cancel()
+	}
+}
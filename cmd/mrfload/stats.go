@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/exitcode"
+	"github.com/gyeh/pricestats/internal/ingest"
+	"github.com/gyeh/pricestats/internal/logging"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Maintain the per-code price statistics tables",
+}
+
+var statsRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Recompute price statistics from the active versions",
+	Long: `Recomputes mrf.price_stats (per code, setting, hospital and payer) and
+mrf.price_stats_by_state (per code, setting and state) from each hospital's
+active version. Publishing or activating a version already refreshes its
+hospital, so this is for statistics that predate the tables or need a
+rebuild; --hospital limits it to hospitals whose name matches.`,
+	Args: cobra.NoArgs,
+	RunE: runStatsRefresh,
+}
+
+var statsOpts struct {
+	hospital string
+}
+
+func init() {
+	f := statsRefreshCmd.Flags()
+	f.StringVar(&statsOpts.hospital, "hospital", "", "Only hospitals whose name contains this (case-insensitive)")
+	f.DurationVar(&cfg.LockTimeout, "lock-timeout", 0, "How long to wait for an ingest of the same hospital (0 = fail at once)")
+	statsCmd.AddCommand(statsRefreshCmd)
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStatsRefresh(cmd *cobra.Command, args []string) error {
+	log := logging.Setup(cfg.LogFormat)
+	ctx := context.Background()
+
+	if cfg.DSN == "" {
+		log.Error().Msg("--dsn or DATABASE_URL is required")
+		os.Exit(exitcode.UsageError)
+	}
+
+	pool, err := db.NewPool(ctx, cfg.DSN, cfg.MaxConns)
+	if err != nil {
+		log.Error().Err(err).Msg("database connection failed")
+		os.Exit(exitcode.DBConnError)
+	}
+	defer pool.Close()
+
+	refreshed, err := ingest.RefreshStats(ctx, pool, log, optFlag(statsOpts.hospital), cfg.LockTimeout)
+	printStatsRefresh(refreshed)
+	if err != nil {
+		log.Error().Err(err).Msg("stats refresh failed")
+		var locked *ingest.LockedError
+		if errors.As(err, &locked) {
+			os.Exit(exitcode.Locked)
+		}
+		os.Exit(exitcode.DBConnError)
+	}
+	return nil
+}
+
+func printStatsRefresh(refreshed []ingest.StatsRefresh) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Refreshed %d hospitals:\n", len(refreshed))
+	if len(refreshed) > 0 {
+		fmt.Fprintln(tw, "HOSPITAL_ID\tHOSPITAL\tHOSPITAL_ROWS\tSTATE_ROWS")
+		for _, r := range refreshed {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%d\n", r.HospitalID, r.HospitalName, r.HospitalRows, r.StateRows)
+		}
+	}
+	tw.Flush()
+}
@@ -0,0 +1,467 @@
+// Package api serves the loaded price data as a read-only JSON HTTP API.
+// Its response types are the public contract and are deliberately separate
+// from the sqlc rows, so the tables can change without breaking clients.
+// Prices and payers come from the active version of each hospital's file
+// only.
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/normalize"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type server struct {
+	q   *sqlcgen.Queries
+	log zerolog.Logger
+}
+
+// New returns the API handler. Routes:
+//
+//	GET /v1/codes/{type}/{code}/prices  ?hospital_id= &state= &payer_id= &plan= &setting=
+//	GET /v1/hospitals                   ?name= &state=
+//	GET /v1/hospitals/{id}/files
+//	GET /v1/payers                      ?name=
+//
+// Every list takes ?limit= (default 100, at most 1000) and ?cursor=, the
+// next_cursor of the previous page.
+func New(pool *pgxpool.Pool, log zerolog.Logger) http.Handler {
+	s := &server{q: sqlcgen.New(pool), log: log}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/codes/{type}/{code}/prices", s.codePrices)
+	mux.HandleFunc("GET /v1/hospitals", s.hospitals)
+	mux.HandleFunc("GET /v1/hospitals/{id}/files", s.hospitalFiles)
+	mux.HandleFunc("GET /v1/payers", s.payers)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "no such endpoint")
+	})
+	return s.logRequests(mux)
+}
+
+// Page is the envelope of every list response. NextCursor is empty on the
+// last page.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Price is one active-version price of a code.
+type Price struct {
+	ID           int64       `json:"id"`
+	FileID       int64       `json:"file_id"`
+	CodeType     string      `json:"code_type"`
+	Code         string      `json:"code"`
+	CodeRaw      string      `json:"code_raw"`
+	Description  string      `json:"description"`
+	Hospital     HospitalRef `json:"hospital"`
+	Setting      *string     `json:"setting"`
+	BillingClass *string     `json:"billing_class"`
+	Payer        *Ref        `json:"payer"`
+	Plan         *Ref        `json:"plan"`
+	Modifiers    *string     `json:"modifiers"`
+	// PriceCents is the negotiated dollar amount, or the gross charge for a
+	// row without one; pages are sorted by it, unpriced rows last.
+	PriceCents              *int64  `json:"price_cents"`
+	GrossChargeCents        *int64  `json:"gross_charge_cents"`
+	DiscountedCashCents     *int64  `json:"discounted_cash_cents"`
+	NegotiatedDollarCents   *int64  `json:"negotiated_dollar_cents"`
+	NegotiatedPercentageBps *int32  `json:"negotiated_percentage_bps"`
+	EstimatedAmountCents    *int64  `json:"estimated_amount_cents"`
+	MinChargeCents          *int64  `json:"min_charge_cents"`
+	MaxChargeCents          *int64  `json:"max_charge_cents"`
+	Methodology             *string `json:"methodology"`
+}
+
+// HospitalRef names the hospital of a price.
+type HospitalRef struct {
+	ID    int64   `json:"id"`
+	Name  string  `json:"name"`
+	State *string `json:"state"`
+}
+
+// Ref names a payer or plan.
+type Ref struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Hospital is a hospital with an active file version.
+type Hospital struct {
+	ID            int64      `json:"id"`
+	Name          string     `json:"name"`
+	Location      *string    `json:"location"`
+	Address       *string    `json:"address"`
+	LicenseNumber *string    `json:"license_number"`
+	State         *string    `json:"state"`
+	ActiveFile    ActiveFile `json:"active_file"`
+}
+
+// ActiveFile identifies the file version a hospital's prices come from.
+type ActiveFile struct {
+	ID            int64   `json:"id"`
+	LastUpdatedOn *string `json:"last_updated_on"` // YYYY-MM-DD
+}
+
+// File is one loaded version of a hospital's file, active or not.
+type File struct {
+	ID            int64     `json:"id"`
+	FileName      string    `json:"file_name"`
+	Version       *string   `json:"version"`
+	LastUpdatedOn *string   `json:"last_updated_on"` // YYYY-MM-DD
+	Status        string    `json:"status"`
+	Active        bool      `json:"active"`
+	ImportedAt    time.Time `json:"imported_at"`
+	RowsRead      *int64    `json:"rows_read"`
+	RowsStaged    *int64    `json:"rows_staged"`
+	RowsRejected  *int64    `json:"rows_rejected"`
+}
+
+// Payer is a payer with rates in an active file version.
+type Payer struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func (s *server) codePrices(w http.ResponseWriter, r *http.Request) {
+	ct, ok := model.CodeTypeByName(strings.ToUpper(r.PathValue("type")))
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("unknown code type %q", r.PathValue("type")))
+		return
+	}
+	code, _ := normalize.CanonicalizeCode(ct.Name, r.PathValue("code"))
+	qs := r.URL.Query()
+	limit, cur, ok := pageParams(w, qs)
+	if !ok {
+		return
+	}
+	params := sqlcgen.PageCodePricesParams{
+		CodeType: ct.Name,
+		CodeNorm: code,
+		State:    optParam(qs, "state"),
+		Plan:     optParam(qs, "plan"),
+		Setting:  optParam(qs, "setting"),
+		RowLimit: limit + 1,
+	}
+	if params.HospitalID, ok = idParam(w, qs, "hospital_id"); !ok {
+		return
+	}
+	if params.PayerID, ok = idParam(w, qs, "payer_id"); !ok {
+		return
+	}
+	if params.Setting != nil {
+		if st := strings.ToLower(*params.Setting); st != "inpatient" && st != "outpatient" {
+			writeError(w, http.StatusBadRequest, "invalid_parameter", "setting must be inpatient or outpatient")
+			return
+		}
+	}
+	if cur != nil {
+		if cur.Key == nil {
+			writeError(w, http.StatusBadRequest, "invalid_cursor", "cursor is not from this endpoint")
+			return
+		}
+		params.AfterCents, params.AfterID = cur.Key, &cur.ID
+	}
+	rows, err := s.q.PageCodePrices(r.Context(), params)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	page := Page[Price]{Data: make([]Price, 0, len(rows))}
+	if len(rows) > int(limit) {
+		last := rows[limit-1]
+		page.NextCursor = encodeCursor(cursor{Key: &last.SortCents, ID: last.PriceRowID})
+		rows = rows[:limit]
+	}
+	for _, row := range rows {
+		page.Data = append(page.Data, priceFromRow(row))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func priceFromRow(r *sqlcgen.PageCodePricesRow) Price {
+	p := Price{
+		ID:                      r.PriceRowID,
+		FileID:                  r.MrfFileID,
+		CodeType:                r.CodeType,
+		Code:                    r.CodeNorm,
+		CodeRaw:                 r.CodeRaw,
+		Description:             r.Description,
+		Hospital:                HospitalRef{ID: r.HospitalID, Name: r.HospitalName, State: r.LicenseState},
+		Setting:                 r.Setting,
+		BillingClass:            r.BillingClass,
+		Modifiers:               r.Modifiers,
+		PriceCents:              r.NegotiatedDollarCents,
+		GrossChargeCents:        r.GrossChargeCents,
+		DiscountedCashCents:     r.DiscountedCashCents,
+		NegotiatedDollarCents:   r.NegotiatedDollarCents,
+		NegotiatedPercentageBps: r.NegotiatedPercentageBps,
+		EstimatedAmountCents:    r.EstimatedAmountCents,
+		MinChargeCents:          r.MinChargeCents,
+		MaxChargeCents:          r.MaxChargeCents,
+		Methodology:             r.Methodology,
+	}
+	if p.PriceCents == nil {
+		p.PriceCents = r.GrossChargeCents
+	}
+	if r.PayerID != nil && r.PayerName != nil {
+		p.Payer = &Ref{ID: *r.PayerID, Name: *r.PayerName}
+	}
+	if r.PlanID != nil && r.PlanName != nil {
+		p.Plan = &Ref{ID: *r.PlanID, Name: *r.PlanName}
+	}
+	return p
+}
+
+func (s *server) hospitals(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	limit, cur, ok := pageParams(w, qs)
+	if !ok {
+		return
+	}
+	params := sqlcgen.PageHospitalsParams{
+		Name:     optParam(qs, "name"),
+		State:    optParam(qs, "state"),
+		RowLimit: limit + 1,
+	}
+	if cur != nil {
+		params.AfterID = &cur.ID
+	}
+	rows, err := s.q.PageHospitals(r.Context(), params)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	page := Page[Hospital]{Data: make([]Hospital, 0, len(rows))}
+	if len(rows) > int(limit) {
+		page.NextCursor = encodeCursor(cursor{ID: rows[limit-1].HospitalID})
+		rows = rows[:limit]
+	}
+	for _, row := range rows {
+		page.Data = append(page.Data, Hospital{
+			ID:            row.HospitalID,
+			Name:          row.HospitalName,
+			Location:      row.HospitalLocation,
+			Address:       row.HospitalAddress,
+			LicenseNumber: row.LicenseNumber,
+			State:         row.LicenseState,
+			ActiveFile:    ActiveFile{ID: row.ActiveMrfFileID, LastUpdatedOn: date(row.ActiveLastUpdatedOn)},
+		})
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *server) hospitalFiles(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_parameter", "hospital id must be an integer")
+		return
+	}
+	qs := r.URL.Query()
+	limit, cur, ok := pageParams(w, qs)
+	if !ok {
+		return
+	}
+	if _, err := s.q.GetHospital(r.Context(), id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("hospital %d not found", id))
+			return
+		}
+		s.internalError(w, r, err)
+		return
+	}
+	params := sqlcgen.PageHospitalFilesParams{HospitalID: id, RowLimit: limit + 1}
+	if cur != nil {
+		params.BeforeID = &cur.ID
+	}
+	rows, err := s.q.PageHospitalFiles(r.Context(), params)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	page := Page[File]{Data: make([]File, 0, len(rows))}
+	if len(rows) > int(limit) {
+		page.NextCursor = encodeCursor(cursor{ID: rows[limit-1].MrfFileID})
+		rows = rows[:limit]
+	}
+	for _, row := range rows {
+		page.Data = append(page.Data, File{
+			ID:            row.MrfFileID,
+			FileName:      row.SourceFileName,
+			Version:       row.Version,
+			LastUpdatedOn: date(row.LastUpdatedOn),
+			Status:        row.Status,
+			Active:        row.IsActive,
+			ImportedAt:    row.ImportedAt.Time,
+			RowsRead:      row.RowsRead,
+			RowsStaged:    row.RowsStaged,
+			RowsRejected:  row.RowsRejected,
+		})
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *server) payers(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	limit, cur, ok := pageParams(w, qs)
+	if !ok {
+		return
+	}
+	params := sqlcgen.PagePayersParams{Name: optParam(qs, "name"), RowLimit: limit + 1}
+	if cur != nil {
+		params.AfterID = &cur.ID
+	}
+	rows, err := s.q.PagePayers(r.Context(), params)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+	page := Page[Payer]{Data: make([]Payer, 0, len(rows))}
+	if len(rows) > int(limit) {
+		page.NextCursor = encodeCursor(cursor{ID: rows[limit-1].PayerID})
+		rows = rows[:limit]
+	}
+	for _, row := range rows {
+		page.Data = append(page.Data, Payer{ID: row.PayerID, Name: row.PayerName})
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// cursor is the keyset position after the last row of a page: the row's
+// sort key (prices only) and id. Clients see it as an opaque string.
+type cursor struct {
+	Key *int64 `json:"k,omitempty"`
+	ID  int64  `json:"i"`
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// pageParams parses ?limit= and ?cursor=, writing a 400 and returning
+// ok=false when either is malformed.
+func pageParams(w http.ResponseWriter, qs url.Values) (limit int32, cur *cursor, ok bool) {
+	limit = defaultLimit
+	if v := qs.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			writeError(w, http.StatusBadRequest, "invalid_parameter", fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+			return 0, nil, false
+		}
+		limit = int32(n)
+	}
+	if v := qs.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_cursor", "cursor is malformed")
+			return 0, nil, false
+		}
+		cur = &c
+	}
+	return limit, cur, true
+}
+
+// idParam parses an optional integer id parameter, writing a 400 and
+// returning ok=false when it is malformed.
+func idParam(w http.ResponseWriter, qs url.Values, name string) (*int64, bool) {
+	v := qs.Get(name)
+	if v == "" {
+		return nil, true
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_parameter", name+" must be an integer")
+		return nil, false
+	}
+	return &n, true
+}
+
+// optParam maps an absent or empty parameter to nil.
+func optParam(qs url.Values, name string) *string {
+	v := qs.Get(name)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+func date(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format(time.DateOnly)
+	return &s
+}
+
+type errorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	var body errorBody
+	body.Error.Code, body.Error.Message = code, msg
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (s *server) internalError(w http.ResponseWriter, r *http.Request, err error) {
+	s.log.Error().Err(err).Str("path", r.URL.Path).Msg("api query failed")
+	writeError(w, http.StatusInternalServerError, "internal", "internal error")
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (s *server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sr, r)
+		s.log.Debug().Str("method", r.Method).Str("path", r.URL.Path).Int("status", sr.status).
+			Dur("elapsed", time.Since(start)).Msg("request")
+	})
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/gyeh/pricestats/internal/config"
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/ingest"
+	"github.com/gyeh/pricestats/internal/logging"
+)
+
+// The ingest tests run their own server on 15432 at the same time.
+const (
+	testPort     = 15433
+	testDB       = "apitest"
+	testUser     = "postgres"
+	testPassword = "postgres"
+)
+
+var (
+	testDSN = fmt.Sprintf("postgresql://%s:%s@localhost:%d/%s?sslmode=disable",
+		testUser, testPassword, testPort, testDB)
+
+	pgOnce     sync.Once
+	pg         *embeddedpostgres.EmbeddedPostgres
+	pgStartErr error
+)
+
+// TestMain stops the embedded postgres if a test started it. It is started
+// on first use, so the tests that need no database run without one.
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if pg != nil && pgStartErr == nil {
+		if err := pg.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to stop embedded postgres: %v\n", err)
+		}
+	}
+	os.Exit(code)
+}
+
+// setupDB starts the embedded postgres once, then creates a connection
+// pool and applies migrations on clean schemas.
+func setupDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+
+	pgOnce.Do(func() {
+		pg = embeddedpostgres.NewDatabase(
+			embeddedpostgres.DefaultConfig().
+				Port(uint32(testPort)).
+				Database(testDB).
+				Username(testUser).
+				Password(testPassword).
+				Version(embeddedpostgres.V16).
+				RuntimePath(filepath.Join(os.TempDir(), "pricestats-api-pg")).
+				StartTimeout(30 * time.Second),
+		)
+		pgStartErr = pg.Start()
+	})
+	if pgStartErr != nil {
+		t.Fatalf("start embedded postgres: %v", pgStartErr)
+	}
+
+	pool, err := pgxpool.New(ctx, testDSN)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	for _, schema := range []string{"mrf", "ingest", "ref"} {
+		if _, err := pool.Exec(ctx, fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schema)); err != nil {
+			t.Fatalf("drop schema %s: %v", schema, err)
+		}
+	}
+	if err := db.ApplyMigrations(ctx, pool, logging.Setup("text")); err != nil {
+		pool.Close()
+		t.Fatalf("migrations: %v", err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func TestPageParams_Limit(t *testing.T) {
+	tests := []struct {
+		query  string
+		limit  int32
+		reject bool
+	}{
+		{query: "", limit: defaultLimit},
+		{query: "limit=1", limit: 1},
+		{query: "limit=1000", limit: maxLimit},
+		{query: "limit=0", reject: true},
+		{query: "limit=-1", reject: true},
+		{query: "limit=1001", reject: true},
+		{query: "limit=ten", reject: true},
+		{query: "limit=1.5", reject: true},
+		{query: "limit=99999999999999999999", reject: true},
+	}
+	for _, tt := range tests {
+		qs, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.query, err)
+		}
+		rec := httptest.NewRecorder()
+		limit, cur, ok := pageParams(rec, qs)
+		if tt.reject {
+			if ok {
+				t.Errorf("%q: accepted with limit %d", tt.query, limit)
+			} else {
+				checkError(t, tt.query, rec, "invalid_parameter")
+			}
+			continue
+		}
+		if !ok || limit != tt.limit || cur != nil || rec.Body.Len() != 0 {
+			t.Errorf("%q: got limit=%d cursor=%v ok=%v body=%q, want limit=%d", tt.query, limit, cur, ok, rec.Body, tt.limit)
+		}
+	}
+}
+
+func TestPageParams_Cursor(t *testing.T) {
+	key := int64(18000)
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		cursor string
+		want   *cursor
+	}{
+		{name: "id only", cursor: encodeCursor(cursor{ID: 7}), want: &cursor{ID: 7}},
+		{name: "key and id", cursor: encodeCursor(cursor{Key: &key, ID: 7}), want: &cursor{Key: &key, ID: 7}},
+		{name: "not base64", cursor: "!!"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"i":7}`))},
+		{name: "not JSON", cursor: raw("seven")},
+		{name: "truncated JSON", cursor: raw(`{"i":7`)},
+		{name: "string id", cursor: raw(`{"i":"7"}`)},
+		{name: "fractional key", cursor: raw(`{"k":1.5,"i":7}`)},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		limit, cur, ok := pageParams(rec, url.Values{"cursor": {tt.cursor}})
+		if tt.want == nil {
+			if ok {
+				t.Errorf("%s: accepted as %+v", tt.name, cur)
+			} else {
+				checkError(t, tt.name, rec, "invalid_cursor")
+			}
+			continue
+		}
+		if !ok || limit != defaultLimit || cur == nil || cur.ID != tt.want.ID ||
+			(cur.Key == nil) != (tt.want.Key == nil) || (cur.Key != nil && *cur.Key != *tt.want.Key) {
+			t.Errorf("%s: got %+v ok=%v, want %+v", tt.name, cur, ok, tt.want)
+		}
+	}
+}
+
+// checkError asserts a 400 with the given error code.
+func checkError(t *testing.T, name string, rec *httptest.ResponseRecorder, code string) {
+	t.Helper()
+	var body errorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s: decode error body %q: %v", name, rec.Body, err)
+	}
+	if rec.Code != http.StatusBadRequest || body.Error.Code != code {
+		t.Errorf("%s: got %d %q, want 400 %q", name, rec.Code, body.Error.Code, code)
+	}
+}
+
+// moneyCSV is a small CSV file whose 99215 row is rejected, so it loads as
+// partial.
+const moneyCSV = `hospital_name,last_updated_on,version,hospital_location,hospital_address
+Money Hospital,2024-07-01,2.0.0,Main Campus,1 Main St
+description,code|1,code|1|type,setting,standard_charge|gross,standard_charge|discounted_cash,standard_charge|min,standard_charge|max,payer_name,plan_name,standard_charge|negotiated_dollar,standard_charge|negotiated_percentage,standard_charge|negotiated_algorithm,standard_charge|methodology
+Office visit,99213,CPT,outpatient,250,200,90,240,,,,,,
+Follow-up visit,99214,CPT,outpatient,300,250,280,100,,,,,,
+Consult,99215,CPT,outpatient,-5,250,100,280,,,,,,
+`
+
+func TestServeAPI(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	// v1 prices 99213 for Cigna, v2 (active) for Aetna instead.
+	dir := t.TempDir()
+	payerRow := "Office visit,99213,CPT,outpatient,250,200,90,240,%s,PPO,180,,,fee schedule\n"
+	var fileIDs []int64
+	for i, payer := range []string{"Cigna", "Aetna"} {
+		path := fmt.Sprintf("%s/v%d.csv", dir, i+1)
+		if err := os.WriteFile(path, []byte(moneyCSV+fmt.Sprintf(payerRow, payer)), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		cfg := &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", ActivateVersion: true}
+		summary, err := ingest.Run(ctx, pool, log, cfg)
+		if err != nil {
+			t.Fatalf("ingest v%d: %v", i+1, err)
+		}
+		fileIDs = append(fileIDs, summary.MRFFileID)
+	}
+
+	srv := httptest.NewServer(New(pool, log))
+	defer srv.Close()
+	get := func(path string, wantStatus int, out any) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != wantStatus {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("GET %s: status %d, want %d: %s", path, resp.StatusCode, wantStatus, body)
+		}
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatalf("GET %s: decode: %v", path, err)
+			}
+		}
+	}
+
+	// Prices: active version only, cheapest first, one per page
+	var prices1, prices2 Page[Price]
+	get("/v1/codes/cpt/99213/prices?limit=1", http.StatusOK, &prices1)
+	if len(prices1.Data) != 1 || prices1.Data[0].Payer == nil || prices1.Data[0].Payer.Name != "Aetna" ||
+		*prices1.Data[0].PriceCents != 18000 || prices1.Data[0].FileID != fileIDs[1] || prices1.NextCursor == "" {
+		t.Fatalf("first page: %+v", prices1)
+	}
+	get("/v1/codes/cpt/99213/prices?limit=1&cursor="+prices1.NextCursor, http.StatusOK, &prices2)
+	if len(prices2.Data) != 1 || prices2.Data[0].Payer != nil || *prices2.Data[0].PriceCents != 25000 || prices2.NextCursor != "" {
+		t.Fatalf("second page: %+v", prices2)
+	}
+	var filtered Page[Price]
+	get(fmt.Sprintf("/v1/codes/CPT/99213/prices?payer_id=%d", prices1.Data[0].Payer.ID), http.StatusOK, &filtered)
+	if len(filtered.Data) != 1 || filtered.Data[0].ID != prices1.Data[0].ID {
+		t.Errorf("payer filter: %+v", filtered)
+	}
+
+	var hospitals Page[Hospital]
+	get("/v1/hospitals?name=money", http.StatusOK, &hospitals)
+	if len(hospitals.Data) != 1 || hospitals.Data[0].ActiveFile.ID != fileIDs[1] ||
+		hospitals.Data[0].ActiveFile.LastUpdatedOn == nil || *hospitals.Data[0].ActiveFile.LastUpdatedOn != "2024-07-01" {
+		t.Fatalf("hospitals: %+v", hospitals)
+	}
+
+	// Files: every version, newest first
+	var files1, files2 Page[File]
+	base := fmt.Sprintf("/v1/hospitals/%d/files", hospitals.Data[0].ID)
+	get(base+"?limit=1", http.StatusOK, &files1)
+	if len(files1.Data) != 1 || files1.Data[0].ID != fileIDs[1] || !files1.Data[0].Active || files1.NextCursor == "" {
+		t.Fatalf("files page 1: %+v", files1)
+	}
+	get(base+"?limit=1&cursor="+files1.NextCursor, http.StatusOK, &files2)
+	if len(files2.Data) != 1 || files2.Data[0].ID != fileIDs[0] || files2.Data[0].Active || files2.NextCursor != "" {
+		t.Fatalf("files page 2: %+v", files2)
+	}
+
+	var payers Page[Payer]
+	get("/v1/payers", http.StatusOK, &payers)
+	if len(payers.Data) != 1 || payers.Data[0].Name != "Aetna" {
+		t.Errorf("payers should only list the active version's: %+v", payers)
+	}
+
+	get("/v1/codes/nope/99213/prices", http.StatusNotFound, nil)
+	get("/v1/hospitals/999999/files", http.StatusNotFound, nil)
+	get("/v1/hospitals?limit=0", http.StatusBadRequest, nil)
+	get("/v1/payers?cursor=%21%21", http.StatusBadRequest, nil)
+	get("/v1/codes/cpt/99213/prices?cursor="+files1.NextCursor, http.StatusBadRequest, nil)
+}
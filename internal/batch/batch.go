@@ -0,0 +1,228 @@
+// Package batch runs the ingest pipeline over many MRF files: every file in
+// a directory, the matches of a glob, or the entries of a manifest.
+package batch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/gyeh/pricestats/internal/config"
+	"github.com/gyeh/pricestats/internal/ingest"
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// Entry is one file to ingest with its per-file overrides of the base config.
+type Entry struct {
+	File            string   `yaml:"file"`
+	HospitalName    string   `yaml:"hospital_name"`
+	ActivateVersion *bool    `yaml:"activate_version"`
+	CodeTypes       []string `yaml:"code_types"`
+}
+
+// Config returns base with the entry's file and overrides applied.
+func (e Entry) Config(base config.Config) config.Config {
+	c := base
+	c.FilePath = e.File
+	if e.HospitalName != "" {
+		c.HospitalName = e.HospitalName
+	}
+	if e.ActivateVersion != nil {
+		c.ActivateVersion = *e.ActivateVersion
+	}
+	if len(e.CodeTypes) > 0 {
+		c.CodeTypes = e.CodeTypes
+	}
+	return c
+}
+
+// manifest is the on-disk YAML structure of a batch manifest.
+type manifest struct {
+	Files []Entry `yaml:"files"`
+}
+
+// LoadManifest reads a YAML manifest. Relative file paths are resolved
+// against the manifest's directory.
+func LoadManifest(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	var m manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	if len(m.Files) == 0 {
+		return nil, fmt.Errorf("manifest %s lists no files", path)
+	}
+	dir := filepath.Dir(path)
+	for i := range m.Files {
+		e := &m.Files[i]
+		if e.File == "" {
+			return nil, fmt.Errorf("manifest entry %d: file is required", i+1)
+		}
+		if !filepath.IsAbs(e.File) {
+			e.File = filepath.Join(dir, e.File)
+		}
+		for _, name := range e.CodeTypes {
+			if _, ok := model.CodeTypeByName(name); !ok {
+				return nil, fmt.Errorf("manifest entry %d (%s): unknown code type %q", i+1, e.File, name)
+			}
+		}
+	}
+	return m.Files, nil
+}
+
+// mrfExtensions are the file extensions FromDir picks up.
+var mrfExtensions = map[string]bool{".parquet": true, ".json": true, ".csv": true}
+
+// FromDir returns an entry for every Parquet, JSON or CSV file directly in
+// dir, sorted by name. Subdirectories are not searched.
+func FromDir(dir string) ([]Entry, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read dir: %w", err)
+	}
+	var entries []Entry
+	for _, de := range des {
+		if !de.Type().IsRegular() || !mrfExtensions[strings.ToLower(filepath.Ext(de.Name()))] {
+			continue
+		}
+		entries = append(entries, Entry{File: filepath.Join(dir, de.Name())})
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no .parquet, .json or .csv files in %s", dir)
+	}
+	return entries, nil
+}
+
+// FromGlob returns an entry for every regular file matching pattern, sorted.
+func FromGlob(pattern string) ([]Entry, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("glob %q: %w", pattern, err)
+	}
+	sort.Strings(matches)
+	var entries []Entry
+	for _, m := range matches {
+		if st, err := os.Stat(m); err == nil && st.Mode().IsRegular() {
+			entries = append(entries, Entry{File: m})
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no files match %q", pattern)
+	}
+	return entries, nil
+}
+
+// Result is the outcome of one file. A Result with neither Summary nor Err
+// was never started because an earlier file failed under fail-fast.
+type Result struct {
+	Entry   Entry
+	Summary *model.IngestSummary
+	Err     error
+}
+
+// Started reports whether the file was attempted.
+func (r Result) Started() bool {
+	return r.Summary != nil || r.Err != nil
+}
+
+// Options controls a batch run.
+type Options struct {
+	// Parallel is the number of files ingested at once; values below 2 run
+	// the files one after another.
+	Parallel int
+	// FailFast stops starting new files after the first failure. Files
+	// already running finish.
+	FailFast bool
+}
+
+// Run ingests entries with ingest.Run, each with base plus the entry's
+// overrides, using up to opts.Parallel workers over the shared pool. A failed
+// file does not stop the others unless opts.FailFast is set. ANALYZE runs
+// once after the last file instead of per file. One Result is returned per
+// entry, in order.
+func Run(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, base config.Config, entries []Entry, opts Options) []Result {
+	results := make([]Result, len(entries))
+	for i, e := range entries {
+		results[i].Entry = e
+	}
+	workers := opts.Parallel
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(entries) {
+		workers = len(entries)
+	}
+	base.SkipAnalyze = true
+
+	var stopped atomic.Bool
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// The dispatcher may hand out one more job before it sees the stop
+				if stopped.Load() || ctx.Err() != nil {
+					continue
+				}
+				results[i].Summary, results[i].Err = runOne(ctx, pool, log, base, entries[i], i, len(entries))
+				if results[i].Err != nil && opts.FailFast {
+					stopped.Store(true)
+				}
+			}
+		}()
+	}
+	for i := range entries {
+		if stopped.Load() || ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, r := range results {
+		if ctx.Err() != nil {
+			break
+		}
+		if r.Summary != nil && r.Summary.Outcome != model.OutcomeSkipped && r.Err == nil {
+			if err := ingest.Analyze(ctx, sqlcgen.New(pool), log); err != nil {
+				log.Warn().Err(err).Msg("batch: ANALYZE failed (non-fatal)")
+			}
+			break
+		}
+	}
+	return results
+}
+
+// runOne ingests a single entry with a logger tagged by file name.
+func runOne(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, base config.Config, e Entry, i, n int) (*model.IngestSummary, error) {
+	cfg := e.Config(base)
+	flog := log.With().Str("file", filepath.Base(e.File)).Logger()
+	flog.Info().Int("index", i+1).Int("of", n).Msg("batch: starting file")
+
+	if err := cfg.Validate(); err != nil {
+		err = &ingest.PipelineError{Phase: "preflight", Err: err}
+		flog.Error().Err(err).Msg("batch: file failed")
+		return nil, err
+	}
+	summary, err := ingest.Run(ctx, pool, flog, &cfg)
+	if err != nil {
+		flog.Error().Err(err).Msg("batch: file failed")
+	}
+	return summary, err
+}
@@ -0,0 +1,115 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gyeh/pricestats/internal/config"
+)
+
+func touch(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.yaml")
+	os.WriteFile(path, []byte(`files:
+  - file: a.parquet
+    hospital_name: General Hospital
+    activate_version: false
+    code_types: [CPT, NDC]
+  - file: /abs/b.csv
+`), 0644)
+
+	entries, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].File != filepath.Join(dir, "a.parquet") || entries[1].File != "/abs/b.csv" {
+		t.Errorf("paths not resolved against manifest dir: %s, %s", entries[0].File, entries[1].File)
+	}
+
+	base := config.Defaults()
+	base.ActivateVersion = true
+	base.CodeTypes = []string{"CPT", "HCPCS", "NDC"}
+	c := entries[0].Config(base)
+	if c.FilePath != entries[0].File || c.HospitalName != "General Hospital" || c.ActivateVersion || len(c.CodeTypes) != 2 {
+		t.Errorf("overrides not applied: %+v", c)
+	}
+	c = entries[1].Config(base)
+	if !c.ActivateVersion || len(c.CodeTypes) != 3 || c.HospitalName != "" {
+		t.Errorf("entry without overrides should keep base: %+v", c)
+	}
+}
+
+func TestLoadManifest_Invalid(t *testing.T) {
+	for name, body := range map[string]string{
+		"empty":        "files: []\n",
+		"missing_file": "files:\n  - hospital_name: X\n",
+		"bad_code":     "files:\n  - file: a.csv\n    code_types: [BOGUS]\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "manifest.yaml")
+			os.WriteFile(path, []byte(body), 0644)
+			if _, err := LoadManifest(path); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestFromDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.csv", "a.parquet", "c.JSON", "notes.txt"} {
+		touch(t, filepath.Join(dir, name))
+	}
+	os.Mkdir(filepath.Join(dir, "sub.csv"), 0755)
+
+	entries, err := FromDir(dir)
+	if err != nil {
+		t.Fatalf("FromDir: %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, filepath.Base(e.File))
+	}
+	want := []string{"a.parquet", "b.csv", "c.JSON"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	if _, err := FromDir(t.TempDir()); err == nil {
+		t.Error("expected error for a directory without MRF files")
+	}
+}
+
+func TestFromGlob(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, filepath.Join(dir, "h2.csv"))
+	touch(t, filepath.Join(dir, "h1.csv"))
+	touch(t, filepath.Join(dir, "other.parquet"))
+
+	entries, err := FromGlob(filepath.Join(dir, "h*.csv"))
+	if err != nil {
+		t.Fatalf("FromGlob: %v", err)
+	}
+	if len(entries) != 2 || filepath.Base(entries[0].File) != "h1.csv" {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+	if _, err := FromGlob(filepath.Join(dir, "*.json")); err == nil {
+		t.Error("expected error when nothing matches")
+	}
+}
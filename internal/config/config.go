@@ -1,47 +1,248 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/normalize"
 
 	"gopkg.in/yaml.v3"
 )
 
-// Config holds all runtime configuration for a mrfload run.
+// Config holds all runtime configuration for a mrfload run. Every field can
+// be set from the YAML config file (key in the yaml tag), the environment
+// (see LoadEnv) and, for most, a command-line flag. Precedence, lowest first:
+// Defaults, config file, selected profile, environment, flags.
 type Config struct {
-	DSN                string
-	FilePath           string
-	HospitalName       string
-	LogFormat          string // "text" or "json"
-	ActivateVersion    bool
-	Force              bool
-	KeepStaging        bool
-	DryRun             bool
-	IncludePayerPrices bool     // opt-in: include payer/plan names and negotiated price fields
-	CodeTypes          []string `yaml:"code_types"` // subset of AllCodeTypes to process
+	DSN                string                `yaml:"dsn"`
+	FilePath           string                `yaml:"file"`
+	HospitalName       string                `yaml:"hospital_name"`
+	LogFormat          string                `yaml:"log_format"` // "text" or "json"
+	ActivateVersion    bool                  `yaml:"activate_version"`
+	Force              bool                  `yaml:"force"`
+	Resume             bool                  `yaml:"resume"` // reuse an interrupted run's intact staging rows
+	KeepStaging        bool                  `yaml:"keep_staging"`
+	DryRun             bool                  `yaml:"dry_run"`
+	IncludePayerPrices bool                  `yaml:"include_payer_prices"` // opt-in: include payer/plan names and negotiated price fields
+	CodeTypes          []string              `yaml:"code_types"`           // subset of AllCodeTypes to process
+	BatchSize          int                   `yaml:"batch_size"`           // source rows read per batch; 0 = 1024
+	ChunkRows          int                   `yaml:"chunk_rows"`           // source rows committed per staging transaction; 0 = 20000. Two chunks are buffered, ~1-2 KB per row
+	CopyRetries        int                   `yaml:"copy_retries"`         // retries of a staging chunk after a connection-class error
+	CopyRetryBackoff   time.Duration         `yaml:"copy_retry_backoff"`   // wait before the first retry, doubling per retry
+	MaxConns           int32                 `yaml:"max_conns"`            // connection pool size; 0 = pgx default
+	Parallel           int                   `yaml:"parallel"`             // files ingested at once in batch mode; 0 = 1
+	LockTimeout        time.Duration         `yaml:"lock_timeout"`         // wait for another ingest's hospital lock; 0 = fail at once
+	RejectPolicy       RejectPolicy          `yaml:"reject_policy"`
+	Retention          RetentionPolicy       `yaml:"retention"`     // what mrfload prune removes
+	MoneyRules         normalize.MoneyPolicy `yaml:"money_rules"`   // zero value = normalize.DefaultMoneyPolicy
+	MarketBasket       MarketBasket          `yaml:"market_basket"` // what mrfload index prices hospitals on
+
+	// Profile selects a named block under profiles: in the config file.
+	Profile string `yaml:"-"`
+	// SkipAnalyze leaves ANALYZE out of Finalize; batch runs analyze once at the end.
+	SkipAnalyze bool `yaml:"-"`
+}
+
+// Defaults returns the configuration used before any file, environment or
+// flag is applied.
+func Defaults() Config {
+	return Config{
+		LogFormat:        "text",
+		BatchSize:        1024,
+		ChunkRows:        20_000,
+		CopyRetries:      5,
+		CopyRetryBackoff: time.Second,
+		MoneyRules:       normalize.DefaultMoneyPolicy(),
+	}
+}
+
+// RejectPolicy decides the outcome of a run from the number of problem rows
+// (staging rejects plus rows without any code) relative to RowsRead. A zero
+// limit is disabled. The zero value configures no limit at all and keeps
+// every run a full success, with the problem rows only reported; once any
+// limit is set, problem rows beyond the tolerance make the run partial.
+type RejectPolicy struct {
+	// TolerateRejects / TolerateRejectPct: at or under either limit the run
+	// still counts as a full success.
+	TolerateRejects   int64   `yaml:"tolerate_rejects"`
+	TolerateRejectPct float64 `yaml:"tolerate_reject_pct"`
+	// MaxRejects / MaxRejectPct: over either limit the run fails and its
+	// serving rows are rolled back.
+	MaxRejects   int64   `yaml:"max_rejects"`
+	MaxRejectPct float64 `yaml:"max_reject_pct"`
+}
+
+// Evaluate classifies a run with bad problem rows out of read source rows,
+// returning the outcome and a human-readable reason.
+func (p RejectPolicy) Evaluate(bad, read int64) (model.Outcome, string) {
+	if bad == 0 {
+		return model.OutcomeSuccess, ""
+	}
+	var pct float64
+	if read > 0 {
+		pct = float64(bad) / float64(read) * 100
+	}
+	if p == (RejectPolicy{}) {
+		return model.OutcomeSuccess, fmt.Sprintf("%d problem rows (%.2f%%); no reject policy configured", bad, pct)
+	}
+
+	if p.MaxRejects > 0 && bad > p.MaxRejects {
+		return model.OutcomeFailed, fmt.Sprintf("%d problem rows exceed max_rejects %d", bad, p.MaxRejects)
+	}
+	if p.MaxRejectPct > 0 && pct > p.MaxRejectPct {
+		return model.OutcomeFailed, fmt.Sprintf("%.2f%% problem rows exceed max_reject_pct %.2f%%", pct, p.MaxRejectPct)
+	}
+	if (p.TolerateRejects > 0 && bad <= p.TolerateRejects) ||
+		(p.TolerateRejectPct > 0 && pct <= p.TolerateRejectPct) {
+		return model.OutcomeSuccess, fmt.Sprintf("%d problem rows (%.2f%%) within tolerance", bad, pct)
+	}
+	return model.OutcomePartial, fmt.Sprintf("%d problem rows (%.2f%%)", bad, pct)
+}
+
+// validate rejects negative limits.
+func (p RejectPolicy) validate() error {
+	if p.TolerateRejects < 0 || p.TolerateRejectPct < 0 || p.MaxRejects < 0 || p.MaxRejectPct < 0 {
+		return fmt.Errorf("reject policy limits must not be negative")
+	}
+	return nil
 }
 
-// yamlConfig is the on-disk YAML structure.
-type yamlConfig struct {
-	CodeTypes []string `yaml:"code_types"`
+// RetentionPolicy decides which superseded file versions and failed
+// attempts mrfload prune removes. A superseded version is an inactive,
+// activatable one imported before its hospital's active version, so the
+// active version and newer loads not yet activated are always kept; one is
+// dropped when either version rule selects it. Zero disables a rule.
+type RetentionPolicy struct {
+	// KeepVersions is how many superseded versions to keep per hospital,
+	// newest first.
+	KeepVersions int `yaml:"keep_versions"`
+	// MaxAgeDays drops superseded versions imported longer ago.
+	MaxAgeDays int `yaml:"max_age_days"`
+	// FailedMaxAgeDays drops failed and cancelled attempts imported longer
+	// ago. The version rules never count or select them.
+	FailedMaxAgeDays int `yaml:"failed_max_age_days"`
+	// DeleteBatchRows is the number of serving rows removed per DELETE; 0 = 50000.
+	DeleteBatchRows int `yaml:"delete_batch_rows"`
+	// Vacuum runs VACUUM (ANALYZE) on the partitions rows were removed from.
+	Vacuum bool `yaml:"vacuum"`
 }
 
-// LoadFromFile reads a YAML config file and merges its values into Config.
+// Enabled reports whether any rule is set.
+func (p RetentionPolicy) Enabled() bool {
+	return p.KeepVersions > 0 || p.MaxAgeDays > 0 || p.FailedMaxAgeDays > 0
+}
+
+// validate rejects negative values.
+func (p RetentionPolicy) validate() error {
+	if p.KeepVersions < 0 || p.MaxAgeDays < 0 || p.FailedMaxAgeDays < 0 || p.DeleteBatchRows < 0 {
+		return fmt.Errorf("retention keep_versions, max_age_days, failed_max_age_days and delete_batch_rows must not be negative")
+	}
+	return nil
+}
+
+// MarketBasket is the list of services mrfload index ranks hospitals on,
+// e.g. common shoppable services. Index results are stored under Name, so
+// rename the basket when its items change.
+type MarketBasket struct {
+	Name  string       `yaml:"name"`
+	Items []BasketItem `yaml:"items"`
+}
+
+// BasketItem is one service of a MarketBasket. Weight is its relative
+// share of the index, e.g. how often the service is bought.
+type BasketItem struct {
+	CodeType string  `yaml:"code_type"`
+	Code     string  `yaml:"code"`
+	Weight   float64 `yaml:"weight"`
+}
+
+// validate checks the items of a non-empty basket. Codes must be valid for
+// their type and are compared in canonical form, so "MS-DRG 1" and
+// "MS-DRG 001" count as the same item.
+func (b MarketBasket) validate() error {
+	if len(b.Items) == 0 {
+		return nil
+	}
+	if b.Name == "" {
+		return fmt.Errorf("market_basket name is required")
+	}
+	seen := map[BasketItem]string{}
+	for _, it := range b.Items {
+		ct, ok := model.CodeTypeByName(it.CodeType)
+		if !ok {
+			return fmt.Errorf("market_basket: unknown code type %q", it.CodeType)
+		}
+		if it.Code == "" {
+			return fmt.Errorf("market_basket: %s item without a code", it.CodeType)
+		}
+		if !(it.Weight > 0) {
+			return fmt.Errorf("market_basket: %s %s weight must be positive", it.CodeType, it.Code)
+		}
+		code, valid := normalize.CanonicalizeCode(ct.Name, it.Code)
+		if !valid {
+			return fmt.Errorf("market_basket: %q is not a valid %s code", it.Code, ct.Name)
+		}
+		key := BasketItem{CodeType: ct.Name, Code: code}
+		if first, dup := seen[key]; dup {
+			return fmt.Errorf("market_basket: %s %s and %s are the same code", ct.Name, first, it.Code)
+		}
+		seen[key] = it.Code
+	}
+	return nil
+}
+
+// fileConfig is the on-disk YAML structure: Config keys at the top level
+// plus named profiles, each holding Config keys that override them.
+type fileConfig struct {
+	Config   `yaml:",inline"`
+	Profiles map[string]yaml.Node `yaml:"profiles"`
+}
+
+// LoadFromFile reads a YAML config file and merges its values into Config:
+// keys present in the file replace the current values, absent keys are left
+// alone. When c.Profile is set, that profile is merged on top. Unknown keys
+// are an error so typos don't silently fall back to defaults.
 func (c *Config) LoadFromFile(path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("read config file: %w", err)
 	}
-	var yc yamlConfig
-	if err := yaml.Unmarshal(data, &yc); err != nil {
+	fc := fileConfig{Config: *c}
+	if err := decodeStrict(data, &fc); err != nil {
 		return fmt.Errorf("parse config file: %w", err)
 	}
-	c.CodeTypes = yc.CodeTypes
+	*c = fc.Config
+
+	if c.Profile != "" {
+		node, ok := fc.Profiles[c.Profile]
+		if !ok {
+			return fmt.Errorf("profile %q not defined in %s", c.Profile, path)
+		}
+		data, err := yaml.Marshal(&node)
+		if err != nil {
+			return fmt.Errorf("profile %q: %w", c.Profile, err)
+		}
+		if err := decodeStrict(data, c); err != nil {
+			return fmt.Errorf("parse profile %q: %w", c.Profile, err)
+		}
+	}
 	return c.validateCodeTypes()
 }
 
+// decodeStrict unmarshals YAML into out, rejecting unknown keys.
+func decodeStrict(data []byte, out any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(out); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
 // validateCodeTypes checks that every entry in CodeTypes is a known code type name.
 // If CodeTypes is empty, it defaults to all AllCodeTypes names.
 func (c *Config) validateCodeTypes() error {
@@ -68,7 +269,34 @@ func (c *Config) Validate() error {
 	if _, err := os.Stat(c.FilePath); err != nil {
 		return fmt.Errorf("file not accessible: %w", err)
 	}
-	return nil
+	return c.Check()
+}
+
+// Check validates the settings that don't depend on the input file and
+// fills CodeTypes with every registered type when none are selected.
+func (c *Config) Check() error {
+	if c.LogFormat != "" && c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("log format must be text or json, got %q", c.LogFormat)
+	}
+	if c.BatchSize < 0 || c.MaxConns < 0 || c.Parallel < 0 || c.LockTimeout < 0 {
+		return fmt.Errorf("batch_size, max_conns, parallel and lock_timeout must not be negative")
+	}
+	if c.ChunkRows < 0 || c.CopyRetries < 0 || c.CopyRetryBackoff < 0 {
+		return fmt.Errorf("chunk_rows, copy_retries and copy_retry_backoff must not be negative")
+	}
+	if err := c.validateCodeTypes(); err != nil {
+		return err
+	}
+	if err := c.RejectPolicy.validate(); err != nil {
+		return err
+	}
+	if err := c.Retention.validate(); err != nil {
+		return err
+	}
+	if err := c.MarketBasket.validate(); err != nil {
+		return err
+	}
+	return c.MoneyRules.Validate()
 }
 
 // ValidateWithDSN checks both file and DSN fields.
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix starts the environment variable for every config key: the
+// prefix plus the upper-cased YAML path, e.g. MRFLOAD_LOG_FORMAT or
+// MRFLOAD_REJECT_POLICY_MAX_REJECTS. List values are comma-separated.
+const EnvPrefix = "MRFLOAD_"
+
+// LoadEnv merges values from the environment into Config. lookup is
+// os.LookupEnv outside tests. DATABASE_URL is accepted for the DSN, with
+// MRFLOAD_DSN taking precedence. Map-valued keys (money_rules.severities)
+// and market_basket.items are file-only.
+func (c *Config) LoadEnv(lookup func(string) (string, bool)) error {
+	if v, ok := lookup("DATABASE_URL"); ok && v != "" {
+		c.DSN = v
+	}
+	return loadEnvStruct(reflect.ValueOf(c).Elem(), EnvPrefix, lookup)
+}
+
+func loadEnvStruct(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + strings.ToUpper(key)
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct {
+			if err := loadEnvStruct(f, name+"_", lookup); err != nil {
+				return err
+			}
+			continue
+		}
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setFromEnv(f, raw); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setFromEnv parses raw into f according to its kind.
+func setFromEnv(f reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	if f.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		f.SetFloat(x)
+	case reflect.Slice:
+		if f.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		var items []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+		f.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
+
+// keywordPassword matches password=... in a keyword/value DSN.
+var keywordPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// MaskDSN hides the password in a URL or keyword/value connection string.
+func MaskDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+		q := u.Query()
+		if q.Has("password") {
+			q.Set("password", "xxxxx")
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	return keywordPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
+
+// Redacted returns a copy of c that is safe to print.
+func (c Config) Redacted() Config {
+	c.DSN = MaskDSN(c.DSN)
+	return c
+}
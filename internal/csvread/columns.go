@@ -0,0 +1,153 @@
+package csvread
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gyeh/pricestats/internal/model"
+)
+
+// columnMap indexes the charge header row.
+type columnMap struct {
+	// fields maps a plain header (e.g. "description", "standard_charge|gross")
+	// to its column index.
+	fields map[string]int
+	// codeCols holds the "code|N" / "code|N|type" column pairs, ordered by N.
+	codeCols []codeColumn
+	// payerPlans holds the payer/plan-specific column groups of the wide layout.
+	payerPlans []*payerPlanColumns
+}
+
+type codeColumn struct {
+	code, typ int
+}
+
+// payerPlanColumns groups the wide-layout columns for one payer/plan pair.
+// A field not present in the file has index -1.
+type payerPlanColumns struct {
+	payer, plan          string
+	negotiatedDollar     int
+	negotiatedPercentage int
+	negotiatedAlgorithm  int
+	methodology          int
+	estimatedAmount      int
+	additionalPayerNotes int
+}
+
+func (pp *payerPlanColumns) hasValues(record []string) bool {
+	for _, i := range []int{pp.negotiatedDollar, pp.negotiatedPercentage, pp.negotiatedAlgorithm, pp.estimatedAmount} {
+		if strings.TrimSpace(cell(record, i)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// mapColumns indexes the raw (as published) charge headers.
+func mapColumns(headers []string) columnMap {
+	m := columnMap{fields: make(map[string]int)}
+	codes := make(map[int]*codeColumn)
+	plans := make(map[[2]string]*payerPlanColumns)
+
+	planFor := func(payer, plan string) *payerPlanColumns {
+		key := [2]string{payer, plan}
+		pp, ok := plans[key]
+		if !ok {
+			pp = &payerPlanColumns{
+				payer: payer, plan: plan,
+				negotiatedDollar: -1, negotiatedPercentage: -1, negotiatedAlgorithm: -1,
+				methodology: -1, estimatedAmount: -1, additionalPayerNotes: -1,
+			}
+			plans[key] = pp
+			m.payerPlans = append(m.payerPlans, pp)
+		}
+		return pp
+	}
+
+	for i, raw := range headers {
+		raw = strings.TrimSpace(raw)
+		h := strings.ToLower(raw)
+		parts := strings.Split(h, "|")
+		// Payer and plan names keep their published casing.
+		rawParts := strings.Split(raw, "|")
+		switch {
+		case parts[0] == "code" && len(parts) >= 2:
+			n, err := strconv.Atoi(parts[1])
+			if err != nil {
+				continue
+			}
+			cc, ok := codes[n]
+			if !ok {
+				cc = &codeColumn{code: -1, typ: -1}
+				codes[n] = cc
+			}
+			if len(parts) == 3 && parts[2] == "type" {
+				cc.typ = i
+			} else {
+				cc.code = i
+			}
+		case parts[0] == "standard_charge" && len(parts) == 4:
+			pp := planFor(strings.TrimSpace(rawParts[1]), strings.TrimSpace(rawParts[2]))
+			switch parts[3] {
+			case "negotiated_dollar":
+				pp.negotiatedDollar = i
+			case "negotiated_percentage":
+				pp.negotiatedPercentage = i
+			case "negotiated_algorithm":
+				pp.negotiatedAlgorithm = i
+			case "methodology":
+				pp.methodology = i
+			}
+		case parts[0] == "estimated_amount" && len(parts) == 3:
+			planFor(strings.TrimSpace(rawParts[1]), strings.TrimSpace(rawParts[2])).estimatedAmount = i
+		case parts[0] == "additional_payer_notes" && len(parts) == 3:
+			planFor(strings.TrimSpace(rawParts[1]), strings.TrimSpace(rawParts[2])).additionalPayerNotes = i
+		default:
+			m.fields[h] = i
+		}
+	}
+
+	nums := make([]int, 0, len(codes))
+	for n := range codes {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	for _, n := range nums {
+		m.codeCols = append(m.codeCols, *codes[n])
+	}
+	return m
+}
+
+func (m columnMap) index(name string) int {
+	if i, ok := m.fields[name]; ok {
+		return i
+	}
+	return -1
+}
+
+func (m columnMap) str(record []string, name string) string {
+	return strings.TrimSpace(cell(record, m.index(name)))
+}
+
+func (m columnMap) optStr(record []string, name string) *string {
+	return optCell(record, m.index(name))
+}
+
+// amount parses the amount under header name as the row's column.
+func (m columnMap) amount(row *model.HospitalChargeRow, record []string, name, column string) *float64 {
+	return parseAmount(row, column, cell(record, m.index(name)))
+}
+
+// codes returns the non-empty code/type pairs of a record in column order.
+func (m columnMap) codes(record []string) []model.CodeEntry {
+	var out []model.CodeEntry
+	for _, cc := range m.codeCols {
+		code := strings.TrimSpace(cell(record, cc.code))
+		if code == "" {
+			continue
+		}
+		out = append(out, model.CodeEntry{Type: cell(record, cc.typ), Code: code})
+	}
+	return out
+}
@@ -0,0 +1,314 @@
+package csvread
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gyeh/pricestats/internal/model"
+)
+
+// Layout is the CMS CSV template variant.
+type Layout string
+
+const (
+	// LayoutTall has one row per item/payer/plan with payer_name and plan_name columns.
+	LayoutTall Layout = "tall"
+	// LayoutWide has one row per item with payer/plan encoded in the column headers,
+	// e.g. "standard_charge|Aetna|PPO|negotiated_dollar".
+	LayoutWide Layout = "wide"
+)
+
+// Reader streams HospitalChargeRow records from a CMS CSV template file.
+// The first two lines hold the hospital metadata headers and values; the
+// third line holds the charge column headers.
+type Reader struct {
+	file   *os.File
+	csv    *csv.Reader
+	layout Layout
+
+	metaHeaders []string
+	headers     []string
+	meta        model.HospitalChargeRow // hospital metadata copied into every row
+	cols        columnMap
+
+	pending []model.HospitalChargeRow
+	done    bool
+}
+
+// Open opens a CMS CSV file, parses the metadata and charge header rows, and
+// detects whether it uses the tall or wide layout.
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open csv file: %w", err)
+	}
+
+	br := bufio.NewReaderSize(f, 1<<20)
+	if bom, _ := br.Peek(3); string(bom) == "\xef\xbb\xbf" {
+		br.Discard(3)
+	}
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+
+	r := &Reader{file: f, csv: cr}
+	if err := r.readHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reader) readHeader() error {
+	metaHeaders, err := r.csv.Read()
+	if err != nil {
+		return fmt.Errorf("read csv metadata headers: %w", err)
+	}
+	r.metaHeaders = normalizeHeaders(metaHeaders)
+
+	metaValues, err := r.csv.Read()
+	if err != nil {
+		return fmt.Errorf("read csv metadata values: %w", err)
+	}
+	r.meta = parseMetadata(r.metaHeaders, metaValues)
+
+	headers, err := r.csv.Read()
+	if err != nil {
+		return fmt.Errorf("read csv charge headers: %w", err)
+	}
+	r.cols = mapColumns(headers)
+	r.headers = normalizeHeaders(headers)
+	r.layout = LayoutWide
+	if _, ok := r.cols.fields["payer_name"]; ok {
+		r.layout = LayoutTall
+	}
+	return nil
+}
+
+// Layout returns the detected template variant.
+func (r *Reader) Layout() Layout {
+	return r.layout
+}
+
+// NumRows returns -1: the row count of a CSV file is only known after a full pass.
+func (r *Reader) NumRows() int64 {
+	return -1
+}
+
+// Read reads up to len(rows) records into the provided slice.
+// Returns the number of rows read and io.EOF when done.
+func (r *Reader) Read(rows []model.HospitalChargeRow) (int, error) {
+	n := 0
+	for n < len(rows) {
+		if len(r.pending) == 0 {
+			if r.done {
+				return n, io.EOF
+			}
+			if err := r.next(); err != nil {
+				return n, err
+			}
+			continue
+		}
+		c := copy(rows[n:], r.pending)
+		r.pending = r.pending[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// next converts the following CSV record into r.pending, or marks the reader
+// done at end of file.
+func (r *Reader) next() error {
+	record, err := r.csv.Read()
+	if err == io.EOF {
+		r.done = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read csv record: %w", err)
+	}
+	if isBlank(record) {
+		return nil
+	}
+	if r.layout == LayoutTall {
+		r.pending = r.tallRows(record)
+	} else {
+		r.pending = r.wideRows(record)
+	}
+	return nil
+}
+
+// Validate checks the metadata and charge headers with ValidateHeaders.
+func (r *Reader) Validate() error {
+	return ValidateHeaders(r.layout, r.metaHeaders, r.headers)
+}
+
+// Close releases all resources.
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+// baseRow builds the hospital-level portion of a charge row shared by both layouts.
+func (r *Reader) baseRow(record []string) model.HospitalChargeRow {
+	row := r.meta
+	row.Description = r.cols.str(record, "description")
+	row.Setting = r.cols.str(record, "setting")
+	row.Modifiers = r.cols.optStr(record, "modifiers")
+	row.BillingClass = r.cols.optStr(record, "billing_class")
+	row.AdditionalGenericNotes = r.cols.optStr(record, "additional_generic_notes")
+	row.GrossCharge = r.cols.amount(&row, record, "standard_charge|gross", "gross_charge")
+	row.DiscountedCash = r.cols.amount(&row, record, "standard_charge|discounted_cash", "discounted_cash")
+	row.MinCharge = r.cols.amount(&row, record, "standard_charge|min", "min_charge")
+	row.MaxCharge = r.cols.amount(&row, record, "standard_charge|max", "max_charge")
+	row.DrugUnitOfMeasurement = r.cols.amount(&row, record, "drug_unit_of_measurement", "drug_unit_of_measurement")
+	row.DrugTypeOfMeasurement = r.cols.optStr(record, "drug_type_of_measurement")
+	return row
+}
+
+func (r *Reader) tallRows(record []string) []model.HospitalChargeRow {
+	row := r.baseRow(record)
+	row.PayerName = r.cols.optStr(record, "payer_name")
+	row.PlanName = r.cols.optStr(record, "plan_name")
+	row.NegotiatedDollar = r.cols.amount(&row, record, "standard_charge|negotiated_dollar", "negotiated_dollar")
+	row.NegotiatedPercentage = r.cols.amount(&row, record, "standard_charge|negotiated_percentage", "negotiated_percentage")
+	row.NegotiatedAlgorithm = r.cols.optStr(record, "standard_charge|negotiated_algorithm")
+	row.Methodology = r.cols.optStr(record, "standard_charge|methodology")
+	row.EstimatedAmount = r.cols.amount(&row, record, "estimated_amount", "estimated_amount")
+	row.AdditionalPayerNotes = r.cols.optStr(record, "additional_payer_notes")
+	return model.ExpandCodes(row, r.cols.codes(record))
+}
+
+// wideRows emits one row per payer/plan pair that has any value in this
+// record, or a single hospital-level row when none do.
+func (r *Reader) wideRows(record []string) []model.HospitalChargeRow {
+	base := r.baseRow(record)
+	codes := r.cols.codes(record)
+
+	var out []model.HospitalChargeRow
+	for _, pp := range r.cols.payerPlans {
+		if !pp.hasValues(record) {
+			continue
+		}
+		row := base
+		payer, plan := pp.payer, pp.plan
+		row.PayerName = &payer
+		row.PlanName = &plan
+		row.NegotiatedDollar = parseAmount(&row, "negotiated_dollar", cell(record, pp.negotiatedDollar))
+		row.NegotiatedPercentage = parseAmount(&row, "negotiated_percentage", cell(record, pp.negotiatedPercentage))
+		row.NegotiatedAlgorithm = optCell(record, pp.negotiatedAlgorithm)
+		row.Methodology = optCell(record, pp.methodology)
+		row.EstimatedAmount = parseAmount(&row, "estimated_amount", cell(record, pp.estimatedAmount))
+		row.AdditionalPayerNotes = optCell(record, pp.additionalPayerNotes)
+		out = append(out, model.ExpandCodes(row, codes)...)
+	}
+	if len(out) == 0 {
+		out = model.ExpandCodes(base, codes)
+	}
+	return out
+}
+
+// parseMetadata maps the metadata header/value rows onto the hospital fields
+// of a HospitalChargeRow.
+func parseMetadata(headers, values []string) model.HospitalChargeRow {
+	var row model.HospitalChargeRow
+	for i, h := range headers {
+		v := strings.TrimSpace(cell(values, i))
+		switch {
+		case h == "hospital_name":
+			row.HospitalName = v
+		case h == "last_updated_on":
+			row.LastUpdatedOn = v
+		case h == "version":
+			row.Version = v
+		case h == "hospital_location":
+			row.HospitalLocation = v
+		case h == "hospital_address":
+			row.HospitalAddress = v
+		case strings.HasPrefix(h, "license_number"):
+			// Header is "license_number|[state]"
+			if v != "" {
+				row.LicenseNumber = &v
+			}
+			if _, state, ok := strings.Cut(h, "|"); ok && state != "" {
+				state = strings.ToUpper(state)
+				row.LicenseState = &state
+			}
+		case isAffirmationHeader(h):
+			row.Affirmation, _ = strconv.ParseBool(strings.ToLower(v))
+		}
+	}
+	return row
+}
+
+func isAffirmationHeader(h string) bool {
+	return strings.HasPrefix(h, "to the best of its knowledge")
+}
+
+func normalizeHeaders(headers []string) []string {
+	out := make([]string, len(headers))
+	for i, h := range headers {
+		out[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+	return out
+}
+
+func isBlank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func cell(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+func optCell(record []string, i int) *string {
+	v := strings.TrimSpace(cell(record, i))
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// parseFloat parses an amount, tolerating "$" and thousands separators.
+// Returns nil for an empty value and ok=false for a non-numeric one.
+func parseFloat(s string) (v *float64, ok bool) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "$")
+	s = strings.ReplaceAll(s, ",", "")
+	if s == "" {
+		return nil, true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &f, true
+}
+
+// parseAmount parses the amount published for column, recording a
+// non-numeric value on row so normalize rejects the row. Only
+// negotiated_percentage may carry a "%" sign.
+func parseAmount(row *model.HospitalChargeRow, column, s string) *float64 {
+	num := s
+	if column == "negotiated_percentage" {
+		num = strings.TrimSuffix(strings.TrimSpace(s), "%")
+	}
+	v, ok := parseFloat(num)
+	if !ok {
+		row.SetUnparsed(column, strings.TrimSpace(s))
+	}
+	return v
+}
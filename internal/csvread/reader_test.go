@@ -0,0 +1,164 @@
+package csvread
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gyeh/pricestats/internal/model"
+)
+
+const metaRows = `hospital_name,last_updated_on,version,hospital_location,hospital_address,license_number|NY,"To the best of its knowledge and belief, the hospital has included all applicable standard charge information"
+General Hospital,2024-07-01,2.0.0,Main Campus,"1 Main St, Albany, NY",12345,true
+`
+
+const tallCSV = metaRows + `description,code|1,code|1|type,code|2,code|2|type,modifiers,setting,drug_unit_of_measurement,drug_type_of_measurement,standard_charge|gross,standard_charge|discounted_cash,payer_name,plan_name,standard_charge|negotiated_dollar,standard_charge|negotiated_percentage,standard_charge|negotiated_algorithm,estimated_amount,standard_charge|methodology,standard_charge|min,standard_charge|max,additional_generic_notes
+Office visit,99213,CPT,0510,RC,,outpatient,,,"$1,250.50",200,Aetna,PPO,120.25,,,,fee schedule,90,240,
+Office visit,99213,CPT,,,,outpatient,,,"1,250.50",200,Cigna,HMO,,55,,,percent of total billed charges,90,240,
+`
+
+const wideCSV = metaRows + `description,code|1,code|1|type,setting,standard_charge|gross,standard_charge|discounted_cash,standard_charge|min,standard_charge|max,standard_charge|Aetna|PPO|negotiated_dollar,standard_charge|Aetna|PPO|methodology,standard_charge|Cigna|HMO|negotiated_dollar,standard_charge|Cigna|HMO|methodology,estimated_amount|Cigna|HMO,additional_generic_notes
+Office visit,99213,CPT,outpatient,250,200,90,240,120.25,fee schedule,,,,
+MRI brain,70551,CPT,outpatient,2000,1500,800,1600,900,fee schedule,950,case rate,1000,
+Supplies,,,inpatient,15,12,,,,,,,,
+`
+
+func writeCSV(t *testing.T, doc string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mrf.csv")
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	return path
+}
+
+func readAll(t *testing.T, r *Reader) []model.HospitalChargeRow {
+	t.Helper()
+	var all []model.HospitalChargeRow
+	buf := make([]model.HospitalChargeRow, 3)
+	for {
+		n, err := r.Read(buf)
+		all = append(all, buf[:n]...)
+		if err == io.EOF {
+			return all
+		}
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+	}
+}
+
+func TestReader_Tall(t *testing.T) {
+	r, err := Open(writeCSV(t, tallCSV))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+	if r.Layout() != LayoutTall {
+		t.Fatalf("expected tall layout, got %s", r.Layout())
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	rows := readAll(t, r)
+	// First record has two codes, second has one
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+	first := rows[0]
+	if first.HospitalName != "General Hospital" || first.LicenseState == nil || *first.LicenseState != "NY" || !first.Affirmation {
+		t.Errorf("unexpected metadata: %+v", first)
+	}
+	if first.GrossCharge == nil || *first.GrossCharge != 1250.50 {
+		t.Errorf("gross charge: got %v, want 1250.50", first.GrossCharge)
+	}
+	if first.CPTCode == nil || *first.CPTCode != "99213" || first.RCCode != nil {
+		t.Errorf("first row should carry only the CPT code: cpt=%v rc=%v", first.CPTCode, first.RCCode)
+	}
+	if rows[1].RCCode == nil || *rows[1].RCCode != "0510" {
+		t.Errorf("second row should carry the RC code: %v", rows[1].RCCode)
+	}
+	if rows[2].PayerName == nil || *rows[2].PayerName != "Cigna" || rows[2].NegotiatedPercentage == nil || *rows[2].NegotiatedPercentage != 55 {
+		t.Errorf("unexpected payer fields: %v %v", rows[2].PayerName, rows[2].NegotiatedPercentage)
+	}
+}
+
+func TestReader_Wide(t *testing.T) {
+	r, err := Open(writeCSV(t, wideCSV))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+	if r.Layout() != LayoutWide {
+		t.Fatalf("expected wide layout, got %s", r.Layout())
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	rows := readAll(t, r)
+	// Office visit: Aetna only; MRI: Aetna + Cigna; Supplies: no payer values
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(rows))
+	}
+	if rows[0].PayerName == nil || *rows[0].PayerName != "Aetna" || *rows[0].PlanName != "PPO" {
+		t.Errorf("payer/plan should keep published casing: %v %v", rows[0].PayerName, rows[0].PlanName)
+	}
+	cigna := rows[2]
+	if cigna.PayerName == nil || *cigna.PayerName != "Cigna" || cigna.EstimatedAmount == nil || *cigna.EstimatedAmount != 1000 {
+		t.Errorf("unexpected Cigna row: %v %v", cigna.PayerName, cigna.EstimatedAmount)
+	}
+	if cigna.Methodology == nil || *cigna.Methodology != "case rate" {
+		t.Errorf("unexpected methodology: %v", cigna.Methodology)
+	}
+	supplies := rows[3]
+	if supplies.PayerName != nil || supplies.GrossCharge == nil || *supplies.GrossCharge != 15 {
+		t.Errorf("unexpected hospital-only row: payer=%v gross=%v", supplies.PayerName, supplies.GrossCharge)
+	}
+}
+
+// A "%" sign is only part of a negotiated percentage; on a dollar amount
+// it makes the value non-numeric.
+func TestParseAmount_PercentSign(t *testing.T) {
+	tests := []struct {
+		column, value string
+		want          *float64
+		unparsed      bool
+	}{
+		{column: "negotiated_percentage", value: "55%", want: ptr(55.0)},
+		{column: "negotiated_percentage", value: " 55 % ", want: ptr(55.0)},
+		{column: "negotiated_percentage", value: "55", want: ptr(55.0)},
+		{column: "gross_charge", value: "250%", unparsed: true},
+		{column: "negotiated_dollar", value: "$1,250.50", want: ptr(1250.5)},
+	}
+	for _, tt := range tests {
+		var row model.HospitalChargeRow
+		got := parseAmount(&row, tt.column, tt.value)
+		if !reflect.DeepEqual(got, tt.want) || (row.Unparsed != nil) != tt.unparsed {
+			t.Errorf("%s %q: got %v unparsed=%+v, want %v unparsed=%v", tt.column, tt.value, got, row.Unparsed, tt.want, tt.unparsed)
+		}
+	}
+}
+
+func ptr(f float64) *float64 { return &f }
+
+func TestValidateHeaders_ReportsAllMissing(t *testing.T) {
+	err := ValidateHeaders(LayoutTall,
+		[]string{"hospital_name", "version"},
+		[]string{"description", "code|1", "code|1|type", "setting", "standard_charge|gross", "standard_charge|discounted_cash",
+			"standard_charge|min", "standard_charge|max", "payer_name", "standard_charge|negotiated_dollar",
+			"standard_charge|negotiated_percentage", "standard_charge|negotiated_algorithm"})
+
+	var mh *MissingHeadersError
+	if !errors.As(err, &mh) {
+		t.Fatalf("expected MissingHeadersError, got %v", err)
+	}
+	want := []string{"metadata:last_updated_on", "plan_name", "standard_charge|methodology"}
+	if !reflect.DeepEqual(mh.Missing, want) {
+		t.Errorf("missing headers: got %v, want %v", mh.Missing, want)
+	}
+}
@@ -0,0 +1,80 @@
+package csvread
+
+import (
+	"fmt"
+	"strings"
+)
+
+// requiredMetaHeaders must appear in the first (metadata) header row.
+var requiredMetaHeaders = []string{"hospital_name", "last_updated_on", "version"}
+
+// requiredChargeHeaders must appear in the charge header row of both layouts.
+var requiredChargeHeaders = []string{
+	"description",
+	"setting",
+	"code|1",
+	"code|1|type",
+	"standard_charge|gross",
+	"standard_charge|discounted_cash",
+	"standard_charge|min",
+	"standard_charge|max",
+}
+
+// requiredTallHeaders are the payer columns only the tall layout carries;
+// the wide layout encodes them in per-payer/plan headers instead.
+var requiredTallHeaders = []string{
+	"payer_name",
+	"plan_name",
+	"standard_charge|negotiated_dollar",
+	"standard_charge|negotiated_percentage",
+	"standard_charge|negotiated_algorithm",
+	"standard_charge|methodology",
+}
+
+// MissingHeadersError lists every required header absent from a CSV file.
+type MissingHeadersError struct {
+	Layout Layout
+	// Missing holds the missing headers in template order; metadata headers
+	// are prefixed with "metadata:" to distinguish the two header rows.
+	Missing []string
+}
+
+func (e *MissingHeadersError) Error() string {
+	return fmt.Sprintf("missing required %s CSV headers: %s", e.Layout, strings.Join(e.Missing, ", "))
+}
+
+// ValidateHeaders checks the (lowercased) metadata and charge header rows
+// against the CMS template for the given layout. Unlike the Parquet
+// ValidateSchema it reports every missing header, not just the first.
+func ValidateHeaders(layout Layout, metaHeaders, chargeHeaders []string) error {
+	var missing []string
+
+	meta := make(map[string]bool, len(metaHeaders))
+	for _, h := range metaHeaders {
+		meta[h] = true
+	}
+	for _, h := range requiredMetaHeaders {
+		if !meta[h] {
+			missing = append(missing, "metadata:"+h)
+		}
+	}
+
+	cols := make(map[string]bool, len(chargeHeaders))
+	for _, h := range chargeHeaders {
+		cols[h] = true
+	}
+	required := requiredChargeHeaders
+	if layout == LayoutTall {
+		required = append(append([]string{}, requiredChargeHeaders...), requiredTallHeaders...)
+	}
+	for _, h := range required {
+		if !cols[h] {
+			missing = append(missing, h)
+		}
+	}
+
+	if len(missing) > 0 {
+		return &MissingHeadersError{Layout: layout, Missing: missing}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/gyeh/pricestats/internal/model"
+	embedsql "github.com/gyeh/pricestats/internal/sql"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// SyncCodeTypes reconciles the database with the model.AllCodeTypes registry:
+// it records each type in ref.code_types and creates any missing staging
+// column, serving partition and partition indexes. Every statement is
+// idempotent, so it is safe to run on each migrate.
+func SyncCodeTypes(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger) error {
+	tmpl, err := template.ParseFS(embedsql.Templates, "templates/code_type_ddl.sql")
+	if err != nil {
+		return fmt.Errorf("parse code type template: %w", err)
+	}
+
+	q := sqlcgen.New(pool)
+	for i, ct := range model.AllCodeTypes {
+		var ddl strings.Builder
+		if err := tmpl.Execute(&ddl, ct); err != nil {
+			return fmt.Errorf("render DDL for code type %s: %w", ct.Name, err)
+		}
+		if _, err := pool.Exec(ctx, ddl.String()); err != nil {
+			return fmt.Errorf("apply DDL for code type %s: %w", ct.Name, err)
+		}
+
+		if err := q.UpsertCodeType(ctx, sqlcgen.UpsertCodeTypeParams{
+			CodeType:        ct.Name,
+			StagingColumn:   ct.Column,
+			PartitionSuffix: ct.Partition,
+			SortOrder:       int32(i),
+		}); err != nil {
+			return fmt.Errorf("register code type %s: %w", ct.Name, err)
+		}
+	}
+
+	log.Info().Int("count", len(model.AllCodeTypes)).Msg("code types synced")
+	return nil
+}
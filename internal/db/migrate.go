@@ -12,7 +12,8 @@ import (
 	embedsql "github.com/gyeh/pricestats/internal/sql"
 )
 
-// ApplyMigrations runs all embedded SQL migrations in filename order.
+// ApplyMigrations runs all embedded SQL migrations in filename order, then
+// syncs the code type registry (staging columns, partitions, indexes).
 // All DDL uses IF NOT EXISTS so migrations are idempotent.
 func ApplyMigrations(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger) error {
 	entries, err := fs.ReadDir(embedsql.Migrations, "migrations")
@@ -41,6 +42,10 @@ func ApplyMigrations(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger
 		}
 	}
 
+	if err := SyncCodeTypes(ctx, pool, log); err != nil {
+		return fmt.Errorf("sync code types: %w", err)
+	}
+
 	log.Info().Int("count", len(entries)).Msg("all migrations applied")
 	return nil
 }
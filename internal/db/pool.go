@@ -8,7 +8,8 @@ import (
 )
 
 // NewPool creates a pgxpool with session-level params suitable for bulk loads.
-func NewPool(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+// maxConns caps the pool size; zero keeps the pgx default.
+func NewPool(ctx context.Context, dsn string, maxConns int32) (*pgxpool.Pool, error) {
 	cfg, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("parse dsn: %w", err)
@@ -16,6 +17,9 @@ func NewPool(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
 
 	// Disable statement timeout for bulk loading sessions.
 	cfg.ConnConfig.RuntimeParams["statement_timeout"] = "0"
+	if maxConns > 0 {
+		cfg.MaxConns = maxConns
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"admin_shutdown", &pgconn.PgError{Code: "57P01"}, true},
+		{"connection_failure", &pgconn.PgError{Code: "08006"}, true},
+		{"deadlock", fmt.Errorf("copy: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{"unique_violation", &pgconn.PgError{Code: "23505"}, false},
+		{"unexpected_eof", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"canceled", fmt.Errorf("copy: %w", context.Canceled), false},
+		{"plain", errors.New("bad row"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	transient := &pgconn.PgError{Code: "08006"}
+	policy := RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	t.Run("succeeds_after_transient", func(t *testing.T) {
+		calls := 0
+		var waits []time.Duration
+		err := Retry(ctx, policy, func(_ int, wait time.Duration, _ error) { waits = append(waits, wait) }, func() error {
+			calls++
+			if calls < 3 {
+				return transient
+			}
+			return nil
+		})
+		if err != nil || calls != 3 {
+			t.Fatalf("got err=%v after %d calls", err, calls)
+		}
+		if len(waits) != 2 || waits[0] != time.Millisecond || waits[1] != 2*time.Millisecond {
+			t.Errorf("backoff waits: %v", waits)
+		}
+	})
+
+	t.Run("gives_up", func(t *testing.T) {
+		calls := 0
+		err := Retry(ctx, policy, nil, func() error { calls++; return transient })
+		if !errors.Is(err, transient) || calls != 4 {
+			t.Errorf("got err=%v after %d calls, want 4 calls", err, calls)
+		}
+	})
+
+	t.Run("permanent_not_retried", func(t *testing.T) {
+		calls := 0
+		permanent := errors.New("bad row")
+		err := Retry(ctx, policy, nil, func() error { calls++; return permanent })
+		if err != permanent || calls != 1 {
+			t.Errorf("got err=%v after %d calls, want 1 call", err, calls)
+		}
+	})
+}
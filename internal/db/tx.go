@@ -11,7 +11,17 @@ import (
 // WithTx executes fn inside a transaction, committing on success and
 // rolling back on error or panic.
 func WithTx(ctx context.Context, pool *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
-	tx, err := pool.Begin(ctx)
+	return withTxOptions(ctx, pool, pgx.TxOptions{}, fn)
+}
+
+// WithSnapshot executes fn inside a read-only REPEATABLE READ transaction,
+// so all of its reads see the same snapshot.
+func WithSnapshot(ctx context.Context, pool *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
+	return withTxOptions(ctx, pool, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, fn)
+}
+
+func withTxOptions(ctx context.Context, pool *pgxpool.Pool, opts pgx.TxOptions, fn func(tx pgx.Tx) error) error {
+	tx, err := pool.BeginTx(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
@@ -0,0 +1,242 @@
+// Package diff compares the serving rows of two loaded versions of a
+// hospital's MRF: items added, removed and repriced, and per code type and
+// payer summaries. The comparison runs in SQL; only the changes come back.
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gyeh/pricestats/internal/ingest"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// ErrDifferentHospitals is returned when the two files belong to different
+// hospitals.
+var ErrDifferentHospitals = errors.New("files belong to different hospitals")
+
+// ErrNotLoaded is returned when a file has no complete serving rows: it
+// failed, was cancelled or has been reaped.
+var ErrNotLoaded = errors.New("file is not loaded")
+
+// loadedStatuses are the statuses of a file whose serving rows are complete.
+var loadedStatuses = map[string]bool{"transformed": true, "partial": true, "active": true}
+
+// Change is how an item differs between the two files.
+type Change string
+
+const (
+	Added   Change = "added"
+	Removed Change = "removed"
+	Changed Change = "changed"
+)
+
+// Item is one priced item that differs between the files. An item is keyed
+// by (code_type, code, setting, payer, plan, modifiers). Its gross charge,
+// negotiated dollar amount and negotiated percentage are compared
+// separately, each the lowest if the file lists the item more than once.
+type Item struct {
+	Change      Change  `json:"change"`
+	CodeType    string  `json:"code_type"`
+	Code        string  `json:"code"`
+	Description string  `json:"description"`
+	Setting     string  `json:"setting,omitempty"`
+	Payer       *string `json:"payer"`
+	Plan        *string `json:"plan"`
+	Modifiers   string  `json:"modifiers,omitempty"`
+
+	FromGrossCents       *int64 `json:"from_gross_cents"`
+	ToGrossCents         *int64 `json:"to_gross_cents"`
+	FromNegotiatedCents  *int64 `json:"from_negotiated_cents"`
+	ToNegotiatedCents    *int64 `json:"to_negotiated_cents"`
+	FromNegotiatedPctBps *int32 `json:"from_negotiated_percentage_bps"`
+	ToNegotiatedPctBps   *int32 `json:"to_negotiated_percentage_bps"`
+	// NegotiatedPctChanged flags a changed item whose negotiated
+	// percentage differs.
+	NegotiatedPctChanged bool `json:"negotiated_pct_changed"`
+	// DeltaCents and PctDelta compare the item's price: the negotiated
+	// dollar amount for a payer row, the gross charge otherwise. They are
+	// set when both prices are; PctDelta also needs a positive old price.
+	DeltaCents *int64   `json:"delta_cents"`
+	PctDelta   *float64 `json:"pct_delta"`
+}
+
+// fromCents and toCents are the item's price in each file.
+func (it *Item) fromCents() *int64 {
+	if it.Payer != nil {
+		return it.FromNegotiatedCents
+	}
+	return it.FromGrossCents
+}
+
+func (it *Item) toCents() *int64 {
+	if it.Payer != nil {
+		return it.ToNegotiatedCents
+	}
+	return it.ToGrossCents
+}
+
+// Summary counts the items of one code type and payer. Payer is nil for
+// the hospital's own charges. Increased, Decreased and the percent
+// statistics follow the item's price as in Item; the statistics cover the
+// repriced items with a positive old price and are nil when there are none.
+type Summary struct {
+	CodeType  string  `json:"code_type"`
+	Payer     *string `json:"payer"`
+	Added     int64   `json:"added"`
+	Removed   int64   `json:"removed"`
+	Changed   int64   `json:"changed"`
+	Unchanged int64   `json:"unchanged"`
+	Increased int64   `json:"increased"`
+	Decreased int64   `json:"decreased"`
+	// NegotiatedPctChanged counts changed items whose negotiated
+	// percentage differs.
+	NegotiatedPctChanged int64    `json:"negotiated_pct_changed"`
+	AvgPctDelta          *float64 `json:"avg_pct_delta"`
+	MedianPctDelta       *float64 `json:"median_pct_delta"`
+	MinPctDelta          *float64 `json:"min_pct_delta"`
+	MaxPctDelta          *float64 `json:"max_pct_delta"`
+}
+
+// Result is the comparison of two files.
+type Result struct {
+	Hospital   string    `json:"hospital"`
+	FromFileID int64     `json:"from_mrf_file_id"`
+	FromFile   string    `json:"from_file"`
+	ToFileID   int64     `json:"to_mrf_file_id"`
+	ToFile     string    `json:"to_file"`
+	Summary    []Summary `json:"summary"`
+	Items      []Item    `json:"items"`
+	// Truncated is set when Items stopped at the item limit.
+	Truncated bool `json:"truncated"`
+}
+
+// Options controls Compare.
+type Options struct {
+	// Limit caps the items listed; 0 lists all. The summary always covers
+	// every item.
+	Limit int32
+	// SummaryOnly skips the item list.
+	SummaryOnly bool
+}
+
+// Compare diffs file fromID against the later file toID. Both must be
+// loaded versions (transformed, partial or active) of the same hospital.
+func Compare(ctx context.Context, q *sqlcgen.Queries, fromID, toID int64, opts Options) (*Result, error) {
+	from, err := ingest.GetFile(ctx, q, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := ingest.GetFile(ctx, q, toID)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range []*ingest.FileInfo{from, to} {
+		if !loadedStatuses[f.Status] {
+			return nil, fmt.Errorf("%w: file %d has status %s; only transformed, partial or active files can be compared",
+				ErrNotLoaded, f.MRFFileID, f.Status)
+		}
+	}
+	if from.HospitalID != to.HospitalID {
+		return nil, fmt.Errorf("%w: file %d is %s, file %d is %s",
+			ErrDifferentHospitals, fromID, from.HospitalName, toID, to.HospitalName)
+	}
+	r := &Result{
+		Hospital:   to.HospitalName,
+		FromFileID: fromID,
+		FromFile:   from.FileName,
+		ToFileID:   toID,
+		ToFile:     to.FileName,
+		Summary:    []Summary{},
+		Items:      []Item{},
+	}
+
+	groups, err := q.DiffFileSummary(ctx, sqlcgen.DiffFileSummaryParams{FromFileID: fromID, ToFileID: toID})
+	if err != nil {
+		return nil, fmt.Errorf("summarize diff: %w", err)
+	}
+	for _, g := range groups {
+		s := Summary{
+			CodeType:  g.CodeType,
+			Payer:     g.PayerName,
+			Added:     g.Added,
+			Removed:   g.Removed,
+			Changed:   g.Changed,
+			Unchanged: g.Unchanged,
+			Increased: g.Increased,
+			Decreased: g.Decreased,
+
+			NegotiatedPctChanged: g.NegotiatedPctChanged,
+		}
+		if g.PctItems > 0 {
+			s.AvgPctDelta, s.MedianPctDelta = &g.AvgPctDelta, &g.MedianPctDelta
+			s.MinPctDelta, s.MaxPctDelta = &g.MinPctDelta, &g.MaxPctDelta
+		}
+		r.Summary = append(r.Summary, s)
+	}
+	if opts.SummaryOnly {
+		return r, nil
+	}
+
+	params := sqlcgen.DiffFileItemsParams{FromFileID: fromID, ToFileID: toID}
+	if opts.Limit > 0 {
+		params.ItemLimit = &opts.Limit
+	}
+	rows, err := q.DiffFileItems(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("list diff items: %w", err)
+	}
+	for _, row := range rows {
+		r.Items = append(r.Items, itemFromRow(row))
+	}
+	r.Truncated = opts.Limit > 0 && len(rows) == int(opts.Limit)
+	return r, nil
+}
+
+// itemFromRow derives the change and deltas of a DiffFileItems row.
+func itemFromRow(row *sqlcgen.DiffFileItemsRow) Item {
+	it := Item{
+		Change:      Changed,
+		Code:        row.CodeNorm,
+		Description: row.Description,
+		Setting:     row.Setting,
+		Payer:       row.PayerName,
+		Plan:        row.PlanName,
+		Modifiers:   row.Modifiers,
+
+		FromGrossCents:       row.FromGrossCents,
+		ToGrossCents:         row.ToGrossCents,
+		FromNegotiatedCents:  row.FromNegotiatedCents,
+		ToNegotiatedCents:    row.ToNegotiatedCents,
+		FromNegotiatedPctBps: row.FromPctBps,
+		ToNegotiatedPctBps:   row.ToPctBps,
+	}
+	switch {
+	case row.FromCodeType == nil:
+		it.Change, it.CodeType = Added, *row.ToCodeType
+	case row.ToCodeType == nil:
+		it.Change, it.CodeType = Removed, *row.FromCodeType
+	default:
+		it.CodeType = *row.FromCodeType
+		it.NegotiatedPctChanged = !equal32(row.FromPctBps, row.ToPctBps)
+	}
+	if from, to := it.fromCents(), it.toCents(); from != nil && to != nil {
+		d := *to - *from
+		it.DeltaCents = &d
+		if *from > 0 {
+			pct := math.Round(float64(d)*10000/float64(*from)) / 100
+			it.PctDelta = &pct
+		}
+	}
+	return it
+}
+
+// equal32 reports whether a and b are both nil or hold the same value.
+func equal32(a, b *int32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
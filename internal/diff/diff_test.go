@@ -0,0 +1,147 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+func strPtr(s string) *string { return &s }
+func i64(n int64) *int64      { return &n }
+func i32(n int32) *int32      { return &n }
+
+func TestItemFromRow(t *testing.T) {
+	tests := []struct {
+		name      string
+		row       sqlcgen.DiffFileItemsRow
+		want      Change
+		wantDelta string
+		wantPct   string
+		// wantNegPct expects NegotiatedPctChanged
+		wantNegPct bool
+	}{
+		{
+			name:      "increase",
+			row:       sqlcgen.DiffFileItemsRow{FromCodeType: strPtr("CPT"), ToCodeType: strPtr("CPT"), FromGrossCents: i64(20000), ToGrossCents: i64(25000)},
+			want:      Changed,
+			wantDelta: "5000",
+			wantPct:   "25.00",
+		},
+		{
+			name:      "decrease_rounded",
+			row:       sqlcgen.DiffFileItemsRow{FromCodeType: strPtr("CPT"), ToCodeType: strPtr("CPT"), FromGrossCents: i64(30000), ToGrossCents: i64(20000)},
+			want:      Changed,
+			wantDelta: "-10000",
+			wantPct:   "-33.33",
+		},
+		{
+			name:      "from_zero_has_no_pct",
+			row:       sqlcgen.DiffFileItemsRow{FromCodeType: strPtr("CPT"), ToCodeType: strPtr("CPT"), FromGrossCents: i64(0), ToGrossCents: i64(100)},
+			want:      Changed,
+			wantDelta: "100",
+		},
+		{
+			name: "price_dropped",
+			row:  sqlcgen.DiffFileItemsRow{FromCodeType: strPtr("CPT"), ToCodeType: strPtr("CPT"), FromGrossCents: i64(100)},
+			want: Changed,
+		},
+		{
+			name: "payer_price_ignores_gross",
+			row: sqlcgen.DiffFileItemsRow{FromCodeType: strPtr("CPT"), ToCodeType: strPtr("CPT"), PayerName: strPtr("Aetna"),
+				FromGrossCents: i64(20000), ToGrossCents: i64(30000), FromNegotiatedCents: i64(10000), ToNegotiatedCents: i64(11000)},
+			want:      Changed,
+			wantDelta: "1000",
+			wantPct:   "10.00",
+		},
+		{
+			name: "dollar_to_percentage",
+			row: sqlcgen.DiffFileItemsRow{FromCodeType: strPtr("CPT"), ToCodeType: strPtr("CPT"), PayerName: strPtr("Aetna"),
+				FromGrossCents: i64(20000), ToGrossCents: i64(20000), FromNegotiatedCents: i64(10000), ToPctBps: i32(5000)},
+			want:       Changed,
+			wantNegPct: true,
+		},
+		{
+			name: "added",
+			row:  sqlcgen.DiffFileItemsRow{ToCodeType: strPtr("HCPCS"), ToGrossCents: i64(100)},
+			want: Added,
+		},
+		{
+			name: "removed",
+			row:  sqlcgen.DiffFileItemsRow{FromCodeType: strPtr("CPT"), FromGrossCents: i64(100)},
+			want: Removed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := itemFromRow(&tt.row)
+			if it.Change != tt.want {
+				t.Errorf("change: got %s, want %s", it.Change, tt.want)
+			}
+			if it.CodeType == "" {
+				t.Error("code type not set")
+			}
+			if got := num(it.DeltaCents); got != tt.wantDelta {
+				t.Errorf("delta: got %q, want %q", got, tt.wantDelta)
+			}
+			if got := pct(it.PctDelta); got != tt.wantPct {
+				t.Errorf("pct: got %q, want %q", got, tt.wantPct)
+			}
+			if it.NegotiatedPctChanged != tt.wantNegPct {
+				t.Errorf("negotiated pct changed: got %v, want %v", it.NegotiatedPctChanged, tt.wantNegPct)
+			}
+		})
+	}
+}
+
+func TestWrite_CSV(t *testing.T) {
+	avg := 12.5
+	r := &Result{
+		Summary: []Summary{{CodeType: "CPT", Payer: strPtr("Aetna"), Changed: 1, Increased: 1, AvgPctDelta: &avg}},
+		Items: []Item{
+			itemFromRow(&sqlcgen.DiffFileItemsRow{FromCodeType: strPtr("CPT"), ToCodeType: strPtr("CPT"),
+				CodeNorm: "99213", Description: "Office visit, new", PayerName: strPtr("Aetna"),
+				FromGrossCents: i64(30000), ToGrossCents: i64(30000),
+				FromNegotiatedCents: i64(20000), ToNegotiatedCents: i64(22500)}),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCSV, r, false); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	want := []string{"changed", "CPT", "99213", "Office visit, new", "", "Aetna", "", "",
+		"30000", "30000", "20000", "22500", "", "", "false", "2500", "12.50"}
+	if len(recs) != 2 || strings.Join(recs[0], ",") != strings.Join(itemColumns, ",") || strings.Join(recs[1], "|") != strings.Join(want, "|") {
+		t.Errorf("items csv: %q", recs)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, FormatCSV, r, true); err != nil {
+		t.Fatalf("Write summary: %v", err)
+	}
+	recs, err = csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if len(recs) != 2 || recs[1][0] != "CPT" || recs[1][4] != "1" || recs[1][8] != "0" || recs[1][9] != "12.50" || recs[1][10] != "" {
+		t.Errorf("summary csv: %q", recs)
+	}
+}
+
+func TestDollars(t *testing.T) {
+	for c, want := range map[int64]string{0: "$0.00", 12345: "$123.45", -5: "-$0.05"} {
+		if got := dollars(&c); got != want {
+			t.Errorf("dollars(%d): got %s, want %s", c, got, want)
+		}
+	}
+	if got := dollars(nil); got != "-" {
+		t.Errorf("dollars(nil): got %s", got)
+	}
+}
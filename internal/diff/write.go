@@ -0,0 +1,185 @@
+package diff
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Format is an output format for a Result.
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatCSV   Format = "csv"
+)
+
+// ParseFormat validates an output format name.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case FormatTable, FormatJSON, FormatCSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported output format %q (want table, json or csv)", name)
+}
+
+// Write encodes r to w. CSV holds one table, so it carries the items, or
+// the summary when summaryOnly is set.
+func Write(w io.Writer, format Format, r *Result, summaryOnly bool) error {
+	switch format {
+	case FormatTable:
+		return writeTable(w, r, summaryOnly)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatCSV:
+		if summaryOnly {
+			return writeSummaryCSV(w, r)
+		}
+		return writeItemsCSV(w, r)
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+var itemColumns = []string{"change", "code_type", "code", "description", "setting", "payer", "plan",
+	"modifiers", "from_gross_cents", "to_gross_cents", "from_negotiated_cents", "to_negotiated_cents",
+	"from_negotiated_percentage_bps", "to_negotiated_percentage_bps", "negotiated_pct_changed",
+	"delta_cents", "pct_delta"}
+
+func writeItemsCSV(w io.Writer, r *Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(itemColumns); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+	for _, it := range r.Items {
+		rec := []string{string(it.Change), it.CodeType, it.Code, it.Description, it.Setting,
+			str(it.Payer), str(it.Plan), it.Modifiers,
+			num(it.FromGrossCents), num(it.ToGrossCents), num(it.FromNegotiatedCents), num(it.ToNegotiatedCents),
+			num32(it.FromNegotiatedPctBps), num32(it.ToNegotiatedPctBps), strconv.FormatBool(it.NegotiatedPctChanged),
+			num(it.DeltaCents), pct(it.PctDelta)}
+		if err := cw.Write(rec); err != nil {
+			return fmt.Errorf("write csv row: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var summaryColumns = []string{"code_type", "payer", "added", "removed", "changed", "unchanged",
+	"increased", "decreased", "negotiated_pct_changed", "avg_pct_delta", "median_pct_delta", "min_pct_delta", "max_pct_delta"}
+
+func writeSummaryCSV(w io.Writer, r *Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(summaryColumns); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+	for _, s := range r.Summary {
+		rec := []string{s.CodeType, str(s.Payer),
+			strconv.FormatInt(s.Added, 10), strconv.FormatInt(s.Removed, 10),
+			strconv.FormatInt(s.Changed, 10), strconv.FormatInt(s.Unchanged, 10),
+			strconv.FormatInt(s.Increased, 10), strconv.FormatInt(s.Decreased, 10),
+			strconv.FormatInt(s.NegotiatedPctChanged, 10),
+			pct(s.AvgPctDelta), pct(s.MedianPctDelta), pct(s.MinPctDelta), pct(s.MaxPctDelta)}
+		if err := cw.Write(rec); err != nil {
+			return fmt.Errorf("write csv row: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTable(w io.Writer, r *Result, summaryOnly bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Hospital:  %s\n", r.Hospital)
+	fmt.Fprintf(tw, "From:      %d (%s)\n", r.FromFileID, r.FromFile)
+	fmt.Fprintf(tw, "To:        %d (%s)\n\n", r.ToFileID, r.ToFile)
+
+	fmt.Fprintln(tw, "CODE_TYPE\tPAYER\tADDED\tREMOVED\tCHANGED\tUNCHANGED\tUP\tDOWN\tNEG_%_CHANGED\tAVG_%\tMEDIAN_%\tMIN_%\tMAX_%")
+	for _, s := range r.Summary {
+		payer := "(gross)"
+		if s.Payer != nil {
+			payer = *s.Payer
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s\n",
+			s.CodeType, payer, s.Added, s.Removed, s.Changed, s.Unchanged, s.Increased, s.Decreased, s.NegotiatedPctChanged,
+			dash(pct(s.AvgPctDelta)), dash(pct(s.MedianPctDelta)), dash(pct(s.MinPctDelta)), dash(pct(s.MaxPctDelta)))
+	}
+	if !summaryOnly {
+		fmt.Fprintf(tw, "\n%d items:\n", len(r.Items))
+		if len(r.Items) > 0 {
+			fmt.Fprintln(tw, "CHANGE\tCODE_TYPE\tCODE\tSETTING\tPAYER\tPLAN\tMODIFIERS\tGROSS_FROM\tGROSS_TO\tNEG_FROM\tNEG_TO\tNEG_%_FROM\tNEG_%_TO\tDELTA\tDELTA_%\tDESCRIPTION")
+			for _, it := range r.Items {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					it.Change, it.CodeType, it.Code, dash(it.Setting), dash(str(it.Payer)), dash(str(it.Plan)),
+					dash(it.Modifiers), dollars(it.FromGrossCents), dollars(it.ToGrossCents),
+					dollars(it.FromNegotiatedCents), dollars(it.ToNegotiatedCents),
+					percent(it.FromNegotiatedPctBps), percent(it.ToNegotiatedPctBps), dollars(it.DeltaCents),
+					dash(pct(it.PctDelta)), it.Description)
+			}
+		}
+		if r.Truncated {
+			fmt.Fprintln(tw, "(item list truncated at --limit)")
+		}
+	}
+	return tw.Flush()
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func num(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(*n, 10)
+}
+
+func num32(n *int32) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(int64(*n), 10)
+}
+
+// percent formats basis points as a percentage.
+func percent(bps *int32) string {
+	if bps == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", float64(*bps)/100)
+}
+
+func pct(p *float64) string {
+	if p == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*p, 'f', 2, 64)
+}
+
+// dollars formats cents as a dollar amount.
+func dollars(c *int64) string {
+	if c == nil {
+		return "-"
+	}
+	n, sign := *c, ""
+	if n < 0 {
+		n, sign = -n, "-"
+	}
+	return fmt.Sprintf("%s$%d.%02d", sign, n/100, n%100)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
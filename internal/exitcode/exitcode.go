@@ -8,4 +8,7 @@ const (
 	CopyError        = 4
 	TransformError   = 5
 	PartialSuccess   = 6
+	BatchFailure     = 7 // one or more files of a batch ingest failed
+	Locked           = 8 // another ingest holds the hospital's lock
+	Cancelled        = 9 // interrupted by SIGINT/SIGTERM
 )
@@ -0,0 +1,391 @@
+// Package index ranks hospitals on a market basket of services: a weighted
+// price index per hospital and payer over the active versions, stored per
+// file version so a hospital's trend can be followed.
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/gyeh/pricestats/internal/config"
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/normalize"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// Item is one basket item of an Entry. PriceCents is the median of the
+// group's prices of the code; ReferenceCents the median of that across
+// every group of the same kind (payers, or hospitals' own charges).
+type Item struct {
+	CodeType       string  `json:"code_type"`
+	Code           string  `json:"code"`
+	Weight         float64 `json:"weight"`
+	PriceCents     *int64  `json:"price_cents"`
+	ReferenceCents *int64  `json:"reference_cents"`
+	Missing        bool    `json:"missing"`
+}
+
+// Entry is the index of one hospital and payer. Payer is nil for the
+// hospital's own gross charges. Index is nil when no item is priced; Rank
+// is 0 for entries left out of the ranking.
+type Entry struct {
+	Rank           int        `json:"rank"`
+	HospitalID     int64      `json:"hospital_id"`
+	HospitalName   string     `json:"hospital_name"`
+	MRFFileID      int64      `json:"mrf_file_id"`
+	LastUpdatedOn  *time.Time `json:"last_updated_on"`
+	PayerID        *int64     `json:"payer_id"`
+	Payer          *string    `json:"payer"`
+	Index          *float64   `json:"index"`
+	ItemsTotal     int        `json:"items_total"`
+	ItemsPriced    int        `json:"items_priced"`
+	WeightCoverage float64    `json:"weight_coverage"`
+	Items          []Item     `json:"items"`
+}
+
+// Missing returns the codes of the entry's unpriced items.
+func (e Entry) Missing() []string {
+	var codes []string
+	for _, it := range e.Items {
+		if it.Missing {
+			codes = append(codes, it.CodeType+" "+it.Code)
+		}
+	}
+	return codes
+}
+
+// Options controls Compute.
+type Options struct {
+	// MinCoverage is the share of the basket weight (0-1) an entry must
+	// price to be ranked.
+	MinCoverage float64
+	// Store saves the results per file version, replacing earlier results
+	// of the same basket and versions.
+	Store bool
+	// Payer limits the returned ranking to payers whose name contains it
+	// (case-insensitive); every entry is still stored.
+	Payer *string
+}
+
+// Result is a basket's entries, ranked cheapest first, then unranked.
+type Result struct {
+	Basket  string  `json:"basket"`
+	Entries []Entry `json:"entries"`
+}
+
+// basketItem is a config.BasketItem with its code canonicalized.
+type basketItem struct {
+	codeType string
+	code     string
+	weight   float64
+}
+
+// Compute prices basket against every hospital's active version and ranks
+// the hospital/payer entries by index.
+func Compute(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, basket config.MarketBasket, opts Options) (*Result, error) {
+	if len(basket.Items) == 0 {
+		return nil, fmt.Errorf("market basket has no items")
+	}
+	items := make([]basketItem, len(basket.Items))
+	params := sqlcgen.ListBasketPricesParams{}
+	for i, it := range basket.Items {
+		ct, ok := model.CodeTypeByName(it.CodeType)
+		if !ok {
+			return nil, fmt.Errorf("unknown code type %q", it.CodeType)
+		}
+		code, _ := normalize.CanonicalizeCode(ct.Name, it.Code)
+		items[i] = basketItem{codeType: ct.Name, code: code, weight: it.Weight}
+		params.CodeTypes = append(params.CodeTypes, ct.Name)
+		params.CodeNorms = append(params.CodeNorms, code)
+	}
+
+	// Both reads share a snapshot: a version switch between them would
+	// leave the outgoing version with every item missing.
+	q := sqlcgen.New(pool)
+	var files []*sqlcgen.ListActiveFilesRow
+	var prices []*sqlcgen.ListBasketPricesRow
+	err := db.WithSnapshot(ctx, pool, func(tx pgx.Tx) error {
+		qtx := q.WithTx(tx)
+		var err error
+		if files, err = qtx.ListActiveFiles(ctx); err != nil {
+			return fmt.Errorf("list active files: %w", err)
+		}
+		if prices, err = qtx.ListBasketPrices(ctx, params); err != nil {
+			return fmt.Errorf("list basket prices: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries := build(items, files, prices)
+	rank(entries, opts.MinCoverage)
+	log.Info().Str("basket", basket.Name).Int("hospitals", len(files)).Int("entries", len(entries)).
+		Msg("price index computed")
+
+	if opts.Store {
+		if err := store(ctx, pool, q, basket.Name, files, entries); err != nil {
+			return nil, err
+		}
+		log.Info().Str("basket", basket.Name).Msg("price index stored")
+	}
+	if opts.Payer != nil {
+		want := strings.ToLower(*opts.Payer)
+		entries = slices.DeleteFunc(entries, func(e Entry) bool {
+			return e.Payer == nil || !strings.Contains(strings.ToLower(*e.Payer), want)
+		})
+		rank(entries, opts.MinCoverage)
+	}
+	return &Result{Basket: basket.Name, Entries: entries}, nil
+}
+
+// build computes an entry for each active version's own charges and for
+// each payer with a price of a basket item in it.
+func build(items []basketItem, files []*sqlcgen.ListActiveFilesRow, prices []*sqlcgen.ListBasketPricesRow) []Entry {
+	type groupKey struct {
+		fileID  int64
+		payerID int64 // 0 = own charges
+	}
+	type itemKey struct{ codeType, code string }
+	type group struct {
+		payerID *int64
+		payer   *string
+		prices  map[itemKey]int64
+	}
+	groups := map[groupKey]*group{}
+	var order []groupKey
+	addGroup := func(k groupKey, payerID *int64, payer *string) *group {
+		g, ok := groups[k]
+		if !ok {
+			g = &group{payerID: payerID, payer: payer, prices: map[itemKey]int64{}}
+			groups[k] = g
+			order = append(order, k)
+		}
+		return g
+	}
+	for _, f := range files {
+		addGroup(groupKey{fileID: f.MrfFileID}, nil, nil)
+	}
+	for _, p := range prices {
+		k := groupKey{fileID: p.MrfFileID}
+		if p.PayerID != nil {
+			k.payerID = *p.PayerID
+		}
+		addGroup(k, p.PayerID, p.PayerName).prices[itemKey{p.CodeType, p.CodeNorm}] = p.PriceCents
+	}
+
+	// Reference prices per kind: payers are compared with payers, own
+	// charges with own charges.
+	refs := map[bool]map[itemKey]*int64{}
+	for _, own := range []bool{true, false} {
+		refs[own] = map[itemKey]*int64{}
+		for _, it := range items {
+			ik := itemKey{it.codeType, it.code}
+			var vals []int64
+			for k, g := range groups {
+				if v, ok := g.prices[ik]; ok && (k.payerID == 0) == own {
+					vals = append(vals, v)
+				}
+			}
+			refs[own][ik] = median(vals)
+		}
+	}
+
+	byFile := map[int64]*sqlcgen.ListActiveFilesRow{}
+	for _, f := range files {
+		byFile[f.MrfFileID] = f
+	}
+	var totalWeight float64
+	for _, it := range items {
+		totalWeight += it.weight
+	}
+	entries := make([]Entry, 0, len(order))
+	for _, k := range order {
+		g, f := groups[k], byFile[k.fileID]
+		if f == nil {
+			continue // activated after ListActiveFiles
+		}
+		e := Entry{
+			HospitalID:    f.HospitalID,
+			HospitalName:  f.HospitalName,
+			MRFFileID:     f.MrfFileID,
+			LastUpdatedOn: f.LastUpdatedOn,
+			PayerID:       g.payerID,
+			Payer:         g.payer,
+			ItemsTotal:    len(items),
+		}
+		var relSum, relWeight, priced float64
+		for _, it := range items {
+			ik := itemKey{it.codeType, it.code}
+			item := Item{CodeType: it.codeType, Code: it.code, Weight: it.weight, ReferenceCents: refs[k.payerID == 0][ik]}
+			if v, ok := g.prices[ik]; ok {
+				item.PriceCents = &v
+				e.ItemsPriced++
+				priced += it.weight
+				if ref := item.ReferenceCents; ref != nil && *ref > 0 {
+					relSum += it.weight * float64(v) / float64(*ref)
+					relWeight += it.weight
+				}
+			} else {
+				item.Missing = true
+			}
+			e.Items = append(e.Items, item)
+		}
+		if relWeight > 0 {
+			idx := relSum / relWeight * 100
+			e.Index = &idx
+		}
+		if totalWeight > 0 {
+			e.WeightCoverage = priced / totalWeight
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// rank sorts entries by index, cheapest first, and numbers those with an
+// index and at least minCoverage of the basket weight; the rest follow
+// unranked.
+func rank(entries []Entry, minCoverage float64) {
+	ranked := func(e Entry) bool { return e.Index != nil && e.WeightCoverage >= minCoverage }
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if ranked(a) != ranked(b) {
+			return ranked(a)
+		}
+		if a.Index != nil && b.Index != nil && *a.Index != *b.Index {
+			return *a.Index < *b.Index
+		}
+		if (a.Index == nil) != (b.Index == nil) {
+			return a.Index != nil
+		}
+		return a.WeightCoverage > b.WeightCoverage
+	})
+	for i := range entries {
+		entries[i].Rank = 0
+		if ranked(entries[i]) {
+			entries[i].Rank = i + 1
+		}
+	}
+}
+
+// median returns the rounded median of vals, or nil when empty.
+func median(vals []int64) *int64 {
+	if len(vals) == 0 {
+		return nil
+	}
+	slices.Sort(vals)
+	n := len(vals)
+	m := vals[n/2]
+	if n%2 == 0 {
+		m = int64(math.Round(float64(vals[n/2-1]+vals[n/2]) / 2))
+	}
+	return &m
+}
+
+// store replaces the basket's stored results of the active versions with
+// entries, in one transaction.
+func store(ctx context.Context, pool *pgxpool.Pool, q *sqlcgen.Queries, basket string, files []*sqlcgen.ListActiveFilesRow, entries []Entry) error {
+	ids := make([]int64, len(files))
+	for i, f := range files {
+		ids[i] = f.MrfFileID
+	}
+	return db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+		qtx := q.WithTx(tx)
+		if _, err := qtx.DeletePriceIndex(ctx, sqlcgen.DeletePriceIndexParams{BasketName: basket, MrfFileIds: ids}); err != nil {
+			return fmt.Errorf("delete stored index: %w", err)
+		}
+		for _, e := range entries {
+			id, err := qtx.InsertPriceIndex(ctx, sqlcgen.InsertPriceIndexParams{
+				BasketName:     basket,
+				HospitalID:     e.HospitalID,
+				MrfFileID:      e.MRFFileID,
+				LastUpdatedOn:  e.LastUpdatedOn,
+				PayerID:        e.PayerID,
+				IndexValue:     e.Index,
+				ItemsTotal:     int32(e.ItemsTotal),
+				ItemsPriced:    int32(e.ItemsPriced),
+				WeightCoverage: e.WeightCoverage,
+			})
+			if err != nil {
+				return fmt.Errorf("store index of file %d: %w", e.MRFFileID, err)
+			}
+			items, err := itemsJSON(e.Items)
+			if err != nil {
+				return fmt.Errorf("encode index items: %w", err)
+			}
+			if err := qtx.InsertPriceIndexItems(ctx, sqlcgen.InsertPriceIndexItemsParams{PriceIndexID: id, Items: items}); err != nil {
+				return fmt.Errorf("store index items of file %d: %w", e.MRFFileID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// itemsJSON encodes items as the records InsertPriceIndexItems reads.
+func itemsJSON(items []Item) ([]byte, error) {
+	type record struct {
+		CodeType       string  `json:"code_type"`
+		CodeNorm       string  `json:"code_norm"`
+		Weight         float64 `json:"weight"`
+		PriceCents     *int64  `json:"price_cents"`
+		ReferenceCents *int64  `json:"reference_cents"`
+	}
+	recs := make([]record, len(items))
+	for i, it := range items {
+		recs[i] = record{it.CodeType, it.Code, it.Weight, it.PriceCents, it.ReferenceCents}
+	}
+	return json.Marshal(recs)
+}
+
+// Point is a stored index of one file version.
+type Point struct {
+	HospitalID     int64      `json:"hospital_id"`
+	HospitalName   string     `json:"hospital_name"`
+	MRFFileID      int64      `json:"mrf_file_id"`
+	LastUpdatedOn  *time.Time `json:"last_updated_on"`
+	Active         bool       `json:"active"`
+	Payer          *string    `json:"payer"`
+	Index          *float64   `json:"index"`
+	ItemsTotal     int32      `json:"items_total"`
+	ItemsPriced    int32      `json:"items_priced"`
+	WeightCoverage float64    `json:"weight_coverage"`
+	ComputedAt     time.Time  `json:"computed_at"`
+}
+
+// Trend returns basket's stored results for the hospitals whose name
+// contains hospital (case-insensitive), per payer, oldest version first.
+func Trend(ctx context.Context, q *sqlcgen.Queries, basket, hospital string, payer *string) ([]Point, error) {
+	rows, err := q.ListPriceIndexTrend(ctx, sqlcgen.ListPriceIndexTrendParams{BasketName: basket, Hospital: hospital, Payer: payer})
+	if err != nil {
+		return nil, fmt.Errorf("list index trend: %w", err)
+	}
+	out := make([]Point, len(rows))
+	for i, r := range rows {
+		out[i] = Point{
+			HospitalID:     r.HospitalID,
+			HospitalName:   r.HospitalName,
+			MRFFileID:      r.MrfFileID,
+			LastUpdatedOn:  r.LastUpdatedOn,
+			Active:         r.IsActive,
+			Payer:          r.PayerName,
+			Index:          r.IndexValue,
+			ItemsTotal:     r.ItemsTotal,
+			ItemsPriced:    r.ItemsPriced,
+			WeightCoverage: r.WeightCoverage,
+			ComputedAt:     r.ComputedAt.Time,
+		}
+	}
+	return out, nil
+}
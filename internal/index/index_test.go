@@ -0,0 +1,118 @@
+package index
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"testing"
+
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+func TestBuild(t *testing.T) {
+	items := []basketItem{{"CPT", "99213", 3}, {"CPT", "99214", 1}}
+	files := []*sqlcgen.ListActiveFilesRow{
+		{MrfFileID: 10, HospitalID: 1, HospitalName: "Cheap"},
+		{MrfFileID: 20, HospitalID: 2, HospitalName: "Dear"},
+		{MrfFileID: 30, HospitalID: 3, HospitalName: "Empty"},
+	}
+	aetnaID, aetna := int64(5), "Aetna"
+	prices := []*sqlcgen.ListBasketPricesRow{
+		{MrfFileID: 10, CodeType: "CPT", CodeNorm: "99213", PriceCents: 100},
+		{MrfFileID: 10, CodeType: "CPT", CodeNorm: "99214", PriceCents: 200},
+		{MrfFileID: 20, CodeType: "CPT", CodeNorm: "99213", PriceCents: 300},
+		{MrfFileID: 20, PayerID: &aetnaID, PayerName: &aetna, CodeType: "CPT", CodeNorm: "99213", PriceCents: 80},
+	}
+	entries := build(items, files, prices)
+	if len(entries) != 4 {
+		t.Fatalf("entries: %+v", entries)
+	}
+	byKey := map[string]Entry{}
+	for _, e := range entries {
+		key := e.HospitalName
+		if e.Payer != nil {
+			key += "/" + *e.Payer
+		}
+		byKey[key] = e
+	}
+
+	// 99213 gross reference: median(100, 300) = 200; 99214: 200.
+	// Cheap: (3*100/200 + 1*200/200) / 4 * 100 = 62.5
+	cheap := byKey["Cheap"]
+	if cheap.Index == nil || math.Abs(*cheap.Index-62.5) > 1e-9 || cheap.ItemsPriced != 2 || cheap.WeightCoverage != 1 {
+		t.Errorf("Cheap: %+v", cheap)
+	}
+	// Dear prices 99213 only: 300/200 * 100 = 150, three quarters of the weight
+	dear := byKey["Dear"]
+	if dear.Index == nil || math.Abs(*dear.Index-150) > 1e-9 || dear.WeightCoverage != 0.75 {
+		t.Errorf("Dear: %+v", dear)
+	}
+	if m := dear.Missing(); len(m) != 1 || m[0] != "CPT 99214" || !dear.Items[1].Missing || dear.Items[1].PriceCents != nil {
+		t.Errorf("Dear missing: %v %+v", m, dear.Items)
+	}
+	// Payers are compared with payers only: Aetna is the only one, so 100
+	if a := byKey["Dear/Aetna"]; a.Index == nil || math.Abs(*a.Index-100) > 1e-9 || *a.Items[0].ReferenceCents != 80 {
+		t.Errorf("Aetna: %+v", a)
+	}
+	if e := byKey["Empty"]; e.Index != nil || e.ItemsPriced != 0 || len(e.Missing()) != 2 {
+		t.Errorf("Empty: %+v", e)
+	}
+
+	rank(entries, 0.9)
+	if entries[0].Rank != 1 {
+		t.Fatalf("Cheap should rank first: %+v", entries[0])
+	}
+	rank(entries, 0.5)
+	if entries[1].Rank != 2 || entries[2].Rank != 3 {
+		t.Errorf("at 50%% coverage Aetna and Dear rank: %+v", entries[1:3])
+	}
+	rank(entries, 0.9)
+	var order []string
+	var ranks []int
+	for _, e := range entries {
+		order = append(order, e.HospitalName)
+		ranks = append(ranks, e.Rank)
+	}
+	// Only Cheap covers 90% of the weight; the rest follow unranked by index
+	if order[0] != "Cheap" || order[1] != "Dear" || entries[1].Payer == nil || order[2] != "Dear" || order[3] != "Empty" {
+		t.Errorf("order: %v", order)
+	}
+	if ranks[0] != 1 || ranks[1] != 0 || ranks[2] != 0 || ranks[3] != 0 {
+		t.Errorf("ranks: %v", ranks)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	if median(nil) != nil {
+		t.Error("median of nothing should be nil")
+	}
+	if m := median([]int64{5, 1, 3}); *m != 3 {
+		t.Errorf("odd: %d", *m)
+	}
+	if m := median([]int64{4, 1, 2, 3}); *m != 3 { // 2.5 rounds up
+		t.Errorf("even: %d", *m)
+	}
+}
+
+func TestWrite_CSV(t *testing.T) {
+	idx := 87.5
+	r := &Result{Basket: "b", Entries: []Entry{{
+		Rank: 1, HospitalID: 7, HospitalName: "General", MRFFileID: 3, Index: &idx, ItemsTotal: 2, ItemsPriced: 1,
+		WeightCoverage: 0.5, Items: []Item{{CodeType: "CPT", Code: "99213"}, {CodeType: "CPT", Code: "99214", Missing: true}},
+	}}}
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCSV, r); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(recs) != 2 {
+		t.Fatalf("csv: %q, %v", recs, err)
+	}
+	got := map[string]string{}
+	for i, col := range recs[0] {
+		got[col] = recs[1][i]
+	}
+	if got["rank"] != "1" || got["index"] != "87.50" || got["payer"] != "" || got["missing"] != "CPT 99214" {
+		t.Errorf("csv row: %v", got)
+	}
+}
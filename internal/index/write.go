@@ -0,0 +1,142 @@
+package index
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Format is an output format for a Result.
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatCSV   Format = "csv"
+)
+
+// ParseFormat validates an output format name.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case FormatTable, FormatJSON, FormatCSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported output format %q (want table, json or csv)", name)
+}
+
+// Write encodes r to w in the given format. CSV has one row per entry, with
+// the missing items' codes joined by semicolons.
+func Write(w io.Writer, format Format, r *Result) error {
+	switch format {
+	case FormatTable:
+		return writeTable(w, r)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatCSV:
+		return writeCSV(w, r)
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+var csvColumns = []string{"rank", "hospital_id", "hospital_name", "mrf_file_id", "last_updated_on", "payer",
+	"index", "items_priced", "items_total", "weight_coverage", "missing"}
+
+func writeCSV(w io.Writer, r *Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+	for _, e := range r.Entries {
+		rank, idx := "", ""
+		if e.Rank > 0 {
+			rank = strconv.Itoa(e.Rank)
+		}
+		if e.Index != nil {
+			idx = strconv.FormatFloat(*e.Index, 'f', 2, 64)
+		}
+		rec := []string{rank, strconv.FormatInt(e.HospitalID, 10), e.HospitalName, strconv.FormatInt(e.MRFFileID, 10),
+			date(e.LastUpdatedOn, ""), str(e.Payer), idx, strconv.Itoa(e.ItemsPriced), strconv.Itoa(e.ItemsTotal),
+			strconv.FormatFloat(e.WeightCoverage, 'f', 4, 64), strings.Join(e.Missing(), ";")}
+		if err := cw.Write(rec); err != nil {
+			return fmt.Errorf("write csv row: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTable(w io.Writer, r *Result) error {
+	fmt.Fprintf(w, "Basket %s: index 100 = market median; payers are compared with payers, gross charges with gross charges\n\n", r.Basket)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANK\tHOSPITAL\tPAYER\tINDEX\tITEMS\tWEIGHT\tFILE\tMISSING")
+	for _, e := range r.Entries {
+		rank, idx, payer := "-", "-", "(gross)"
+		if e.Rank > 0 {
+			rank = strconv.Itoa(e.Rank)
+		}
+		if e.Index != nil {
+			idx = fmt.Sprintf("%.1f", *e.Index)
+		}
+		if e.Payer != nil {
+			payer = *e.Payer
+		}
+		missing := strings.Join(e.Missing(), ", ")
+		if missing == "" {
+			missing = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d/%d\t%.0f%%\t%d (%s)\t%s\n", rank, e.HospitalName, payer, idx,
+			e.ItemsPriced, e.ItemsTotal, e.WeightCoverage*100, e.MRFFileID, date(e.LastUpdatedOn, "-"), missing)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d entries\n", len(r.Entries))
+	return err
+}
+
+// WriteTrend prints stored results as a table, one block per hospital and
+// payer, oldest version first.
+func WriteTrend(w io.Writer, points []Point) error {
+	if len(points) == 0 {
+		_, err := fmt.Fprintln(w, "No stored index results")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOSPITAL\tPAYER\tLAST_UPDATED\tFILE\tINDEX\tITEMS\tWEIGHT\tCOMPUTED")
+	for _, p := range points {
+		idx, payer, file := "-", "(gross)", strconv.FormatInt(p.MRFFileID, 10)
+		if p.Index != nil {
+			idx = fmt.Sprintf("%.1f", *p.Index)
+		}
+		if p.Payer != nil {
+			payer = *p.Payer
+		}
+		if p.Active {
+			file += " (active)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d/%d\t%.0f%%\t%s\n", p.HospitalName, payer, date(p.LastUpdatedOn, "-"),
+			file, idx, p.ItemsPriced, p.ItemsTotal, p.WeightCoverage*100, p.ComputedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func date(t *time.Time, none string) string {
+	if t == nil {
+		return none
+	}
+	return t.Format(time.DateOnly)
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
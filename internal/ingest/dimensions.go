@@ -14,6 +14,8 @@ import (
 // UpsertDimensions upserts payers and plans from the staging batch into ref tables.
 func UpsertDimensions(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, batchID uuid.UUID) error {
 	start := time.Now()
+	dimensionsMu.Lock()
+	defer dimensionsMu.Unlock()
 
 	// Upsert payers
 	tag, err := q.UpsertPayers(ctx, batchID)
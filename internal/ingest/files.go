@@ -0,0 +1,194 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// FileInfo describes one loaded MRF file version (a row of ingest.mrf_files).
+type FileInfo struct {
+	MRFFileID     int64      `json:"mrf_file_id"`
+	HospitalID    int64      `json:"hospital_id"`
+	HospitalName  string     `json:"hospital_name"`
+	FileName      string     `json:"source_file_name"`
+	FileSHA256    string     `json:"source_file_sha256"`
+	Version       *string    `json:"version"`
+	LastUpdatedOn *time.Time `json:"last_updated_on"`
+	Status        string     `json:"status"`
+	IsActive      bool       `json:"is_active"`
+	ImportedAt    time.Time  `json:"imported_at"`
+	IngestBatchID *uuid.UUID `json:"ingest_batch_id"`
+	RowsRead      *int64     `json:"rows_read"`
+	RowsStaged    *int64     `json:"rows_staged"`
+	RowsRejected  *int64     `json:"rows_rejected"`
+	// ServingRows counts the file's rows in mrf.prices_by_code per code type.
+	ServingRows map[string]int64 `json:"serving_rows"`
+}
+
+// FileFilter selects files for ListFiles; nil fields match everything.
+type FileFilter struct {
+	MRFFileID *int64
+	Hospital  *string // case-insensitive substring of the hospital name
+	Status    *string
+	IsActive  *bool
+}
+
+// ErrFileNotFound is returned for an unknown mrf_file_id.
+var ErrFileNotFound = errors.New("mrf file not found")
+
+// ListFiles returns the files matching f with their serving row counts.
+func ListFiles(ctx context.Context, q *sqlcgen.Queries, f FileFilter) ([]FileInfo, error) {
+	rows, err := q.ListMRFFiles(ctx, sqlcgen.ListMRFFilesParams{
+		MrfFileID: f.MRFFileID,
+		Hospital:  f.Hospital,
+		Status:    f.Status,
+		IsActive:  f.IsActive,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+	files := make([]FileInfo, len(rows))
+	ids := make([]int64, len(rows))
+	byID := make(map[int64]*FileInfo, len(rows))
+	for i, r := range rows {
+		files[i] = FileInfo{
+			MRFFileID:     r.MrfFileID,
+			HospitalID:    r.HospitalID,
+			HospitalName:  r.HospitalName,
+			FileName:      r.SourceFileName,
+			FileSHA256:    r.SourceFileSha256,
+			Version:       r.Version,
+			LastUpdatedOn: r.LastUpdatedOn,
+			Status:        r.Status,
+			IsActive:      r.IsActive,
+			ImportedAt:    r.ImportedAt.Time,
+			IngestBatchID: r.IngestBatchID,
+			RowsRead:      r.RowsRead,
+			RowsStaged:    r.RowsStaged,
+			RowsRejected:  r.RowsRejected,
+			ServingRows:   map[string]int64{},
+		}
+		ids[i] = r.MrfFileID
+		byID[r.MrfFileID] = &files[i]
+	}
+	if len(ids) == 0 {
+		return files, nil
+	}
+
+	counts, err := q.CountServingByCodeType(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("count serving rows: %w", err)
+	}
+	for _, c := range counts {
+		byID[c.MrfFileID].ServingRows[c.CodeType] = c.RowCount
+	}
+	return files, nil
+}
+
+// GetFile returns one file, or ErrFileNotFound.
+func GetFile(ctx context.Context, q *sqlcgen.Queries, mRFFileID int64) (*FileInfo, error) {
+	files, err := ListFiles(ctx, q, FileFilter{MRFFileID: &mRFFileID})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrFileNotFound, mRFFileID)
+	}
+	return &files[0], nil
+}
+
+// activatableStatuses are the statuses of a file whose serving rows are complete.
+var activatableStatuses = map[string]bool{"transformed": true, "partial": true, "active": true}
+
+// ActivateFile makes a loaded file its hospital's active version, e.g. to
+// roll back to an earlier one. It runs Finalize's deactivate/activate in one
+// transaction under the hospital's advisory lock, so it cannot interleave
+// with an ingest of the same hospital. A partial file stays partial; the
+// version it replaces goes back to transformed. The statistics and price
+// history follow, so rolling back also rolls the history back.
+func ActivateFile(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, mRFFileID int64, lockTimeout time.Duration) error {
+	q := sqlcgen.New(pool)
+	return withFileLock(ctx, pool, q, mRFFileID, lockTimeout, func(f *FileInfo) error {
+		if !activatableStatuses[f.Status] {
+			return fmt.Errorf("file %d has status %s; only transformed, partial or active files can be activated", f.MRFFileID, f.Status)
+		}
+		return db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+			_, err := Finalize(ctx, q.WithTx(tx), log, f.HospitalID, f.MRFFileID, true)
+			return err
+		})
+	})
+}
+
+// DeleteFile removes a file that is not the active version: its serving,
+// staging and rejected rows and its ingest.mrf_files record, in one
+// transaction under the hospital's advisory lock.
+func DeleteFile(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, mRFFileID int64, lockTimeout time.Duration) error {
+	q := sqlcgen.New(pool)
+	return withFileLock(ctx, pool, q, mRFFileID, lockTimeout, func(f *FileInfo) error {
+		if f.IsActive {
+			return fmt.Errorf("file %d is the active version of %s; activate another version first", f.MRFFileID, f.HospitalName)
+		}
+		err := deleteFileRows(ctx, pool, q, f.MRFFileID)
+		if err == nil {
+			log.Info().Int64("mrf_file_id", f.MRFFileID).Str("file", f.FileName).Msg("file deleted")
+		}
+		return err
+	})
+}
+
+// deleteFileRows removes an inactive file's serving, staging and rejected
+// rows and its ingest.mrf_files record in one transaction.
+func deleteFileRows(ctx context.Context, pool *pgxpool.Pool, q *sqlcgen.Queries, mRFFileID int64) error {
+	return db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+		qtx := q.WithTx(tx)
+		if err := qtx.DeleteServingByFile(ctx, mRFFileID); err != nil {
+			return fmt.Errorf("delete serving rows: %w", err)
+		}
+		if err := qtx.DeleteStagingByFile(ctx, mRFFileID); err != nil {
+			return fmt.Errorf("delete staging rows: %w", err)
+		}
+		// rejected_rows references mrf_files
+		if err := qtx.DeleteRejectsByFile(ctx, mRFFileID); err != nil {
+			return fmt.Errorf("delete rejected rows: %w", err)
+		}
+		n, err := qtx.DeleteMRFFile(ctx, mRFFileID)
+		if err != nil {
+			return fmt.Errorf("delete file record: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("file %d became active", mRFFileID)
+		}
+		return nil
+	})
+}
+
+// withFileLock looks up the file, takes its hospital's lock and calls fn.
+func withFileLock(ctx context.Context, pool *pgxpool.Pool, q *sqlcgen.Queries, mRFFileID int64, lockTimeout time.Duration, fn func(f *FileInfo) error) error {
+	f, err := GetFile(ctx, q, mRFFileID)
+	if err != nil {
+		return err
+	}
+	locks, err := OpenLocks(ctx, pool, uuid.New(), lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer locks.Close()
+	if err := locks.LockHospital(ctx, f.HospitalID); err != nil {
+		return err
+	}
+	// Re-read under the lock
+	if f, err = GetFile(ctx, q, mRFFileID); err != nil {
+		return err
+	}
+	return fn(f)
+}
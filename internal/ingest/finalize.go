@@ -10,7 +10,11 @@ import (
 	"github.com/gyeh/pricestats/internal/sqlcgen"
 )
 
-// Finalize activates the version, deactivates older versions, and runs ANALYZE.
+// Finalize activates the version, deactivates older versions, refreshes
+// the hospital's price statistics and records the version in its price
+// history. Run calls it inside the transaction that
+// replaced the file's serving rows, so readers switch from the old version
+// to the new one at commit.
 func Finalize(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, hospitalID, mRFFileID int64, activate bool) (time.Duration, error) {
 	start := time.Now()
 
@@ -30,6 +34,13 @@ func Finalize(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, hospi
 			return 0, fmt.Errorf("activate version: %w", err)
 		}
 		log.Info().Int64("mrf_file_id", mRFFileID).Msg("version activated")
+
+		if _, err := RefreshHospitalStats(ctx, q, log, hospitalID); err != nil {
+			return 0, fmt.Errorf("refresh stats: %w", err)
+		}
+		if err := RecordHistory(ctx, q, log, mRFFileID); err != nil {
+			return 0, fmt.Errorf("record history: %w", err)
+		}
 	} else {
 		// Just mark as transformed
 		if err := q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{MrfFileID: mRFFileID, Status: "transformed"}); err != nil {
@@ -37,14 +48,17 @@ func Finalize(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, hospi
 		}
 	}
 
-	// ANALYZE
+	return time.Since(start), nil
+}
+
+// Analyze refreshes planner statistics on the serving and staging tables.
+func Analyze(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger) error {
 	if err := q.AnalyzePrices(ctx); err != nil {
-		return 0, fmt.Errorf("analyze prices: %w", err)
+		return fmt.Errorf("analyze prices: %w", err)
 	}
 	if err := q.AnalyzeStaging(ctx); err != nil {
-		return 0, fmt.Errorf("analyze staging: %w", err)
+		return fmt.Errorf("analyze staging: %w", err)
 	}
 	log.Info().Msg("ANALYZE complete")
-
-	return time.Since(start), nil
+	return nil
 }
@@ -0,0 +1,176 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// heartbeatInterval is how often a running ingest refreshes
+// mrf_files.heartbeat_at between status changes.
+const heartbeatInterval = 30 * time.Second
+
+// startHeartbeat refreshes the file's heartbeat until the returned stop
+// function is called or ctx is done.
+func startHeartbeat(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, mRFFileID int64) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(heartbeatInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := q.TouchMRFFile(ctx, mRFFileID); err != nil && ctx.Err() == nil {
+					log.Warn().Err(err).Msg("heartbeat failed")
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// GCOptions controls GC.
+type GCOptions struct {
+	// StaleAfter is how long a file in a running status (pending, staging,
+	// staged, transforming) may go without a heartbeat before it is reaped.
+	StaleAfter time.Duration
+	// OrphanAfter is how long after a file's last activity the staging
+	// batches of a failed or cancelled run, or of a superseded run, are kept.
+	OrphanAfter time.Duration
+	// DryRun reports what would be reaped without changing anything.
+	DryRun bool
+}
+
+// GCFile is a stuck file found by GC.
+type GCFile struct {
+	MRFFileID   int64
+	FileName    string
+	Status      string
+	LastSeen    time.Time
+	StagingRows int64
+	// Skipped explains why a candidate was left alone, e.g. its hospital
+	// is locked by a live ingest.
+	Skipped string
+}
+
+// GCBatch is an orphaned staging batch found by GC.
+type GCBatch struct {
+	MRFFileID     int64
+	IngestBatchID uuid.UUID
+	FileStatus    string
+	LastSeen      time.Time
+	StagingRows   int64
+}
+
+// GCReport lists what GC reaped, or would reap in a dry run.
+type GCReport struct {
+	Files   []GCFile
+	Batches []GCBatch
+}
+
+// GC reaps the leftovers of crashed ingests. A file stuck in a running
+// status past opts.StaleAfter has its staging rows and, unless it is the
+// active version, its serving rows deleted, and is marked failed. A file
+// whose hospital lock is held by a live ingest is skipped. Staging batches
+// of failed, cancelled or superseded runs idle past opts.OrphanAfter are
+// deleted.
+func GC(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, opts GCOptions) (*GCReport, error) {
+	q := sqlcgen.New(pool)
+	report := &GCReport{}
+
+	stale, err := q.ListStaleFiles(ctx, opts.StaleAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("list stale files: %w", err)
+	}
+	for _, f := range stale {
+		gf := GCFile{
+			MRFFileID:   f.MrfFileID,
+			FileName:    f.SourceFileName,
+			Status:      f.Status,
+			LastSeen:    f.LastSeen.Time,
+			StagingRows: f.StagingRows,
+		}
+		if !opts.DryRun {
+			if gf.Skipped, err = reapFile(ctx, pool, q, f, opts.StaleAfter); err != nil {
+				return report, fmt.Errorf("reap file %d: %w", f.MrfFileID, err)
+			}
+		}
+		log.Info().Int64("mrf_file_id", gf.MRFFileID).Str("status", gf.Status).
+			Time("last_seen", gf.LastSeen).Str("skipped", gf.Skipped).Bool("dry_run", opts.DryRun).
+			Msg("gc: stale file")
+		report.Files = append(report.Files, gf)
+	}
+
+	batches, err := q.ListOrphanBatches(ctx, opts.OrphanAfter.Seconds())
+	if err != nil {
+		return report, fmt.Errorf("list orphan batches: %w", err)
+	}
+	for _, b := range batches {
+		gb := GCBatch{
+			MRFFileID:     b.MrfFileID,
+			IngestBatchID: b.IngestBatchID,
+			FileStatus:    b.Status,
+			LastSeen:      b.LastSeen.Time,
+			StagingRows:   b.StagingRows,
+		}
+		if !opts.DryRun {
+			if _, err := q.DeleteStagingBatch(ctx, b.IngestBatchID); err != nil {
+				return report, fmt.Errorf("delete staging batch %s: %w", b.IngestBatchID, err)
+			}
+		}
+		log.Info().Int64("mrf_file_id", gb.MRFFileID).Str("batch", gb.IngestBatchID.String()).
+			Int64("staging_rows", gb.StagingRows).Bool("dry_run", opts.DryRun).
+			Msg("gc: orphaned staging batch")
+		report.Batches = append(report.Batches, gb)
+	}
+	return report, nil
+}
+
+// reapFile fails one stale file under its hospital's lock and deletes its
+// staging rows and, if it is not the active version, its serving rows. It
+// returns why the file was skipped, if it was.
+func reapFile(ctx context.Context, pool *pgxpool.Pool, q *sqlcgen.Queries, f *sqlcgen.ListStaleFilesRow, staleAfter time.Duration) (skipped string, err error) {
+	locks, err := OpenLocks(ctx, pool, uuid.New(), 0)
+	if err != nil {
+		return "", err
+	}
+	defer locks.Close()
+	if err := locks.LockHospital(ctx, f.HospitalID); err != nil {
+		var locked *LockedError
+		if errors.As(err, &locked) {
+			return "hospital locked by a live ingest", nil
+		}
+		return "", err
+	}
+
+	n, err := q.FailStaleFile(ctx, sqlcgen.FailStaleFileParams{MrfFileID: f.MrfFileID, StaleAfterSecs: staleAfter.Seconds()})
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "heartbeat resumed", nil
+	}
+	if err := q.DeleteStagingByFile(ctx, f.MrfFileID); err != nil {
+		return "", fmt.Errorf("delete staging rows: %w", err)
+	}
+	if !f.IsActive {
+		if err := q.DeleteServingByFile(ctx, f.MrfFileID); err != nil {
+			return "", fmt.Errorf("delete serving rows: %w", err)
+		}
+	}
+	return "", nil
+}
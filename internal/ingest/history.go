@@ -0,0 +1,275 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/normalize"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// RecordHistory folds the serving rows of a version being activated into
+// mrf.price_history as of the file's last_updated_on: items the file drops
+// or reprices get their current period closed, items it republishes
+// unchanged extend theirs, and new or repriced items open one. Finalize
+// calls it in the transaction that activates the version, so a load that
+// is not activated leaves the history alone. Activating a version dated
+// before the hospital's latest recorded one is a rollback: the periods the
+// later versions opened are dropped and the ones they closed reopened
+// before the file is folded in.
+func RecordHistory(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, mRFFileID int64) error {
+	dates, err := q.GetHistoryDates(ctx, mRFFileID)
+	if err != nil {
+		return fmt.Errorf("get history dates: %w", err)
+	}
+	if dates.ValidFrom.Time.Before(dates.LatestValidFrom.Time) {
+		withdrawn, err := q.DeleteLaterPriceHistory(ctx, sqlcgen.DeleteLaterPriceHistoryParams{
+			HospitalID: dates.HospitalID, ValidFrom: dates.ValidFrom,
+		})
+		if err != nil {
+			return fmt.Errorf("delete later price history: %w", err)
+		}
+		reopened, err := q.ReopenPriceHistory(ctx, sqlcgen.ReopenPriceHistoryParams{
+			HospitalID: dates.HospitalID, ValidFrom: dates.ValidFrom,
+		})
+		if err != nil {
+			return fmt.Errorf("reopen price history: %w", err)
+		}
+		log.Info().Time("valid_from", dates.ValidFrom.Time).Time("latest", dates.LatestValidFrom.Time).
+			Int64("withdrawn", withdrawn).Int64("reopened", reopened).Msg("price history rolled back")
+	}
+
+	items, err := q.FillHistoryItems(ctx, mRFFileID)
+	if err != nil {
+		return fmt.Errorf("fill history items: %w", err)
+	}
+	closed, err := q.ClosePriceHistory(ctx, sqlcgen.ClosePriceHistoryParams{
+		ValidFrom: dates.ValidFrom, HospitalID: dates.HospitalID, MrfFileID: mRFFileID,
+	})
+	if err != nil {
+		return fmt.Errorf("close price history: %w", err)
+	}
+	replaced, err := q.DeleteReplacedPriceHistory(ctx, sqlcgen.DeleteReplacedPriceHistoryParams{
+		HospitalID: dates.HospitalID, ValidFrom: dates.ValidFrom, MrfFileID: mRFFileID,
+	})
+	if err != nil {
+		return fmt.Errorf("delete replaced price history: %w", err)
+	}
+	if err := q.ExtendPriceHistory(ctx, sqlcgen.ExtendPriceHistoryParams{MrfFileID: mRFFileID, HospitalID: dates.HospitalID}); err != nil {
+		return fmt.Errorf("extend price history: %w", err)
+	}
+	opened, err := q.InsertPriceHistory(ctx, sqlcgen.InsertPriceHistoryParams{
+		HospitalID: dates.HospitalID, ValidFrom: dates.ValidFrom, MrfFileID: mRFFileID,
+	})
+	if err != nil {
+		return fmt.Errorf("insert price history: %w", err)
+	}
+	if err := q.ClearHistoryItems(ctx, mRFFileID); err != nil {
+		return fmt.Errorf("clear history items: %w", err)
+	}
+	log.Info().Time("valid_from", dates.ValidFrom.Time).Int64("items", items).Int64("closed", closed).
+		Int64("replaced", replaced).Int64("opened", opened).Msg("price history recorded")
+	return nil
+}
+
+// historyStatuses are the file statuses whose serving rows were published.
+var historyStatuses = map[string]bool{"transformed": true, "partial": true, "active": true}
+
+// RebuildHistory recomputes the price history of the hospitals matching
+// hospital (a case-insensitive substring) by replaying their published
+// files still on disk, oldest last_updated_on first, up to and including
+// the active version, in one transaction per hospital under the
+// hospital's advisory lock. Later loads that were never activated are left
+// out, and a hospital without an active version is left without history.
+// Periods only known from pruned versions are lost. It returns the number
+// of files replayed.
+func RebuildHistory(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, hospital string, lockTimeout time.Duration) (int, error) {
+	q := sqlcgen.New(pool)
+	files, err := ListFiles(ctx, q, FileFilter{Hospital: &hospital})
+	if err != nil {
+		return 0, err
+	}
+	names := map[int64]string{}
+	for _, f := range files {
+		if historyStatuses[f.Status] {
+			names[f.HospitalID] = f.HospitalName
+		}
+	}
+
+	replayed := 0
+	for hospitalID, name := range names {
+		n, err := rebuildLocked(ctx, pool, q, log, hospital, hospitalID, lockTimeout)
+		if err != nil {
+			return replayed, fmt.Errorf("rebuild history of %s: %w", name, err)
+		}
+		replayed += n
+	}
+	return replayed, nil
+}
+
+// rebuildLocked replays one hospital's files under its advisory lock,
+// listing them again once the lock is held so a concurrent activation is
+// not missed.
+func rebuildLocked(ctx context.Context, pool *pgxpool.Pool, q *sqlcgen.Queries, log zerolog.Logger, hospital string, hospitalID int64, lockTimeout time.Duration) (int, error) {
+	locks, err := OpenLocks(ctx, pool, uuid.New(), lockTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer locks.Close()
+	if err := locks.LockHospital(ctx, hospitalID); err != nil {
+		return 0, err
+	}
+
+	files, err := ListFiles(ctx, q, FileFilter{Hospital: &hospital})
+	if err != nil {
+		return 0, err
+	}
+	var hfiles []FileInfo
+	for _, f := range files {
+		if f.HospitalID == hospitalID && historyStatuses[f.Status] {
+			hfiles = append(hfiles, f)
+		}
+	}
+	hfiles = replayFiles(hfiles)
+	err = db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+		qtx := q.WithTx(tx)
+		if _, err := qtx.DeleteHospitalPriceHistory(ctx, hospitalID); err != nil {
+			return fmt.Errorf("delete price history: %w", err)
+		}
+		for _, f := range hfiles {
+			flog := log.With().Int64("mrf_file_id", f.MRFFileID).Logger()
+			if err := RecordHistory(ctx, qtx, flog, f.MRFFileID); err != nil {
+				return fmt.Errorf("file %d: %w", f.MRFFileID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(hfiles), nil
+}
+
+// replayFiles orders a hospital's published files by the date their prices
+// take effect and cuts the list after the active version; none are kept
+// when no version is active.
+func replayFiles(files []FileInfo) []FileInfo {
+	sort.SliceStable(files, func(i, j int) bool {
+		di, dj := historyDate(files[i]), historyDate(files[j])
+		if !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		return files[i].MRFFileID < files[j].MRFFileID
+	})
+	for i, f := range files {
+		if f.IsActive {
+			return files[:i+1]
+		}
+	}
+	return nil
+}
+
+// historyDate is the date a file's prices take effect, as GetHistoryDates
+// computes it.
+func historyDate(f FileInfo) time.Time {
+	if f.LastUpdatedOn != nil {
+		return *f.LastUpdatedOn
+	}
+	y, m, d := f.ImportedAt.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// PricePeriod is one item's prices over [ValidFrom, ValidTo); ValidTo is
+// nil while they are current.
+type PricePeriod struct {
+	Hospital                string     `json:"hospital"`
+	CodeType                string     `json:"code_type"`
+	Code                    string     `json:"code"`
+	Description             string     `json:"description"`
+	Setting                 *string    `json:"setting"`
+	Payer                   *string    `json:"payer"`
+	Plan                    *string    `json:"plan"`
+	Modifiers               *string    `json:"modifiers"`
+	GrossChargeCents        *int64     `json:"gross_charge_cents"`
+	DiscountedCashCents     *int64     `json:"discounted_cash_cents"`
+	NegotiatedDollarCents   *int64     `json:"negotiated_dollar_cents"`
+	NegotiatedPercentageBps *int32     `json:"negotiated_percentage_bps"`
+	EstimatedAmountCents    *int64     `json:"estimated_amount_cents"`
+	ValidFrom               time.Time  `json:"valid_from"`
+	ValidTo                 *time.Time `json:"valid_to"`
+	FirstMRFFileID          int64      `json:"first_mrf_file_id"`
+	LastMRFFileID           int64      `json:"last_mrf_file_id"`
+}
+
+// HistoryFilter selects the periods ListHistory returns. Hospital and Code
+// are required.
+type HistoryFilter struct {
+	Hospital string  // case-insensitive substring of the hospital name
+	Code     string  // raw code, canonicalized like the transform does
+	CodeType *string // e.g. "CPT"; nil matches every type
+	Payer    *string // case-insensitive substring of the payer name
+}
+
+// ListHistory returns the price periods of a code at the matching
+// hospitals, grouped by item and oldest first within an item.
+func ListHistory(ctx context.Context, q *sqlcgen.Queries, f HistoryFilter) ([]PricePeriod, error) {
+	rows, err := q.ListPriceHistory(ctx, sqlcgen.ListPriceHistoryParams{
+		Hospital:  f.Hospital,
+		CodeNorms: codeNorms(f.Code, f.CodeType),
+		CodeType:  f.CodeType,
+		Payer:     f.Payer,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list price history: %w", err)
+	}
+	out := make([]PricePeriod, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, PricePeriod{
+			Hospital:                r.HospitalName,
+			CodeType:                r.CodeType,
+			Code:                    r.CodeNorm,
+			Description:             r.Description,
+			Setting:                 r.Setting,
+			Payer:                   r.PayerName,
+			Plan:                    r.PlanName,
+			Modifiers:               r.Modifiers,
+			GrossChargeCents:        r.GrossChargeCents,
+			DiscountedCashCents:     r.DiscountedCashCents,
+			NegotiatedDollarCents:   r.NegotiatedDollarCents,
+			NegotiatedPercentageBps: r.NegotiatedPercentageBps,
+			EstimatedAmountCents:    r.EstimatedAmountCents,
+			ValidFrom:               r.ValidFrom.Time,
+			ValidTo:                 r.ValidTo,
+			FirstMRFFileID:          r.FirstMrfFileID,
+			LastMRFFileID:           r.LastMrfFileID,
+		})
+	}
+	return out, nil
+}
+
+// codeNorms canonicalizes a raw code for codeType, or for every registered
+// type when codeType is nil.
+func codeNorms(code string, codeType *string) []string {
+	if codeType != nil {
+		norm, _ := normalize.CanonicalizeCode(*codeType, code)
+		return []string{norm}
+	}
+	seen := map[string]bool{}
+	var norms []string
+	for _, ct := range model.AllCodeTypes {
+		if norm, _ := normalize.CanonicalizeCode(ct.Name, code); !seen[norm] {
+			seen[norm] = true
+			norms = append(norms, norm)
+		}
+	}
+	return norms
+}
@@ -2,6 +2,7 @@ package ingest_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -11,15 +12,22 @@ import (
 	"time"
 
 	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	goparquet "github.com/parquet-go/parquet-go"
 
+	"github.com/gyeh/pricestats/internal/batch"
 	"github.com/gyeh/pricestats/internal/config"
 	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/diff"
+	"github.com/gyeh/pricestats/internal/index"
 	"github.com/gyeh/pricestats/internal/ingest"
 	"github.com/gyeh/pricestats/internal/logging"
 	"github.com/gyeh/pricestats/internal/model"
 	"github.com/gyeh/pricestats/internal/normalize"
+	"github.com/gyeh/pricestats/internal/prices"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
 )
 
 const (
@@ -347,9 +355,8 @@ func TestEndToEnd_DefaultMode(t *testing.T) {
 						rowNum, sc.codeType)
 					continue
 				}
-				// code_raw in DB should match the normalized code from staging
-				// (codes are normalized in the Go layer, then normalized again in SQL)
-				expectedNorm := normalizeCodeStr(*pqPtr)
+				// code_norm is the type-aware canonical form of the staged raw code
+				expectedNorm := canonicalCodeStr(sc.codeType, *pqPtr)
 				if sc.codeNorm != expectedNorm {
 					t.Errorf("row %d code_type %s: code_norm got %q, want %q",
 						rowNum, sc.codeType, sc.codeNorm, expectedNorm)
@@ -641,7 +648,7 @@ func TestEndToEnd_ServingDataParityWithParquet(t *testing.T) {
 				if ptr == nil || strings.TrimSpace(*ptr) == "" {
 					continue
 				}
-				norm := normalizeCodeStr(*ptr)
+				norm := canonicalCodeStr(name, *ptr)
 				if !dbCodes[codeKey{codeType: name, codeNorm: norm}] {
 					if missing < 10 {
 						t.Errorf("missing from DB: code_type=%s code_norm=%s (raw=%s)",
@@ -676,20 +683,12 @@ func assertCentsMatch(t *testing.T, label string, dollars *float64, cents *int64
 	}
 }
 
-// normalizeCodeStr mirrors the SQL normalization: upper + strip non-alphanumeric.
-// This matches the Go normalize.NormalizeCode logic and the SQL
-// upper(regexp_replace(code_raw, '[^A-Za-z0-9]', '', 'g')).
-func normalizeCodeStr(raw string) string {
-	s := strings.TrimSpace(raw)
-	s = strings.ToUpper(s)
-	// Strip non-alphanumeric
-	var b strings.Builder
-	for _, r := range s {
-		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
-			b.WriteRune(r)
-		}
-	}
-	return b.String()
+// canonicalCodeStr is the code_norm expected for a raw source code: the Go
+// canonicalizer, which TestCanonicalizeCode_SQLParity holds equal to the
+// mrf.canonicalize_code function used by the transform.
+func canonicalCodeStr(codeType, raw string) string {
+	norm, _ := normalize.CanonicalizeCode(codeType, strings.TrimSpace(raw))
+	return norm
 }
 
 func TestEndToEnd_CodeTypeFilter(t *testing.T) {
@@ -751,5 +750,1337 @@ func TestEndToEnd_CodeTypeFilter(t *testing.T) {
 	_ = summary // used for pipeline return check
 }
 
+// rejectsCSV is a tall CMS CSV with one stageable row and one row missing
+// its description.
+const rejectsCSV = `hospital_name,last_updated_on,version,hospital_location,hospital_address
+Reject Hospital,2024-07-01,2.0.0,Main Campus,1 Main St
+description,code|1,code|1|type,setting,standard_charge|gross,standard_charge|discounted_cash,standard_charge|min,standard_charge|max,payer_name,plan_name,standard_charge|negotiated_dollar,standard_charge|negotiated_percentage,standard_charge|negotiated_algorithm,standard_charge|methodology
+Office visit,99213,CPT,outpatient,250,200,90,240,,,,,,
+,99214,CPT,outpatient,300,250,100,280,,,,,,
+`
+
+func TestEndToEnd_RejectsQuarantined(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	path := t.TempDir() + "/rejects.csv"
+	if err := os.WriteFile(path, []byte(rejectsCSV), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	cfg := &config.Config{
+		DSN:       testDSN,
+		FilePath:  path,
+		LogFormat: "text",
+	}
+	summary, err := ingest.Run(ctx, pool, log, cfg)
+	if err != nil {
+		t.Fatalf("pipeline.Run: %v", err)
+	}
+	if summary.RowsRead != 2 || summary.RowsStaged != 1 || summary.RowsRejected != 1 {
+		t.Fatalf("unexpected counts: read=%d staged=%d rejected=%d", summary.RowsRead, summary.RowsStaged, summary.RowsRejected)
+	}
+	// The default policy sets no limit, so the reject is only reported
+	if summary.Outcome != model.OutcomeSuccess || summary.OutcomeReason == "" {
+		t.Errorf("Outcome: got %s (%q), want %s with a reason", summary.Outcome, summary.OutcomeReason, model.OutcomeSuccess)
+	}
+	var status string
+	pool.QueryRow(ctx, "SELECT status FROM ingest.mrf_files WHERE mrf_file_id = $1", summary.MRFFileID).Scan(&status)
+	if status != "transformed" {
+		t.Errorf("file status: got %s, want transformed", status)
+	}
+
+	var rowNum int64
+	var reason, field, code string
+	err = pool.QueryRow(ctx,
+		`SELECT source_row_number, reason_code, field_name, raw_row->>'cpt_code'
+		 FROM ingest.rejected_rows WHERE mrf_file_id = $1`, summary.MRFFileID).
+		Scan(&rowNum, &reason, &field, &code)
+	if err != nil {
+		t.Fatalf("query rejected row: %v", err)
+	}
+	if rowNum != 2 || reason != string(normalize.ReasonMissingRequired) || field != "description" || code != "99214" {
+		t.Errorf("unexpected reject: row=%d reason=%s field=%s code=%s", rowNum, reason, field, code)
+	}
+
+	// Re-importing replaces the file's quarantined rows rather than appending
+	cfg.Force = true
+	if _, err := ingest.Run(ctx, pool, log, cfg); err != nil {
+		t.Fatalf("re-import: %v", err)
+	}
+	var count int64
+	pool.QueryRow(ctx, "SELECT count(*) FROM ingest.rejected_rows WHERE mrf_file_id = $1", summary.MRFFileID).Scan(&count)
+	if count != 1 {
+		t.Errorf("expected 1 rejected row after re-import, got %d", count)
+	}
+}
+
+// Rows left out by code_types were asked for: they are counted apart and
+// do not count against the reject policy.
+func TestEndToEnd_CodeTypesFilterNotAProblem(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	body := strings.Replace(moneyCSV, "Consult,99215,CPT,outpatient,-5,250,100,280,,,,,,\n",
+		"Heart failure,291,MS-DRG,inpatient,25000,20000,18000,30000,,,,,,\n", 1)
+	path := t.TempDir() + "/drg.csv"
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	cfg := &config.Config{
+		DSN:          testDSN,
+		FilePath:     path,
+		LogFormat:    "text",
+		CodeTypes:    []string{"MS-DRG"},
+		RejectPolicy: config.RejectPolicy{MaxRejectPct: 10},
+	}
+	summary, err := ingest.Run(ctx, pool, log, cfg)
+	if err != nil {
+		t.Fatalf("pipeline.Run: %v", err)
+	}
+	if summary.Outcome != model.OutcomeSuccess || summary.OutcomeReason != "" {
+		t.Errorf("Outcome: got %s (%q), want a clean success", summary.Outcome, summary.OutcomeReason)
+	}
+	if summary.RowsFilteredOut != 2 || summary.RowsWithoutCodes != 0 || summary.RowsInsertedServing != 1 {
+		t.Errorf("unexpected counts: filtered_out=%d without_codes=%d serving=%d",
+			summary.RowsFilteredOut, summary.RowsWithoutCodes, summary.RowsInsertedServing)
+	}
+}
+
+func TestEndToEnd_RejectPolicyFailure(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	path := t.TempDir() + "/rejects.csv"
+	if err := os.WriteFile(path, []byte(rejectsCSV), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	cfg := &config.Config{
+		DSN:             testDSN,
+		FilePath:        path,
+		LogFormat:       "text",
+		ActivateVersion: true,
+		RejectPolicy:    config.RejectPolicy{MaxRejectPct: 10},
+	}
+	summary, err := ingest.Run(ctx, pool, log, cfg)
+
+	var pe *ingest.PipelineError
+	if !errors.As(err, &pe) || pe.Phase != "policy" {
+		t.Fatalf("expected policy PipelineError, got %v", err)
+	}
+	if summary == nil || summary.Outcome != model.OutcomeFailed || summary.OutcomeReason == "" {
+		t.Fatalf("expected failed summary with reason, got %+v", summary)
+	}
+
+	var serving int64
+	pool.QueryRow(ctx, "SELECT count(*) FROM mrf.prices_by_code WHERE mrf_file_id = $1", summary.MRFFileID).Scan(&serving)
+	if serving != 0 {
+		t.Errorf("serving rows should be rolled back, got %d", serving)
+	}
+	var status string
+	var active bool
+	pool.QueryRow(ctx, "SELECT status, is_active FROM ingest.mrf_files WHERE mrf_file_id = $1", summary.MRFFileID).Scan(&status, &active)
+	if status != "failed" || active {
+		t.Errorf("file should be failed and inactive: status=%s active=%v", status, active)
+	}
+}
+
+// moneyCSV has a clean row, a row whose min exceeds its max (flagged by
+// default) and a row with a negative gross charge (rejected by default).
+const moneyCSV = `hospital_name,last_updated_on,version,hospital_location,hospital_address
+Money Hospital,2024-07-01,2.0.0,Main Campus,1 Main St
+description,code|1,code|1|type,setting,standard_charge|gross,standard_charge|discounted_cash,standard_charge|min,standard_charge|max,payer_name,plan_name,standard_charge|negotiated_dollar,standard_charge|negotiated_percentage,standard_charge|negotiated_algorithm,standard_charge|methodology
+Office visit,99213,CPT,outpatient,250,200,90,240,,,,,,
+Follow-up visit,99214,CPT,outpatient,300,250,280,100,,,,,,
+Consult,99215,CPT,outpatient,-5,250,100,280,,,,,,
+`
+
+func TestEndToEnd_MoneyRulesQualityFlags(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	path := t.TempDir() + "/money.csv"
+	if err := os.WriteFile(path, []byte(moneyCSV), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	cfg := &config.Config{
+		DSN:       testDSN,
+		FilePath:  path,
+		LogFormat: "text",
+	}
+	summary, err := ingest.Run(ctx, pool, log, cfg)
+	if err != nil {
+		t.Fatalf("pipeline.Run: %v", err)
+	}
+	if summary.RowsStaged != 2 || summary.RowsRejected != 1 {
+		t.Fatalf("unexpected counts: staged=%d rejected=%d", summary.RowsStaged, summary.RowsRejected)
+	}
+
+	var reason, detail string
+	pool.QueryRow(ctx, "SELECT reason_code, detail FROM ingest.rejected_rows WHERE mrf_file_id = $1", summary.MRFFileID).Scan(&reason, &detail)
+	if reason != string(normalize.ReasonInvalidAmount) || !strings.HasPrefix(detail, string(normalize.RuleNegative)) {
+		t.Errorf("unexpected reject: reason=%s detail=%s", reason, detail)
+	}
+
+	flags := map[string][]string{}
+	rows, err := pool.Query(ctx, "SELECT code_norm, quality_flags FROM mrf.prices_by_code WHERE mrf_file_id = $1", summary.MRFFileID)
+	if err != nil {
+		t.Fatalf("query serving: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		var f []string
+		if err := rows.Scan(&code, &f); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		flags[code] = f
+	}
+	if f := flags["99213"]; f != nil {
+		t.Errorf("clean row should have NULL quality_flags, got %v", f)
+	}
+	if f := flags["99214"]; len(f) != 1 || f[0] != "min_exceeds_max:min_charge" {
+		t.Errorf("99214 quality_flags: got %v", f)
+	}
+}
+
+func TestEndToEnd_Resume(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	path := t.TempDir() + "/rejects.csv"
+	if err := os.WriteFile(path, []byte(rejectsCSV), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	// A policy failure with --keep-staging leaves a failed file whose
+	// staging completed
+	cfg := &config.Config{
+		DSN:             testDSN,
+		FilePath:        path,
+		LogFormat:       "text",
+		ActivateVersion: true,
+		KeepStaging:     true,
+		RejectPolicy:    config.RejectPolicy{MaxRejectPct: 10},
+	}
+	failed, err := ingest.Run(ctx, pool, log, cfg)
+	if err == nil {
+		t.Fatal("expected policy failure")
+	}
+	var batchID string
+	var rowsStaged int64
+	pool.QueryRow(ctx, "SELECT ingest_batch_id::text, rows_staged FROM ingest.mrf_files WHERE mrf_file_id = $1",
+		failed.MRFFileID).Scan(&batchID, &rowsStaged)
+	if batchID != failed.IngestBatchID || rowsStaged != failed.RowsStaged {
+		t.Fatalf("recorded batch %s/%d, want %s/%d", batchID, rowsStaged, failed.IngestBatchID, failed.RowsStaged)
+	}
+
+	cfg.RejectPolicy = config.RejectPolicy{MaxRejectPct: 100}
+	cfg.Resume = true
+	resumed, err := ingest.Run(ctx, pool, log, cfg)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if !resumed.Resumed || resumed.IngestBatchID != failed.IngestBatchID {
+		t.Errorf("expected staging of batch %s reused, got resumed=%v batch=%s", failed.IngestBatchID, resumed.Resumed, resumed.IngestBatchID)
+	}
+	if resumed.RowsStaged != failed.RowsStaged || resumed.RowsRejected != failed.RowsRejected || resumed.RowsInsertedServing == 0 {
+		t.Errorf("resumed counts: %+v", resumed)
+	}
+	var status string
+	pool.QueryRow(ctx, "SELECT status FROM ingest.mrf_files WHERE mrf_file_id = $1", resumed.MRFFileID).Scan(&status)
+	if status != "partial" {
+		t.Errorf("status after resume: got %s, want partial", status)
+	}
+
+	// Incomplete staging is not reused
+	if _, err := pool.Exec(ctx, "UPDATE ingest.mrf_files SET status = 'failed' WHERE mrf_file_id = $1", resumed.MRFFileID); err != nil {
+		t.Fatalf("reset status: %v", err)
+	}
+	if _, err := pool.Exec(ctx, "DELETE FROM ingest.stage_charge_rows WHERE ctid IN (SELECT ctid FROM ingest.stage_charge_rows LIMIT 1)"); err != nil {
+		t.Fatalf("delete staging row: %v", err)
+	}
+	restaged, err := ingest.Run(ctx, pool, log, cfg)
+	if err != nil {
+		t.Fatalf("resume with damaged staging: %v", err)
+	}
+	if restaged.Resumed || restaged.IngestBatchID == failed.IngestBatchID || restaged.RowsStaged != failed.RowsStaged {
+		t.Errorf("expected a fresh staging run, got %+v", restaged)
+	}
+}
+
+func TestEndToEnd_ChunkedStagingCheckpoint(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	cfg := &config.Config{
+		DSN:             testDSN,
+		FilePath:        fixtureFile(),
+		LogFormat:       "text",
+		ActivateVersion: true,
+		KeepStaging:     true,
+		ChunkRows:       10,
+	}
+	full, err := ingest.Run(ctx, pool, log, cfg)
+	if err != nil {
+		t.Fatalf("pipeline.Run: %v", err)
+	}
+	if full.RowsRead <= 20 {
+		t.Skipf("fixture too small for a mid-file checkpoint: %d rows", full.RowsRead)
+	}
+	var checkpoint int64
+	pool.QueryRow(ctx, "SELECT staged_through_row FROM ingest.mrf_files WHERE mrf_file_id = $1", full.MRFFileID).Scan(&checkpoint)
+	if checkpoint != full.RowsRead {
+		t.Errorf("checkpoint after staging: got %d, want %d", checkpoint, full.RowsRead)
+	}
+
+	// Simulate a COPY that died after the second chunk committed
+	for _, stmt := range []string{
+		"DELETE FROM ingest.stage_charge_rows WHERE mrf_file_id = $1 AND source_row_number > 20",
+		"DELETE FROM ingest.rejected_rows WHERE mrf_file_id = $1 AND source_row_number > 20",
+		"DELETE FROM mrf.prices_by_code WHERE mrf_file_id = $1",
+		`UPDATE ingest.mrf_files SET status = 'staging', is_active = false, staged_through_row = 20,
+		 rows_read = NULL, rows_staged = NULL, rows_rejected = NULL WHERE mrf_file_id = $1`,
+	} {
+		if _, err := pool.Exec(ctx, stmt, full.MRFFileID); err != nil {
+			t.Fatalf("simulate interruption: %v", err)
+		}
+	}
+
+	cfg.Resume = true
+	resumed, err := ingest.Run(ctx, pool, log, cfg)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if !resumed.Resumed || resumed.IngestBatchID != full.IngestBatchID {
+		t.Errorf("expected batch %s continued, got resumed=%v batch=%s", full.IngestBatchID, resumed.Resumed, resumed.IngestBatchID)
+	}
+	if resumed.RowsRead != full.RowsRead || resumed.RowsStaged != full.RowsStaged ||
+		resumed.RowsRejected != full.RowsRejected || resumed.RowsInsertedServing != full.RowsInsertedServing {
+		t.Errorf("resumed counts differ:\n got  %+v\n want %+v", resumed, full)
+	}
+	var dupes int64
+	pool.QueryRow(ctx, `SELECT count(*) - count(DISTINCT source_row_number) FROM ingest.stage_charge_rows
+		WHERE mrf_file_id = $1`, full.MRFFileID).Scan(&dupes)
+	if dupes != 0 {
+		t.Errorf("%d source rows staged twice", dupes)
+	}
+}
+
+func TestEndToEnd_Cancelled(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	// Block the transform on a serving table lock, then cancel while the
+	// run waits on it
+	blocker, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer blocker.Rollback(ctx)
+	if _, err := blocker.Exec(ctx, "LOCK TABLE mrf.prices_by_code IN ACCESS EXCLUSIVE MODE"); err != nil {
+		t.Fatalf("lock serving table: %v", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		for runCtx.Err() == nil {
+			var n int64
+			pool.QueryRow(ctx, "SELECT count(*) FROM ingest.mrf_files WHERE status = 'transforming'").Scan(&n)
+			if n > 0 {
+				cancel()
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	cfg := &config.Config{DSN: testDSN, FilePath: fixtureFile(), LogFormat: "text", ActivateVersion: true}
+	_, err = ingest.Run(runCtx, pool, log, cfg)
+	var pe *ingest.PipelineError
+	if !errors.As(err, &pe) || !errors.Is(err, context.Canceled) || pe.Phase != "transform" {
+		t.Fatalf("expected cancelled transform PipelineError, got %v", err)
+	}
+	blocker.Rollback(ctx)
+
+	var status string
+	var staged int64
+	pool.QueryRow(ctx, "SELECT status FROM ingest.mrf_files").Scan(&status)
+	pool.QueryRow(ctx, "SELECT count(*) FROM ingest.stage_charge_rows").Scan(&staged)
+	if status != "cancelled" || staged != 0 {
+		t.Errorf("after cancel: status=%s staging rows=%d, want cancelled and 0", status, staged)
+	}
+
+	// The cancelled file is picked up again by a later run
+	summary, err := ingest.Run(ctx, pool, log, cfg)
+	if err != nil || summary.Outcome == model.OutcomeSkipped {
+		t.Fatalf("re-run after cancel: %v %+v", err, summary)
+	}
+}
+
+func TestEndToEnd_ReadersSeeOneCompleteVersion(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	moneyPath := t.TempDir() + "/money.csv"
+	if err := os.WriteFile(moneyPath, []byte(moneyCSV), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	base := config.Config{
+		DSN:             testDSN,
+		FilePath:        fixtureFile(),
+		LogFormat:       "text",
+		HospitalName:    "Swap Hospital",
+		ActivateVersion: true,
+	}
+	cfg := base
+	first, err := ingest.Run(ctx, pool, log, &cfg)
+	if err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	var hospitalID int64
+	pool.QueryRow(ctx, "SELECT hospital_id FROM ingest.mrf_files WHERE mrf_file_id = $1", first.MRFFileID).Scan(&hospitalID)
+
+	// The reader checks, in one statement, that the hospital's active
+	// serving rows come from a single file and number exactly that file's
+	// complete row count: the fixture's, or the two clean rows of moneyCSV.
+	const moneyServingRows = 2
+	done := make(chan struct{})
+	readerErr := make(chan error, 1)
+	var reads int
+	go func() {
+		defer close(readerErr)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			var files, rows, fileID int64
+			err := pool.QueryRow(ctx, `
+				SELECT count(DISTINCT p.mrf_file_id), count(*), coalesce(min(p.mrf_file_id), 0)
+				FROM mrf.prices_by_code p
+				JOIN ingest.mrf_files f USING (mrf_file_id)
+				WHERE f.hospital_id = $1 AND f.is_active`, hospitalID).Scan(&files, &rows, &fileID)
+			if err != nil {
+				readerErr <- err
+				return
+			}
+			reads++
+			want := int64(moneyServingRows)
+			if fileID == first.MRFFileID {
+				want = first.RowsInsertedServing
+			}
+			if files != 1 || rows != want {
+				readerErr <- fmt.Errorf("read %d: %d active files, %d rows of file %d (complete: %d)", reads, files, rows, fileID, want)
+				return
+			}
+		}
+	}()
+
+	// Re-import the active file in place, then publish a different file
+	cfg = base
+	cfg.Force = true
+	if _, err := ingest.Run(ctx, pool, log, &cfg); err != nil {
+		t.Fatalf("forced re-import: %v", err)
+	}
+	cfg = base
+	cfg.FilePath = moneyPath
+	second, err := ingest.Run(ctx, pool, log, &cfg)
+	if err != nil {
+		t.Fatalf("second file: %v", err)
+	}
+	close(done)
+	if err := <-readerErr; err != nil {
+		t.Fatal(err)
+	}
+	if reads == 0 {
+		t.Fatal("reader made no reads")
+	}
+
+	var rows int64
+	pool.QueryRow(ctx, `SELECT count(*) FROM mrf.prices_by_code p JOIN ingest.mrf_files f USING (mrf_file_id)
+		WHERE f.hospital_id = $1 AND f.is_active`, hospitalID).Scan(&rows)
+	if rows != moneyServingRows || second.RowsInsertedServing != moneyServingRows {
+		t.Errorf("after publish: %d active rows (run reported %d), want %d", rows, second.RowsInsertedServing, moneyServingRows)
+	}
+}
+
+func TestGC(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	dir := t.TempDir()
+	ingestFile := func(name, body, hospital string) *model.IngestSummary {
+		t.Helper()
+		path := dir + "/" + name
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		cfg := &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", KeepStaging: true, HospitalName: hospital}
+		s, err := ingest.Run(ctx, pool, log, cfg)
+		if err != nil {
+			t.Fatalf("ingest %s: %v", name, err)
+		}
+		return s
+	}
+	failedRun := ingestFile("rejects.csv", rejectsCSV, "")
+	crashed := ingestFile("money.csv", moneyCSV, "")
+	locked := ingestFile("locked.csv", moneyCSV, "Locked Hospital")
+
+	// Simulate a failed run two days ago and two crashes mid-transform
+	exec := func(sql string, args ...any) {
+		t.Helper()
+		if _, err := pool.Exec(ctx, sql, args...); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	exec("UPDATE ingest.mrf_files SET status = 'failed', heartbeat_at = now() - interval '48 hours' WHERE mrf_file_id = $1", failedRun.MRFFileID)
+	exec(`UPDATE ingest.mrf_files SET status = 'transforming', heartbeat_at = now() - interval '2 hours'
+		WHERE mrf_file_id = ANY($1)`, []int64{crashed.MRFFileID, locked.MRFFileID})
+
+	var hospitalID int64
+	pool.QueryRow(ctx, "SELECT hospital_id FROM ingest.mrf_files WHERE mrf_file_id = $1", locked.MRFFileID).Scan(&hospitalID)
+	holder, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer holder.Release()
+	if _, err := holder.Exec(ctx, "SELECT pg_advisory_lock($1, $2)", ingest.LockClassHospital, int32(hospitalID)); err != nil {
+		t.Fatalf("hold hospital lock: %v", err)
+	}
+	defer holder.Exec(ctx, "SELECT pg_advisory_unlock_all()")
+
+	count := func(sql string, id int64) int64 {
+		var n int64
+		pool.QueryRow(ctx, sql, id).Scan(&n)
+		return n
+	}
+	const stagingSQL = "SELECT count(*) FROM ingest.stage_charge_rows WHERE mrf_file_id = $1"
+	const servingSQL = "SELECT count(*) FROM mrf.prices_by_code WHERE mrf_file_id = $1"
+	const statusSQL = "SELECT count(*) FROM ingest.mrf_files WHERE mrf_file_id = $1 AND status = 'failed'"
+
+	opts := ingest.GCOptions{StaleAfter: time.Hour, OrphanAfter: 24 * time.Hour, DryRun: true}
+	report, err := ingest.GC(ctx, pool, log, opts)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(report.Files) != 2 || len(report.Batches) != 1 || report.Batches[0].MRFFileID != failedRun.MRFFileID {
+		t.Fatalf("dry run report: %+v", report)
+	}
+	if count(stagingSQL, crashed.MRFFileID) == 0 || count(statusSQL, crashed.MRFFileID) != 0 {
+		t.Fatal("dry run changed the crashed file")
+	}
+
+	opts.DryRun = false
+	report, err = ingest.GC(ctx, pool, log, opts)
+	if err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	for _, f := range report.Files {
+		if skipped := f.Skipped != ""; skipped != (f.MRFFileID == locked.MRFFileID) {
+			t.Errorf("file %d: skipped=%q", f.MRFFileID, f.Skipped)
+		}
+	}
+	if count(stagingSQL, crashed.MRFFileID) != 0 || count(servingSQL, crashed.MRFFileID) != 0 || count(statusSQL, crashed.MRFFileID) != 1 {
+		t.Error("crashed file should be failed with its staging and serving rows deleted")
+	}
+	if count(stagingSQL, locked.MRFFileID) == 0 || count(statusSQL, locked.MRFFileID) != 0 {
+		t.Error("file of a locked hospital should be left alone")
+	}
+	if count(stagingSQL, failedRun.MRFFileID) != 0 {
+		t.Error("orphaned staging batch should be deleted")
+	}
+}
+
+func TestFiles_ActivateAndDelete(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+	q := sqlcgen.New(pool)
+
+	dir := t.TempDir()
+	var ids []int64
+	for _, f := range []struct{ name, body string }{{"v1.csv", rejectsCSV}, {"v2.csv", moneyCSV}} {
+		path := dir + "/" + f.name
+		if err := os.WriteFile(path, []byte(f.body), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		cfg := &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", ActivateVersion: true, HospitalName: "Versioned Hospital"}
+		s, err := ingest.Run(ctx, pool, log, cfg)
+		if err != nil {
+			t.Fatalf("ingest %s: %v", f.name, err)
+		}
+		ids = append(ids, s.MRFFileID)
+	}
+	v1, v2 := ids[0], ids[1]
+
+	hospital, active := "versioned", true
+	files, err := ingest.ListFiles(ctx, q, ingest.FileFilter{Hospital: &hospital, IsActive: &active})
+	if err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+	if len(files) != 1 || files[0].MRFFileID != v2 || files[0].ServingRows["CPT"] != 2 {
+		t.Fatalf("active files: %+v", files)
+	}
+
+	// Roll back to v1
+	if err := ingest.ActivateFile(ctx, pool, log, v1, 0); err != nil {
+		t.Fatalf("ActivateFile: %v", err)
+	}
+	f1, _ := ingest.GetFile(ctx, q, v1)
+	f2, _ := ingest.GetFile(ctx, q, v2)
+	if !f1.IsActive || f2.IsActive {
+		t.Errorf("after rollback: v1 active=%v, v2 active=%v", f1.IsActive, f2.IsActive)
+	}
+
+	if err := ingest.DeleteFile(ctx, pool, log, v1, 0); err == nil {
+		t.Error("deleting the active version should fail")
+	}
+	if err := ingest.DeleteFile(ctx, pool, log, v2, 0); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if _, err := ingest.GetFile(ctx, q, v2); !errors.Is(err, ingest.ErrFileNotFound) {
+		t.Errorf("deleted file: expected ErrFileNotFound, got %v", err)
+	}
+	var serving int64
+	pool.QueryRow(ctx, "SELECT count(*) FROM mrf.prices_by_code WHERE mrf_file_id = $1", v2).Scan(&serving)
+	if serving != 0 {
+		t.Errorf("deleted file left %d serving rows", serving)
+	}
+}
+
+// Activating a partial file keeps it partial, and the version it replaces
+// stops being listed as active.
+func TestFiles_ActivatePartialKeepsStatus(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+	q := sqlcgen.New(pool)
+
+	// v1 rejects a row (partial); v2 without the negative gross is clean
+	clean := strings.Replace(moneyCSV, "Consult,99215,CPT,outpatient,-5,250,100,280,,,,,,\n", "", 1)
+	dir := t.TempDir()
+	for _, f := range []struct{ name, body string }{{"v1.csv", rejectsCSV}, {"v2.csv", clean}} {
+		path := dir + "/" + f.name
+		if err := os.WriteFile(path, []byte(f.body), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		cfg := &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", ActivateVersion: true, HospitalName: "Status Hospital",
+			RejectPolicy: config.RejectPolicy{MaxRejectPct: 100}}
+		if _, err := ingest.Run(ctx, pool, log, cfg); err != nil {
+			t.Fatalf("ingest %s: %v", f.name, err)
+		}
+	}
+	hospital := "status hospital"
+	all, err := ingest.ListFiles(ctx, q, ingest.FileFilter{Hospital: &hospital})
+	if err != nil || len(all) != 2 {
+		t.Fatalf("ListFiles: %+v, %v", all, err)
+	}
+	v1, v2 := all[0].MRFFileID, all[1].MRFFileID
+
+	byStatus := func(status string) []int64 {
+		t.Helper()
+		files, err := ingest.ListFiles(ctx, q, ingest.FileFilter{Hospital: &hospital, Status: &status})
+		if err != nil {
+			t.Fatalf("ListFiles %s: %v", status, err)
+		}
+		var out []int64
+		for _, f := range files {
+			out = append(out, f.MRFFileID)
+		}
+		return out
+	}
+	if got := byStatus("active"); len(got) != 1 || got[0] != v2 {
+		t.Fatalf("active files before rollback: %v, want [%d]", got, v2)
+	}
+
+	if err := ingest.ActivateFile(ctx, pool, log, v1, 0); err != nil {
+		t.Fatalf("ActivateFile: %v", err)
+	}
+	f1, _ := ingest.GetFile(ctx, q, v1)
+	if !f1.IsActive || f1.Status != "partial" {
+		t.Errorf("activated partial file: active=%v status=%s, want active partial", f1.IsActive, f1.Status)
+	}
+	if got := byStatus("active"); len(got) != 0 {
+		t.Errorf("replaced version still listed as active: %v", got)
+	}
+	if got := byStatus("partial"); len(got) != 1 || got[0] != v1 {
+		t.Errorf("partial files: %v, want [%d]", got, v1)
+	}
+	if got := byStatus("transformed"); len(got) != 1 || got[0] != v2 {
+		t.Errorf("transformed files: %v, want [%d]", got, v2)
+	}
+
+	// Rolling forward again restores v2 as the only active file
+	if err := ingest.ActivateFile(ctx, pool, log, v2, 0); err != nil {
+		t.Fatalf("ActivateFile: %v", err)
+	}
+	if got := byStatus("active"); len(got) != 1 || got[0] != v2 {
+		t.Errorf("active files after roll forward: %v, want [%d]", got, v2)
+	}
+	if got := byStatus("partial"); len(got) != 1 || got[0] != v1 {
+		t.Errorf("partial files after roll forward: %v, want [%d]", got, v1)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+	q := sqlcgen.New(pool)
+
+	dir := t.TempDir()
+	var ids []int64
+	for i, body := range []string{rejectsCSV, moneyCSV, strings.Replace(moneyCSV, "2024-07-01", "2024-08-01", 1)} {
+		path := fmt.Sprintf("%s/v%d.csv", dir, i+1)
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		cfg := &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", ActivateVersion: true, HospitalName: "Pruned Hospital"}
+		s, err := ingest.Run(ctx, pool, log, cfg)
+		if err != nil {
+			t.Fatalf("ingest v%d: %v", i+1, err)
+		}
+		ids = append(ids, s.MRFFileID)
+	}
+	v1, v2, v3 := ids[0], ids[1], ids[2]
+	policy := config.RetentionPolicy{KeepVersions: 1, DeleteBatchRows: 1, Vacuum: true}
+
+	report, err := ingest.Prune(ctx, pool, log, policy, 0, true)
+	if err != nil {
+		t.Fatalf("Prune dry run: %v", err)
+	}
+	if len(report.Files) != 1 || report.Files[0].MRFFileID != v1 || report.Files[0].Reason != "beyond keep_versions" {
+		t.Fatalf("dry run: %+v", report.Files)
+	}
+	if _, err := ingest.GetFile(ctx, q, v1); err != nil {
+		t.Fatalf("dry run deleted v1: %v", err)
+	}
+
+	report, err = ingest.Prune(ctx, pool, log, policy, 0, false)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if len(report.Files) != 1 || report.Files[0].Skipped != "" || report.ServingRowsDeleted != 1 {
+		t.Fatalf("prune: %+v", report)
+	}
+	if len(report.Vacuumed) != 1 || report.Vacuumed[0] != `"mrf"."prices_by_code_cpt"` {
+		t.Errorf("vacuumed: %v", report.Vacuumed)
+	}
+	if _, err := ingest.GetFile(ctx, q, v1); !errors.Is(err, ingest.ErrFileNotFound) {
+		t.Errorf("pruned file: expected ErrFileNotFound, got %v", err)
+	}
+	var serving int64
+	pool.QueryRow(ctx, "SELECT count(*) FROM mrf.prices_by_code WHERE mrf_file_id = $1", v1).Scan(&serving)
+	if serving != 0 {
+		t.Errorf("pruned file left %d serving rows", serving)
+	}
+	for _, id := range []int64{v2, v3} {
+		f, err := ingest.GetFile(ctx, q, id)
+		if err != nil || f.ServingRows["CPT"] != 2 {
+			t.Errorf("file %d should be kept intact: %+v, %v", id, f, err)
+		}
+	}
+}
+
+// A failed attempt and a newer load that was never activated must not push
+// the last superseded version, the rollback target, out of keep_versions.
+func TestPrune_FailedAttemptInHistory(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+	q := sqlcgen.New(pool)
+
+	dir := t.TempDir()
+	runs := []struct {
+		body     string
+		activate bool
+		policy   config.RejectPolicy
+	}{
+		{rejectsCSV, true, config.RejectPolicy{}}, // v1: superseded by v2
+		{moneyCSV, true, config.RejectPolicy{}},   // v2: active
+		{strings.Replace(rejectsCSV, "2024-07-01", "2024-09-01", 1), true, config.RejectPolicy{MaxRejectPct: 10}}, // v3: failed
+		{strings.Replace(moneyCSV, "2024-07-01", "2024-10-01", 1), false, config.RejectPolicy{}},                  // v4: not activated
+	}
+	var ids []int64
+	for i, r := range runs {
+		path := fmt.Sprintf("%s/v%d.csv", dir, i+1)
+		if err := os.WriteFile(path, []byte(r.body), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		cfg := &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", ActivateVersion: r.activate,
+			RejectPolicy: r.policy, HospitalName: "Retained Hospital"}
+		s, err := ingest.Run(ctx, pool, log, cfg)
+		if (err != nil && i != 2) || s == nil {
+			t.Fatalf("ingest v%d: %v", i+1, err)
+		}
+		ids = append(ids, s.MRFFileID)
+	}
+	v1, v3 := ids[0], ids[2]
+	if f, _ := ingest.GetFile(ctx, q, v3); f == nil || f.Status != "failed" {
+		t.Fatalf("v3 should be a failed attempt: %+v", f)
+	}
+	// Age every file past the age limits below
+	if _, err := pool.Exec(ctx, "UPDATE ingest.mrf_files SET imported_at = imported_at - interval '10 days' WHERE mrf_file_id = ANY($1)", ids); err != nil {
+		t.Fatalf("age files: %v", err)
+	}
+
+	prunable := func(policy config.RetentionPolicy) []ingest.PrunedFile {
+		t.Helper()
+		report, err := ingest.Prune(ctx, pool, log, policy, 0, true)
+		if err != nil {
+			t.Fatalf("Prune dry run %+v: %v", policy, err)
+		}
+		return report.Files
+	}
+	if files := prunable(config.RetentionPolicy{KeepVersions: 1}); len(files) != 0 {
+		t.Errorf("keep_versions=1 should keep the only superseded version: %+v", files)
+	}
+	if files := prunable(config.RetentionPolicy{MaxAgeDays: 5}); len(files) != 1 || files[0].MRFFileID != v1 || files[0].Reason != "older than max_age_days" {
+		t.Errorf("max_age_days should select only the superseded version: %+v", files)
+	}
+
+	report, err := ingest.Prune(ctx, pool, log, config.RetentionPolicy{FailedMaxAgeDays: 5}, 0, false)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if len(report.Files) != 1 || report.Files[0].MRFFileID != v3 || report.Files[0].Reason != "failed attempt older than failed_max_age_days" {
+		t.Fatalf("failed_max_age_days should select only the failed attempt: %+v", report.Files)
+	}
+	if _, err := ingest.GetFile(ctx, q, v3); !errors.Is(err, ingest.ErrFileNotFound) {
+		t.Errorf("pruned failed attempt: expected ErrFileNotFound, got %v", err)
+	}
+	for _, id := range []int64{ids[0], ids[1], ids[3]} {
+		if _, err := ingest.GetFile(ctx, q, id); err != nil {
+			t.Errorf("file %d should be kept: %v", id, err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+	q := sqlcgen.New(pool)
+
+	// v2 reprices 99213 (250 -> 300) and replaces 99214 with 99212
+	v2 := strings.Replace(moneyCSV, "Office visit,99213,CPT,outpatient,250", "Office visit,99213,CPT,outpatient,300", 1)
+	v2 = strings.Replace(v2, ",99214,", ",99212,", 1)
+	dir := t.TempDir()
+	var ids []int64
+	for i, body := range []string{moneyCSV, v2} {
+		path := fmt.Sprintf("%s/v%d.csv", dir, i+1)
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		cfg := &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", ActivateVersion: true}
+		s, err := ingest.Run(ctx, pool, log, cfg)
+		if err != nil {
+			t.Fatalf("ingest v%d: %v", i+1, err)
+		}
+		ids = append(ids, s.MRFFileID)
+	}
+
+	r, err := diff.Compare(ctx, q, ids[0], ids[1], diff.Options{})
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+	got := map[string]diff.Item{}
+	for _, it := range r.Items {
+		got[it.Code] = it
+	}
+	if len(r.Items) != 3 || got["99212"].Change != diff.Added || got["99214"].Change != diff.Removed {
+		t.Fatalf("items: %+v", r.Items)
+	}
+	repriced := got["99213"]
+	if repriced.Change != diff.Changed || *repriced.FromGrossCents != 25000 || *repriced.ToGrossCents != 30000 ||
+		*repriced.DeltaCents != 5000 || *repriced.PctDelta != 20 {
+		t.Errorf("repriced item: %+v", repriced)
+	}
+	if len(r.Summary) != 1 {
+		t.Fatalf("summary: %+v", r.Summary)
+	}
+	s := r.Summary[0]
+	if s.CodeType != "CPT" || s.Payer != nil || s.Added != 1 || s.Removed != 1 || s.Changed != 1 ||
+		s.Unchanged != 0 || s.Increased != 1 || s.MedianPctDelta == nil || *s.MedianPctDelta != 20 {
+		t.Errorf("summary: %+v", s)
+	}
+
+	if r, err = diff.Compare(ctx, q, ids[0], ids[1], diff.Options{Limit: 1}); err != nil || len(r.Items) != 1 || !r.Truncated {
+		t.Errorf("limited: %v, %+v", err, r)
+	}
+	if _, err := diff.Compare(ctx, q, ids[0], ids[0]+1000, diff.Options{}); !errors.Is(err, ingest.ErrFileNotFound) {
+		t.Errorf("unknown file: expected ErrFileNotFound, got %v", err)
+	}
+	if _, err := pool.Exec(ctx, "UPDATE ingest.mrf_files SET status = 'failed' WHERE mrf_file_id = $1", ids[0]); err != nil {
+		t.Fatalf("fail v1: %v", err)
+	}
+	if _, err := diff.Compare(ctx, q, ids[0], ids[1], diff.Options{}); !errors.Is(err, diff.ErrNotLoaded) {
+		t.Errorf("failed file: expected ErrNotLoaded, got %v", err)
+	}
+}
+
+func TestPriceHistory(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+	q := sqlcgen.New(pool)
+
+	// version dates moneyCSV and reprices its 99213 gross charge (250)
+	version := func(date, gross string) string {
+		body := strings.Replace(moneyCSV, "2024-07-01", date, 1)
+		return strings.Replace(body, "Office visit,99213,CPT,outpatient,250", "Office visit,99213,CPT,outpatient,"+gross, 1)
+	}
+	dir := t.TempDir()
+	ingestVersion := func(name, body string, activate bool) int64 {
+		t.Helper()
+		path := dir + "/" + name
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		cfg := &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", ActivateVersion: activate}
+		s, err := ingest.Run(ctx, pool, log, cfg)
+		if err != nil {
+			t.Fatalf("ingest %s: %v", name, err)
+		}
+		return s.MRFFileID
+	}
+	gross := func(periods []ingest.PricePeriod) string {
+		var parts []string
+		for _, p := range periods {
+			to := "current"
+			if p.ValidTo != nil {
+				to = p.ValidTo.Format(time.DateOnly)
+			}
+			parts = append(parts, fmt.Sprintf("%s..%s=%d", p.ValidFrom.Format(time.DateOnly), to, *p.GrossChargeCents))
+		}
+		return strings.Join(parts, " ")
+	}
+	history := func(code string) []ingest.PricePeriod {
+		t.Helper()
+		periods, err := ingest.ListHistory(ctx, q, ingest.HistoryFilter{Hospital: "money", Code: code})
+		if err != nil {
+			t.Fatalf("ListHistory: %v", err)
+		}
+		return periods
+	}
+
+	v1 := ingestVersion("v1.csv", moneyCSV, true)
+	v2 := ingestVersion("v2.csv", version("2024-08-01", "300"), true)
+
+	if got, want := gross(history("99213")), "2024-07-01..2024-08-01=25000 2024-08-01..current=30000"; got != want {
+		t.Errorf("99213 history: got %s, want %s", got, want)
+	}
+	unchanged := history("99214")
+	if len(unchanged) != 1 || unchanged[0].ValidTo != nil || unchanged[0].FirstMRFFileID != v1 || unchanged[0].LastMRFFileID != v2 {
+		t.Errorf("99214 should be one open period from v1 to v2: %+v", unchanged)
+	}
+
+	// Loads that are not activated leave the history alone until a rebuild,
+	// which replays up to the active version only
+	ingestVersion("v0.csv", version("2024-06-01", "200"), false)
+	ingestVersion("v3.csv", version("2024-09-01", "350"), false)
+	if got, want := gross(history("99213")), "2024-07-01..2024-08-01=25000 2024-08-01..current=30000"; got != want {
+		t.Errorf("unactivated loads recorded: got %s, want %s", got, want)
+	}
+	n, err := ingest.RebuildHistory(ctx, pool, log, "Money", 0)
+	if err != nil || n != 3 {
+		t.Fatalf("RebuildHistory: %d files, %v", n, err)
+	}
+	want := "2024-06-01..2024-07-01=20000 2024-07-01..2024-08-01=25000 2024-08-01..current=30000"
+	if got := gross(history("99213")); got != want {
+		t.Errorf("rebuilt 99213 history: got %s, want %s", got, want)
+	}
+
+	// A rebuild waits for the hospital's lock like an ingest
+	holder, err := ingest.OpenLocks(ctx, pool, uuid.New(), 0)
+	if err != nil {
+		t.Fatalf("OpenLocks: %v", err)
+	}
+	files, err := ingest.ListFiles(ctx, q, ingest.FileFilter{MRFFileID: &v1})
+	if err != nil || len(files) != 1 {
+		t.Fatalf("ListFiles: %v", err)
+	}
+	if err := holder.LockHospital(ctx, files[0].HospitalID); err != nil {
+		t.Fatalf("LockHospital: %v", err)
+	}
+	var locked *ingest.LockedError
+	if _, err := ingest.RebuildHistory(ctx, pool, log, "Money", 0); !errors.As(err, &locked) {
+		t.Errorf("rebuild under another lock: expected LockedError, got %v", err)
+	}
+	holder.Close()
+
+	// Rolling back to v1 withdraws v2's period and reopens v1's
+	if err := ingest.ActivateFile(ctx, pool, log, v1, 0); err != nil {
+		t.Fatalf("ActivateFile v1: %v", err)
+	}
+	if got, want := gross(history("99213")), "2024-06-01..2024-07-01=20000 2024-07-01..current=25000"; got != want {
+		t.Errorf("99213 history after rollback: got %s, want %s", got, want)
+	}
+	if got := history("99214"); len(got) != 1 || got[0].ValidTo != nil || got[0].LastMRFFileID != v1 {
+		t.Errorf("99214 should be credited to v1 after rollback: %+v", got)
+	}
+
+	// Rolling forward to v2 records it again
+	if err := ingest.ActivateFile(ctx, pool, log, v2, 0); err != nil {
+		t.Fatalf("ActivateFile v2: %v", err)
+	}
+	if got := gross(history("99213")); got != want {
+		t.Errorf("99213 history after roll forward: got %s, want %s", got, want)
+	}
+}
+
+func TestLookupPrices_ActiveVersionOnly(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+	q := sqlcgen.New(pool)
+
+	dir := t.TempDir()
+	v2 := strings.Replace(moneyCSV, "Office visit,99213,CPT,outpatient,250", "Office visit,99213,CPT,outpatient,300", 1)
+	for i, body := range []string{moneyCSV, v2} {
+		path := fmt.Sprintf("%s/v%d.csv", dir, i+1)
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		cfg := &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", ActivateVersion: true}
+		if _, err := ingest.Run(ctx, pool, log, cfg); err != nil {
+			t.Fatalf("ingest v%d: %v", i+1, err)
+		}
+	}
+
+	rows, _, err := prices.Lookup(ctx, q, prices.Filter{CodeType: "cpt", Codes: []string{"99213"}})
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if len(rows) != 1 || *rows[0].PriceCents != 30000 || rows[0].HospitalName != "Money Hospital" {
+		t.Fatalf("expected the active version's row only: %+v", rows)
+	}
+
+	// Market basket: grouped by code
+	rows, _, err = prices.Lookup(ctx, q, prices.Filter{CodeType: "CPT", Codes: []string{"99214", " 99213"}})
+	if err != nil {
+		t.Fatalf("Lookup basket: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Code != "99213" || rows[1].Code != "99214" {
+		t.Errorf("basket: %+v", rows)
+	}
+
+	outpatient, inpatient := "Outpatient", "inpatient"
+	if rows, _, _ := prices.Lookup(ctx, q, prices.Filter{CodeType: "CPT", Codes: []string{"99213"}, Setting: &outpatient}); len(rows) != 1 {
+		t.Errorf("outpatient: %d rows", len(rows))
+	}
+	if rows, _, _ := prices.Lookup(ctx, q, prices.Filter{CodeType: "CPT", Codes: []string{"99213"}, Setting: &inpatient}); len(rows) != 0 {
+		t.Errorf("inpatient: %d rows", len(rows))
+	}
+
+	// The limit applies per code, so every code of a basket is kept
+	v3 := strings.Replace(moneyCSV, "Money Hospital", "Other Hospital", 1)
+	path := dir + "/other.csv"
+	if err := os.WriteFile(path, []byte(v3), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	if _, err := ingest.Run(ctx, pool, log, &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", ActivateVersion: true}); err != nil {
+		t.Fatalf("ingest other hospital: %v", err)
+	}
+	rows, truncated, err := prices.Lookup(ctx, q, prices.Filter{CodeType: "CPT", Codes: []string{"99213", "99214"}, Limit: 1})
+	if err != nil {
+		t.Fatalf("Lookup limited basket: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Code != "99213" || rows[1].Code != "99214" ||
+		len(truncated) != 2 || truncated[0] != "99213" || truncated[1] != "99214" {
+		t.Errorf("limited basket: rows %+v, truncated %v", rows, truncated)
+	}
+}
+
+// statsCSV is a California hospital's file of outpatient rows, each
+// "code,gross,payer,negotiated".
+func statsCSV(hospital string, rows ...string) string {
+	var b strings.Builder
+	b.WriteString("hospital_name,last_updated_on,version,license_number|CA\n")
+	fmt.Fprintf(&b, "%s,2024-07-01,1.0.0,123\n", hospital)
+	b.WriteString("description,code|1,code|1|type,setting,standard_charge|gross,payer_name,plan_name,standard_charge|negotiated_dollar,standard_charge|methodology\n")
+	for _, r := range rows {
+		f := strings.Split(r, ",")
+		plan, method := "", ""
+		if f[2] != "" {
+			plan, method = "PPO", "fee schedule"
+		}
+		fmt.Fprintf(&b, "Visit,%s,CPT,outpatient,%s,%s,%s,%s,%s\n", f[0], f[1], f[2], plan, f[3], method)
+	}
+	return b.String()
+}
+
+func TestPriceStats(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+	dir := t.TempDir()
+
+	ingestCSV := func(name, body string) {
+		t.Helper()
+		path := dir + "/" + name
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		cfg := &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", ActivateVersion: true}
+		if _, err := ingest.Run(ctx, pool, log, cfg); err != nil {
+			t.Fatalf("ingest %s: %v", name, err)
+		}
+	}
+	ingestCSV("a.csv", statsCSV("Stats A", "99213,100,,", "99213,200,,", "99213,300,,", "99213,400,,", "99213,500,,", "99213,,Aetna,150"))
+	ingestCSV("b1.csv", statsCSV("Stats B", "99213,600,,", "99214,50,,"))
+
+	// Hospital A's gross charges: $100..$500
+	var rows, grossCount, p10, median, iqr int64
+	var cv float64
+	err := pool.QueryRow(ctx, `SELECT s.row_count, s.gross_count, s.gross_p10_cents, s.gross_median_cents, s.gross_iqr_cents, s.gross_cv
+		FROM mrf.price_stats s JOIN ref.hospitals h USING (hospital_id)
+		WHERE h.hospital_name = 'Stats A' AND s.code_norm = '99213' AND s.payer_id IS NULL`).
+		Scan(&rows, &grossCount, &p10, &median, &iqr, &cv)
+	if err != nil {
+		t.Fatalf("hospital stats: %v", err)
+	}
+	if rows != 5 || grossCount != 5 || p10 != 14000 || median != 30000 || iqr != 20000 || math.Abs(cv-math.Sqrt(20000)/300) > 1e-9 {
+		t.Errorf("hospital A gross stats: rows=%d count=%d p10=%d median=%d iqr=%d cv=%f", rows, grossCount, p10, median, iqr, cv)
+	}
+	var negMedian int64
+	err = pool.QueryRow(ctx, `SELECT s.negotiated_median_cents FROM mrf.price_stats s
+		JOIN ref.payers py USING (payer_id) WHERE py.payer_name = 'Aetna' AND s.code_norm = '99213'`).Scan(&negMedian)
+	if err != nil || negMedian != 15000 {
+		t.Errorf("Aetna stats: %d, %v", negMedian, err)
+	}
+
+	stateStats := func(code string) (hospitals, rows, grossCount, grossMax int64, ok bool) {
+		t.Helper()
+		err := pool.QueryRow(ctx, `SELECT hospital_count, row_count, gross_count, gross_max_cents FROM mrf.price_stats_by_state
+			WHERE code_type = 'CPT' AND code_norm = $1 AND setting = 'outpatient' AND license_state = 'CA'`, code).
+			Scan(&hospitals, &rows, &grossCount, &grossMax)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, 0, 0, 0, false
+		}
+		if err != nil {
+			t.Fatalf("state stats: %v", err)
+		}
+		return hospitals, rows, grossCount, grossMax, true
+	}
+	if h, r, g, top, ok := stateStats("99213"); !ok || h != 2 || r != 7 || g != 6 || top != 60000 {
+		t.Errorf("CA 99213: hospitals=%d rows=%d gross=%d max=%d ok=%v", h, r, g, top, ok)
+	}
+	if _, _, _, _, ok := stateStats("99214"); !ok {
+		t.Error("CA 99214 missing")
+	}
+
+	// A new version of B is refreshed incrementally: its dropped code leaves
+	// the state rollup, and A's rows are untouched.
+	var aRefreshed time.Time
+	pool.QueryRow(ctx, `SELECT max(refreshed_at) FROM mrf.price_stats s JOIN ref.hospitals h USING (hospital_id)
+		WHERE h.hospital_name = 'Stats A'`).Scan(&aRefreshed)
+	ingestCSV("b2.csv", statsCSV("Stats B", "99213,800,,"))
+	if h, r, _, top, ok := stateStats("99213"); !ok || h != 2 || r != 7 || top != 80000 {
+		t.Errorf("CA 99213 after v2: hospitals=%d rows=%d max=%d ok=%v", h, r, top, ok)
+	}
+	if _, _, _, _, ok := stateStats("99214"); ok {
+		t.Error("CA 99214 should be gone with B's v1")
+	}
+	var aAfter time.Time
+	pool.QueryRow(ctx, `SELECT max(refreshed_at) FROM mrf.price_stats s JOIN ref.hospitals h USING (hospital_id)
+		WHERE h.hospital_name = 'Stats A'`).Scan(&aAfter)
+	if !aAfter.Equal(aRefreshed) {
+		t.Errorf("hospital A was refreshed by B's ingest: %v -> %v", aRefreshed, aAfter)
+	}
+
+	// A full refresh rebuilds the tables
+	if _, err := pool.Exec(ctx, "TRUNCATE mrf.price_stats, mrf.price_stats_by_state"); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	refreshed, err := ingest.RefreshStats(ctx, pool, log, nil, 0)
+	if err != nil {
+		t.Fatalf("RefreshStats: %v", err)
+	}
+	if len(refreshed) != 2 || refreshed[0].HospitalRows != 2 || refreshed[1].HospitalRows != 1 {
+		t.Errorf("refreshed: %+v", refreshed)
+	}
+	if h, r, _, top, ok := stateStats("99213"); !ok || h != 2 || r != 7 || top != 80000 {
+		t.Errorf("CA 99213 after refresh: hospitals=%d rows=%d max=%d ok=%v", h, r, top, ok)
+	}
+}
+
+func TestPriceIndex(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+	q := sqlcgen.New(pool)
+	dir := t.TempDir()
+
+	ingestCSV := func(name, body string) {
+		t.Helper()
+		path := dir + "/" + name
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		cfg := &config.Config{DSN: testDSN, FilePath: path, LogFormat: "text", ActivateVersion: true}
+		if _, err := ingest.Run(ctx, pool, log, cfg); err != nil {
+			t.Fatalf("ingest %s: %v", name, err)
+		}
+	}
+	ingestCSV("cheap.csv", statsCSV("Cheap Hospital", "99213,100,,", "99214,200,,", "99213,,Aetna,80"))
+	ingestCSV("dear1.csv", statsCSV("Dear Hospital", "99213,300,,"))
+
+	basket := config.MarketBasket{Name: "test", Items: []config.BasketItem{
+		{CodeType: "CPT", Code: "99213", Weight: 3},
+		{CodeType: "CPT", Code: "99214", Weight: 1},
+	}}
+	res, err := index.Compute(ctx, pool, log, basket, index.Options{MinCoverage: 0.9, Store: true})
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	if len(res.Entries) != 3 {
+		t.Fatalf("entries: %+v", res.Entries)
+	}
+	// Gross reference: 99213 median($100, $300) = $200, 99214 $200
+	first := res.Entries[0]
+	if first.Rank != 1 || first.HospitalName != "Cheap Hospital" || first.Payer != nil ||
+		first.Index == nil || math.Abs(*first.Index-62.5) > 1e-9 {
+		t.Errorf("first: %+v", first)
+	}
+	for _, e := range res.Entries[1:] {
+		if e.Rank != 0 || e.WeightCoverage != 0.75 {
+			t.Errorf("under-covered entries are unranked: %+v", e)
+		}
+	}
+
+	var stored, missing int
+	pool.QueryRow(ctx, "SELECT count(*) FROM mrf.price_index WHERE basket_name = 'test'").Scan(&stored)
+	pool.QueryRow(ctx, "SELECT count(*) FROM mrf.price_index_items WHERE missing AND code_norm = '99214'").Scan(&missing)
+	if stored != 3 || missing != 2 {
+		t.Errorf("stored %d entries with %d missing 99214 items, want 3 and 2", stored, missing)
+	}
+
+	// A new version is stored next to the old one; recomputing replaces the
+	// active versions' rows only.
+	ingestCSV("dear2.csv", statsCSV("Dear Hospital", "99213,100,,", "99214,100,,"))
+	if _, err := index.Compute(ctx, pool, log, basket, index.Options{Store: true}); err != nil {
+		t.Fatalf("Compute v2: %v", err)
+	}
+	if _, err := index.Compute(ctx, pool, log, basket, index.Options{Store: true}); err != nil {
+		t.Fatalf("Compute again: %v", err)
+	}
+	points, err := index.Trend(ctx, q, "test", "dear", nil)
+	if err != nil {
+		t.Fatalf("Trend: %v", err)
+	}
+	if len(points) != 2 || points[0].Active || !points[1].Active || points[1].ItemsPriced != 2 ||
+		points[0].Index == nil || math.Abs(*points[0].Index-150) > 1e-9 {
+		t.Errorf("trend: %+v", points)
+	}
+
+	aetna := "aet"
+	res, err = index.Compute(ctx, pool, log, basket, index.Options{Payer: &aetna})
+	if err != nil {
+		t.Fatalf("Compute payer: %v", err)
+	}
+	if len(res.Entries) != 1 || *res.Entries[0].Payer != "Aetna" || res.Entries[0].Rank != 1 {
+		t.Errorf("payer ranking: %+v", res.Entries)
+	}
+}
+
+func TestBatch_ContinuesAfterFailure(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	dir := t.TempDir()
+	good := dir + "/rejects.csv"
+	if err := os.WriteFile(good, []byte(rejectsCSV), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	entries := []batch.Entry{
+		{File: dir + "/missing.csv"},
+		{File: good, HospitalName: "Renamed Hospital"},
+		{File: fixtureFile()},
+	}
+	base := config.Config{DSN: testDSN, LogFormat: "text"}
+
+	results := batch.Run(ctx, pool, log, base, entries, batch.Options{})
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	var pe *ingest.PipelineError
+	if !errors.As(results[0].Err, &pe) || pe.Phase != "preflight" {
+		t.Errorf("missing file: expected preflight error, got %v", results[0].Err)
+	}
+	for _, r := range results[1:] {
+		if r.Err != nil || r.Summary == nil {
+			t.Errorf("%s: expected success after earlier failure, got %v", r.Entry.File, r.Err)
+		}
+	}
+
+	// The manifest hospital name replaces the one published in the file
+	var name string
+	err := pool.QueryRow(ctx,
+		`SELECT h.hospital_name FROM ingest.mrf_files f JOIN ref.hospitals h USING (hospital_id)
+		 WHERE f.mrf_file_id = $1`, results[1].Summary.MRFFileID).Scan(&name)
+	if err != nil || name != "Renamed Hospital" {
+		t.Errorf("hospital name override: got %q (%v)", name, err)
+	}
+
+	// Fail-fast stops at the first failure and leaves the rest unstarted
+	results = batch.Run(ctx, pool, log, base, entries, batch.Options{FailFast: true})
+	if results[0].Err == nil || results[1].Started() || results[2].Started() {
+		t.Errorf("fail-fast should stop after the first file: %+v", results)
+	}
+}
+
+func TestBatch_ParallelSameHospital(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+	log := logging.Setup("text")
+
+	dir := t.TempDir()
+	var entries []batch.Entry
+	for name, body := range map[string]string{"a.csv": rejectsCSV, "b.csv": moneyCSV} {
+		path := dir + "/" + name
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+		entries = append(entries, batch.Entry{File: path, HospitalName: "Shared Hospital"})
+	}
+	entries = append(entries, batch.Entry{File: fixtureFile()})
+	base := config.Config{DSN: testDSN, LogFormat: "text", ActivateVersion: true, IncludePayerPrices: true}
+
+	results := batch.Run(ctx, pool, log, base, entries, batch.Options{Parallel: 3})
+	for _, r := range results {
+		if r.Err != nil {
+			t.Fatalf("%s: %v", r.Entry.File, r.Err)
+		}
+	}
+
+	var hospitals, active int64
+	pool.QueryRow(ctx, "SELECT count(*) FROM ref.hospitals WHERE hospital_name = 'Shared Hospital'").Scan(&hospitals)
+	if hospitals != 1 {
+		t.Errorf("expected one hospital row, got %d", hospitals)
+	}
+	pool.QueryRow(ctx,
+		`SELECT count(*) FROM ingest.mrf_files f JOIN ref.hospitals h USING (hospital_id)
+		 WHERE h.hospital_name = 'Shared Hospital' AND f.is_active`).Scan(&active)
+	if active != 1 {
+		t.Errorf("expected exactly one active version, got %d", active)
+	}
+}
+
 // Ensure normalize package is used (compile check).
 var _ = normalize.NormalizeCode
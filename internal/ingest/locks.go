@@ -0,0 +1,271 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// In-process locks for Runs executing concurrently over one pool (batch
+// ingest with --parallel). Each guards a step that races when two files
+// touch the same rows:
+var (
+	// resolveHospitalMu: ref.hospitals has no unique name, so lookup-then-insert
+	// must not interleave.
+	resolveHospitalMu sync.Mutex
+	// dimensionsMu: concurrent multi-row ON CONFLICT inserts into ref.payers and
+	// ref.plans can deadlock on each other's uncommitted keys.
+	dimensionsMu sync.Mutex
+	// hospitalLocks, fileLocks: files of one hospital (or with one content
+	// hash) in the same batch queue here before polling the advisory lock.
+	hospitalLocks keyedMutex
+	fileLocks     keyedMutex
+)
+
+// keyedMutex hands out one lock per key. A key is dropped once nobody holds
+// or waits for it.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[int64]*keyedLock
+}
+
+type keyedLock struct {
+	sem    chan struct{} // capacity 1, full while held
+	refs   int           // holder and waiters
+	holder string        // batch ID of the holder
+}
+
+// lock waits until key is free or deadline passes and returns its unlock
+// function. A deadline already past still takes a free key.
+func (k *keyedMutex) lock(ctx context.Context, key int64, batchID, resource string, deadline time.Time) (func(), error) {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[int64]*keyedLock)
+	}
+	kl, ok := k.locks[key]
+	if !ok {
+		kl = &keyedLock{sem: make(chan struct{}, 1)}
+		k.locks[key] = kl
+	}
+	kl.refs++
+	k.mu.Unlock()
+
+	select {
+	case kl.sem <- struct{}{}:
+	default:
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		select {
+		case kl.sem <- struct{}{}:
+		case <-ctx.Done():
+			k.drop(key, kl)
+			return nil, ctx.Err()
+		case <-timer.C:
+			k.mu.Lock()
+			holder := kl.holder
+			k.mu.Unlock()
+			k.drop(key, kl)
+			return nil, &LockedError{Resource: resource, HolderBatchID: holder, InProcess: true}
+		}
+	}
+
+	k.mu.Lock()
+	kl.holder = batchID
+	k.mu.Unlock()
+	return func() {
+		k.mu.Lock()
+		kl.holder = ""
+		k.mu.Unlock()
+		<-kl.sem
+		k.drop(key, kl)
+	}, nil
+}
+
+// drop releases one reference to key's lock, deleting the key on the last.
+func (k *keyedMutex) drop(key int64, kl *keyedLock) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	kl.refs--
+	if kl.refs == 0 {
+		delete(k.locks, key)
+	}
+}
+
+// Advisory lock classes, the first key of the two-int4 form ("mrfH", "mrfS",
+// "mrfR") and pg_locks.classid. The second key is the hospital_id (folded to
+// 32 bits), a hash of the file's SHA-256 or a hash of a license state.
+const (
+	LockClassHospital   int32 = 0x6d726648
+	LockClassFileSHA    int32 = 0x6d726653
+	LockClassStateStats int32 = 0x6d726652
+)
+
+// lockAppPrefix starts the application_name of a lock session; the batch ID follows.
+const lockAppPrefix = "mrfload:"
+
+// lockPollInterval is how often a waiting lock is retried.
+const lockPollInterval = 250 * time.Millisecond
+
+// LockedError reports a lock still held by another session after the wait timeout.
+type LockedError struct {
+	Resource string // e.g. "hospital 12"
+	// HolderBatchID is the ingest batch holding the lock, or "" if the
+	// holder is not an mrfload session or released it meanwhile.
+	HolderBatchID string
+	HolderPID     int32
+	// InProcess is set when the holder is another Run in this process.
+	InProcess bool
+}
+
+func (e *LockedError) Error() string {
+	switch {
+	case e.InProcess && e.HolderBatchID == "":
+		return fmt.Sprintf("%s is locked by another run in this process", e.Resource)
+	case e.InProcess:
+		return fmt.Sprintf("%s is locked by ingest batch %s in this process", e.Resource, e.HolderBatchID)
+	case e.HolderBatchID == "":
+		return fmt.Sprintf("%s is locked by another session (pid %d)", e.Resource, e.HolderPID)
+	default:
+		return fmt.Sprintf("%s is locked by ingest batch %s (pid %d)", e.Resource, e.HolderBatchID, e.HolderPID)
+	}
+}
+
+// Locks holds the Postgres advisory locks of one Run, serializing ingests of
+// the same hospital across processes. The locks live on a dedicated
+// connection outside the pool, with application_name "mrfload:<batch>" so a
+// blocked process can name the holder; Close drops the connection and with
+// it every lock.
+type Locks struct {
+	conn       *pgx.Conn
+	q          *sqlcgen.Queries
+	batchID    string
+	timeout    time.Duration
+	release    []func()
+	unlockFile func()
+}
+
+// OpenLocks connects the lock session for batchID. timeout bounds each lock
+// wait; zero fails at once when a lock is taken.
+func OpenLocks(ctx context.Context, pool *pgxpool.Pool, batchID uuid.UUID, timeout time.Duration) (*Locks, error) {
+	cc := pool.Config().ConnConfig.Copy()
+	cc.RuntimeParams["application_name"] = lockAppPrefix + batchID.String()
+	conn, err := pgx.ConnectConfig(ctx, cc)
+	if err != nil {
+		return nil, fmt.Errorf("connect lock session: %w", err)
+	}
+	return &Locks{conn: conn, q: sqlcgen.New(conn), batchID: batchID.String(), timeout: timeout}, nil
+}
+
+// LockHospital takes the hospital's lock until Close. Runs in this process
+// queue on an in-process lock first; the timeout covers both waits.
+func (l *Locks) LockHospital(ctx context.Context, hospitalID int64) error {
+	resource := fmt.Sprintf("hospital %d", hospitalID)
+	deadline := time.Now().Add(l.timeout)
+	unlock, err := hospitalLocks.lock(ctx, hospitalID, l.batchID, resource, deadline)
+	if err != nil {
+		return err
+	}
+	l.release = append(l.release, unlock)
+	return l.acquire(ctx, LockClassHospital, foldKey(hospitalID), resource, deadline)
+}
+
+// LockFile takes the lock for a file's content hash, held while the file is
+// registered; release it with UnlockFile.
+func (l *Locks) LockFile(ctx context.Context, sha string) error {
+	resource := fmt.Sprintf("file %s", sha)
+	deadline := time.Now().Add(l.timeout)
+	unlock, err := fileLocks.lock(ctx, int64(shaKey(sha)), l.batchID, resource, deadline)
+	if err != nil {
+		return err
+	}
+	l.unlockFile = unlock
+	return l.acquire(ctx, LockClassFileSHA, shaKey(sha), resource, deadline)
+}
+
+// UnlockFile releases the lock taken by LockFile.
+func (l *Locks) UnlockFile(ctx context.Context, sha string) error {
+	if l.unlockFile != nil {
+		l.unlockFile()
+		l.unlockFile = nil
+	}
+	_, err := l.q.AdvisoryUnlock(ctx, sqlcgen.AdvisoryUnlockParams{ClassID: LockClassFileSHA, ObjID: shaKey(sha)})
+	return err
+}
+
+// Close releases every lock and closes the lock session.
+func (l *Locks) Close() {
+	_ = l.conn.Close(context.Background())
+	if l.unlockFile != nil {
+		l.unlockFile()
+		l.unlockFile = nil
+	}
+	for _, unlock := range l.release {
+		unlock()
+	}
+	l.release = nil
+}
+
+// acquire polls for the lock until deadline, then reports its holder.
+func (l *Locks) acquire(ctx context.Context, class, key int32, resource string, deadline time.Time) error {
+	for {
+		ok, err := l.q.TryAdvisoryLock(ctx, sqlcgen.TryAdvisoryLockParams{ClassID: class, ObjID: key})
+		if err != nil {
+			return fmt.Errorf("lock %s: %w", resource, err)
+		}
+		if ok {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return l.lockedError(ctx, class, key, resource)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+func (l *Locks) lockedError(ctx context.Context, class, key int32, resource string) error {
+	lerr := &LockedError{Resource: resource}
+	holder, err := l.q.AdvisoryLockHolder(ctx, sqlcgen.AdvisoryLockHolderParams{ClassID: class, ObjID: key})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("lock %s: find holder: %w", resource, err)
+	}
+	if err == nil {
+		lerr.HolderPID = holder.Pid
+		if batch, ok := strings.CutPrefix(holder.ApplicationName, lockAppPrefix); ok {
+			lerr.HolderBatchID = batch
+		}
+	}
+	return lerr
+}
+
+// foldKey maps an int64 ID onto the int4 lock key.
+func foldKey(id int64) int32 {
+	return int32(id ^ id>>32)
+}
+
+// shaKey hashes a file digest onto the int4 lock key.
+func shaKey(sha string) int32 {
+	h := fnv.New32a()
+	h.Write([]byte(sha))
+	return int32(h.Sum32())
+}
+
+// stateKey hashes a license state onto the int4 lock key.
+func stateKey(state string) int32 {
+	h := fnv.New32a()
+	h.Write([]byte(state))
+	return int32(h.Sum32())
+}
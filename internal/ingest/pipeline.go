@@ -2,13 +2,17 @@ package ingest
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog"
 
 	"github.com/gyeh/pricestats/internal/config"
+	"github.com/gyeh/pricestats/internal/db"
 	"github.com/gyeh/pricestats/internal/model"
 	"github.com/gyeh/pricestats/internal/sqlcgen"
 )
@@ -28,14 +32,37 @@ func (e *PipelineError) Unwrap() error {
 }
 
 // Run executes the full ingest pipeline: preflight → stage → dimensions →
-// transform → finalize → cleanup.
-func Run(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, cfg *config.Config) (*model.IngestSummary, error) {
+// transform → policy → finalize → cleanup. Transform through finalize run in
+// one transaction. When the reject policy fails the run, Run returns both
+// the summary and a "policy" PipelineError. From
+// preflight on, Run holds the hospital's advisory lock; if another ingest
+// keeps it past cfg.LockTimeout the "preflight" PipelineError wraps a
+// *LockedError. If ctx is cancelled mid-run, the file is marked cancelled,
+// the batch's staging rows are deleted, and the PipelineError wraps
+// ctx.Err().
+func Run(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, cfg *config.Config) (summary *model.IngestSummary, err error) {
 	totalStart := time.Now()
 	q := sqlcgen.New(pool)
 
 	// Phase 1: Preflight
 	log.Info().Str("file", cfg.FilePath).Msg("starting preflight")
-	pf, err := Preflight(ctx, q, log, cfg.FilePath, cfg.Force)
+	if err := CheckCodeTypes(ctx, q); err != nil {
+		return nil, &PipelineError{Phase: "preflight", Err: err}
+	}
+	batchID := uuid.New()
+	locks, err := OpenLocks(ctx, pool, batchID, cfg.LockTimeout)
+	if err != nil {
+		return nil, &PipelineError{Phase: "preflight", Err: err}
+	}
+	defer locks.Close()
+
+	pf, err := Preflight(ctx, q, log, cfg.FilePath, PreflightOptions{
+		Force:        cfg.Force,
+		HospitalName: cfg.HospitalName,
+		BatchID:      batchID,
+		Resume:       cfg.Resume,
+		Locks:        locks,
+	})
 	if err != nil {
 		return nil, &PipelineError{Phase: "preflight", Err: err}
 	}
@@ -51,29 +78,38 @@ func Run(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, cfg *confi
 			MRFFileID:     pf.MRFFileID,
 			IngestBatchID: pf.IngestBatchID.String(),
 			DurationTotal: time.Since(totalStart),
+			Outcome:       model.OutcomeSkipped,
 		}, nil
 	}
 
-	// Phase 2: Stage
-	log.Info().Msg("starting staging")
-	if err := q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "staging", MrfFileID: pf.MRFFileID}); err != nil {
-		return nil, &PipelineError{Phase: "stage", Err: err}
-	}
-
-	// Delete orphaned staging rows from prior failed imports of this file
-	if err := q.DeleteStagingByFile(ctx, pf.MRFFileID); err != nil {
-		_ = q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "failed", MrfFileID: pf.MRFFileID})
-		return nil, &PipelineError{Phase: "stage", Err: fmt.Errorf("delete old staging rows: %w", err)}
-	}
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			err = cancelRun(ctx, q, log, pf, err)
+		}
+	}()
+	stopHeartbeat := startHeartbeat(ctx, q, log, pf.MRFFileID)
+	defer stopHeartbeat()
 
-	stageResult, err := Stage(ctx, pool, log, pf, cfg.IncludePayerPrices)
-	if err != nil {
-		_ = q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "failed", MrfFileID: pf.MRFFileID})
-		return nil, &PipelineError{Phase: "stage", Err: err}
+	// --resume: reuse the staging of an interrupted run when it is intact
+	var resume *ResumePoint
+	if cfg.Resume {
+		resume, err = FindResumePoint(ctx, q, log, pf.MRFFileID)
+		if err != nil {
+			return nil, &PipelineError{Phase: "preflight", Err: err}
+		}
 	}
 
-	if err := q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "staged", MrfFileID: pf.MRFFileID}); err != nil {
-		return nil, &PipelineError{Phase: "stage", Err: err}
+	// Phase 2: Stage
+	var stageResult *StageResult
+	if resume != nil && resume.Checkpoint == nil {
+		pf.IngestBatchID = resume.IngestBatchID
+		stageResult = &resume.Stage
+	} else {
+		stageResult, err = runStage(ctx, pool, q, log, cfg, pf, resume)
+		if err != nil {
+			_ = q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "failed", MrfFileID: pf.MRFFileID})
+			return nil, &PipelineError{Phase: "stage", Err: err}
+		}
 	}
 
 	// Phase 3: Dimension upserts (only when payer/plan data is included)
@@ -93,28 +129,93 @@ func Run(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, cfg *confi
 		return nil, &PipelineError{Phase: "transform", Err: err}
 	}
 
-	// Delete old serving rows before inserting new ones (no-op on first import)
-	if err := q.DeleteServingByFile(ctx, pf.MRFFileID); err != nil {
-		_ = q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "failed", MrfFileID: pf.MRFFileID})
-		return nil, &PipelineError{Phase: "transform", Err: fmt.Errorf("delete old serving rows: %w", err)}
+	summary = &model.IngestSummary{
+		FilePath:      pf.FilePath,
+		FileSHA256:    pf.FileSHA256,
+		MRFFileID:     pf.MRFFileID,
+		IngestBatchID: pf.IngestBatchID.String(),
+		Resumed:       resume != nil,
+		RowsRead:      stageResult.RowsRead,
+		RowsStaged:    stageResult.RowsStaged,
+		RowsRejected:  stageResult.RowsRejected,
+		DurationRead:  stageResult.Duration,
+		DurationCopy:  stageResult.Duration,
 	}
 
-	transformResult, err := Transform(ctx, q, log, pf.IngestBatchID, cfg.CodeTypes)
-	if err != nil {
-		_ = q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "failed", MrfFileID: pf.MRFFileID})
-		return nil, &PipelineError{Phase: "transform", Err: err}
-	}
+	// Phases 4-5 swap the serving rows in one transaction: deleting the
+	// file's old rows, the transform, the policy check and the version flip
+	// commit together, so readers see either the old version or the new one.
+	// A failure at any step rolls the swap back and leaves serving untouched.
+	var finalizeDur time.Duration
+	err = db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+		qtx := q.WithTx(tx)
 
-	if err := q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "transformed", MrfFileID: pf.MRFFileID}); err != nil {
-		return nil, &PipelineError{Phase: "transform", Err: err}
-	}
+		// Delete old serving rows before inserting new ones (no-op on first import)
+		if err := qtx.DeleteServingByFile(ctx, pf.MRFFileID); err != nil {
+			return &PipelineError{Phase: "transform", Err: fmt.Errorf("delete old serving rows: %w", err)}
+		}
+
+		transformResult, err := Transform(ctx, tx, log, pf.IngestBatchID, cfg.CodeTypes)
+		if err != nil {
+			return &PipelineError{Phase: "transform", Err: err}
+		}
+		summary.RowsWithoutCodes = transformResult.RowsWithoutCodes
+		summary.RowsFilteredOut = transformResult.RowsFilteredOut
+		summary.RowsInsertedServing = transformResult.RowsInserted
+		summary.DurationTransform = transformResult.Duration
+
+		if err := qtx.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "transformed", MrfFileID: pf.MRFFileID}); err != nil {
+			return &PipelineError{Phase: "transform", Err: err}
+		}
+
+		// Reject policy: staging rejects and codeless rows both count as
+		// problem rows; rows the code_types filter drops were asked for
+		summary.Outcome, summary.OutcomeReason = cfg.RejectPolicy.Evaluate(
+			summary.RowsRejected+summary.RowsWithoutCodes, summary.RowsRead)
+		if summary.Outcome == model.OutcomeFailed {
+			log.Error().Str("reason", summary.OutcomeReason).Msg("reject policy exceeded, rolling back serving rows")
+			return &PipelineError{Phase: "policy", Err: fmt.Errorf("reject policy: %s", summary.OutcomeReason)}
+		}
 
-	// Phase 5: Finalize
-	log.Info().Msg("finalizing")
-	finalizeDur, err := Finalize(ctx, q, log, pf.HospitalID, pf.MRFFileID, cfg.ActivateVersion)
+		// Phase 5: Finalize
+		log.Info().Msg("finalizing")
+		finalizeDur, err = Finalize(ctx, qtx, log, pf.HospitalID, pf.MRFFileID, cfg.ActivateVersion)
+		if err != nil {
+			return &PipelineError{Phase: "finalize", Err: err}
+		}
+		if summary.Outcome == model.OutcomePartial {
+			if err := qtx.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "partial", MrfFileID: pf.MRFFileID}); err != nil {
+				return &PipelineError{Phase: "finalize", Err: err}
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		_ = q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "failed", MrfFileID: pf.MRFFileID})
-		return nil, &PipelineError{Phase: "finalize", Err: err}
+		var pe *PipelineError
+		if !errors.As(err, &pe) {
+			// Commit failed
+			return nil, &PipelineError{Phase: "finalize", Err: err}
+		}
+		if pe.Phase != "policy" {
+			return nil, err
+		}
+		if !cfg.KeepStaging {
+			if err := Cleanup(ctx, q, log, pf.IngestBatchID); err != nil {
+				log.Warn().Err(err).Msg("staging cleanup failed (non-fatal)")
+			}
+		}
+		summary.DurationTotal = time.Since(totalStart)
+		return summary, err
+	}
+
+	// ANALYZE runs outside the swap transaction; batch runs analyze once at the end
+	if !cfg.SkipAnalyze {
+		analyzeStart := time.Now()
+		if err := Analyze(ctx, q, log); err != nil {
+			log.Warn().Err(err).Msg("ANALYZE failed (non-fatal)")
+		}
+		finalizeDur += time.Since(analyzeStart)
 	}
 
 	// Phase 6: Cleanup staging
@@ -125,29 +226,102 @@ func Run(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, cfg *confi
 		}
 	}
 
-	summary := &model.IngestSummary{
-		FilePath:            pf.FilePath,
-		FileSHA256:          pf.FileSHA256,
-		MRFFileID:           pf.MRFFileID,
-		IngestBatchID:       pf.IngestBatchID.String(),
-		RowsRead:            stageResult.RowsRead,
-		RowsStaged:          stageResult.RowsStaged,
-		RowsRejected:        stageResult.RowsRejected,
-		RowsInsertedServing: transformResult.RowsInserted,
-		DurationRead:        stageResult.Duration,
-		DurationCopy:        stageResult.Duration,
-		DurationTransform:   transformResult.Duration,
-		DurationFinalize:    finalizeDur,
-		DurationTotal:       time.Since(totalStart),
-	}
+	summary.DurationFinalize = finalizeDur
+	summary.DurationTotal = time.Since(totalStart)
 
 	log.Info().
 		Int64("rows_read", summary.RowsRead).
 		Int64("rows_staged", summary.RowsStaged).
 		Int64("rows_serving", summary.RowsInsertedServing).
 		Int64("rows_rejected", summary.RowsRejected).
+		Int64("rows_without_codes", summary.RowsWithoutCodes).
+		Int64("rows_filtered_out", summary.RowsFilteredOut).
+		Str("outcome", string(summary.Outcome)).
 		Str("total_duration", summary.DurationTotal.String()).
 		Msg("ingest pipeline complete")
 
 	return summary, nil
 }
+
+// cancelTimeout bounds the cleanup after a cancelled run.
+const cancelTimeout = 30 * time.Second
+
+// cancelRun marks the file cancelled and deletes the batch's staging rows
+// after ctx was cancelled, then returns err in the same phase wrapping
+// ctx.Err(). The bookkeeping runs on a fresh context.
+func cancelRun(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, pf *PreflightResult, err error) error {
+	log.Warn().Err(err).Msg("ingest cancelled, cleaning up")
+	cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelTimeout)
+	defer cancel()
+
+	if uerr := q.UpdateMRFStatus(cctx, sqlcgen.UpdateMRFStatusParams{Status: "cancelled", MrfFileID: pf.MRFFileID}); uerr != nil {
+		log.Warn().Err(uerr).Msg("mark file cancelled failed")
+	}
+	if cerr := Cleanup(cctx, q, log, pf.IngestBatchID); cerr != nil {
+		log.Warn().Err(cerr).Msg("staging cleanup failed")
+	}
+
+	phase := "cancelled"
+	var pe *PipelineError
+	if errors.As(err, &pe) {
+		phase, err = pe.Phase, pe.Err
+	}
+	if !errors.Is(err, ctx.Err()) {
+		err = fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	return &PipelineError{Phase: phase, Err: err}
+}
+
+// runStage clears the file's staging and rejected rows from earlier runs,
+// records the new batch on the file, streams the file into staging, and
+// records the staging counts that a later --resume checks. When resume has
+// a checkpoint, the interrupted batch's rows are kept and staging continues
+// after it.
+func runStage(ctx context.Context, pool *pgxpool.Pool, q *sqlcgen.Queries, log zerolog.Logger, cfg *config.Config, pf *PreflightResult, resume *ResumePoint) (*StageResult, error) {
+	log.Info().Msg("starting staging")
+	if err := q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{Status: "staging", MrfFileID: pf.MRFFileID}); err != nil {
+		return nil, err
+	}
+
+	opts := StageOptions{
+		IncludePayerPrices: cfg.IncludePayerPrices,
+		MoneyRules:         cfg.MoneyRules,
+		BatchSize:          cfg.BatchSize,
+		ChunkRows:          cfg.ChunkRows,
+		Retry: db.RetryPolicy{
+			MaxRetries: cfg.CopyRetries,
+			Backoff:    cfg.CopyRetryBackoff,
+			MaxBackoff: maxCopyRetryBackoff,
+		},
+	}
+	if resume != nil {
+		pf.IngestBatchID = resume.IngestBatchID
+		opts.Checkpoint = resume.Checkpoint
+	} else {
+		// Delete orphaned staging rows from prior failed imports of this file
+		if err := q.DeleteStagingByFile(ctx, pf.MRFFileID); err != nil {
+			return nil, fmt.Errorf("delete old staging rows: %w", err)
+		}
+		if err := q.DeleteRejectsByFile(ctx, pf.MRFFileID); err != nil {
+			return nil, fmt.Errorf("delete old rejected rows: %w", err)
+		}
+		if err := q.StartMRFBatch(ctx, sqlcgen.StartMRFBatchParams{IngestBatchID: &pf.IngestBatchID, MrfFileID: pf.MRFFileID}); err != nil {
+			return nil, fmt.Errorf("record batch: %w", err)
+		}
+	}
+
+	stageResult, err := Stage(ctx, pool, log, pf, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.RecordStagedCounts(ctx, sqlcgen.RecordStagedCountsParams{
+		RowsRead:     &stageResult.RowsRead,
+		RowsStaged:   &stageResult.RowsStaged,
+		RowsRejected: &stageResult.RowsRejected,
+		MrfFileID:    pf.MRFFileID,
+	}); err != nil {
+		return nil, fmt.Errorf("record staged counts: %w", err)
+	}
+	return stageResult, nil
+}
@@ -5,15 +5,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 
+	"github.com/gyeh/pricestats/internal/jsonread"
 	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/mrfread"
 	"github.com/gyeh/pricestats/internal/normalize"
-	"github.com/gyeh/pricestats/internal/parquetread"
 	"github.com/gyeh/pricestats/internal/sqlcgen"
 )
 
@@ -25,30 +27,59 @@ type PreflightResult struct {
 	FileSHA256 string
 	// FileSize is the file size in bytes from os.Stat.
 	FileSize int64
+	// Format is the detected source layout (Parquet, CMS JSON or CMS CSV).
+	Format mrfread.Format
 	// HospitalID is the DB primary key for the hospital, resolved (or created) by
-	// matching the hospital name from the first row of the Parquet file.
+	// matching the hospital name from the first row of the source file.
 	HospitalID int64
 	// MRFFileID is the DB primary key for this MRF file record, returned by
 	// RegisterMRFFile (inserted or looked up via hospital_id + sha256).
 	MRFFileID int64
-	// IngestBatchID is a freshly generated UUIDv4 that uniquely identifies this
-	// ingest run, used to tag staged rows for later transform/cleanup.
+	// IngestBatchID is a UUIDv4 (PreflightOptions.BatchID or freshly generated)
+	// that uniquely identifies this ingest run, used to tag staged rows for
+	// later transform/cleanup.
 	IngestBatchID uuid.UUID
-	// NumRows is the total row count reported by the Parquet file metadata.
+	// Layout is what opening a CMS JSON file learned (metadata and charge
+	// array offset), so Stage reopens it without a second header scan; nil
+	// for the other formats.
+	Layout *jsonread.Layout
+	// NumRows is the total row count reported by the file metadata, or -1
+	// for streaming formats (CMS JSON/CSV) that cannot report it without a full pass.
 	NumRows int64
 	// AlreadyLoaded is true when the file's sha256 already exists in the DB with
-	// status "active" or "transformed" and force mode is off, signaling the
+	// status "active", "transformed" or "partial" and force mode is off, signaling the
 	// pipeline can skip this file.
 	AlreadyLoaded bool
-	// FirstRow is the first row read from the Parquet file, used to extract
+	// FirstRow is the first row read from the source file, used to extract
 	// hospital metadata (name, location, address, license) for resolution.
 	FirstRow *model.HospitalChargeRow
 }
 
+// PreflightOptions controls Preflight.
+type PreflightOptions struct {
+	// Force re-imports a file that is already loaded.
+	Force bool
+	// HospitalName, when set, replaces the name published in the file for
+	// hospital resolution.
+	HospitalName string
+	// BatchID becomes PreflightResult.IngestBatchID; zero generates one.
+	BatchID uuid.UUID
+	// Resume keeps the recorded status of an existing, not yet loaded file
+	// instead of resetting it to pending, so the run can pick it up.
+	Resume bool
+	// Locks, when set, serializes registration on the file hash and takes
+	// the hospital lock, which stays held until Locks.Close.
+	Locks *Locks
+}
+
 // Preflight opens the file, computes SHA-256, validates the schema,
 // resolves the hospital, and registers the MRF file.
-func Preflight(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, filePath string, force bool) (*PreflightResult, error) {
+func Preflight(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, filePath string, opts PreflightOptions) (*PreflightResult, error) {
 	start := time.Now()
+	batchID := opts.BatchID
+	if batchID == uuid.Nil {
+		batchID = uuid.New()
+	}
 
 	// Compute file hash
 	sha, err := normalize.FileHash(filePath)
@@ -56,19 +87,30 @@ func Preflight(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, file
 		return nil, fmt.Errorf("preflight hash: %w", err)
 	}
 
+	if opts.Locks != nil {
+		if err := opts.Locks.LockFile(ctx, sha); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := opts.Locks.UnlockFile(context.Background(), sha); err != nil {
+				log.Warn().Err(err).Msg("release file lock failed")
+			}
+		}()
+	}
+
 	stat, err := os.Stat(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("preflight stat: %w", err)
 	}
 
-	// Open and validate Parquet schema
-	reader, err := parquetread.Open(filePath)
+	// Open and validate the source file
+	reader, format, err := mrfread.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("preflight open: %w", err)
 	}
 	defer reader.Close()
 
-	if err := parquetread.ValidateSchema(reader.Schema()); err != nil {
+	if err := reader.Validate(); err != nil {
 		return nil, fmt.Errorf("preflight validate: %w", err)
 	}
 
@@ -81,10 +123,14 @@ func Preflight(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, file
 		return nil, fmt.Errorf("preflight read first row: %w", err)
 	}
 	firstRow := &rows[0]
+	if opts.HospitalName != "" {
+		firstRow.HospitalName = opts.HospitalName
+	}
 
 	log.Info().
 		Str("file", filepath.Base(filePath)).
 		Str("sha256", sha).
+		Str("format", string(format)).
 		Int64("rows", numRows).
 		Str("hospital", firstRow.HospitalName).
 		Dur("duration", time.Since(start)).
@@ -95,9 +141,14 @@ func Preflight(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, file
 	if err != nil {
 		return nil, fmt.Errorf("preflight resolve hospital: %w", err)
 	}
+	if opts.Locks != nil {
+		if err := opts.Locks.LockHospital(ctx, hospitalID); err != nil {
+			return nil, err
+		}
+	}
 
 	// Register MRF file
-	mRFFileID, alreadyLoaded, err := registerMRFFile(ctx, q, hospitalID, filePath, sha, stat.Size(), firstRow, force)
+	mRFFileID, alreadyLoaded, err := registerMRFFile(ctx, q, hospitalID, filePath, sha, stat.Size(), firstRow, opts.Force, opts.Resume)
 	if err != nil {
 		return nil, fmt.Errorf("preflight register file: %w", err)
 	}
@@ -106,16 +157,44 @@ func Preflight(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, file
 		FilePath:      filePath,
 		FileSHA256:    sha,
 		FileSize:      stat.Size(),
+		Format:        format,
 		HospitalID:    hospitalID,
 		MRFFileID:     mRFFileID,
-		IngestBatchID: uuid.New(),
+		IngestBatchID: batchID,
+		Layout:        mrfread.LayoutOf(reader),
 		NumRows:       numRows,
 		AlreadyLoaded: alreadyLoaded,
 		FirstRow:      firstRow,
 	}, nil
 }
 
+// CheckCodeTypes verifies that every code type in the Go registry has been
+// provisioned in ref.code_types, i.e. its staging column and partition exist.
+func CheckCodeTypes(ctx context.Context, q *sqlcgen.Queries) error {
+	rows, err := q.ListCodeTypes(ctx)
+	if err != nil {
+		return fmt.Errorf("list code types: %w", err)
+	}
+	known := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		known[r.CodeType] = true
+	}
+	var missing []string
+	for _, ct := range model.AllCodeTypes {
+		if !known[ct.Name] {
+			missing = append(missing, ct.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("code types not provisioned in database: %s (run `mrfload migrate`)", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
 func resolveHospital(ctx context.Context, q *sqlcgen.Queries, row *model.HospitalChargeRow) (int64, error) {
+	resolveHospitalMu.Lock()
+	defer resolveHospitalMu.Unlock()
+
 	// Try to find existing hospital by name first
 	hospitalID, err := q.LookupHospitalByName(ctx, row.HospitalName)
 	if err == nil {
@@ -141,7 +220,7 @@ func resolveHospital(ctx context.Context, q *sqlcgen.Queries, row *model.Hospita
 	return hospitalID, nil
 }
 
-func registerMRFFile(ctx context.Context, q *sqlcgen.Queries, hospitalID int64, filePath, sha string, fileSize int64, row *model.HospitalChargeRow, force bool) (int64, bool, error) {
+func registerMRFFile(ctx context.Context, q *sqlcgen.Queries, hospitalID int64, filePath, sha string, fileSize int64, row *model.HospitalChargeRow, force, resume bool) (int64, bool, error) {
 	lastUpdated := normalize.ParseDate(row.LastUpdatedOn)
 	affirmation := row.Affirmation
 
@@ -165,10 +244,14 @@ func registerMRFFile(ctx context.Context, q *sqlcgen.Queries, hospitalID int64,
 			return 0, false, fmt.Errorf("lookup existing mrf_file: %w", err2)
 		}
 
-		if !force && (lookupResult.Status == "active" || lookupResult.Status == "transformed") {
+		if !force && (lookupResult.Status == "active" || lookupResult.Status == "transformed" || lookupResult.Status == "partial") {
 			return lookupResult.MrfFileID, true, nil
 		}
 
+		if resume {
+			return lookupResult.MrfFileID, false, nil
+		}
+
 		// Reset status for re-import
 		if err3 := q.UpdateMRFStatus(ctx, sqlcgen.UpdateMRFStatusParams{MrfFileID: lookupResult.MrfFileID, Status: "pending"}); err3 != nil {
 			return 0, false, fmt.Errorf("reset mrf status: %w", err3)
@@ -0,0 +1,160 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/gyeh/pricestats/internal/config"
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// defaultDeleteBatchRows is the serving rows removed per DELETE when the
+// policy leaves delete_batch_rows at zero.
+const defaultDeleteBatchRows = 50_000
+
+// PrunedFile is a superseded file version or failed attempt removed by Prune.
+type PrunedFile struct {
+	MRFFileID    int64
+	HospitalName string
+	FileName     string
+	Status       string
+	ImportedAt   time.Time
+	// Reason names the retention rule that selected the file.
+	Reason string
+	// ServingRows counts the rows removed from mrf.prices_by_code per code type.
+	ServingRows map[string]int64
+	// Skipped explains why a candidate was left alone, e.g. it was
+	// activated or re-imported while prune waited for its hospital's lock.
+	Skipped string
+}
+
+// PruneReport lists what Prune removed, or would remove in a dry run.
+type PruneReport struct {
+	Files              []PrunedFile
+	ServingRowsDeleted int64
+	// Vacuumed names the serving partitions VACUUM (ANALYZE) ran on.
+	Vacuumed []string
+}
+
+// Prune removes the inactive files policy selects: their serving
+// rows, in batches of policy.DeleteBatchRows so no statement holds its locks
+// for long, then their staging and rejected rows and file records. Each file
+// is removed under its hospital's advisory lock, waiting up to lockTimeout.
+// With policy.Vacuum the partitions rows were removed from are vacuumed
+// afterwards. A dry run only reports the candidates.
+func Prune(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, policy config.RetentionPolicy, lockTimeout time.Duration, dryRun bool) (*PruneReport, error) {
+	q := sqlcgen.New(pool)
+	report := &PruneReport{}
+
+	candidates, err := q.ListPrunableFiles(ctx, sqlcgen.ListPrunableFilesParams{
+		KeepVersions:     int32(policy.KeepVersions),
+		MaxAgeDays:       int32(policy.MaxAgeDays),
+		FailedMaxAgeDays: int32(policy.FailedMaxAgeDays),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list prunable files: %w", err)
+	}
+
+	batchRows := policy.DeleteBatchRows
+	if batchRows == 0 {
+		batchRows = defaultDeleteBatchRows
+	}
+	partitions := map[string]bool{}
+	for _, c := range candidates {
+		pf := PrunedFile{
+			MRFFileID:    c.MrfFileID,
+			HospitalName: c.HospitalName,
+			FileName:     c.SourceFileName,
+			Status:       c.Status,
+			ImportedAt:   c.ImportedAt.Time,
+			Reason:       "older than max_age_days",
+			ServingRows:  map[string]int64{},
+		}
+		switch {
+		case c.VersionRank == 0:
+			pf.Reason = "failed attempt older than failed_max_age_days"
+		case policy.KeepVersions > 0 && c.VersionRank > int64(policy.KeepVersions):
+			pf.Reason = "beyond keep_versions"
+		}
+		counts, err := q.CountServingByCodeType(ctx, []int64{c.MrfFileID})
+		if err != nil {
+			return report, fmt.Errorf("count serving rows of file %d: %w", c.MrfFileID, err)
+		}
+		for _, r := range counts {
+			pf.ServingRows[r.CodeType] = r.RowCount
+		}
+
+		if !dryRun {
+			if pf.Skipped, err = pruneFile(ctx, pool, q, c.MrfFileID, c.Status, int32(batchRows), lockTimeout); err != nil {
+				return report, fmt.Errorf("prune file %d: %w", c.MrfFileID, err)
+			}
+		}
+		if pf.Skipped == "" {
+			for ct, n := range pf.ServingRows {
+				report.ServingRowsDeleted += n
+				partitions[ct] = true
+			}
+		}
+		log.Info().Int64("mrf_file_id", pf.MRFFileID).Str("file", pf.FileName).Str("reason", pf.Reason).
+			Str("skipped", pf.Skipped).Bool("dry_run", dryRun).Msg("prune: file selected")
+		report.Files = append(report.Files, pf)
+	}
+
+	if policy.Vacuum && !dryRun {
+		for ct := range partitions {
+			info, ok := model.CodeTypeByName(ct)
+			if !ok {
+				continue
+			}
+			table := pgx.Identifier{"mrf", "prices_by_code_" + info.Partition}.Sanitize()
+			if _, err := pool.Exec(ctx, "VACUUM (ANALYZE) "+table); err != nil {
+				return report, fmt.Errorf("vacuum %s: %w", table, err)
+			}
+			report.Vacuumed = append(report.Vacuumed, table)
+		}
+		sort.Strings(report.Vacuumed)
+		if len(report.Vacuumed) > 0 {
+			log.Info().Strs("partitions", report.Vacuumed).Msg("prune: VACUUM complete")
+		}
+	}
+	return report, nil
+}
+
+// pruneFile deletes one inactive file under its hospital's lock, its serving
+// rows batchRows at a time. The file must still have the status it was
+// selected with, so a failed attempt re-imported meanwhile is kept. It
+// returns why the file was skipped, if it was.
+func pruneFile(ctx context.Context, pool *pgxpool.Pool, q *sqlcgen.Queries, mRFFileID int64, status string, batchRows int32, lockTimeout time.Duration) (skipped string, err error) {
+	err = withFileLock(ctx, pool, q, mRFFileID, lockTimeout, func(f *FileInfo) error {
+		if f.IsActive {
+			skipped = "activated meanwhile"
+			return nil
+		}
+		if f.Status != status {
+			skipped = "status changed meanwhile to " + f.Status
+			return nil
+		}
+		for {
+			n, err := q.DeleteServingBatch(ctx, sqlcgen.DeleteServingBatchParams{MrfFileID: mRFFileID, BatchRows: batchRows})
+			if err != nil {
+				return fmt.Errorf("delete serving rows: %w", err)
+			}
+			if n == 0 {
+				break
+			}
+		}
+		return deleteFileRows(ctx, pool, q, mRFFileID)
+	})
+	if errors.Is(err, ErrFileNotFound) {
+		return "deleted meanwhile", nil
+	}
+	return skipped, err
+}
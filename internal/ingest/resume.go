@@ -0,0 +1,79 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// ResumePoint is the staging an interrupted run of a file left behind.
+type ResumePoint struct {
+	// Status is the file's recorded status, e.g. "staged" or "failed".
+	Status string
+	// IngestBatchID is the batch whose staging rows are reused.
+	IngestBatchID uuid.UUID
+	// Stage holds the counts recorded when staging completed.
+	Stage StageResult
+	// Checkpoint is set when staging was interrupted after committing some
+	// chunks; staging then continues after Checkpoint.Row instead of being
+	// skipped.
+	Checkpoint *StageCheckpoint
+}
+
+// FindResumePoint returns where an earlier run of the file can be resumed,
+// or nil when it must start over. Completed staging is reused when its
+// counts were recorded and all of the batch's staged rows are still there;
+// interrupted staging continues from its checkpoint. Dimensions, transform
+// and finalize always re-run, as they are idempotent and the serving swap
+// is one transaction.
+func FindResumePoint(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, mRFFileID int64) (*ResumePoint, error) {
+	b, err := q.GetMRFFileBatch(ctx, mRFFileID)
+	if err != nil {
+		return nil, fmt.Errorf("look up file batch: %w", err)
+	}
+	ev := log.Info().Int64("mrf_file_id", mRFFileID).Str("status", b.Status)
+	switch {
+	case b.IngestBatchID == nil:
+		ev.Msg("resume: no earlier batch recorded, starting over")
+		return nil, nil
+	case b.Status == "cancelled":
+		// A cancelled run deletes its staging rows
+		ev.Str("batch", b.IngestBatchID.String()).Msg("resume: earlier run was cancelled, starting over")
+		return nil, nil
+	case b.RowsStaged == nil && b.StagedThroughRow != nil:
+		// Chunks commit with their checkpoint, so the rows present are
+		// exactly those up to it
+		cp := &StageCheckpoint{Row: *b.StagedThroughRow, RowsStaged: b.StagedNow, RowsRejected: b.RejectedNow}
+		ev.Str("batch", b.IngestBatchID.String()).Int64("checkpoint", cp.Row).
+			Msg("resume: staging was interrupted, continuing after checkpoint")
+		return &ResumePoint{Status: b.Status, IngestBatchID: *b.IngestBatchID, Checkpoint: cp}, nil
+	case b.RowsStaged == nil:
+		ev.Str("batch", b.IngestBatchID.String()).Msg("resume: staging did not start, starting over")
+		return nil, nil
+	case b.StagedNow != *b.RowsStaged:
+		ev.Str("batch", b.IngestBatchID.String()).
+			Int64("rows_staged", *b.RowsStaged).
+			Int64("rows_present", b.StagedNow).
+			Msg("resume: staging rows are incomplete, starting over")
+		return nil, nil
+	}
+
+	rp := &ResumePoint{
+		Status:        b.Status,
+		IngestBatchID: *b.IngestBatchID,
+		Stage:         StageResult{RowsStaged: *b.RowsStaged},
+	}
+	if b.RowsRead != nil {
+		rp.Stage.RowsRead = *b.RowsRead
+	}
+	if b.RowsRejected != nil {
+		rp.Stage.RowsRejected = *b.RowsRejected
+	}
+	ev.Str("batch", rp.IngestBatchID.String()).Int64("rows_staged", rp.Stage.RowsStaged).
+		Msg("resume: staging intact, skipping stage")
+	return rp, nil
+}
@@ -2,8 +2,10 @@ package ingest_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -11,7 +13,9 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/ingest"
 	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/normalize"
 	"github.com/gyeh/pricestats/internal/sqlcgen"
 )
 
@@ -134,8 +138,48 @@ func TestMigrations_PartitionsExist(t *testing.T) {
 	if err != nil {
 		t.Fatalf("query partitions: %v", err)
 	}
-	if count != 5 {
-		t.Errorf("expected 5 partitions, got %d", count)
+	if count != len(model.AllCodeTypes) {
+		t.Errorf("expected %d partitions, got %d", len(model.AllCodeTypes), count)
+	}
+}
+
+func TestMigrations_CodeTypeRegistrySynced(t *testing.T) {
+	pool := setupDB(t)
+	q := sqlcgen.New(pool)
+	ctx := context.Background()
+
+	rows, err := q.ListCodeTypes(ctx)
+	if err != nil {
+		t.Fatalf("list code types: %v", err)
+	}
+	if len(rows) != len(model.AllCodeTypes) {
+		t.Fatalf("expected %d ref.code_types rows, got %d", len(model.AllCodeTypes), len(rows))
+	}
+	for i, ct := range model.AllCodeTypes {
+		if rows[i].CodeType != ct.Name || rows[i].StagingColumn != ct.Column || rows[i].PartitionSuffix != ct.Partition {
+			t.Errorf("row %d: got %+v, want %+v", i, rows[i], ct)
+		}
+
+		// Staging column and partition indexes exist for every registered type
+		var hasCol bool
+		pool.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM information_schema.columns
+			  WHERE table_schema = 'ingest' AND table_name = 'stage_charge_rows' AND column_name = $1)`,
+			ct.Column).Scan(&hasCol)
+		if !hasCol {
+			t.Errorf("staging column %s missing", ct.Column)
+		}
+		var idxCount int
+		pool.QueryRow(ctx,
+			`SELECT count(*) FROM pg_indexes WHERE schemaname = 'mrf' AND tablename = $1`,
+			"prices_by_code_"+ct.Partition).Scan(&idxCount)
+		if idxCount < 4 {
+			t.Errorf("partition %s: expected at least 4 indexes, got %d", ct.Partition, idxCount)
+		}
+	}
+
+	if err := ingest.CheckCodeTypes(ctx, q); err != nil {
+		t.Errorf("CheckCodeTypes after migrate: %v", err)
 	}
 }
 
@@ -319,7 +363,7 @@ func TestCopyToStaging(t *testing.T) {
 	t.Run("single_row", func(t *testing.T) {
 		row := makeStagingRow(batchID, fileID, 1,
 			func(r *model.StagingRow) {
-				r.CPTCode = strPtr("99213")
+				r.SetCode("CPT", strPtr("99213"))
 				r.GrossChargeCents = int64Ptr(15000)
 			},
 		)
@@ -354,7 +398,7 @@ func TestCopyToStaging(t *testing.T) {
 		ch := make(chan *model.StagingRow, 10)
 		for i := int64(1); i <= 5; i++ {
 			ch <- makeStagingRow(batch2, fileID, i,
-				func(r *model.StagingRow) { r.HCPCSCode = strPtr(fmt.Sprintf("J%04d", i)) },
+				func(r *model.StagingRow) { r.SetCode("HCPCS", strPtr(fmt.Sprintf("J%04d", i))) },
 			)
 		}
 		close(ch)
@@ -426,7 +470,7 @@ func TestUpsertPayers(t *testing.T) {
 			row := makeStagingRow(batchID, fileID, int64(i+1), func(r *model.StagingRow) {
 				r.PayerName = strPtr(payer)
 				r.PayerNameNorm = strPtr(payer) // simplified for test
-				r.CPTCode = strPtr("99213")
+				r.SetCode("CPT", strPtr("99213"))
 			})
 			insertStagingRow(t, pool, row)
 		}
@@ -496,7 +540,7 @@ func TestUpsertPlans(t *testing.T) {
 			r.PayerNameNorm = strPtr(p.payer)
 			r.PlanName = strPtr(p.plan)
 			r.PlanNameNorm = strPtr(p.plan)
-			r.CPTCode = strPtr("99213")
+			r.SetCode("CPT", strPtr("99213"))
 		})
 		insertStagingRow(t, pool, row)
 	}
@@ -579,13 +623,13 @@ func TestTransformWideToLong(t *testing.T) {
 
 	t.Run("single_code_produces_one_row", func(t *testing.T) {
 		row := makeStagingRow(batchID, fileID, 1, func(r *model.StagingRow) {
-			r.CPTCode = strPtr("99213")
+			r.SetCode("CPT", strPtr("99213"))
 			r.GrossChargeCents = int64Ptr(15000)
 			r.Description = "Office visit"
 		})
 		insertStagingRow(t, pool, row)
 
-		tag, err := q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batchID})
+		tag, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batchID})
 		if err != nil {
 			t.Fatalf("transform: %v", err)
 		}
@@ -622,14 +666,14 @@ func TestTransformWideToLong(t *testing.T) {
 	t.Run("multiple_codes_explode", func(t *testing.T) {
 		batch2 := uuid.New()
 		row := makeStagingRow(batch2, fileID, 1, func(r *model.StagingRow) {
-			r.CPTCode = strPtr("99213")
-			r.HCPCSCode = strPtr("J0120")
-			r.NDCCode = strPtr("0250")
+			r.SetCode("CPT", strPtr("99213"))
+			r.SetCode("HCPCS", strPtr("J0120"))
+			r.SetCode("NDC", strPtr("0250"))
 			r.Description = "Multi code"
 		})
 		insertStagingRow(t, pool, row)
 
-		tag, err := q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batch2})
+		tag, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch2})
 		if err != nil {
 			t.Fatalf("transform: %v", err)
 		}
@@ -655,13 +699,13 @@ func TestTransformWideToLong(t *testing.T) {
 	t.Run("null_and_empty_codes_filtered", func(t *testing.T) {
 		batch3 := uuid.New()
 		row := makeStagingRow(batch3, fileID, 1, func(r *model.StagingRow) {
-			r.CPTCode = strPtr("99213") // non-null
-			r.HCPCSCode = strPtr("")    // empty string — should be filtered
+			r.SetCode("CPT", strPtr("99213"))     // non-null
+			r.SetCode("HCPCS", strPtr("")) // empty string — should be filtered
 			// all others nil — should be filtered
 		})
 		insertStagingRow(t, pool, row)
 
-		tag, err := q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batch3})
+		tag, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch3})
 		if err != nil {
 			t.Fatalf("transform: %v", err)
 		}
@@ -676,11 +720,11 @@ func TestTransformWideToLong(t *testing.T) {
 	t.Run("code_normalization", func(t *testing.T) {
 		batch4 := uuid.New()
 		row := makeStagingRow(batch4, fileID, 1, func(r *model.StagingRow) {
-			r.CPTCode = strPtr("99.213-A") // has punctuation
+			r.SetCode("CPT", strPtr("99.213-A")) // has punctuation
 		})
 		insertStagingRow(t, pool, row)
 
-		q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batch4})
+		ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch4})
 
 		var codeRaw, codeNorm string
 		pool.QueryRow(ctx,
@@ -700,15 +744,15 @@ func TestTransformWideToLong(t *testing.T) {
 	t.Run("all_5_code_types", func(t *testing.T) {
 		batch5 := uuid.New()
 		row := makeStagingRow(batch5, fileID, 1, func(r *model.StagingRow) {
-			r.CPTCode = strPtr("C1")
-			r.HCPCSCode = strPtr("H1")
-			r.MSDRGCode = strPtr("M1")
-			r.NDCCode = strPtr("N1")
-			r.CDTCode = strPtr("CT1")
+			r.SetCode("CPT", strPtr("C1"))
+			r.SetCode("HCPCS", strPtr("H1"))
+			r.SetCode("MS-DRG", strPtr("M1"))
+			r.SetCode("NDC", strPtr("N1"))
+			r.SetCode("CDT", strPtr("CT1"))
 		})
 		insertStagingRow(t, pool, row)
 
-		tag, err := q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batch5})
+		tag, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch5})
 		if err != nil {
 			t.Fatalf("transform: %v", err)
 		}
@@ -732,10 +776,42 @@ func TestTransformWideToLong(t *testing.T) {
 		pool.Exec(ctx, "DELETE FROM ingest.stage_charge_rows WHERE ingest_batch_id = $1", batch5)
 	})
 
+	t.Run("all_registered_code_types", func(t *testing.T) {
+		batch9 := uuid.New()
+		row := makeStagingRow(batch9, fileID, 1, func(r *model.StagingRow) {
+			for i, ct := range model.AllCodeTypes {
+				r.SetCode(ct.Name, strPtr(fmt.Sprintf("X%d", i)))
+			}
+		})
+		insertStagingRow(t, pool, row)
+
+		tag, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch9})
+		if err != nil {
+			t.Fatalf("transform: %v", err)
+		}
+		if tag.RowsAffected() != int64(len(model.AllCodeTypes)) {
+			t.Errorf("expected %d serving rows, got %d", len(model.AllCodeTypes), tag.RowsAffected())
+		}
+
+		// Every code type lands in its own partition
+		for _, ct := range model.AllCodeTypes {
+			var count int64
+			pool.QueryRow(ctx,
+				fmt.Sprintf("SELECT count(*) FROM mrf.prices_by_code_%s WHERE mrf_file_id = $1", ct.Partition),
+				fileID).Scan(&count)
+			if count != 1 {
+				t.Errorf("partition %s: expected 1 row, got %d", ct.Partition, count)
+			}
+		}
+
+		pool.Exec(ctx, "DELETE FROM mrf.prices_by_code WHERE mrf_file_id = $1", fileID)
+		pool.Exec(ctx, "DELETE FROM ingest.stage_charge_rows WHERE ingest_batch_id = $1", batch9)
+	})
+
 	t.Run("money_values_preserved", func(t *testing.T) {
 		batch6 := uuid.New()
 		row := makeStagingRow(batch6, fileID, 1, func(r *model.StagingRow) {
-			r.CPTCode = strPtr("99213")
+			r.SetCode("CPT", strPtr("99213"))
 			r.GrossChargeCents = int64Ptr(15099)
 			r.DiscountedCashCents = int64Ptr(10050)
 			r.NegotiatedDollarCents = int64Ptr(8000)
@@ -746,7 +822,7 @@ func TestTransformWideToLong(t *testing.T) {
 		})
 		insertStagingRow(t, pool, row)
 
-		q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batch6})
+		ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch6})
 
 		var gross, disc, neg, est, min, max *int64
 		var negPct *int32
@@ -786,7 +862,7 @@ func TestTransformWideToLong(t *testing.T) {
 
 		// Set up payer and plan
 		row := makeStagingRow(batch7, fileID, 1, func(r *model.StagingRow) {
-			r.CPTCode = strPtr("99213")
+			r.SetCode("CPT", strPtr("99213"))
 			r.PayerName = strPtr("Aetna")
 			r.PayerNameNorm = strPtr("aetna")
 			r.PlanName = strPtr("Gold PPO")
@@ -798,7 +874,7 @@ func TestTransformWideToLong(t *testing.T) {
 		q.UpsertPayers(ctx, batch7)
 		q.UpsertPlans(ctx, batch7)
 
-		q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batch7})
+		ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch7})
 
 		var payerID, planID *int64
 		var payerRaw, planRaw *string
@@ -828,7 +904,7 @@ func TestTransformWideToLong(t *testing.T) {
 		row := makeStagingRow(batch8, fileID, 1) // no codes set
 		insertStagingRow(t, pool, row)
 
-		tag, err := q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batch8})
+		tag, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch8})
 		if err != nil {
 			t.Fatalf("transform: %v", err)
 		}
@@ -853,16 +929,16 @@ func TestTransformWideToLong_CodeTypeFilter(t *testing.T) {
 
 	// Stage a row with all 5 codes set
 	row := makeStagingRow(batchID, fileID, 1, func(r *model.StagingRow) {
-		r.CPTCode = strPtr("C1")
-		r.HCPCSCode = strPtr("H1")
-		r.MSDRGCode = strPtr("M1")
-		r.NDCCode = strPtr("N1")
-		r.CDTCode = strPtr("CT1")
+		r.SetCode("CPT", strPtr("C1"))
+		r.SetCode("HCPCS", strPtr("H1"))
+		r.SetCode("MS-DRG", strPtr("M1"))
+		r.SetCode("NDC", strPtr("N1"))
+		r.SetCode("CDT", strPtr("CT1"))
 	})
 	insertStagingRow(t, pool, row)
 
 	t.Run("filter_to_subset", func(t *testing.T) {
-		tag, err := q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{
+		tag, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{
 			IngestBatchID: batchID,
 			CodeTypes:     []string{"CPT", "HCPCS"},
 		})
@@ -877,7 +953,7 @@ func TestTransformWideToLong_CodeTypeFilter(t *testing.T) {
 	})
 
 	t.Run("nil_code_types_includes_all", func(t *testing.T) {
-		tag, err := q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{
+		tag, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{
 			IngestBatchID: batchID,
 			CodeTypes:     nil,
 		})
@@ -1065,18 +1141,18 @@ func TestDeleteServingByFile(t *testing.T) {
 	batch2 := uuid.New()
 	for i := int64(1); i <= 3; i++ {
 		insertStagingRow(t, pool, makeStagingRow(batch1, file1, i, func(r *model.StagingRow) {
-			r.CPTCode = strPtr(fmt.Sprintf("9921%d", i))
+			r.SetCode("CPT", strPtr(fmt.Sprintf("9921%d", i)))
 		}))
 	}
 	for i := int64(1); i <= 2; i++ {
 		insertStagingRow(t, pool, makeStagingRow(batch2, file2, i, func(r *model.StagingRow) {
-			r.HCPCSCode = strPtr(fmt.Sprintf("J010%d", i))
+			r.SetCode("HCPCS", strPtr(fmt.Sprintf("J010%d", i)))
 		}))
 	}
-	if _, err := q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batch1}); err != nil {
+	if _, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch1}); err != nil {
 		t.Fatalf("transform batch1: %v", err)
 	}
-	if _, err := q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batch2}); err != nil {
+	if _, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch2}); err != nil {
 		t.Fatalf("transform batch2: %v", err)
 	}
 
@@ -1120,12 +1196,12 @@ func TestDeleteServingByFile(t *testing.T) {
 		batchA := uuid.New()
 		for i := int64(1); i <= 2; i++ {
 			insertStagingRow(t, pool, makeStagingRow(batchA, fileID, i, func(r *model.StagingRow) {
-				r.CPTCode = strPtr("99213")
+				r.SetCode("CPT", strPtr("99213"))
 				r.GrossChargeCents = int64Ptr(10000)
 				r.Description = fmt.Sprintf("original charge %d", i)
 			}))
 		}
-		tag, err := q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batchA})
+		tag, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batchA})
 		if err != nil {
 			t.Fatalf("first transform: %v", err)
 		}
@@ -1144,12 +1220,12 @@ func TestDeleteServingByFile(t *testing.T) {
 		batchB := uuid.New()
 		for i := int64(1); i <= 3; i++ {
 			insertStagingRow(t, pool, makeStagingRow(batchB, fileID, i, func(r *model.StagingRow) {
-				r.CPTCode = strPtr("99214")
+				r.SetCode("CPT", strPtr("99214"))
 				r.GrossChargeCents = int64Ptr(20000)
 				r.Description = fmt.Sprintf("updated charge %d", i)
 			}))
 		}
-		tag, err = q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{IngestBatchID: batchB})
+		tag, err = ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batchB})
 		if err != nil {
 			t.Fatalf("second transform: %v", err)
 		}
@@ -1188,12 +1264,12 @@ func TestDeleteStagingByFile(t *testing.T) {
 	batch2 := uuid.New()
 	for i := int64(1); i <= 3; i++ {
 		insertStagingRow(t, pool, makeStagingRow(batch1, file1, i, func(r *model.StagingRow) {
-			r.CPTCode = strPtr("99213")
+			r.SetCode("CPT", strPtr("99213"))
 		}))
 	}
 	for i := int64(1); i <= 2; i++ {
 		insertStagingRow(t, pool, makeStagingRow(batch2, file2, i, func(r *model.StagingRow) {
-			r.CPTCode = strPtr("99214")
+			r.SetCode("CPT", strPtr("99214"))
 		}))
 	}
 
@@ -1232,7 +1308,7 @@ func TestDeleteStagingByFile(t *testing.T) {
 		batchA := uuid.New()
 		for i := int64(1); i <= 3; i++ {
 			insertStagingRow(t, pool, makeStagingRow(batchA, fileID, i, func(r *model.StagingRow) {
-				r.CPTCode = strPtr("99213")
+				r.SetCode("CPT", strPtr("99213"))
 			}))
 		}
 
@@ -1253,7 +1329,7 @@ func TestDeleteStagingByFile(t *testing.T) {
 		batchB := uuid.New()
 		for i := int64(1); i <= 2; i++ {
 			insertStagingRow(t, pool, makeStagingRow(batchB, fileID, i, func(r *model.StagingRow) {
-				r.CPTCode = strPtr("99214")
+				r.SetCode("CPT", strPtr("99214"))
 			}))
 		}
 
@@ -1393,3 +1469,348 @@ func TestChannelSource(t *testing.T) {
 func setupLog() zerolog.Logger {
 	return zerolog.Nop()
 }
+
+// ---------- rejected_rows ----------
+
+func insertRejectedRow(t *testing.T, pool *pgxpool.Pool, r *model.RejectedRow) {
+	t.Helper()
+	_, err := pool.CopyFrom(context.Background(),
+		pgx.Identifier{"ingest", "rejected_rows"},
+		model.RejectedColumns(),
+		pgx.CopyFromRows([][]any{r.CopyValues()}),
+	)
+	if err != nil {
+		t.Fatalf("insert rejected row: %v", err)
+	}
+}
+
+func TestRejectedRows(t *testing.T) {
+	pool := setupDB(t)
+	q := sqlcgen.New(pool)
+	ctx := context.Background()
+
+	hospitalID := insertHospital(t, q, "Reject Hospital")
+	fileID := insertMRFFile(t, q, hospitalID, "sha-rejects")
+	otherFileID := insertMRFFile(t, q, hospitalID, "sha-rejects-other")
+	batch := uuid.New()
+
+	for i, reason := range []string{"missing_required", "invalid_amount", "missing_required"} {
+		insertRejectedRow(t, pool, &model.RejectedRow{
+			IngestBatchID:   batch,
+			MRFFileID:       fileID,
+			SourceRowNumber: int64(10 - i),
+			ReasonCode:      reason,
+			FieldName:       strPtr("description"),
+			RawRow:          []byte(`{"description":""}`),
+		})
+	}
+	insertRejectedRow(t, pool, &model.RejectedRow{
+		IngestBatchID: batch, MRFFileID: otherFileID, SourceRowNumber: 1,
+		ReasonCode: "missing_required", RawRow: []byte(`{}`),
+	})
+
+	t.Run("count_by_reason", func(t *testing.T) {
+		groups, err := q.CountRejectsByReason(ctx, fileID)
+		if err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		if len(groups) != 2 || groups[0].ReasonCode != "missing_required" || groups[0].RowCount != 2 {
+			t.Errorf("unexpected groups: %+v", groups)
+		}
+	})
+
+	t.Run("list_ordered_filtered_limited", func(t *testing.T) {
+		rows, err := q.ListRejectedRows(ctx, sqlcgen.ListRejectedRowsParams{MrfFileID: fileID})
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(rows) != 3 || rows[0].SourceRowNumber != 8 {
+			t.Fatalf("expected 3 rows ordered by row number, got %+v", rows)
+		}
+		if string(rows[0].RawRow) != `{"description": ""}` {
+			t.Errorf("raw_row: got %s", rows[0].RawRow)
+		}
+
+		rows, err = q.ListRejectedRows(ctx, sqlcgen.ListRejectedRowsParams{
+			MrfFileID: fileID, ReasonCode: strPtr("missing_required"), RowLimit: int32Ptr(1),
+		})
+		if err != nil {
+			t.Fatalf("list filtered: %v", err)
+		}
+		if len(rows) != 1 || rows[0].ReasonCode != "missing_required" {
+			t.Errorf("unexpected filtered rows: %+v", rows)
+		}
+	})
+
+	t.Run("delete_by_file", func(t *testing.T) {
+		if err := q.DeleteRejectsByFile(ctx, fileID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		var remaining int64
+		pool.QueryRow(ctx, "SELECT count(*) FROM ingest.rejected_rows").Scan(&remaining)
+		if remaining != 1 {
+			t.Errorf("only the other file's reject should remain, got %d rows", remaining)
+		}
+	})
+}
+
+// ---------- count_codeless_rows.sql ----------
+
+func TestCountCodelessRows(t *testing.T) {
+	pool := setupDB(t)
+	q := sqlcgen.New(pool)
+	ctx := context.Background()
+
+	hospitalID := insertHospital(t, q, "Codeless Hospital")
+	fileID := insertMRFFile(t, q, hospitalID, "sha-codeless")
+	batch := uuid.New()
+
+	insertStagingRow(t, pool, makeStagingRow(batch, fileID, 1, func(r *model.StagingRow) {
+		r.SetCode("CPT", strPtr("99213"))
+	}))
+	insertStagingRow(t, pool, makeStagingRow(batch, fileID, 2, func(r *model.StagingRow) {
+		r.SetCode("NDC", strPtr("0250"))
+	}))
+	insertStagingRow(t, pool, makeStagingRow(batch, fileID, 3, func(r *model.StagingRow) {
+		r.SetCode("HCPCS", strPtr(""))
+	}))
+
+	n, err := ingest.CountCodelessRows(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch})
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != (ingest.CodelessCounts{Codeless: 1}) {
+		t.Errorf("all code types: expected 1 codeless row, got %+v", n)
+	}
+
+	// The filter drops the NDC row, but it is not codeless
+	n, err = ingest.CountCodelessRows(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch, CodeTypes: []string{"CPT"}})
+	if err != nil {
+		t.Fatalf("count filtered: %v", err)
+	}
+	if n != (ingest.CodelessCounts{Codeless: 1, FilteredOut: 1}) {
+		t.Errorf("CPT only: expected 1 codeless and 1 filtered-out row, got %+v", n)
+	}
+}
+
+// ---------- diff_file_summary.sql ----------
+
+func TestDiffFileSummary(t *testing.T) {
+	pool := setupDB(t)
+	q := sqlcgen.New(pool)
+	ctx := context.Background()
+
+	hospitalID := insertHospital(t, q, "Diff Hospital")
+	type price struct {
+		code       string
+		payer      bool // Aetna, else the hospital's own charge
+		gross      int64
+		negotiated *int64
+		pctBps     *int32
+	}
+	load := func(sha string, prices []price) int64 {
+		fileID := insertMRFFile(t, q, hospitalID, sha)
+		batch := uuid.New()
+		for i, p := range prices {
+			insertStagingRow(t, pool, makeStagingRow(batch, fileID, int64(i+1), func(r *model.StagingRow) {
+				r.SetCode("CPT", strPtr(p.code))
+				r.GrossChargeCents = int64Ptr(p.gross)
+				r.NegotiatedDollarCents = p.negotiated
+				r.NegotiatedPercentageBPS = p.pctBps
+				if p.payer {
+					r.PayerName = strPtr("Aetna")
+					r.PayerNameNorm = strPtr("aetna")
+				}
+			}))
+		}
+		if _, err := q.UpsertPayers(ctx, batch); err != nil {
+			t.Fatalf("upsert payers: %v", err)
+		}
+		if _, err := ingest.TransformWideToLong(ctx, pool, ingest.TransformWideToLongParams{IngestBatchID: batch}); err != nil {
+			t.Fatalf("transform: %v", err)
+		}
+		return fileID
+	}
+	from := load("sha-diff1", []price{
+		{code: "99211", gross: 10000},
+		{code: "99212", gross: 20000},
+		{code: "99213", payer: true, gross: 30000, negotiated: int64Ptr(15000)},
+		{code: "99214", payer: true, gross: 40000, negotiated: int64Ptr(20000)},
+	})
+	to := load("sha-diff2", []price{
+		{code: "99211", gross: 10000},                                           // unchanged
+		{code: "99212", gross: 25000},                                           // gross +25%
+		{code: "99213", payer: true, gross: 30000, pctBps: int32Ptr(5000)},      // dollar rate became a percentage
+		{code: "99214", payer: true, gross: 50000, negotiated: int64Ptr(18000)}, // negotiated -10%, gross ignored
+	})
+
+	rows, err := q.DiffFileSummary(ctx, sqlcgen.DiffFileSummaryParams{FromFileID: from, ToFileID: to})
+	if err != nil {
+		t.Fatalf("DiffFileSummary: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected a hospital and a payer group, got %d", len(rows))
+	}
+
+	t.Run("hospital_charges", func(t *testing.T) {
+		g := rows[0]
+		if g.PayerName != nil || g.Changed != 1 || g.Unchanged != 1 || g.Increased != 1 || g.Decreased != 0 ||
+			g.NegotiatedPctChanged != 0 || g.PctItems != 1 || g.AvgPctDelta != 25 || g.MedianPctDelta != 25 {
+			t.Errorf("got %+v", g)
+		}
+	})
+
+	t.Run("payer_rates", func(t *testing.T) {
+		g := rows[1]
+		if g.PayerName == nil || *g.PayerName != "Aetna" || g.Changed != 2 || g.Unchanged != 0 ||
+			g.Increased != 0 || g.Decreased != 1 || g.NegotiatedPctChanged != 1 ||
+			g.PctItems != 1 || g.AvgPctDelta != -10 || g.MinPctDelta != -10 || g.MaxPctDelta != -10 {
+			t.Errorf("got %+v", g)
+		}
+	})
+}
+
+// ---------- canonicalize_code (010) ----------
+
+func TestCanonicalizeCode_SQLParity(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+
+	cases := []struct{ codeType, raw string }{
+		{"CPT", "99213"}, {"CPT", "0001F"}, {"CPT", "99.213-a"}, {"CPT", "9921"},
+		{"HCPCS", "J0120"}, {"HCPCS", "j-0120"}, {"HCPCS", "99213"}, {"HCPCS", "J012"},
+		{"NDC", "0573-0150-20"}, {"NDC", "50090-347-01"}, {"NDC", "50090-3470-1"},
+		{"NDC", "50090-3470-01"}, {"NDC", "50090 - 3470 - 01"}, {"NDC", "00573015020"},
+		{"NDC", "0573015020"}, {"NDC", "573-0150-20"}, {"NDC", "0573-0150-20-1"}, {"NDC", "05A3-0150-20"},
+		{"MS-DRG", "1"}, {"MS-DRG", "65"}, {"MS-DRG", "470"}, {"MS-DRG", "4701"}, {"MS-DRG", "MS470"},
+		{"DRG", "7"}, {"DRG", "APR661-1"},
+		{"CDT", "D0120"}, {"CDT", "0120"}, {"CDT", "d-0120"}, {"CDT", "D012"},
+		{"RC", "0450"}, {"LOCAL", "abc-123"}, {"APR-DRG", "661-1"},
+	}
+	for _, c := range cases {
+		want, _ := normalize.CanonicalizeCode(c.codeType, c.raw)
+		var got string
+		if err := pool.QueryRow(ctx, "SELECT mrf.canonicalize_code($1, $2)", c.codeType, c.raw).Scan(&got); err != nil {
+			t.Fatalf("canonicalize_code(%s, %q): %v", c.codeType, c.raw, err)
+		}
+		if got != want {
+			t.Errorf("canonicalize_code(%s, %q): SQL %q, Go %q", c.codeType, c.raw, got, want)
+		}
+	}
+}
+
+// ---------- advisory locks ----------
+
+func TestAdvisoryLocks(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+
+	// Another mrfload process holding hospital 42
+	holderBatch := uuid.New()
+	cc := pool.Config().ConnConfig.Copy()
+	cc.RuntimeParams["application_name"] = "mrfload:" + holderBatch.String()
+	holder, err := pgx.ConnectConfig(ctx, cc)
+	if err != nil {
+		t.Fatalf("connect holder: %v", err)
+	}
+	defer holder.Close(ctx)
+	if _, err := holder.Exec(ctx, "SELECT pg_advisory_lock($1, $2)", ingest.LockClassHospital, int32(42)); err != nil {
+		t.Fatalf("holder lock: %v", err)
+	}
+
+	t.Run("times_out_naming_holder", func(t *testing.T) {
+		locks, err := ingest.OpenLocks(ctx, pool, uuid.New(), 300*time.Millisecond)
+		if err != nil {
+			t.Fatalf("OpenLocks: %v", err)
+		}
+		defer locks.Close()
+
+		start := time.Now()
+		err = locks.LockHospital(ctx, 42)
+		var locked *ingest.LockedError
+		if !errors.As(err, &locked) {
+			t.Fatalf("expected LockedError, got %v", err)
+		}
+		if locked.HolderBatchID != holderBatch.String() {
+			t.Errorf("holder batch: got %q, want %s", locked.HolderBatchID, holderBatch)
+		}
+		if time.Since(start) < 300*time.Millisecond {
+			t.Errorf("returned before the lock timeout: %s", time.Since(start))
+		}
+
+		// Other hospitals are unaffected
+		if err := locks.LockHospital(ctx, 43); err != nil {
+			t.Errorf("lock hospital 43: %v", err)
+		}
+	})
+
+	t.Run("close_releases", func(t *testing.T) {
+		if _, err := holder.Exec(ctx, "SELECT pg_advisory_unlock($1, $2)", ingest.LockClassHospital, int32(42)); err != nil {
+			t.Fatalf("holder unlock: %v", err)
+		}
+		locks, err := ingest.OpenLocks(ctx, pool, uuid.New(), 0)
+		if err != nil {
+			t.Fatalf("OpenLocks: %v", err)
+		}
+		if err := locks.LockHospital(ctx, 42); err != nil {
+			t.Fatalf("LockHospital: %v", err)
+		}
+		locks.Close()
+
+		var ok bool
+		if err := holder.QueryRow(ctx, "SELECT pg_try_advisory_lock($1, $2)", ingest.LockClassHospital, int32(42)).Scan(&ok); err != nil || !ok {
+			t.Errorf("lock should be free after Close: ok=%v err=%v", ok, err)
+		}
+	})
+}
+
+func TestAdvisoryLocks_InProcessWait(t *testing.T) {
+	pool := setupDB(t)
+	ctx := context.Background()
+
+	holderBatch := uuid.New()
+	holder, err := ingest.OpenLocks(ctx, pool, holderBatch, 0)
+	if err != nil {
+		t.Fatalf("OpenLocks: %v", err)
+	}
+	defer holder.Close()
+	if err := holder.LockHospital(ctx, 77); err != nil {
+		t.Fatalf("holder LockHospital: %v", err)
+	}
+
+	waiter, err := ingest.OpenLocks(ctx, pool, uuid.New(), 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("OpenLocks: %v", err)
+	}
+	defer waiter.Close()
+
+	t.Run("times_out", func(t *testing.T) {
+		start := time.Now()
+		err := waiter.LockHospital(ctx, 77)
+		var locked *ingest.LockedError
+		if !errors.As(err, &locked) || !locked.InProcess {
+			t.Fatalf("expected in-process LockedError, got %v", err)
+		}
+		if locked.HolderBatchID != holderBatch.String() {
+			t.Errorf("holder batch: got %q, want %s", locked.HolderBatchID, holderBatch)
+		}
+		if time.Since(start) < 200*time.Millisecond {
+			t.Errorf("returned before the lock timeout: %s", time.Since(start))
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		if err := waiter.LockHospital(cctx, 77); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("close_releases", func(t *testing.T) {
+		holder.Close()
+		if err := waiter.LockHospital(ctx, 77); err != nil {
+			t.Fatalf("LockHospital after holder closed: %v", err)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package ingest
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -12,11 +13,21 @@ import (
 
 	"github.com/gyeh/pricestats/internal/db"
 	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/mrfread"
 	"github.com/gyeh/pricestats/internal/normalize"
-	"github.com/gyeh/pricestats/internal/parquetread"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
 )
 
-const readBatchSize = 1024
+// defaultReadBatchSize is used when StageOptions.BatchSize is zero.
+const defaultReadBatchSize = 1024
+
+// defaultChunkRows is used when StageOptions.ChunkRows is zero. A chunk is
+// buffered as [][]any at roughly 1-2 KB per source row, and Stage holds two
+// (one being copied, one read ahead), so 20,000 rows is about 40-80 MB.
+const defaultChunkRows = 20_000
+
+// maxCopyRetryBackoff caps the doubling wait between chunk retries.
+const maxCopyRetryBackoff = 30 * time.Second
 
 // StageResult holds metrics from the staging phase.
 type StageResult struct {
@@ -26,89 +37,234 @@ type StageResult struct {
 	Duration     time.Duration
 }
 
-// Stage streams rows from the Parquet file, normalizes them, and COPY-loads
-// them into the staging table via a channel-backed CopyFromSource.
-func Stage(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, pf *PreflightResult, includePayerPrices bool) (*StageResult, error) {
+// StageOptions controls how source rows are normalized and loaded.
+type StageOptions struct {
+	IncludePayerPrices bool
+	MoneyRules         normalize.MoneyPolicy
+	BatchSize          int // source rows per read; 0 = defaultReadBatchSize
+	// ChunkRows is the number of source rows committed per staging
+	// transaction; 0 = defaultChunkRows. Memory grows with it: two chunks
+	// are buffered at once.
+	ChunkRows int
+	// Retry governs re-sending a chunk after a transient COPY failure.
+	Retry db.RetryPolicy
+	// Checkpoint, when set, continues an interrupted staging of the same
+	// batch: rows up to Checkpoint.Row are skipped and the earlier counts
+	// carried into the result.
+	Checkpoint *StageCheckpoint
+}
+
+// StageCheckpoint is the progress of a staging run as of its last
+// committed chunk.
+type StageCheckpoint struct {
+	Row          int64 // last committed source_row_number
+	RowsStaged   int64
+	RowsRejected int64
+}
+
+// stageChunk is the staged and quarantined rows of consecutive source rows
+// up to lastRow, committed together with the checkpoint.
+type stageChunk struct {
+	rows    [][]any
+	rejects [][]any
+	lastRow int64
+}
+
+// Stage streams rows from the source file, normalizes them, and COPY-loads
+// them into the staging table in chunks of opts.ChunkRows source rows. Each
+// chunk's staged rows, rejected rows and checkpoint (ingest.mrf_files
+// .staged_through_row) commit in one transaction, and a chunk that fails with
+// a transient error is retried per opts.Retry, so a dropped connection only
+// costs the chunk in flight.
+func Stage(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, pf *PreflightResult, opts StageOptions) (*StageResult, error) {
 	start := time.Now()
+	readBatchSize := opts.BatchSize
+	if readBatchSize <= 0 {
+		readBatchSize = defaultReadBatchSize
+	}
+	chunkRows := int64(opts.ChunkRows)
+	if chunkRows <= 0 {
+		chunkRows = defaultChunkRows
+	}
+	var skipThrough int64
+	result := &StageResult{}
+	if cp := opts.Checkpoint; cp != nil {
+		skipThrough = cp.Row
+		result.RowsStaged = cp.RowsStaged
+		result.RowsRejected = cp.RowsRejected
+		log.Info().Int64("checkpoint", cp.Row).Msg("continuing staging after checkpoint")
+	}
 
-	reader, err := parquetread.Open(pf.FilePath)
+	reader, _, err := mrfread.Reopen(pf.FilePath, pf.Layout)
 	if err != nil {
 		return nil, fmt.Errorf("stage open: %w", err)
 	}
 	defer reader.Close()
 
-	ch := make(chan *model.StagingRow, readBatchSize)
+	// One chunk is read ahead while the previous one is copied. The producer
+	// stops early once a chunk fails for good.
+	chunks := make(chan *stageChunk, 1)
 	errCh := make(chan error, 1)
+	prodCtx, stopProducer := context.WithCancel(ctx)
+	defer stopProducer()
 
-	var rowsRead, rowsRejected int64
-
-	// Producer goroutine: read Parquet → normalize → push to channel
+	// Producer goroutine: read source rows → normalize → group into chunks
 	go func() {
-		defer close(ch)
+		defer close(chunks)
 		buf := make([]model.HospitalChargeRow, readBatchSize)
 		var rowNum int64
+		chunk := &stageChunk{}
+		send := func() bool {
+			select {
+			case chunks <- chunk:
+				chunk = &stageChunk{}
+				return true
+			case <-prodCtx.Done():
+				errCh <- prodCtx.Err()
+				return false
+			}
+		}
 
 		for {
 			n, readErr := reader.Read(buf)
 			for i := 0; i < n; i++ {
 				rowNum++
-				rowsRead++
-
-				staging, normErr := normalize.ToStagingRow(&buf[i], pf.IngestBatchID, pf.MRFFileID, rowNum, includePayerPrices)
-				if normErr != nil {
-					rowsRejected++
-					log.Warn().Err(normErr).Int64("row", rowNum).Msg("row rejected")
+				result.RowsRead++
+				if rowNum <= skipThrough {
 					continue
 				}
 
-				select {
-				case ch <- staging:
-				case <-ctx.Done():
-					errCh <- ctx.Err()
+				staging, normErr := normalize.ToStagingRow(&buf[i], pf.IngestBatchID, pf.MRFFileID, rowNum, opts.IncludePayerPrices, opts.MoneyRules)
+				if normErr != nil {
+					log.Debug().Err(normErr).Int64("row", rowNum).Msg("row rejected")
+					rej, err := rejectValues(&buf[i], pf, rowNum, normErr)
+					if err != nil {
+						errCh <- err
+						return
+					}
+					chunk.rejects = append(chunk.rejects, rej)
+				} else {
+					chunk.rows = append(chunk.rows, staging.CopyValues())
+				}
+				chunk.lastRow = rowNum
+				if rowNum%chunkRows == 0 && !send() {
 					return
 				}
 			}
 			if readErr == io.EOF {
+				if chunk.lastRow > 0 && !send() {
+					return
+				}
 				break
 			}
 			if readErr != nil {
-				errCh <- fmt.Errorf("read parquet at row %d: %w", rowNum, readErr)
+				errCh <- fmt.Errorf("read %s at row %d: %w", pf.Format, rowNum, readErr)
 				return
 			}
 		}
 		errCh <- nil
 	}()
 
-	// Consumer: COPY from channel into staging table
-	source := db.NewChannelSource(ch)
-	rowsStaged, err := pool.CopyFrom(ctx,
-		pgx.Identifier{"ingest", "stage_charge_rows"},
-		model.StagingColumns(),
-		source,
-	)
+	// Consumer: commit each chunk, retrying transient failures
+	var copyErr error
+	for chunk := range chunks {
+		if copyErr != nil {
+			continue // drain so the producer can exit
+		}
+		copyErr = db.Retry(ctx, opts.Retry, func(attempt int, wait time.Duration, err error) {
+			log.Warn().Err(err).
+				Int("attempt", attempt).
+				Int64("chunk_end_row", chunk.lastRow).
+				Str("backoff", wait.String()).
+				Msg("staging chunk failed, retrying")
+		}, func() error {
+			return commitChunk(ctx, pool, pf.MRFFileID, chunk)
+		})
+		if copyErr != nil {
+			stopProducer()
+		} else {
+			result.RowsStaged += int64(len(chunk.rows))
+			result.RowsRejected += int64(len(chunk.rejects))
+			log.Debug().Int64("checkpoint", chunk.lastRow).Msg("staging chunk committed")
+		}
+	}
 
 	// Wait for producer to finish
 	prodErr := <-errCh
+	if copyErr != nil {
+		return nil, fmt.Errorf("stage copy: %w", copyErr)
+	}
 	if prodErr != nil {
 		return nil, fmt.Errorf("stage producer: %w", prodErr)
 	}
-	if err != nil {
-		return nil, fmt.Errorf("stage copy: %w", err)
-	}
 
 	dur := time.Since(start)
+	result.Duration = dur
 	log.Info().
-		Int64("rows_read", rowsRead).
-		Int64("rows_staged", rowsStaged).
-		Int64("rows_rejected", rowsRejected).
+		Int64("rows_read", result.RowsRead).
+		Int64("rows_staged", result.RowsStaged).
+		Int64("rows_rejected", result.RowsRejected).
 		Str("duration", dur.String()).
-		Float64("rows_per_sec", float64(rowsStaged)/dur.Seconds()).
+		Float64("rows_per_sec", float64(result.RowsStaged)/dur.Seconds()).
 		Msg("staging complete")
 
-	return &StageResult{
-		RowsRead:     rowsRead,
-		RowsStaged:   rowsStaged,
-		RowsRejected: rowsRejected,
-		Duration:     dur,
-	}, nil
+	return result, nil
+}
+
+// commitChunk COPYs a chunk's staged and rejected rows and advances the
+// file's checkpoint in one transaction.
+func commitChunk(ctx context.Context, pool *pgxpool.Pool, mRFFileID int64, chunk *stageChunk) error {
+	return db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+		if len(chunk.rows) > 0 {
+			if _, err := tx.CopyFrom(ctx,
+				pgx.Identifier{"ingest", "stage_charge_rows"},
+				model.StagingColumns(),
+				pgx.CopyFromRows(chunk.rows),
+			); err != nil {
+				return err
+			}
+		}
+		if len(chunk.rejects) > 0 {
+			if _, err := tx.CopyFrom(ctx,
+				pgx.Identifier{"ingest", "rejected_rows"},
+				model.RejectedColumns(),
+				pgx.CopyFromRows(chunk.rejects),
+			); err != nil {
+				return fmt.Errorf("copy rejected rows: %w", err)
+			}
+		}
+		return sqlcgen.New(tx).SetStageCheckpoint(ctx, sqlcgen.SetStageCheckpointParams{
+			StagedThroughRow: &chunk.lastRow,
+			MrfFileID:        mRFFileID,
+		})
+	})
+}
+
+// rejectValues builds the quarantine row for a source row with the reason
+// carried by normErr.
+func rejectValues(row *model.HospitalChargeRow, pf *PreflightResult, rowNum int64, normErr error) ([]any, error) {
+	raw, err := row.RawJSON()
+	if err != nil {
+		return nil, fmt.Errorf("encode rejected row %d: %w", rowNum, err)
+	}
+
+	rej := &model.RejectedRow{
+		IngestBatchID:   pf.IngestBatchID,
+		MRFFileID:       pf.MRFFileID,
+		SourceRowNumber: rowNum,
+		RawRow:          raw,
+	}
+	var rowErr *normalize.RowError
+	if errors.As(normErr, &rowErr) {
+		rej.ReasonCode = string(rowErr.Reason)
+		rej.FieldName = &rowErr.Field
+		if rowErr.Detail != "" {
+			rej.Detail = &rowErr.Detail
+		}
+	} else {
+		detail := normErr.Error()
+		rej.ReasonCode = "normalize_error"
+		rej.Detail = &detail
+	}
+	return rej.CopyValues(), nil
 }
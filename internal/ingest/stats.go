@@ -0,0 +1,134 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/gyeh/pricestats/internal/db"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// StatsRefresh reports one hospital's statistics refresh.
+type StatsRefresh struct {
+	HospitalID   int64  `json:"hospital_id"`
+	HospitalName string `json:"hospital_name"`
+	// HospitalRows are the hospital's mrf.price_stats rows now; StateRows
+	// the mrf.price_stats_by_state rows recomputed for its codes.
+	HospitalRows int64 `json:"hospital_rows"`
+	StateRows    int64 `json:"state_rows"`
+}
+
+// RefreshHospitalStats recomputes a hospital's mrf.price_stats rows from its
+// active version, then the mrf.price_stats_by_state rows of every code the
+// hospital had or now has. Finalize calls it in the transaction that
+// activates a version, so the statistics switch with the prices. The
+// state rollups are recomputed under a transaction-scoped lock per state,
+// so hospitals of one state publishing at once cannot lose each other's
+// rows.
+func RefreshHospitalStats(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, hospitalID int64) (StatsRefresh, error) {
+	r := StatsRefresh{HospitalID: hospitalID}
+	before, err := q.ListHospitalStatsKeys(ctx, hospitalID)
+	if err != nil {
+		return r, fmt.Errorf("list stats keys: %w", err)
+	}
+	if _, err := q.DeleteHospitalStats(ctx, hospitalID); err != nil {
+		return r, fmt.Errorf("delete hospital stats: %w", err)
+	}
+	if r.HospitalRows, err = q.InsertHospitalStats(ctx, hospitalID); err != nil {
+		return r, fmt.Errorf("insert hospital stats: %w", err)
+	}
+	after, err := q.ListHospitalStatsKeys(ctx, hospitalID)
+	if err != nil {
+		return r, fmt.Errorf("list stats keys: %w", err)
+	}
+
+	keys := sqlcgen.InsertStateStatsParams{}
+	seen := map[sqlcgen.ListHospitalStatsKeysRow]bool{}
+	states := map[string]bool{}
+	for _, k := range append(before, after...) {
+		if seen[*k] {
+			continue
+		}
+		seen[*k] = true
+		states[k.LicenseState] = true
+		keys.CodeTypes = append(keys.CodeTypes, k.CodeType)
+		keys.CodeNorms = append(keys.CodeNorms, k.CodeNorm)
+		keys.Settings = append(keys.Settings, k.Setting)
+		keys.States = append(keys.States, k.LicenseState)
+	}
+	if len(seen) == 0 {
+		return r, nil
+	}
+	// A hospital whose license state changed takes two state locks, so take
+	// them in sorted order to avoid deadlocking with another refresh
+	for _, s := range sortedKeys(states) {
+		if err := q.AdvisoryXactLock(ctx, sqlcgen.AdvisoryXactLockParams{ClassID: LockClassStateStats, ObjID: stateKey(s)}); err != nil {
+			return r, fmt.Errorf("lock state %s stats: %w", s, err)
+		}
+	}
+	if _, err := q.DeleteStateStats(ctx, sqlcgen.DeleteStateStatsParams(keys)); err != nil {
+		return r, fmt.Errorf("delete state stats: %w", err)
+	}
+	if r.StateRows, err = q.InsertStateStats(ctx, keys); err != nil {
+		return r, fmt.Errorf("insert state stats: %w", err)
+	}
+	log.Info().Int64("hospital_id", hospitalID).Int64("hospital_rows", r.HospitalRows).
+		Int64("state_rows", r.StateRows).Msg("price statistics refreshed")
+	return r, nil
+}
+
+// RefreshStats recomputes the statistics of the hospitals matching hospital
+// (a case-insensitive substring; nil for all) that have an active version
+// or statistics left from one, each in its own transaction under the
+// hospital's advisory lock. It returns the hospitals refreshed before any
+// error.
+func RefreshStats(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger, hospital *string, lockTimeout time.Duration) ([]StatsRefresh, error) {
+	q := sqlcgen.New(pool)
+	hospitals, err := q.ListStatsHospitals(ctx, hospital)
+	if err != nil {
+		return nil, fmt.Errorf("list hospitals: %w", err)
+	}
+	var out []StatsRefresh
+	for _, h := range hospitals {
+		r, err := refreshLocked(ctx, pool, q, log, h.HospitalID, lockTimeout)
+		if err != nil {
+			return out, fmt.Errorf("refresh stats of %s: %w", h.HospitalName, err)
+		}
+		r.HospitalName = h.HospitalName
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func refreshLocked(ctx context.Context, pool *pgxpool.Pool, q *sqlcgen.Queries, log zerolog.Logger, hospitalID int64, lockTimeout time.Duration) (StatsRefresh, error) {
+	locks, err := OpenLocks(ctx, pool, uuid.New(), lockTimeout)
+	if err != nil {
+		return StatsRefresh{}, err
+	}
+	defer locks.Close()
+	if err := locks.LockHospital(ctx, hospitalID); err != nil {
+		return StatsRefresh{}, err
+	}
+	var r StatsRefresh
+	err = db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+		r, err = RefreshHospitalStats(ctx, q.WithTx(tx), log, hospitalID)
+		return err
+	})
+	return r, err
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
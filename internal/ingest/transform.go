@@ -3,26 +3,101 @@ package ingest
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/rs/zerolog"
 
+	"github.com/gyeh/pricestats/internal/model"
+	embedsql "github.com/gyeh/pricestats/internal/sql"
 	"github.com/gyeh/pricestats/internal/sqlcgen"
 )
 
 // TransformResult holds metrics from the wide→long transformation.
 type TransformResult struct {
-	RowsInserted int64
-	Duration     time.Duration
+	RowsInserted     int64
+	RowsWithoutCodes int64 // staged rows with no code of any registered type
+	RowsFilteredOut  int64 // staged rows whose codes are all outside the code_types filter
+	Duration         time.Duration
+}
+
+// TransformWideToLongParams selects the staged batch to transform and,
+// optionally, the code types to keep (nil keeps all).
+type TransformWideToLongParams struct {
+	IngestBatchID uuid.UUID
+	CodeTypes     []string
+}
+
+var (
+	registrySQLMu sync.Mutex
+	registrySQL   = make(map[string]string)
+)
+
+// registryTemplateSQL renders the named template from internal/sql/templates
+// over the code type registry. The registry is fixed at build time, so each
+// template is rendered once.
+func registryTemplateSQL(name string) (string, error) {
+	registrySQLMu.Lock()
+	defer registrySQLMu.Unlock()
+	if q, ok := registrySQL[name]; ok {
+		return q, nil
+	}
+
+	tmpl, err := template.ParseFS(embedsql.Templates, "templates/"+name)
+	if err != nil {
+		return "", fmt.Errorf("parse template %s: %w", name, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, model.AllCodeTypes); err != nil {
+		return "", fmt.Errorf("render template %s: %w", name, err)
+	}
+	registrySQL[name] = b.String()
+	return registrySQL[name], nil
+}
+
+// TransformWideToLong unpivots every registered code column of the staged
+// batch into mrf.prices_by_code.
+func TransformWideToLong(ctx context.Context, db sqlcgen.DBTX, arg TransformWideToLongParams) (pgconn.CommandTag, error) {
+	query, err := registryTemplateSQL("transform_wide_to_long.sql")
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return db.Exec(ctx, query, arg.IngestBatchID, arg.CodeTypes)
+}
+
+// CodelessCounts are the staged rows of a batch that produce no serving
+// rows.
+type CodelessCounts struct {
+	// Codeless rows carry no code of any registered type.
+	Codeless int64
+	// FilteredOut rows carry codes, but only of types outside the
+	// code_types filter.
+	FilteredOut int64
+}
+
+// CountCodelessRows counts the staged rows of the batch that the wide→long
+// transform drops, telling rows without any code from rows the code_types
+// filter excludes.
+func CountCodelessRows(ctx context.Context, db sqlcgen.DBTX, arg TransformWideToLongParams) (CodelessCounts, error) {
+	var c CodelessCounts
+	query, err := registryTemplateSQL("count_codeless_rows.sql")
+	if err != nil {
+		return c, err
+	}
+	err = db.QueryRow(ctx, query, arg.IngestBatchID, arg.CodeTypes).Scan(&c.Codeless, &c.FilteredOut)
+	return c, err
 }
 
 // Transform executes the wide→long INSERT...SELECT from staging into the
 // serving table (mrf.prices_by_code).
-func Transform(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, batchID uuid.UUID, codeTypes []string) (*TransformResult, error) {
+func Transform(ctx context.Context, db sqlcgen.DBTX, log zerolog.Logger, batchID uuid.UUID, codeTypes []string) (*TransformResult, error) {
 	start := time.Now()
 
-	tag, err := q.TransformWideToLong(ctx, sqlcgen.TransformWideToLongParams{
+	tag, err := TransformWideToLong(ctx, db, TransformWideToLongParams{
 		IngestBatchID: batchID,
 		CodeTypes:     codeTypes,
 	})
@@ -30,17 +105,29 @@ func Transform(ctx context.Context, q *sqlcgen.Queries, log zerolog.Logger, batc
 		return nil, fmt.Errorf("transform wide to long: %w", err)
 	}
 
+	dropped, err := CountCodelessRows(ctx, db, TransformWideToLongParams{
+		IngestBatchID: batchID,
+		CodeTypes:     codeTypes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("count codeless rows: %w", err)
+	}
+
 	dur := time.Since(start)
 	rows := tag.RowsAffected()
 
 	log.Info().
 		Int64("rows_inserted", rows).
+		Int64("rows_without_codes", dropped.Codeless).
+		Int64("rows_filtered_out", dropped.FilteredOut).
 		Str("duration", dur.String()).
 		Float64("rows_per_sec", float64(rows)/dur.Seconds()).
 		Msg("transform complete")
 
 	return &TransformResult{
-		RowsInserted: rows,
-		Duration:     dur,
+		RowsInserted:     rows,
+		RowsWithoutCodes: dropped.Codeless,
+		RowsFilteredOut:  dropped.FilteredOut,
+		Duration:         dur,
 	}, nil
 }
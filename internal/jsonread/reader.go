@@ -0,0 +1,273 @@
+package jsonread
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gyeh/pricestats/internal/model"
+)
+
+// chargesKey is the top-level key holding the (potentially multi-GB) array of
+// charge items in the CMS v2.x JSON template.
+const chargesKey = "standard_charge_information"
+
+// Reader streams HospitalChargeRow records out of a CMS v2.x JSON
+// standard-charge file. Only one charge item is decoded at a time, so memory
+// use is bounded by the largest single item rather than the file size.
+type Reader struct {
+	path    string
+	file    *os.File
+	dec     *json.Decoder
+	bom     int64
+	header  fileHeader
+	offset  int64
+	pending []model.HospitalChargeRow
+	done    bool
+}
+
+// Layout is what Open learned about a file: its metadata and the byte
+// offset of the first charge item. OpenAt reuses it to skip the header
+// scan, which reads the whole file when metadata follows the charge array.
+type Layout struct {
+	header fileHeader
+	offset int64
+}
+
+// Open opens a CMS JSON file, reads the hospital metadata, and positions the
+// stream at the first charge item.
+func Open(path string) (*Reader, error) {
+	r := &Reader{path: path}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	complete, err := r.scanHeader()
+	if err != nil {
+		r.file.Close()
+		return nil, err
+	}
+	if complete || !r.header.sawCharges {
+		return r, nil
+	}
+
+	// The header scan skipped past the charge array looking for metadata
+	// that follows it, so reopen at the array start.
+	r.file.Close()
+	if err := r.openAt(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// OpenAt opens a CMS JSON file at the first charge item with the metadata
+// of a Layout taken from an earlier Reader of the same file.
+func OpenAt(path string, l *Layout) (*Reader, error) {
+	r := &Reader{path: path, header: l.header, offset: l.offset}
+	if !r.header.sawCharges {
+		// Nothing to read; Validate reports the missing array
+		if err := r.open(); err != nil {
+			return nil, err
+		}
+		r.done = true
+		return r, nil
+	}
+	if err := r.openAt(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Layout returns the file's metadata and charge array position for OpenAt.
+func (r *Reader) Layout() *Layout {
+	return &Layout{header: r.header, offset: r.offset}
+}
+
+func (r *Reader) open() error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("open json file: %w", err)
+	}
+	r.file = f
+	br := bufio.NewReaderSize(f, 1<<20)
+	if bom, _ := br.Peek(3); string(bom) == "\xef\xbb\xbf" {
+		br.Discard(3)
+		r.bom = 3
+	}
+	r.dec = json.NewDecoder(br)
+	r.dec.UseNumber()
+	return nil
+}
+
+// openAt opens the file at r.offset, just inside the charge array. The
+// decoder is handed the opening bracket again so it tracks the array; it
+// stops at the closing one and never reads the rest of the object.
+func (r *Reader) openAt() error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("open json file: %w", err)
+	}
+	if _, err := f.Seek(r.offset, io.SeekStart); err != nil {
+		f.Close()
+		return fmt.Errorf("seek %s: %w", chargesKey, err)
+	}
+	r.file = f
+	r.dec = json.NewDecoder(io.MultiReader(strings.NewReader("["), bufio.NewReaderSize(f, 1<<20)))
+	r.dec.UseNumber()
+	if err := expectDelim(r.dec, '['); err != nil {
+		f.Close()
+		return fmt.Errorf("seek %s: %w", chargesKey, err)
+	}
+	r.done = false
+	return nil
+}
+
+// scanHeader walks the top-level object collecting hospital metadata and
+// the offset of the charge array. It returns complete=true when it stopped
+// at the opening bracket of the array with every metadata field already
+// known, leaving the decoder there. Otherwise it skips the array so fields
+// written after it (CMS does not fix the key order) still reach every row.
+func (r *Reader) scanHeader() (complete bool, err error) {
+	if err := expectDelim(r.dec, '{'); err != nil {
+		return false, fmt.Errorf("read json header: %w", err)
+	}
+	for r.dec.More() {
+		key, err := readKey(r.dec)
+		if err != nil {
+			return false, fmt.Errorf("read json header: %w", err)
+		}
+		if key != chargesKey {
+			if err := r.header.decodeField(r.dec, key); err != nil {
+				return false, fmt.Errorf("read json header field %s: %w", key, err)
+			}
+			continue
+		}
+
+		if err := expectDelim(r.dec, '['); err != nil {
+			return false, fmt.Errorf("read %s: %w", chargesKey, err)
+		}
+		r.header.sawCharges = true
+		r.offset = r.bom + r.dec.InputOffset()
+		if r.header.complete() {
+			return true, nil
+		}
+		for r.dec.More() {
+			if err := skipValue(r.dec); err != nil {
+				return false, fmt.Errorf("skip %s: %w", chargesKey, err)
+			}
+		}
+		if err := expectDelim(r.dec, ']'); err != nil {
+			return false, fmt.Errorf("skip %s: %w", chargesKey, err)
+		}
+	}
+	r.done = true
+	return false, nil
+}
+
+// NumRows returns -1: the row count of a JSON file is only known after a full pass.
+func (r *Reader) NumRows() int64 {
+	return -1
+}
+
+// Read reads up to len(rows) records into the provided slice.
+// Returns the number of rows read and io.EOF when done.
+func (r *Reader) Read(rows []model.HospitalChargeRow) (int, error) {
+	n := 0
+	for n < len(rows) {
+		if len(r.pending) == 0 {
+			if r.done {
+				return n, io.EOF
+			}
+			if err := r.next(); err != nil {
+				return n, err
+			}
+			continue
+		}
+		c := copy(rows[n:], r.pending)
+		r.pending = r.pending[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// next decodes the following charge item into r.pending, or marks the
+// reader done at the end of the array.
+func (r *Reader) next() error {
+	if !r.dec.More() {
+		r.done = true
+		return nil
+	}
+	var item chargeItem
+	if err := r.dec.Decode(&item); err != nil {
+		return fmt.Errorf("read json charge item: %w", err)
+	}
+	r.pending = item.rows(&r.header)
+	return nil
+}
+
+// Validate checks that the file carries the metadata and charge array
+// required to produce rows, reporting every missing field.
+func (r *Reader) Validate() error {
+	missing := r.header.missing()
+	if r.header.HospitalName == "" && r.header.seen["hospital_name"] {
+		missing = append([]string{"hospital_name"}, missing...)
+	}
+	if !r.header.sawCharges {
+		missing = append(missing, chargesKey)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Close releases all resources.
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+func readKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("expected object key, got %v", tok)
+	}
+	return key, nil
+}
+
+// skipValue consumes the next value token-by-token without materializing it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package jsonread
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gyeh/pricestats/internal/model"
+)
+
+const chargesDoc = `[
+    {
+      "description": "Office visit",
+      "code_information": [
+        {"code": "99213", "type": "CPT"},
+        {"code": "0510", "type": "RC"}
+      ],
+      "standard_charges": [
+        {
+          "setting": "outpatient",
+          "gross_charge": 250.5,
+          "discounted_cash": "200.00",
+          "minimum": 90,
+          "maximum": 240,
+          "modifiers": ["25"],
+          "payers_information": [
+            {"payer_name": "Aetna", "plan_name": "PPO", "standard_charge_dollar": 120.25, "methodology": "fee schedule"},
+            {"payer_name": "Cigna", "plan_name": "HMO", "standard_charge_percentage": 55}
+          ]
+        }
+      ]
+    },
+    {
+      "description": "Aspirin",
+      "drug_information": {"unit": "81", "type": "ME"},
+      "code_information": [{"code": "0573-0150-20", "type": "NDC"}],
+      "standard_charges": [{"setting": "inpatient", "gross_charge": 1.1}]
+    }
+  ]`
+
+const headerDoc = `"hospital_name": "General Hospital",
+  "last_updated_on": "2024-07-01",
+  "version": "2.0.0",
+  "hospital_location": ["Main Campus", "East Wing"],
+  "hospital_address": ["1 Main St, Springfield, IL"],
+  "license_information": {"license_number": "12345", "state": "IL"},
+  "affirmation": {"affirmation": "To the best of its knowledge...", "confirm_affirmation": true}`
+
+func writeDoc(t *testing.T, doc string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mrf.json")
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	return path
+}
+
+func readAll(t *testing.T, r *Reader) []model.HospitalChargeRow {
+	t.Helper()
+	var all []model.HospitalChargeRow
+	buf := make([]model.HospitalChargeRow, 2)
+	for {
+		n, err := r.Read(buf)
+		all = append(all, buf[:n]...)
+		if err == io.EOF {
+			return all
+		}
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+	}
+}
+
+func TestReader_MetadataFirst(t *testing.T) {
+	path := writeDoc(t, "{\n  "+headerDoc+",\n  \"standard_charge_information\": "+chargesDoc+",\n  \"modifier_information\": []\n}")
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	rows := readAll(t, r)
+	// 2 codes × 2 payers for the office visit + 1 code for aspirin
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows, got %d", len(rows))
+	}
+
+	first := rows[0]
+	if first.HospitalName != "General Hospital" || first.HospitalLocation != "Main Campus|East Wing" {
+		t.Errorf("unexpected hospital metadata: %q / %q", first.HospitalName, first.HospitalLocation)
+	}
+	if first.LicenseState == nil || *first.LicenseState != "IL" || !first.Affirmation {
+		t.Errorf("unexpected license/affirmation: %v %v", first.LicenseState, first.Affirmation)
+	}
+	if first.CPTCode == nil || *first.CPTCode != "99213" || first.RCCode != nil {
+		t.Errorf("first row should carry only the CPT code: cpt=%v rc=%v", first.CPTCode, first.RCCode)
+	}
+	if first.PayerName == nil || *first.PayerName != "Aetna" || first.NegotiatedDollar == nil || *first.NegotiatedDollar != 120.25 {
+		t.Errorf("unexpected payer fields: %v %v", first.PayerName, first.NegotiatedDollar)
+	}
+	if first.DiscountedCash == nil || *first.DiscountedCash != 200 {
+		t.Errorf("quoted discounted_cash not parsed: %v", first.DiscountedCash)
+	}
+	if first.Modifiers == nil || *first.Modifiers != "25" {
+		t.Errorf("unexpected modifiers: %v", first.Modifiers)
+	}
+
+	drug := rows[4]
+	if drug.NDCCode == nil || *drug.NDCCode != "0573-0150-20" || drug.PayerName != nil {
+		t.Errorf("unexpected drug row: ndc=%v payer=%v", drug.NDCCode, drug.PayerName)
+	}
+	if drug.DrugUnitOfMeasurement == nil || *drug.DrugUnitOfMeasurement != 81 {
+		t.Errorf("unexpected drug unit: %v", drug.DrugUnitOfMeasurement)
+	}
+}
+
+func TestReader_MetadataAfterCharges(t *testing.T) {
+	path := writeDoc(t, "{\n  \"standard_charge_information\": "+chargesDoc+",\n  "+headerDoc+"\n}")
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+
+	rows := readAll(t, r)
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows, got %d", len(rows))
+	}
+	if rows[0].HospitalName != "General Hospital" || rows[0].LastUpdatedOn != "2024-07-01" {
+		t.Errorf("metadata after the charge array was not applied: %+v", rows[0])
+	}
+}
+
+// Only hospital_name precedes the charge array: the metadata written after
+// it must still reach every row.
+func TestReader_NameBeforeChargesRestAfter(t *testing.T) {
+	path := writeDoc(t, "{\n  \"hospital_name\": \"General Hospital\",\n  \"standard_charge_information\": "+chargesDoc+
+		",\n  \"last_updated_on\": \"2024-07-01\",\n  \"version\": \"2.0.0\",\n"+
+		"  \"license_information\": {\"license_number\": \"12345\", \"state\": \"IL\"},\n"+
+		"  \"affirmation\": {\"confirm_affirmation\": true}\n}")
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	rows := readAll(t, r)
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows, got %d", len(rows))
+	}
+	for i, row := range rows {
+		if row.LastUpdatedOn != "2024-07-01" || row.Version != "2.0.0" || row.LicenseState == nil || *row.LicenseState != "IL" || !row.Affirmation {
+			t.Errorf("row %d: metadata after the charge array was dropped: %q %q %v %v",
+				i, row.LastUpdatedOn, row.Version, row.LicenseState, row.Affirmation)
+		}
+	}
+}
+
+// OpenAt resumes at the charge array with the metadata Open collected, in
+// either key order and past a byte order mark.
+func TestReader_OpenAtLayout(t *testing.T) {
+	docs := map[string]string{
+		"metadata first": "{\n  " + headerDoc + ",\n  \"standard_charge_information\": " + chargesDoc + "\n}",
+		"metadata after": "\xef\xbb\xbf{\n  \"standard_charge_information\": " + chargesDoc + ",\n  " + headerDoc + "\n}",
+	}
+	for name, doc := range docs {
+		path := writeDoc(t, doc)
+		r, err := Open(path)
+		if err != nil {
+			t.Fatalf("%s: Open: %v", name, err)
+		}
+		want := readAll(t, r)
+		layout := r.Layout()
+		r.Close()
+
+		r, err = OpenAt(path, layout)
+		if err != nil {
+			t.Fatalf("%s: OpenAt: %v", name, err)
+		}
+		if err := r.Validate(); err != nil {
+			t.Errorf("%s: Validate: %v", name, err)
+		}
+		got := readAll(t, r)
+		r.Close()
+		if len(got) != 5 || len(got) != len(want) {
+			t.Fatalf("%s: expected 5 rows, got %d (Open read %d)", name, len(got), len(want))
+		}
+		for i := range got {
+			if got[i].Description != want[i].Description || got[i].HospitalName != "General Hospital" ||
+				got[i].LicenseState == nil || *got[i].LicenseState != "IL" {
+				t.Errorf("%s: row %d differs: %+v vs %+v", name, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestReader_MissingRequiredFields(t *testing.T) {
+	path := writeDoc(t, `{"hospital_name": "General Hospital", "standard_charge_information": [], "version": "2.0.0"}`)
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+	err = r.Validate()
+	if err == nil || err.Error() != "missing required fields: last_updated_on" {
+		t.Fatalf("expected last_updated_on to be reported missing, got %v", err)
+	}
+
+	path = writeDoc(t, `{"standard_charge_information": []}`)
+	r2, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r2.Close()
+	err = r2.Validate()
+	if err == nil || err.Error() != "missing required fields: hospital_name, last_updated_on, version" {
+		t.Fatalf("expected every required field to be reported missing, got %v", err)
+	}
+}
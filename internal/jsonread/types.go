@@ -0,0 +1,299 @@
+package jsonread
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gyeh/pricestats/internal/model"
+)
+
+// fileHeader holds the top-level hospital metadata of a CMS JSON file.
+type fileHeader struct {
+	HospitalName     string
+	LastUpdatedOn    string
+	Version          string
+	HospitalLocation string
+	HospitalAddress  string
+	LicenseNumber    *string
+	LicenseState     *string
+	Affirmation      bool
+
+	sawCharges bool
+	// seen holds the metadata fields decoded so far, by headerFields name.
+	seen map[string]bool
+}
+
+// headerFields are the top-level metadata fields decodeField reads, and
+// requiredFields those a file must carry to be ingested.
+var (
+	headerFields   = []string{"hospital_name", "last_updated_on", "version", "hospital_location", "hospital_address", "license_information", "affirmation"}
+	requiredFields = []string{"hospital_name", "last_updated_on", "version"}
+)
+
+// complete reports whether every metadata field has been decoded, so
+// nothing after the charge array can change the rows.
+func (h *fileHeader) complete() bool {
+	for _, f := range headerFields {
+		if !h.seen[f] {
+			return false
+		}
+	}
+	return true
+}
+
+// missing returns the required fields not decoded, in requiredFields order.
+func (h *fileHeader) missing() []string {
+	var out []string
+	for _, f := range requiredFields {
+		if !h.seen[f] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// decodeField decodes the value for a top-level key. Unrecognized keys
+// (e.g. modifier_information) are skipped without being materialized.
+func (h *fileHeader) decodeField(dec *json.Decoder, key string) error {
+	if key == "attestation" {
+		key = "affirmation"
+	}
+	if h.seen == nil {
+		h.seen = make(map[string]bool, len(headerFields))
+	}
+	h.seen[key] = true
+	switch key {
+	case "hospital_name":
+		return dec.Decode(&h.HospitalName)
+	case "last_updated_on":
+		return dec.Decode(&h.LastUpdatedOn)
+	case "version":
+		return dec.Decode(&h.Version)
+	case "hospital_location":
+		var v flexStrings
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		h.HospitalLocation = v.join()
+	case "hospital_address":
+		var v flexStrings
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		h.HospitalAddress = v.join()
+	case "license_information":
+		var v struct {
+			LicenseNumber *string `json:"license_number"`
+			State         *string `json:"state"`
+		}
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		h.LicenseNumber = v.LicenseNumber
+		h.LicenseState = v.State
+	case "affirmation":
+		var v struct {
+			ConfirmAffirmation *bool `json:"confirm_affirmation"`
+			ConfirmAttestation *bool `json:"confirm_attestation"`
+		}
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		if v.ConfirmAffirmation != nil {
+			h.Affirmation = *v.ConfirmAffirmation
+		}
+		if v.ConfirmAttestation != nil {
+			h.Affirmation = *v.ConfirmAttestation
+		}
+	default:
+		return skipValue(dec)
+	}
+	return nil
+}
+
+// chargeItem is one element of standard_charge_information.
+type chargeItem struct {
+	Description     string           `json:"description"`
+	DrugInformation *drugInformation `json:"drug_information"`
+	CodeInformation []codeInfo       `json:"code_information"`
+	StandardCharges []standardCharge `json:"standard_charges"`
+}
+
+type drugInformation struct {
+	Unit flexFloat `json:"unit"`
+	Type *string   `json:"type"`
+}
+
+type codeInfo struct {
+	Code string `json:"code"`
+	Type string `json:"type"`
+}
+
+type standardCharge struct {
+	Setting                string             `json:"setting"`
+	Minimum                flexFloat          `json:"minimum"`
+	Maximum                flexFloat          `json:"maximum"`
+	GrossCharge            flexFloat          `json:"gross_charge"`
+	DiscountedCash         flexFloat          `json:"discounted_cash"`
+	Modifiers              flexStrings        `json:"modifiers"`
+	BillingClass           *string            `json:"billing_class"`
+	AdditionalGenericNotes *string            `json:"additional_generic_notes"`
+	PayersInformation      []payerInformation `json:"payers_information"`
+}
+
+type payerInformation struct {
+	PayerName                *string   `json:"payer_name"`
+	PlanName                 *string   `json:"plan_name"`
+	AdditionalPayerNotes     *string   `json:"additional_payer_notes"`
+	StandardChargeDollar     flexFloat `json:"standard_charge_dollar"`
+	StandardChargeAlgorithm  *string   `json:"standard_charge_algorithm"`
+	StandardChargePercentage flexFloat `json:"standard_charge_percentage"`
+	EstimatedAmount          flexFloat `json:"estimated_amount"`
+	Methodology              *string   `json:"methodology"`
+}
+
+// rows flattens a charge item into one HospitalChargeRow per
+// code/payer/plan combination. Standard charges without payer information
+// produce rows carrying only the hospital-level charges.
+func (it *chargeItem) rows(h *fileHeader) []model.HospitalChargeRow {
+	codes := make([]model.CodeEntry, len(it.CodeInformation))
+	for i, c := range it.CodeInformation {
+		codes[i] = model.CodeEntry{Type: c.Type, Code: c.Code}
+	}
+
+	var out []model.HospitalChargeRow
+	for _, sc := range it.StandardCharges {
+		base := model.HospitalChargeRow{
+			Description:            it.Description,
+			Setting:                sc.Setting,
+			Modifiers:              sc.Modifiers.ptr(),
+			AdditionalGenericNotes: sc.AdditionalGenericNotes,
+			BillingClass:           sc.BillingClass,
+
+			HospitalName:     h.HospitalName,
+			LastUpdatedOn:    h.LastUpdatedOn,
+			Version:          h.Version,
+			HospitalLocation: h.HospitalLocation,
+			HospitalAddress:  h.HospitalAddress,
+			LicenseNumber:    h.LicenseNumber,
+			LicenseState:     h.LicenseState,
+			Affirmation:      h.Affirmation,
+		}
+		base.GrossCharge = sc.GrossCharge.value(&base, "gross_charge")
+		base.DiscountedCash = sc.DiscountedCash.value(&base, "discounted_cash")
+		base.MinCharge = sc.Minimum.value(&base, "min_charge")
+		base.MaxCharge = sc.Maximum.value(&base, "max_charge")
+		if it.DrugInformation != nil {
+			base.DrugUnitOfMeasurement = it.DrugInformation.Unit.value(&base, "drug_unit_of_measurement")
+			base.DrugTypeOfMeasurement = it.DrugInformation.Type
+		}
+
+		if len(sc.PayersInformation) == 0 {
+			out = append(out, model.ExpandCodes(base, codes)...)
+			continue
+		}
+		for _, p := range sc.PayersInformation {
+			row := base
+			row.PayerName = p.PayerName
+			row.PlanName = p.PlanName
+			row.AdditionalPayerNotes = p.AdditionalPayerNotes
+			row.NegotiatedDollar = p.StandardChargeDollar.value(&row, "negotiated_dollar")
+			row.NegotiatedAlgorithm = p.StandardChargeAlgorithm
+			row.NegotiatedPercentage = p.StandardChargePercentage.value(&row, "negotiated_percentage")
+			row.EstimatedAmount = p.EstimatedAmount.value(&row, "estimated_amount")
+			row.Methodology = p.Methodology
+			out = append(out, model.ExpandCodes(row, codes)...)
+		}
+	}
+	return out
+}
+
+// flexFloat accepts a JSON number, a numeric string, or null. Hospitals are
+// inconsistent about quoting amounts, so both encodings are tolerated. Any
+// other value (e.g. "N/A") is kept as published in raw so the row, not the
+// file, is rejected.
+type flexFloat struct {
+	v     float64
+	valid bool
+	raw   string
+}
+
+func (f *flexFloat) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s := string(data)
+	raw := s
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		raw = strings.TrimSpace(s)
+		s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
+		s = strings.TrimPrefix(s, "$")
+		if s == "" {
+			return nil
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		f.raw = raw
+		return nil
+	}
+	f.v, f.valid = v, true
+	return nil
+}
+
+func (f flexFloat) ptr() *float64 {
+	if !f.valid {
+		return nil
+	}
+	v := f.v
+	return &v
+}
+
+// value returns the amount for column, recording an unparsed value on row.
+func (f flexFloat) value(row *model.HospitalChargeRow, column string) *float64 {
+	if f.raw != "" {
+		row.SetUnparsed(column, f.raw)
+	}
+	return f.ptr()
+}
+
+// flexStrings accepts either a single JSON string or an array of strings.
+type flexStrings []string
+
+func (f *flexStrings) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var ss []string
+		if err := json.Unmarshal(data, &ss); err != nil {
+			return err
+		}
+		*f = ss
+		return nil
+	}
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s != nil {
+		*f = []string{*s}
+	}
+	return nil
+}
+
+func (f flexStrings) join() string {
+	return strings.Join(f, "|")
+}
+
+func (f flexStrings) ptr() *string {
+	if len(f) == 0 {
+		return nil
+	}
+	s := f.join()
+	return &s
+}
@@ -1,5 +1,12 @@
 package model
 
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
 // CodeType represents one of the supported CMS-defined billing code types.
 type CodeType struct {
 	Name       string // e.g. "CPT"
@@ -7,13 +14,32 @@ type CodeType struct {
 	Partition  string // partition table suffix, e.g. "cpt"
 }
 
-// AllCodeTypes lists the supported CMS-defined code types in canonical order.
+// AllCodeTypes is the code type registry: the single list that drives the
+// staging columns, the wide→long transform, and the serving partitions and
+// indexes (created by `mrfload migrate`, which also persists it to
+// ref.code_types). Adding a code type means adding an entry here and a
+// matching parquet-tagged field on HospitalChargeRow with its
+// codeFieldsByColumn accessor, then re-running migrate.
 var AllCodeTypes = []CodeType{
 	{Name: "CPT", Column: "cpt_code", Partition: "cpt"},
 	{Name: "HCPCS", Column: "hcpcs_code", Partition: "hcpcs"},
 	{Name: "MS-DRG", Column: "ms_drg_code", Partition: "ms_drg"},
 	{Name: "NDC", Column: "ndc_code", Partition: "ndc"},
 	{Name: "CDT", Column: "cdt_code", Partition: "cdt"},
+	{Name: "RC", Column: "rc_code", Partition: "rc"},
+	{Name: "ICD", Column: "icd_code", Partition: "icd"},
+	{Name: "DRG", Column: "drg_code", Partition: "drg"},
+	{Name: "CDM", Column: "cdm_code", Partition: "cdm"},
+	{Name: "LOCAL", Column: "local_code", Partition: "local"},
+	{Name: "APC", Column: "apc_code", Partition: "apc"},
+	{Name: "EAPG", Column: "eapg_code", Partition: "eapg"},
+	{Name: "HIPPS", Column: "hipps_code", Partition: "hipps"},
+	{Name: "R-DRG", Column: "r_drg_code", Partition: "r_drg"},
+	{Name: "S-DRG", Column: "s_drg_code", Partition: "s_drg"},
+	{Name: "APS-DRG", Column: "aps_drg_code", Partition: "aps_drg"},
+	{Name: "AP-DRG", Column: "ap_drg_code", Partition: "ap_drg"},
+	{Name: "APR-DRG", Column: "apr_drg_code", Partition: "apr_drg"},
+	{Name: "TRIS-DRG", Column: "tris_drg_code", Partition: "tris_drg"},
 }
 
 // CodeTypeColumns returns just the column names for all code types.
@@ -34,3 +60,72 @@ func CodeTypeByName(name string) (CodeType, bool) {
 	}
 	return CodeType{}, false
 }
+
+// CodeIndex returns the registry position of the named code type, or -1.
+func CodeIndex(name string) int {
+	if i, ok := codeIndexByName[name]; ok {
+		return i
+	}
+	return -1
+}
+
+var (
+	codeTypeNamePattern  = regexp.MustCompile(`^[A-Z0-9-]+$`)
+	codeTypeIdentPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// codeFields maps each registry position to the accessor of the
+// HospitalChargeRow field whose parquet tag matches the entry's Column, and
+// codeIndexByName each registered name to its position.
+var (
+	codeFields      = buildCodeFields()
+	codeIndexByName = buildCodeIndexByName()
+)
+
+// buildCodeFields resolves the registry against HospitalChargeRow and
+// checks that names and identifiers are safe to interpolate into DDL/SQL.
+// A bad registry entry is a programming error, so it panics at init.
+func buildCodeFields() []func(*HospitalChargeRow) **string {
+	byTag := make(map[string]int)
+	rt := reflect.TypeOf(HospitalChargeRow{})
+	for i := 0; i < rt.NumField(); i++ {
+		tag, _, _ := strings.Cut(rt.Field(i).Tag.Get("parquet"), ",")
+		if rt.Field(i).Type == reflect.TypeOf((*string)(nil)) {
+			byTag[tag] = i
+		}
+	}
+
+	fields := make([]func(*HospitalChargeRow) **string, len(AllCodeTypes))
+	for i, ct := range AllCodeTypes {
+		if !codeTypeNamePattern.MatchString(ct.Name) ||
+			!codeTypeIdentPattern.MatchString(ct.Column) ||
+			!codeTypeIdentPattern.MatchString(ct.Partition) {
+			panic(fmt.Sprintf("model: invalid code type registry entry %+v", ct))
+		}
+		f, ok := byTag[ct.Column]
+		if !ok {
+			panic(fmt.Sprintf("model: code type %s has no HospitalChargeRow field tagged %q", ct.Name, ct.Column))
+		}
+		field, ok := codeFieldsByColumn[ct.Column]
+		if !ok {
+			panic(fmt.Sprintf("model: code type %s has no accessor in codeFieldsByColumn", ct.Name))
+		}
+		// The accessor must address the tagged field.
+		var row HospitalChargeRow
+		probe := ct.Column
+		*field(&row) = &probe
+		if reflect.ValueOf(row).Field(f).Interface().(*string) != &probe {
+			panic(fmt.Sprintf("model: accessor for %q does not address its tagged field", ct.Column))
+		}
+		fields[i] = field
+	}
+	return fields
+}
+
+func buildCodeIndexByName() map[string]int {
+	m := make(map[string]int, len(AllCodeTypes))
+	for i, ct := range AllCodeTypes {
+		m[ct.Name] = i
+	}
+	return m
+}
@@ -1,5 +1,7 @@
 package model
 
+import "strings"
+
 // HospitalChargeRow mirrors the Parquet schema for a single charge line.
 // Money fields are float64 matching Parquet representation; they get
 // converted to integer cents during normalization.
@@ -66,15 +68,102 @@ type HospitalChargeRow struct {
 	LicenseNumber    *string `parquet:"license_number,optional"`
 	LicenseState     *string `parquet:"license_state,optional"`
 	Affirmation      bool    `parquet:"affirmation"`
+
+	// Unparsed is the first amount a text reader (JSON, CSV) could not
+	// parse as a number. The amount itself is left nil; normalize rejects
+	// the row with the published text. Not a Parquet column.
+	Unparsed *UnparsedValue `parquet:"-"`
+}
+
+// UnparsedValue is a published value that does not parse as its column's type.
+type UnparsedValue struct {
+	Column string // Parquet column name, e.g. "gross_charge"
+	Value  string
+}
+
+// SetUnparsed records value as the row's unparsed amount for column unless
+// an earlier column already holds it.
+func (r *HospitalChargeRow) SetUnparsed(column, value string) {
+	if r.Unparsed == nil {
+		r.Unparsed = &UnparsedValue{Column: column, Value: value}
+	}
 }
 
-// CodeValues returns a map of code_type_name -> *string for the supported code columns.
+// CodeValues returns a map of code_type_name -> *string for every registered code type.
 func (r *HospitalChargeRow) CodeValues() map[string]*string {
-	return map[string]*string{
-		"CPT":    r.CPTCode,
-		"HCPCS":  r.HCPCSCode,
-		"MS-DRG": r.MSDRGCode,
-		"NDC":    r.NDCCode,
-		"CDT":    r.CDTCode,
+	m := make(map[string]*string, len(AllCodeTypes))
+	for i, ct := range AllCodeTypes {
+		m[ct.Name] = r.CodeValue(i)
+	}
+	return m
+}
+
+// CodeValue returns the code column for the registry entry at position i of AllCodeTypes.
+func (r *HospitalChargeRow) CodeValue(i int) *string {
+	return *codeFields[i](r)
+}
+
+// SetCode stores v in the code column for the given CMS code type name
+// (e.g. "CPT", "APR-DRG"). Returns false if the type is not registered.
+func (r *HospitalChargeRow) SetCode(name string, v *string) bool {
+	i := CodeIndex(name)
+	if i < 0 {
+		return false
+	}
+	*codeFields[i](r) = v
+	return true
+}
+
+// codeFieldsByColumn addresses each code field of HospitalChargeRow by its
+// parquet column. Code values are read and written for every row, so they
+// go through these accessors rather than reflection; buildCodeFields checks
+// them against the struct tags at init.
+var codeFieldsByColumn = map[string]func(*HospitalChargeRow) **string{
+	"cpt_code":      func(r *HospitalChargeRow) **string { return &r.CPTCode },
+	"hcpcs_code":    func(r *HospitalChargeRow) **string { return &r.HCPCSCode },
+	"ms_drg_code":   func(r *HospitalChargeRow) **string { return &r.MSDRGCode },
+	"ndc_code":      func(r *HospitalChargeRow) **string { return &r.NDCCode },
+	"rc_code":       func(r *HospitalChargeRow) **string { return &r.RCCode },
+	"icd_code":      func(r *HospitalChargeRow) **string { return &r.ICDCode },
+	"drg_code":      func(r *HospitalChargeRow) **string { return &r.DRGCode },
+	"cdm_code":      func(r *HospitalChargeRow) **string { return &r.CDMCode },
+	"local_code":    func(r *HospitalChargeRow) **string { return &r.LOCALCode },
+	"apc_code":      func(r *HospitalChargeRow) **string { return &r.APCCode },
+	"eapg_code":     func(r *HospitalChargeRow) **string { return &r.EAPGCode },
+	"hipps_code":    func(r *HospitalChargeRow) **string { return &r.HIPPSCode },
+	"cdt_code":      func(r *HospitalChargeRow) **string { return &r.CDTCode },
+	"r_drg_code":    func(r *HospitalChargeRow) **string { return &r.RDRGCode },
+	"s_drg_code":    func(r *HospitalChargeRow) **string { return &r.SDRGCode },
+	"aps_drg_code":  func(r *HospitalChargeRow) **string { return &r.APSDRGCode },
+	"ap_drg_code":   func(r *HospitalChargeRow) **string { return &r.APDRGCode },
+	"apr_drg_code":  func(r *HospitalChargeRow) **string { return &r.APRDRGCode },
+	"tris_drg_code": func(r *HospitalChargeRow) **string { return &r.TRISDRGCode },
+}
+
+// CodeEntry is a single code/type pair as listed in the CMS JSON and CSV templates.
+type CodeEntry struct {
+	Type string
+	Code string
+}
+
+// ExpandCodes returns one copy of base per code entry with a recognized type,
+// each carrying only that code. If no entry is usable, base is returned alone
+// so its charges are still staged.
+func ExpandCodes(base HospitalChargeRow, codes []CodeEntry) []HospitalChargeRow {
+	var out []HospitalChargeRow
+	for _, c := range codes {
+		if c.Code == "" {
+			continue
+		}
+		row := base
+		code := c.Code
+		if !row.SetCode(strings.ToUpper(strings.TrimSpace(c.Type)), &code) {
+			continue
+		}
+		out = append(out, row)
+	}
+	if len(out) == 0 {
+		out = append(out, base)
 	}
+	return out
 }
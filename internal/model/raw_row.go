@@ -0,0 +1,153 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// chargeColumn is one Parquet column of HospitalChargeRow.
+type chargeColumn struct {
+	name  string
+	index int
+}
+
+// chargeColumns lists HospitalChargeRow's columns in struct order.
+var chargeColumns = func() []chargeColumn {
+	rt := reflect.TypeOf(HospitalChargeRow{})
+	cols := make([]chargeColumn, 0, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("parquet"), ",")
+		if name == "-" {
+			continue
+		}
+		cols = append(cols, chargeColumn{name: name, index: i})
+	}
+	return cols
+}()
+
+// ChargeColumnNames returns the Parquet column names of HospitalChargeRow in
+// struct order.
+func ChargeColumnNames() []string {
+	names := make([]string, len(chargeColumns))
+	for i, c := range chargeColumns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// ChargeColumnStrings formats every column of r as text, in ChargeColumnNames
+// order. Nil values become empty strings; an unparsed amount is written as
+// published.
+func (r *HospitalChargeRow) ChargeColumnStrings() []string {
+	rv := reflect.ValueOf(r).Elem()
+	out := make([]string, len(chargeColumns))
+	for i, c := range chargeColumns {
+		if u := r.Unparsed; u != nil && u.Column == c.name {
+			out[i] = u.Value
+			continue
+		}
+		f := rv.Field(c.index)
+		if f.Kind() == reflect.Pointer {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+		switch f.Kind() {
+		case reflect.Float64:
+			out[i] = strconv.FormatFloat(f.Float(), 'f', -1, 64)
+		case reflect.Bool:
+			out[i] = strconv.FormatBool(f.Bool())
+		default:
+			out[i] = f.String()
+		}
+	}
+	return out
+}
+
+// RawJSON encodes the row as a JSON object keyed by Parquet column name, as
+// stored in ingest.rejected_rows.raw_row. Null columns are omitted and
+// non-finite floats are written as strings ("NaN", "+Inf", "-Inf") so the
+// values that caused a reject survive the round trip. An unparsed amount is
+// written as its published text.
+func (r *HospitalChargeRow) RawJSON() ([]byte, error) {
+	rv := reflect.ValueOf(r).Elem()
+	m := make(map[string]any, len(chargeColumns))
+	for _, c := range chargeColumns {
+		if u := r.Unparsed; u != nil && u.Column == c.name {
+			m[c.name] = u.Value
+			continue
+		}
+		f := rv.Field(c.index)
+		if f.Kind() == reflect.Pointer {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+		if f.Kind() == reflect.Float64 {
+			if v := f.Float(); math.IsNaN(v) || math.IsInf(v, 0) {
+				m[c.name] = strconv.FormatFloat(v, 'f', -1, 64)
+				continue
+			}
+		}
+		m[c.name] = f.Interface()
+	}
+	return json.Marshal(m)
+}
+
+// ParseRawJSON decodes a RawJSON document back into a HospitalChargeRow.
+// Unknown keys are ignored; a non-numeric string in an amount column is
+// restored as the row's Unparsed value.
+func ParseRawJSON(data []byte) (HospitalChargeRow, error) {
+	var row HospitalChargeRow
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return row, fmt.Errorf("decode raw row: %w", err)
+	}
+
+	rv := reflect.ValueOf(&row).Elem()
+	for _, c := range chargeColumns {
+		raw, ok := m[c.name]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		f := rv.Field(c.index)
+		t := f.Type()
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		v := reflect.New(t)
+		if t.Kind() == reflect.Float64 {
+			var s string
+			if json.Unmarshal(raw, &s) == nil {
+				fv, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					row.SetUnparsed(c.name, s)
+					continue
+				}
+				v.Elem().SetFloat(fv)
+				setField(f, v)
+				continue
+			}
+		}
+		if err := json.Unmarshal(raw, v.Interface()); err != nil {
+			return row, fmt.Errorf("decode raw row %s: %w", c.name, err)
+		}
+		setField(f, v)
+	}
+	return row, nil
+}
+
+// setField stores the pointer v into f, dereferencing it for value fields.
+func setField(f, v reflect.Value) {
+	if f.Kind() == reflect.Pointer {
+		f.Set(v)
+		return
+	}
+	f.Set(v.Elem())
+}
@@ -0,0 +1,42 @@
+package model
+
+import "github.com/google/uuid"
+
+// RejectedRow is a source row quarantined during staging, destined for
+// ingest.rejected_rows.
+type RejectedRow struct {
+	IngestBatchID   uuid.UUID
+	MRFFileID       int64
+	SourceRowNumber int64
+	ReasonCode      string
+	FieldName       *string
+	Detail          *string
+	// RawRow is the source row as produced by HospitalChargeRow.RawJSON.
+	RawRow []byte
+}
+
+// RejectedColumns returns the ordered column names for COPY into ingest.rejected_rows.
+func RejectedColumns() []string {
+	return []string{
+		"ingest_batch_id",
+		"mrf_file_id",
+		"source_row_number",
+		"reason_code",
+		"field_name",
+		"detail",
+		"raw_row",
+	}
+}
+
+// CopyValues returns the row values in the same order as RejectedColumns().
+func (r *RejectedRow) CopyValues() []any {
+	return []any{
+		r.IngestBatchID,
+		r.MRFFileID,
+		r.SourceRowNumber,
+		r.ReasonCode,
+		r.FieldName,
+		r.Detail,
+		r.RawRow,
+	}
+}
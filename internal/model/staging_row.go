@@ -30,12 +30,9 @@ type StagingRow struct {
 	Setting      *string
 	BillingClass *string
 
-	// Wide code columns (normalized)
-	CPTCode   *string
-	HCPCSCode *string
-	MSDRGCode *string
-	NDCCode   *string
-	CDTCode   *string
+	// Wide code columns (normalized), parallel to AllCodeTypes. A nil or
+	// short slice means the remaining codes are NULL.
+	Codes []*string
 
 	// Payer / plan
 	PayerName     *string
@@ -61,56 +58,91 @@ type StagingRow struct {
 	Modifiers              *string
 	AdditionalGenericNotes *string
 	AdditionalPayerNotes   *string
+
+	// QualityFlags lists the money rules the row tripped without being
+	// rejected, as "rule:field". Nil when the row is clean.
+	QualityFlags []string
+}
+
+// Code returns the normalized code for the named code type, or nil.
+func (r *StagingRow) Code(name string) *string {
+	i := CodeIndex(name)
+	if i < 0 || i >= len(r.Codes) {
+		return nil
+	}
+	return r.Codes[i]
+}
+
+// SetCode stores v as the code for the named code type. Returns false if
+// the type is not registered.
+func (r *StagingRow) SetCode(name string, v *string) bool {
+	i := CodeIndex(name)
+	if i < 0 {
+		return false
+	}
+	if len(r.Codes) < len(AllCodeTypes) {
+		r.Codes = append(r.Codes, make([]*string, len(AllCodeTypes)-len(r.Codes))...)
+	}
+	r.Codes[i] = v
+	return true
+}
+
+// stagingLeadColumns precede the registry-driven code columns in COPY order.
+var stagingLeadColumns = []string{
+	"ingest_batch_id",
+	"mrf_file_id",
+	"source_row_number",
+	"source_row_hash",
+	"hospital_name",
+	"hospital_location",
+	"hospital_address",
+	"license_number",
+	"license_state",
+	"version",
+	"last_updated_on",
+	"affirmation",
+	"description",
+	"setting",
+	"billing_class",
+}
+
+// stagingTrailColumns follow the code columns in COPY order.
+var stagingTrailColumns = []string{
+	"payer_name",
+	"payer_name_norm",
+	"plan_name",
+	"plan_name_norm",
+	"gross_charge_cents",
+	"discounted_cash_cents",
+	"negotiated_dollar_cents",
+	"negotiated_percentage_bps",
+	"estimated_amount_cents",
+	"min_charge_cents",
+	"max_charge_cents",
+	"methodology",
+	"negotiated_algorithm",
+	"drug_unit",
+	"drug_unit_type",
+	"modifiers",
+	"additional_generic_notes",
+	"additional_payer_notes",
+	"quality_flags",
 }
 
 // StagingColumns returns the ordered column names for COPY into ingest.stage_charge_rows.
+// Code columns come from the AllCodeTypes registry.
 func StagingColumns() []string {
-	return []string{
-		"ingest_batch_id",
-		"mrf_file_id",
-		"source_row_number",
-		"source_row_hash",
-		"hospital_name",
-		"hospital_location",
-		"hospital_address",
-		"license_number",
-		"license_state",
-		"version",
-		"last_updated_on",
-		"affirmation",
-		"description",
-		"setting",
-		"billing_class",
-		"cpt_code",
-		"hcpcs_code",
-		"ms_drg_code",
-		"ndc_code",
-		"cdt_code",
-		"payer_name",
-		"payer_name_norm",
-		"plan_name",
-		"plan_name_norm",
-		"gross_charge_cents",
-		"discounted_cash_cents",
-		"negotiated_dollar_cents",
-		"negotiated_percentage_bps",
-		"estimated_amount_cents",
-		"min_charge_cents",
-		"max_charge_cents",
-		"methodology",
-		"negotiated_algorithm",
-		"drug_unit",
-		"drug_unit_type",
-		"modifiers",
-		"additional_generic_notes",
-		"additional_payer_notes",
-	}
+	cols := make([]string, 0, len(stagingLeadColumns)+len(AllCodeTypes)+len(stagingTrailColumns))
+	cols = append(cols, stagingLeadColumns...)
+	cols = append(cols, CodeTypeColumns()...)
+	return append(cols, stagingTrailColumns...)
 }
 
 // CopyValues returns the row values in the same order as StagingColumns(),
 // suitable for pgx CopyFromSource.
 func (r *StagingRow) CopyValues() []any {
-	return []any{
+	vals := make([]any, 0, len(stagingLeadColumns)+len(AllCodeTypes)+len(stagingTrailColumns))
+	vals = append(vals,
 		r.IngestBatchID,
 		r.MRFFileID,
 		r.SourceRowNumber,
@@ -126,11 +158,15 @@ func (r *StagingRow) CopyValues() []any {
 		r.Description,
 		r.Setting,
 		r.BillingClass,
-		r.CPTCode,
-		r.HCPCSCode,
-		r.MSDRGCode,
-		r.NDCCode,
-		r.CDTCode,
+	)
+	for i := range AllCodeTypes {
+		var v *string
+		if i < len(r.Codes) {
+			v = r.Codes[i]
+		}
+		vals = append(vals, v)
+	}
+	return append(vals,
 		r.PayerName,
 		r.PayerNameNorm,
 		r.PlanName,
@@ -149,5 +185,6 @@ func (r *StagingRow) CopyValues() []any {
 		r.Modifiers,
 		r.AdditionalGenericNotes,
 		r.AdditionalPayerNotes,
-	}
+		r.QualityFlags,
+	)
 }
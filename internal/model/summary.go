@@ -2,6 +2,20 @@ package model
 
 import "time"
 
+// Outcome is the overall result of an ingest run.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	// OutcomePartial means rows were rejected or dropped but stayed within the
+	// reject policy; the file is marked 'partial'.
+	OutcomePartial Outcome = "partial"
+	// OutcomeFailed means the reject policy was exceeded and serving rows were rolled back.
+	OutcomeFailed Outcome = "failed"
+	// OutcomeSkipped means the file was already loaded and nothing was done.
+	OutcomeSkipped Outcome = "skipped"
+)
+
 // IngestSummary captures metrics from a single file ingest run.
 type IngestSummary struct {
 	FilePath            string
@@ -11,6 +25,8 @@ type IngestSummary struct {
 	RowsRead            int64
 	RowsStaged          int64
 	RowsRejected        int64
+	RowsWithoutCodes    int64 // staged rows with no code of any registered type
+	RowsFilteredOut     int64 // staged rows whose codes are all outside the code_types filter
 	RowsInsertedServing int64
 	RowsExplodedByCode  map[string]int64
 	DurationRead        time.Duration
@@ -18,4 +34,12 @@ type IngestSummary struct {
 	DurationTransform   time.Duration
 	DurationFinalize    time.Duration
 	DurationTotal       time.Duration
+
+	// Resumed is set when staging was skipped and the rows of an earlier,
+	// interrupted run were reused (ingest --resume).
+	Resumed bool
+
+	// Outcome is the reject policy verdict; OutcomeReason explains it.
+	Outcome       Outcome
+	OutcomeReason string
 }
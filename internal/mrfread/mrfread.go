@@ -0,0 +1,128 @@
+package mrfread
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gyeh/pricestats/internal/csvread"
+	"github.com/gyeh/pricestats/internal/jsonread"
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/parquetread"
+)
+
+// Format identifies the on-disk layout of an MRF source file.
+type Format string
+
+const (
+	FormatParquet Format = "parquet"
+	FormatJSON    Format = "json"
+	// FormatCSV covers both the tall and wide CMS CSV templates; the csvread
+	// reader tells them apart from the header row.
+	FormatCSV Format = "csv"
+)
+
+// Reader streams HospitalChargeRow records from an MRF file regardless of its format.
+type Reader interface {
+	// NumRows returns the total row count, or -1 if the format can only
+	// report it after a full pass.
+	NumRows() int64
+	// Read reads up to len(rows) records and returns io.EOF when done.
+	Read(rows []model.HospitalChargeRow) (int, error)
+	// Validate checks that the file carries the columns/fields required to ingest it.
+	Validate() error
+	Close() error
+}
+
+// parquetMagic is the 4-byte header every Parquet file starts with.
+var parquetMagic = []byte("PAR1")
+
+// DetectFormat sniffs the leading bytes of the file, falling back to the
+// file extension when the content is inconclusive.
+func DetectFormat(path string) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file for format detection: %w", err)
+	}
+	defer f.Close()
+
+	head, _ := bufio.NewReader(f).Peek(512)
+	if bytes.HasPrefix(head, parquetMagic) {
+		return FormatParquet, nil
+	}
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return FormatJSON, nil
+	}
+	// CMS CSV templates open with the metadata header row
+	if bytes.HasPrefix(bytes.ToLower(bytes.TrimLeft(trimmed, `"`)), []byte("hospital_name")) {
+		return FormatCSV, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".parquet":
+		return FormatParquet, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unrecognized MRF file format: %s", filepath.Base(path))
+}
+
+// Open detects the file format and returns a streaming Reader for it.
+func Open(path string) (Reader, Format, error) {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch format {
+	case FormatParquet:
+		r, err := parquetread.Open(path)
+		if err != nil {
+			return nil, format, err
+		}
+		return r, format, nil
+	case FormatJSON:
+		r, err := jsonread.Open(path)
+		if err != nil {
+			return nil, format, err
+		}
+		return r, format, nil
+	case FormatCSV:
+		r, err := csvread.Open(path)
+		if err != nil {
+			return nil, format, err
+		}
+		return r, format, nil
+	}
+	return nil, format, fmt.Errorf("unsupported format %q", format)
+}
+
+// LayoutOf returns what opening r learned about its file for Reopen: the
+// metadata and charge array offset of a CMS JSON file, nil for the other
+// formats.
+func LayoutOf(r Reader) *jsonread.Layout {
+	if jr, ok := r.(*jsonread.Reader); ok {
+		return jr.Layout()
+	}
+	return nil
+}
+
+// Reopen opens a file Open already read, resuming a CMS JSON file at its
+// charge array with layout instead of scanning the header again. A nil
+// layout falls back to Open.
+func Reopen(path string, layout *jsonread.Layout) (Reader, Format, error) {
+	if layout == nil {
+		return Open(path)
+	}
+	r, err := jsonread.OpenAt(path, layout)
+	if err != nil {
+		return nil, FormatJSON, err
+	}
+	return r, FormatJSON, nil
+}
@@ -0,0 +1,90 @@
+package mrfread
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/normalize"
+)
+
+func writeFile(t *testing.T, name, doc string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	return path
+}
+
+func readAll(t *testing.T, path string) []model.HospitalChargeRow {
+	t.Helper()
+	r, _, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open %s: %v", filepath.Base(path), err)
+	}
+	defer r.Close()
+	var all []model.HospitalChargeRow
+	buf := make([]model.HospitalChargeRow, 4)
+	for {
+		n, err := r.Read(buf)
+		all = append(all, buf[:n]...)
+		if err == io.EOF {
+			return all
+		}
+		if err != nil {
+			t.Fatalf("read %s: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+// A non-numeric amount rejects its row the same way in both text formats
+// and does not fail the file.
+func TestOpen_NonNumericAmountRejectsRow(t *testing.T) {
+	jsonPath := writeFile(t, "mrf.json", `{
+  "hospital_name": "General Hospital",
+  "last_updated_on": "2024-07-01",
+  "version": "2.0.0",
+  "standard_charge_information": [
+    {"description": "Office visit", "code_information": [{"code": "99213", "type": "CPT"}],
+     "standard_charges": [{"setting": "outpatient", "gross_charge": "N/A", "discounted_cash": 200}]},
+    {"description": "MRI brain", "code_information": [{"code": "70551", "type": "CPT"}],
+     "standard_charges": [{"setting": "outpatient", "gross_charge": 2000}]}
+  ]
+}`)
+	csvPath := writeFile(t, "mrf.csv", `hospital_name,last_updated_on,version
+General Hospital,2024-07-01,2.0.0
+description,code|1,code|1|type,setting,standard_charge|gross,standard_charge|discounted_cash
+Office visit,99213,CPT,outpatient,N/A,200
+MRI brain,70551,CPT,outpatient,2000,
+`)
+
+	for _, path := range []string{jsonPath, csvPath} {
+		rows := readAll(t, path)
+		if len(rows) != 2 {
+			t.Fatalf("%s: expected 2 rows, got %d", filepath.Base(path), len(rows))
+		}
+		bad := rows[0]
+		if bad.GrossCharge != nil || bad.Unparsed == nil || *bad.Unparsed != (model.UnparsedValue{Column: "gross_charge", Value: "N/A"}) {
+			t.Errorf("%s: unexpected unparsed amount: gross=%v unparsed=%+v", filepath.Base(path), bad.GrossCharge, bad.Unparsed)
+		}
+
+		_, err := normalize.ToStagingRow(&bad, uuid.New(), 1, 1, false, normalize.MoneyPolicy{})
+		var rowErr *normalize.RowError
+		if !errors.As(err, &rowErr) {
+			t.Fatalf("%s: expected RowError, got %v", filepath.Base(path), err)
+		}
+		if rowErr.Reason != normalize.ReasonInvalidAmount || rowErr.Field != "gross_charge" || rowErr.Detail != `not a number: "N/A"` {
+			t.Errorf("%s: unexpected reject: %+v", filepath.Base(path), rowErr)
+		}
+
+		if _, err := normalize.ToStagingRow(&rows[1], uuid.New(), 1, 2, false, normalize.MoneyPolicy{}); err != nil {
+			t.Errorf("%s: following row should stage: %v", filepath.Base(path), err)
+		}
+	}
+}
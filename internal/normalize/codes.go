@@ -24,3 +24,118 @@ func NormalizeCode(v *string) *string {
 	}
 	return &s
 }
+
+// TrimCode trims whitespace from a raw code, returning nil if nothing is left.
+// Staging keeps codes in this form so the SQL transform canonicalizes the
+// same input the Go validator saw.
+func TrimCode(v *string) *string {
+	if v == nil {
+		return nil
+	}
+	s := strings.TrimSpace(*v)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// asciiSpace matches the \s class of Postgres regular expressions.
+const asciiSpace = " \t\n\r\f\v"
+
+var (
+	cptPattern     = regexp.MustCompile(`^[0-9]{4}[0-9A-Z]$`)
+	hcpcsPattern   = regexp.MustCompile(`^[A-Z][0-9]{4}$`)
+	drgPattern     = regexp.MustCompile(`^[0-9]{1,3}$`)
+	cdtPattern     = regexp.MustCompile(`^D?[0-9]{4}$`)
+	ndcDigits      = regexp.MustCompile(`^[0-9]+$`)
+	ndc11Pattern   = regexp.MustCompile(`^[0-9]{11}$`)
+	ndcSegmentSkew = map[[3]int]int{ // segment lengths → index of the segment to zero-pad
+		{4, 4, 2}: 0,
+		{5, 3, 2}: 1,
+		{5, 4, 1}: 2,
+	}
+)
+
+// CanonicalizeCode returns the canonical code_norm for a raw code of the
+// given CMS code type, and whether the code is valid for that type:
+//
+//   - CPT: 4 digits + 1 digit/letter (Category I/II/III), e.g. "99213", "0001F"
+//   - HCPCS: Level II letter + 4 digits, or a CPT-shaped Level I code
+//   - NDC: 11-digit 5-4-2, converted from hyphenated 4-4-2, 5-3-2 or 5-4-1
+//   - MS-DRG: 1–3 digits, zero-padded to 3
+//   - DRG: zero-padded to 3 when numeric; other payer DRG forms pass through
+//   - CDT: "D" + 4 digits, adding the D when missing
+//
+// Other types only get the generic upper-case/strip normalization and are
+// always valid. Invalid codes fall back to the generic form as well.
+//
+// mrf.canonicalize_code (migration 010) implements the same rules for the
+// transform; TestCanonicalizeCode_SQLParity keeps the two in step.
+func CanonicalizeCode(codeType, raw string) (string, bool) {
+	norm := strings.ToUpper(nonAlphanumeric.ReplaceAllString(raw, ""))
+
+	switch codeType {
+	case "CPT":
+		return norm, cptPattern.MatchString(norm)
+	case "HCPCS":
+		return norm, hcpcsPattern.MatchString(norm) || cptPattern.MatchString(norm)
+	case "NDC":
+		if ndc, ok := canonicalNDC(strings.Trim(raw, asciiSpace)); ok {
+			return ndc, true
+		}
+		return norm, false
+	case "MS-DRG":
+		if drgPattern.MatchString(norm) {
+			return padLeft(norm, 3), true
+		}
+		return norm, false
+	case "DRG":
+		if drgPattern.MatchString(norm) {
+			return padLeft(norm, 3), true
+		}
+		return norm, true
+	case "CDT":
+		if cdtPattern.MatchString(norm) {
+			return "D" + norm[len(norm)-4:], true
+		}
+		return norm, false
+	}
+	return norm, true
+}
+
+// canonicalNDC converts a hyphenated 10-digit NDC (or an unhyphenated
+// 11-digit one) to the 11-digit 5-4-2 form. Unhyphenated 10-digit NDCs are
+// ambiguous and rejected.
+func canonicalNDC(s string) (string, bool) {
+	if !strings.Contains(s, "-") {
+		return s, ndc11Pattern.MatchString(s)
+	}
+	parts := strings.Split(s, "-")
+	if len(parts) != 3 {
+		return "", false
+	}
+	var lens [3]int
+	for i, p := range parts {
+		parts[i] = strings.Trim(p, asciiSpace)
+		if !ndcDigits.MatchString(parts[i]) {
+			return "", false
+		}
+		lens[i] = len(parts[i])
+	}
+	if lens == [3]int{5, 4, 2} {
+		return strings.Join(parts, ""), true
+	}
+	i, ok := ndcSegmentSkew[lens]
+	if !ok {
+		return "", false
+	}
+	parts[i] = "0" + parts[i]
+	return strings.Join(parts, ""), true
+}
+
+func padLeft(s string, n int) string {
+	if len(s) >= n {
+		return s
+	}
+	return strings.Repeat("0", n-len(s)) + s
+}
@@ -0,0 +1,74 @@
+package normalize
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/gyeh/pricestats/internal/model"
+)
+
+func TestCanonicalizeCode(t *testing.T) {
+	tests := []struct {
+		codeType, raw string
+		want          string
+		valid         bool
+	}{
+		{"CPT", "99213", "99213", true},
+		{"CPT", "0001f", "0001F", true},
+		{"CPT", "9921", "9921", false},
+		{"CPT", "992134", "992134", false},
+		{"HCPCS", "j0120", "J0120", true},
+		{"HCPCS", "99213", "99213", true},
+		{"HCPCS", "JJ120", "JJ120", false},
+		{"NDC", "0573-0150-20", "00573015020", true}, // 4-4-2
+		{"NDC", "50090-347-01", "50090034701", true}, // 5-3-2
+		{"NDC", "50090-3470-1", "50090347001", true}, // 5-4-1
+		{"NDC", "50090-3470-01", "50090347001", true},
+		{"NDC", "00573015020", "00573015020", true},
+		{"NDC", "0573015020", "0573015020", false}, // ambiguous 10-digit
+		{"NDC", "573-0150-20", "573015020", false},
+		{"MS-DRG", "1", "001", true},
+		{"MS-DRG", "65", "065", true},
+		{"MS-DRG", "470", "470", true},
+		{"MS-DRG", "4701", "4701", false},
+		{"DRG", "7", "007", true},
+		{"DRG", "APR661-1", "APR6611", true},
+		{"CDT", "D0120", "D0120", true},
+		{"CDT", "0120", "D0120", true},
+		{"CDT", "D012", "D012", false},
+		{"LOCAL", "abc-123", "ABC123", true},
+	}
+	for _, tt := range tests {
+		got, valid := CanonicalizeCode(tt.codeType, tt.raw)
+		if got != tt.want || valid != tt.valid {
+			t.Errorf("CanonicalizeCode(%s, %q) = %q, %v; want %q, %v", tt.codeType, tt.raw, got, valid, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestToStagingRow_InvalidCodeRejected(t *testing.T) {
+	bad := "9921"
+	row := &model.HospitalChargeRow{Description: "Office visit", HospitalName: "General Hospital", CPTCode: &bad}
+
+	_, err := ToStagingRow(row, uuid.New(), 1, 1, false, MoneyPolicy{})
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) {
+		t.Fatalf("expected RowError, got %v", err)
+	}
+	if rowErr.Reason != ReasonInvalidCode || rowErr.Field != "cpt_code" {
+		t.Errorf("unexpected reject: %+v", rowErr)
+	}
+
+	// Valid codes are staged as published; the transform canonicalizes them
+	ndc := " 0573-0150-20 "
+	row = &model.HospitalChargeRow{Description: "Drug", HospitalName: "General Hospital", NDCCode: &ndc}
+	s, err := ToStagingRow(row, uuid.New(), 1, 2, false, MoneyPolicy{})
+	if err != nil {
+		t.Fatalf("ToStagingRow: %v", err)
+	}
+	if got := s.Code("NDC"); got == nil || *got != "0573-0150-20" {
+		t.Errorf("staged NDC: got %v, want trimmed raw code", got)
+	}
+}
@@ -0,0 +1,238 @@
+package normalize
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gyeh/pricestats/internal/model"
+)
+
+// Severity is how a failed money rule is handled.
+type Severity string
+
+const (
+	// SeverityReject quarantines the whole row.
+	SeverityReject Severity = "reject"
+	// SeverityNull stages the row with the offending field(s) set to NULL.
+	SeverityNull Severity = "null"
+	// SeverityFlag stages the value unchanged.
+	SeverityFlag Severity = "flag"
+)
+
+// MoneyRule names a sanity check on the money and percentage fields.
+// Rules that do not reject the row are recorded in the row's quality_flags
+// as "rule:field", e.g. "negative:gross_charge".
+type MoneyRule string
+
+const (
+	// RuleNonFinite: NaN, ±Inf, or too large to store as cents / basis points.
+	RuleNonFinite MoneyRule = "non_finite"
+	// RuleNegative: an amount or percentage below zero.
+	RuleNegative MoneyRule = "negative"
+	// RuleOverCap: an amount above MaxDollars or a percentage above MaxPercentage.
+	RuleOverCap MoneyRule = "over_cap"
+	// RuleMinExceedsMax: min_charge greater than max_charge.
+	RuleMinExceedsMax MoneyRule = "min_exceeds_max"
+	// RuleCashExceedsGross: discounted_cash greater than gross_charge.
+	RuleCashExceedsGross MoneyRule = "cash_exceeds_gross"
+)
+
+// MoneyPolicy configures the money rules. Zero fields fall back to
+// DefaultMoneyPolicy, so the zero value is the default policy.
+type MoneyPolicy struct {
+	// MaxDollars caps every dollar amount.
+	MaxDollars float64 `yaml:"max_dollars"`
+	// MaxPercentage caps negotiated_percentage (100 = 100%).
+	MaxPercentage float64 `yaml:"max_percentage"`
+	// Severities overrides the default severity per rule.
+	Severities map[MoneyRule]Severity `yaml:"severities"`
+}
+
+// DefaultMoneyPolicy returns the built-in caps and severities.
+func DefaultMoneyPolicy() MoneyPolicy {
+	return MoneyPolicy{
+		MaxDollars:    10_000_000,
+		MaxPercentage: 1000,
+		Severities: map[MoneyRule]Severity{
+			RuleNonFinite:        SeverityReject,
+			RuleNegative:         SeverityReject,
+			RuleOverCap:          SeverityNull,
+			RuleMinExceedsMax:    SeverityFlag,
+			RuleCashExceedsGross: SeverityFlag,
+		},
+	}
+}
+
+var defaultMoneyPolicy = DefaultMoneyPolicy()
+
+func (p MoneyPolicy) maxDollars() float64 {
+	if p.MaxDollars > 0 {
+		return p.MaxDollars
+	}
+	return defaultMoneyPolicy.MaxDollars
+}
+
+func (p MoneyPolicy) maxPercentage() float64 {
+	if p.MaxPercentage > 0 {
+		return p.MaxPercentage
+	}
+	return defaultMoneyPolicy.MaxPercentage
+}
+
+func (p MoneyPolicy) severity(r MoneyRule) Severity {
+	if s, ok := p.Severities[r]; ok {
+		return s
+	}
+	return defaultMoneyPolicy.Severities[r]
+}
+
+// Validate checks the caps, rule names and severities. Non-finite values
+// cannot be stored, so RuleNonFinite only accepts reject or null.
+func (p MoneyPolicy) Validate() error {
+	if p.MaxDollars < 0 || p.MaxPercentage < 0 {
+		return fmt.Errorf("money rule caps must not be negative")
+	}
+	if p.MaxDollars*100 >= math.MaxInt64 {
+		return fmt.Errorf("max_dollars %v does not fit in int64 cents", p.MaxDollars)
+	}
+	if p.MaxPercentage*100 > math.MaxInt32 {
+		return fmt.Errorf("max_percentage %v does not fit in int32 basis points", p.MaxPercentage)
+	}
+	rules := make([]string, 0, len(p.Severities))
+	for r := range p.Severities {
+		rules = append(rules, string(r))
+	}
+	sort.Strings(rules)
+	for _, name := range rules {
+		r := MoneyRule(name)
+		if _, ok := defaultMoneyPolicy.Severities[r]; !ok {
+			return fmt.Errorf("unknown money rule %q", r)
+		}
+		switch s := p.Severities[r]; s {
+		case SeverityReject, SeverityNull:
+		case SeverityFlag:
+			if r == RuleNonFinite {
+				return fmt.Errorf("money rule %q cannot use severity %q", r, s)
+			}
+		default:
+			return fmt.Errorf("money rule %q: unknown severity %q", r, s)
+		}
+	}
+	return nil
+}
+
+// moneyFields are the row's amounts as they will be staged. Rules with
+// SeverityNull clear them here, never on the source row, so quarantined and
+// exported rows keep the published values.
+type moneyFields struct {
+	gross, cash, min, max *float64
+	negDollar, estimated  *float64
+	pct                   *float64
+}
+
+// moneyCheck applies a MoneyPolicy to one row, collecting quality flags.
+type moneyCheck struct {
+	policy MoneyPolicy
+	flags  []string
+}
+
+// checkMoney runs the money rules over row in column order. Payer amounts
+// are only checked when they will be staged.
+func checkMoney(row *model.HospitalChargeRow, includePayerPrices bool, policy MoneyPolicy) (moneyFields, []string, error) {
+	m := moneyFields{
+		gross: row.GrossCharge,
+		cash:  row.DiscountedCash,
+		min:   row.MinCharge,
+		max:   row.MaxCharge,
+	}
+	if includePayerPrices {
+		m.negDollar = row.NegotiatedDollar
+		m.estimated = row.EstimatedAmount
+		m.pct = row.NegotiatedPercentage
+	}
+
+	c := &moneyCheck{policy: policy}
+	amounts := []struct {
+		field string
+		v     **float64
+	}{
+		{"gross_charge", &m.gross},
+		{"discounted_cash", &m.cash},
+		{"min_charge", &m.min},
+		{"max_charge", &m.max},
+		{"negotiated_dollar", &m.negDollar},
+		{"estimated_amount", &m.estimated},
+	}
+	for _, a := range amounts {
+		if err := c.amount(a.field, a.v, false); err != nil {
+			return m, nil, err
+		}
+	}
+	if err := c.amount("negotiated_percentage", &m.pct, true); err != nil {
+		return m, nil, err
+	}
+
+	if m.min != nil && m.max != nil && *m.min > *m.max {
+		detail := fmt.Sprintf("min %v > max %v", *m.min, *m.max)
+		if err := c.apply(RuleMinExceedsMax, "min_charge", detail, &m.min, &m.max); err != nil {
+			return m, nil, err
+		}
+	}
+	if m.cash != nil && m.gross != nil && *m.cash > *m.gross {
+		detail := fmt.Sprintf("cash %v > gross %v", *m.cash, *m.gross)
+		if err := c.apply(RuleCashExceedsGross, "discounted_cash", detail, &m.cash); err != nil {
+			return m, nil, err
+		}
+	}
+	return m, c.flags, nil
+}
+
+// amount checks a single dollar amount (or percentage when pct is set).
+func (c *moneyCheck) amount(field string, v **float64, pct bool) error {
+	if *v == nil {
+		return nil
+	}
+	x := **v
+	// bound is where the stored integer (cents or basis points) overflows
+	limit, bound := c.policy.maxDollars(), float64(math.MaxInt64)
+	if pct {
+		limit, bound = c.policy.maxPercentage(), float64(math.MaxInt32)
+	}
+
+	var rule MoneyRule
+	switch {
+	case math.IsNaN(x) || math.IsInf(x, 0) || math.Abs(x*100) >= bound:
+		rule = RuleNonFinite
+	case x < 0:
+		rule = RuleNegative
+	case x > limit:
+		rule = RuleOverCap
+	default:
+		return nil
+	}
+	return c.apply(rule, field, fmt.Sprintf("value %v", x), v)
+}
+
+// apply handles a failed rule according to its severity. The first field
+// names the rule in the reject or flag; all fields are nulled for SeverityNull.
+func (c *moneyCheck) apply(rule MoneyRule, field, detail string, fields ...**float64) error {
+	sev := c.policy.severity(rule)
+	if rule == RuleNonFinite && sev == SeverityFlag {
+		sev = SeverityNull
+	}
+	switch sev {
+	case SeverityReject:
+		reason := ReasonInvalidAmount
+		if field == "negotiated_percentage" {
+			reason = ReasonInvalidPercentage
+		}
+		return &RowError{Reason: reason, Field: field, Detail: fmt.Sprintf("%s: %s", rule, detail)}
+	case SeverityNull:
+		for _, f := range fields {
+			*f = nil
+		}
+	}
+	c.flags = append(c.flags, string(rule)+":"+field)
+	return nil
+}
@@ -0,0 +1,154 @@
+package normalize
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/gyeh/pricestats/internal/model"
+)
+
+func f64(v float64) *float64 { return &v }
+
+func TestToStagingRow_MoneyRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		row        model.HospitalChargeRow
+		policy     MoneyPolicy
+		wantReason RejectReason
+		wantField  string
+		wantFlags  []string
+		check      func(t *testing.T, s *model.StagingRow)
+	}{
+		{
+			name: "clean",
+			row:  model.HospitalChargeRow{GrossCharge: f64(100), DiscountedCash: f64(80), MinCharge: f64(50), MaxCharge: f64(90)},
+		},
+		{
+			name:       "nan_rejected",
+			row:        model.HospitalChargeRow{GrossCharge: f64(math.NaN())},
+			wantReason: ReasonInvalidAmount,
+			wantField:  "gross_charge",
+		},
+		{
+			name:       "overflow_rejected",
+			row:        model.HospitalChargeRow{MaxCharge: f64(1e17)},
+			wantReason: ReasonInvalidAmount,
+			wantField:  "max_charge",
+		},
+		{
+			name:       "negative_rejected",
+			row:        model.HospitalChargeRow{DiscountedCash: f64(-5)},
+			wantReason: ReasonInvalidAmount,
+			wantField:  "discounted_cash",
+		},
+		{
+			name:      "negative_nulled",
+			row:       model.HospitalChargeRow{GrossCharge: f64(-5)},
+			policy:    MoneyPolicy{Severities: map[MoneyRule]Severity{RuleNegative: SeverityNull}},
+			wantFlags: []string{"negative:gross_charge"},
+			check: func(t *testing.T, s *model.StagingRow) {
+				if s.GrossChargeCents != nil {
+					t.Errorf("gross_charge_cents: got %d, want NULL", *s.GrossChargeCents)
+				}
+			},
+		},
+		{
+			name:      "over_cap_nulled",
+			row:       model.HospitalChargeRow{GrossCharge: f64(1e15), DiscountedCash: f64(10)},
+			wantFlags: []string{"over_cap:gross_charge"},
+			check: func(t *testing.T, s *model.StagingRow) {
+				if s.GrossChargeCents != nil || s.DiscountedCashCents == nil {
+					t.Errorf("expected only gross_charge nulled: %+v", s)
+				}
+			},
+		},
+		{
+			name:      "custom_cap_flagged",
+			row:       model.HospitalChargeRow{GrossCharge: f64(600)},
+			policy:    MoneyPolicy{MaxDollars: 500, Severities: map[MoneyRule]Severity{RuleOverCap: SeverityFlag}},
+			wantFlags: []string{"over_cap:gross_charge"},
+			check: func(t *testing.T, s *model.StagingRow) {
+				if s.GrossChargeCents == nil || *s.GrossChargeCents != 60000 {
+					t.Errorf("flagged value should be kept, got %v", s.GrossChargeCents)
+				}
+			},
+		},
+		{
+			name:       "percentage_over_cap_rejected",
+			row:        model.HospitalChargeRow{NegotiatedPercentage: f64(12000)},
+			policy:     MoneyPolicy{Severities: map[MoneyRule]Severity{RuleOverCap: SeverityReject}},
+			wantReason: ReasonInvalidPercentage,
+			wantField:  "negotiated_percentage",
+		},
+		{
+			name:      "min_exceeds_max_flagged",
+			row:       model.HospitalChargeRow{MinCharge: f64(200), MaxCharge: f64(100)},
+			wantFlags: []string{"min_exceeds_max:min_charge"},
+		},
+		{
+			name:      "min_exceeds_max_nulls_both",
+			row:       model.HospitalChargeRow{MinCharge: f64(200), MaxCharge: f64(100)},
+			policy:    MoneyPolicy{Severities: map[MoneyRule]Severity{RuleMinExceedsMax: SeverityNull}},
+			wantFlags: []string{"min_exceeds_max:min_charge"},
+			check: func(t *testing.T, s *model.StagingRow) {
+				if s.MinChargeCents != nil || s.MaxChargeCents != nil {
+					t.Error("expected min and max nulled")
+				}
+			},
+		},
+		{
+			name:       "cash_exceeds_gross_rejected",
+			row:        model.HospitalChargeRow{GrossCharge: f64(100), DiscountedCash: f64(150)},
+			policy:     MoneyPolicy{Severities: map[MoneyRule]Severity{RuleCashExceedsGross: SeverityReject}},
+			wantReason: ReasonInvalidAmount,
+			wantField:  "discounted_cash",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := tt.row
+			row.HospitalName = "General Hospital"
+			row.Description = "Office visit"
+
+			s, err := ToStagingRow(&row, uuid.New(), 1, 1, true, tt.policy)
+			if tt.wantReason != "" {
+				var rowErr *RowError
+				if !errors.As(err, &rowErr) {
+					t.Fatalf("expected RowError, got %v", err)
+				}
+				if rowErr.Reason != tt.wantReason || rowErr.Field != tt.wantField {
+					t.Errorf("reject: got %s/%s, want %s/%s", rowErr.Reason, rowErr.Field, tt.wantReason, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ToStagingRow: %v", err)
+			}
+			if !slices.Equal(s.QualityFlags, tt.wantFlags) {
+				t.Errorf("quality flags: got %v, want %v", s.QualityFlags, tt.wantFlags)
+			}
+			if tt.check != nil {
+				tt.check(t, s)
+			}
+		})
+	}
+}
+
+func TestToStagingRow_PayerAmountsUncheckedWhenExcluded(t *testing.T) {
+	row := &model.HospitalChargeRow{
+		HospitalName:     "General Hospital",
+		Description:      "Office visit",
+		NegotiatedDollar: f64(math.Inf(1)),
+	}
+	s, err := ToStagingRow(row, uuid.New(), 1, 1, false, MoneyPolicy{})
+	if err != nil {
+		t.Fatalf("payer amounts are dropped without --include-payer-prices, got %v", err)
+	}
+	if s.NegotiatedDollarCents != nil || s.QualityFlags != nil {
+		t.Errorf("unexpected payer amount or flags: %v %v", s.NegotiatedDollarCents, s.QualityFlags)
+	}
+}
@@ -1,6 +1,10 @@
 package normalize
 
 import (
+	"fmt"
+	"math"
+	"strings"
+
 	"github.com/google/uuid"
 
 	"github.com/gyeh/pricestats/internal/model"
@@ -8,7 +12,21 @@ import (
 
 // ToStagingRow converts a Parquet-read HospitalChargeRow into a normalized StagingRow.
 // When includePayerPrices is false, payer/plan names and negotiated price fields are nulled out.
-func ToStagingRow(row *model.HospitalChargeRow, batchID uuid.UUID, mRFFileID int64, rowNum int64, includePayerPrices bool) (*model.StagingRow, error) {
+// Money fields are checked against money (see MoneyPolicy); rules that do not
+// reject the row are recorded in QualityFlags. Rows that cannot be staged are
+// reported with a *RowError.
+func ToStagingRow(row *model.HospitalChargeRow, batchID uuid.UUID, mRFFileID int64, rowNum int64, includePayerPrices bool, money MoneyPolicy) (*model.StagingRow, error) {
+	if err := checkRow(row); err != nil {
+		return nil, err
+	}
+	if err := checkUnparsed(row, includePayerPrices); err != nil {
+		return nil, err
+	}
+	amounts, flags, err := checkMoney(row, includePayerPrices, money)
+	if err != nil {
+		return nil, err
+	}
+
 	s := &model.StagingRow{
 		IngestBatchID:   batchID,
 		MRFFileID:       mRFFileID,
@@ -27,24 +45,27 @@ func ToStagingRow(row *model.HospitalChargeRow, batchID uuid.UUID, mRFFileID int
 		Setting:      optStr(row.Setting),
 		BillingClass: row.BillingClass,
 
-		// Normalize code columns
-		CPTCode:   NormalizeCode(row.CPTCode),
-		HCPCSCode: NormalizeCode(row.HCPCSCode),
-		MSDRGCode: NormalizeCode(row.MSDRGCode),
-		NDCCode:   NormalizeCode(row.NDCCode),
-		CDTCode:   NormalizeCode(row.CDTCode),
-
 		// Hospital-level charges (always included)
-		GrossChargeCents:    DollarsToCents(row.GrossCharge),
-		DiscountedCashCents: DollarsToCents(row.DiscountedCash),
-		MinChargeCents:      DollarsToCents(row.MinCharge),
-		MaxChargeCents:      DollarsToCents(row.MaxCharge),
+		GrossChargeCents:    DollarsToCents(amounts.gross),
+		DiscountedCashCents: DollarsToCents(amounts.cash),
+		MinChargeCents:      DollarsToCents(amounts.min),
+		MaxChargeCents:      DollarsToCents(amounts.max),
 
 		DrugUnit:     row.DrugUnitOfMeasurement,
 		DrugUnitType: row.DrugTypeOfMeasurement,
 
 		Modifiers:              row.Modifiers,
 		AdditionalGenericNotes: row.AdditionalGenericNotes,
+
+		QualityFlags: flags,
+	}
+
+	// Code columns are staged trimmed but otherwise as published; the
+	// transform canonicalizes them into code_norm with the same rules that
+	// checkRow validated.
+	s.Codes = make([]*string, len(model.AllCodeTypes))
+	for i := range model.AllCodeTypes {
+		s.Codes[i] = TrimCode(row.CodeValue(i))
 	}
 
 	// Payer-specific fields: only populated when --include-payer-prices is set
@@ -53,9 +74,9 @@ func ToStagingRow(row *model.HospitalChargeRow, batchID uuid.UUID, mRFFileID int
 		s.PayerNameNorm = NormalizeName(row.PayerName)
 		s.PlanName = row.PlanName
 		s.PlanNameNorm = NormalizeName(row.PlanName)
-		s.NegotiatedDollarCents = DollarsToCents(row.NegotiatedDollar)
-		s.NegotiatedPercentageBPS = PercentToBasisPoints(row.NegotiatedPercentage)
-		s.EstimatedAmountCents = DollarsToCents(row.EstimatedAmount)
+		s.NegotiatedDollarCents = DollarsToCents(amounts.negDollar)
+		s.NegotiatedPercentageBPS = PercentToBasisPoints(amounts.pct)
+		s.EstimatedAmountCents = DollarsToCents(amounts.estimated)
 		s.Methodology = row.Methodology
 		s.NegotiatedAlgorithm = row.NegotiatedAlgorithm
 		s.AdditionalPayerNotes = row.AdditionalPayerNotes
@@ -76,6 +97,51 @@ func ToStagingRow(row *model.HospitalChargeRow, batchID uuid.UUID, mRFFileID int
 	return s, nil
 }
 
+// checkRow validates the required fields, codes and drug unit. Money
+// fields are checked separately by checkMoney.
+func checkRow(row *model.HospitalChargeRow) error {
+	if strings.TrimSpace(row.HospitalName) == "" {
+		return &RowError{Reason: ReasonMissingRequired, Field: "hospital_name"}
+	}
+	if strings.TrimSpace(row.Description) == "" {
+		return &RowError{Reason: ReasonMissingRequired, Field: "description"}
+	}
+	for i, ct := range model.AllCodeTypes {
+		code := TrimCode(row.CodeValue(i))
+		if code == nil {
+			continue
+		}
+		if _, ok := CanonicalizeCode(ct.Name, *code); !ok {
+			return &RowError{Reason: ReasonInvalidCode, Field: ct.Column, Detail: fmt.Sprintf("%s code %q", ct.Name, *code)}
+		}
+	}
+	if v := row.DrugUnitOfMeasurement; v != nil && (math.IsNaN(*v) || math.IsInf(*v, 0)) {
+		return &RowError{Reason: ReasonInvalidAmount, Field: "drug_unit_of_measurement", Detail: fmt.Sprintf("value %v out of range", *v)}
+	}
+	return nil
+}
+
+// payerAmounts are the amount columns staged only with payer prices.
+var payerAmounts = map[string]bool{
+	"negotiated_dollar":     true,
+	"negotiated_percentage": true,
+	"estimated_amount":      true,
+}
+
+// checkUnparsed rejects a row carrying an amount its reader could not parse.
+// Payer amounts only count when they will be staged.
+func checkUnparsed(row *model.HospitalChargeRow, includePayerPrices bool) error {
+	u := row.Unparsed
+	if u == nil || (!includePayerPrices && payerAmounts[u.Column]) {
+		return nil
+	}
+	reason := ReasonInvalidAmount
+	if u.Column == "negotiated_percentage" {
+		reason = ReasonInvalidPercentage
+	}
+	return &RowError{Reason: reason, Field: u.Column, Detail: fmt.Sprintf("not a number: %q", u.Value)}
+}
+
 func optStr(s string) *string {
 	if s == "" {
 		return nil
@@ -0,0 +1,34 @@
+package normalize
+
+import "fmt"
+
+// RejectReason is the structured reason code stored with a quarantined row.
+type RejectReason string
+
+const (
+	// ReasonMissingRequired marks a row lacking a field the staging table requires.
+	ReasonMissingRequired RejectReason = "missing_required"
+	// ReasonInvalidAmount marks a dollar amount that is not a number or fails
+	// a money rule with SeverityReject; the detail names the rule.
+	ReasonInvalidAmount RejectReason = "invalid_amount"
+	// ReasonInvalidPercentage marks a percentage that is not a number or
+	// fails a money rule with SeverityReject.
+	ReasonInvalidPercentage RejectReason = "invalid_percentage"
+	// ReasonInvalidCode marks a code that does not match its code type's format.
+	ReasonInvalidCode RejectReason = "invalid_code"
+)
+
+// RowError explains why a source row could not be normalized. Field is the
+// source (Parquet) column name of the offending value.
+type RowError struct {
+	Reason RejectReason
+	Field  string
+	Detail string
+}
+
+func (e *RowError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%s: %s", e.Reason, e.Field)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Reason, e.Field, e.Detail)
+}
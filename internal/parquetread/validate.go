@@ -41,3 +41,8 @@ func ValidateSchema(schema *parquet.Schema) error {
 
 	return nil
 }
+
+// Validate checks the reader's schema with ValidateSchema.
+func (r *Reader) Validate() error {
+	return ValidateSchema(r.Schema())
+}
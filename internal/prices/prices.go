@@ -0,0 +1,147 @@
+// Package prices looks up the active-version prices of billing codes in
+// mrf.prices_by_code, for one code or a market basket of them.
+package prices
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/normalize"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// Filter selects the rows Lookup returns. CodeType and at least one code
+// are required; nil filters match everything.
+type Filter struct {
+	CodeType string
+	Codes    []string // raw codes, canonicalized like the transform does
+	Hospital *string  // case-insensitive substring of the hospital name
+	State    *string  // hospital license state, e.g. "CA"
+	Payer    *string  // case-insensitive substring of the payer name
+	Plan     *string  // case-insensitive substring of the plan name
+	Setting  *string  // inpatient or outpatient; also matches "both"
+	Limit    int32    // rows per code; 0 returns every row
+}
+
+// Price is one active-version serving row.
+type Price struct {
+	CodeType     string  `json:"code_type"`
+	Code         string  `json:"code"`
+	CodeRaw      string  `json:"code_raw"`
+	Description  string  `json:"description"`
+	HospitalID   int64   `json:"hospital_id"`
+	HospitalName string  `json:"hospital_name"`
+	State        *string `json:"state"`
+	Setting      *string `json:"setting"`
+	BillingClass *string `json:"billing_class"`
+	Payer        *string `json:"payer"`
+	Plan         *string `json:"plan"`
+	Modifiers    *string `json:"modifiers"`
+	// PriceCents is the negotiated dollar amount, or the gross charge for a
+	// row without one; rows are sorted by it.
+	PriceCents              *int64  `json:"price_cents"`
+	GrossChargeCents        *int64  `json:"gross_charge_cents"`
+	DiscountedCashCents     *int64  `json:"discounted_cash_cents"`
+	NegotiatedDollarCents   *int64  `json:"negotiated_dollar_cents"`
+	NegotiatedPercentageBps *int32  `json:"negotiated_percentage_bps"`
+	EstimatedAmountCents    *int64  `json:"estimated_amount_cents"`
+	MinChargeCents          *int64  `json:"min_charge_cents"`
+	MaxChargeCents          *int64  `json:"max_charge_cents"`
+	Methodology             *string `json:"methodology"`
+	MRFFileID               int64   `json:"mrf_file_id"`
+	PriceRowID              int64   `json:"price_row_id"`
+}
+
+// Lookup returns the active-version rows matching f, grouped by code and
+// cheapest first within a code, and the codes whose rows were cut at
+// f.Limit.
+func Lookup(ctx context.Context, q *sqlcgen.Queries, f Filter) ([]Price, []string, error) {
+	ct, ok := model.CodeTypeByName(strings.ToUpper(f.CodeType))
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown code type %q", f.CodeType)
+	}
+	if len(f.Codes) == 0 {
+		return nil, nil, fmt.Errorf("no codes to look up")
+	}
+	norms := make([]string, len(f.Codes))
+	for i, c := range f.Codes {
+		norms[i], _ = normalize.CanonicalizeCode(ct.Name, c)
+	}
+	params := sqlcgen.LookupPricesParams{
+		CodeType:  ct.Name,
+		CodeNorms: norms,
+		Hospital:  f.Hospital,
+		State:     f.State,
+		Payer:     f.Payer,
+		Plan:      f.Plan,
+		Setting:   f.Setting,
+	}
+	if f.Limit > 0 {
+		params.CodeLimit = &f.Limit
+	}
+	rows, err := q.LookupPrices(ctx, params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("lookup prices: %w", err)
+	}
+	out := make([]Price, 0, len(rows))
+	var truncated []string
+	for _, r := range rows {
+		if f.Limit > 0 && r.CodeRows > int64(f.Limit) && (len(out) == 0 || out[len(out)-1].Code != r.CodeNorm) {
+			truncated = append(truncated, r.CodeNorm)
+		}
+		p := Price{
+			CodeType:                r.CodeType,
+			Code:                    r.CodeNorm,
+			CodeRaw:                 r.CodeRaw,
+			Description:             r.Description,
+			HospitalID:              r.HospitalID,
+			HospitalName:            r.HospitalName,
+			State:                   r.LicenseState,
+			Setting:                 r.Setting,
+			BillingClass:            r.BillingClass,
+			Payer:                   r.PayerName,
+			Plan:                    r.PlanName,
+			Modifiers:               r.Modifiers,
+			PriceCents:              r.NegotiatedDollarCents,
+			GrossChargeCents:        r.GrossChargeCents,
+			DiscountedCashCents:     r.DiscountedCashCents,
+			NegotiatedDollarCents:   r.NegotiatedDollarCents,
+			NegotiatedPercentageBps: r.NegotiatedPercentageBps,
+			EstimatedAmountCents:    r.EstimatedAmountCents,
+			MinChargeCents:          r.MinChargeCents,
+			MaxChargeCents:          r.MaxChargeCents,
+			Methodology:             r.Methodology,
+			MRFFileID:               r.MrfFileID,
+			PriceRowID:              r.PriceRowID,
+		}
+		if p.PriceCents == nil {
+			p.PriceCents = r.GrossChargeCents
+		}
+		out = append(out, p)
+	}
+	return out, truncated, nil
+}
+
+// ReadCodes reads a market basket: one code per line, with blank lines and
+// lines starting with # skipped. Duplicates are dropped.
+func ReadCodes(r io.Reader) ([]string, error) {
+	var codes []string
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		codes = append(codes, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read codes: %w", err)
+	}
+	return codes, nil
+}
@@ -0,0 +1,57 @@
+package prices
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadCodes(t *testing.T) {
+	in := "# knee replacement basket\n99213\n\n  27447 \n99213\n#99999\nG0121\n"
+	codes, err := ReadCodes(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ReadCodes: %v", err)
+	}
+	if want := []string{"99213", "27447", "G0121"}; !slices.Equal(codes, want) {
+		t.Errorf("got %v, want %v", codes, want)
+	}
+}
+
+func TestWrite_CSV(t *testing.T) {
+	payer, price := "Aetna", int64(12345)
+	rows := []Price{{
+		CodeType: "CPT", Code: "99213", CodeRaw: "99213", Description: "Office visit, new",
+		HospitalID: 7, HospitalName: "General Hospital", Payer: &payer,
+		PriceCents: &price, NegotiatedDollarCents: &price, MRFFileID: 3, PriceRowID: 42,
+	}}
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCSV, rows); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if len(recs) != 2 || len(recs[1]) != len(csvColumns) {
+		t.Fatalf("csv: %q", recs)
+	}
+	got := map[string]string{}
+	for i, col := range recs[0] {
+		got[col] = recs[1][i]
+	}
+	if got["description"] != "Office visit, new" || got["payer"] != "Aetna" || got["price_cents"] != "12345" ||
+		got["gross_charge_cents"] != "" || got["price_row_id"] != "42" {
+		t.Errorf("csv row: %v", got)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected an error for xml")
+	}
+	if f, err := ParseFormat("csv"); err != nil || f != FormatCSV {
+		t.Errorf("csv: %v, %v", f, err)
+	}
+}
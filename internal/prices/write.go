@@ -0,0 +1,127 @@
+package prices
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Format is an output format for Lookup results.
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatCSV   Format = "csv"
+)
+
+// ParseFormat validates an output format name.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case FormatTable, FormatJSON, FormatCSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported output format %q (want table, json or csv)", name)
+}
+
+// Write encodes rows to w in the given format.
+func Write(w io.Writer, format Format, rows []Price) error {
+	switch format {
+	case FormatTable:
+		return writeTable(w, rows)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case FormatCSV:
+		return writeCSV(w, rows)
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+var csvColumns = []string{"code_type", "code", "code_raw", "description", "hospital_id", "hospital_name", "state",
+	"setting", "billing_class", "payer", "plan", "modifiers", "price_cents", "gross_charge_cents",
+	"discounted_cash_cents", "negotiated_dollar_cents", "negotiated_percentage_bps", "estimated_amount_cents",
+	"min_charge_cents", "max_charge_cents", "methodology", "mrf_file_id", "price_row_id"}
+
+func writeCSV(w io.Writer, rows []Price) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+	for _, p := range rows {
+		rec := []string{p.CodeType, p.Code, p.CodeRaw, p.Description, strconv.FormatInt(p.HospitalID, 10),
+			p.HospitalName, str(p.State), str(p.Setting), str(p.BillingClass), str(p.Payer), str(p.Plan),
+			str(p.Modifiers), num(p.PriceCents), num(p.GrossChargeCents), num(p.DiscountedCashCents),
+			num(p.NegotiatedDollarCents), num32(p.NegotiatedPercentageBps), num(p.EstimatedAmountCents),
+			num(p.MinChargeCents), num(p.MaxChargeCents), str(p.Methodology),
+			strconv.FormatInt(p.MRFFileID, 10), strconv.FormatInt(p.PriceRowID, 10)}
+		if err := cw.Write(rec); err != nil {
+			return fmt.Errorf("write csv row %d: %w", p.PriceRowID, err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTable(w io.Writer, rows []Price) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODE\tHOSPITAL\tSTATE\tSETTING\tPAYER\tPLAN\tPRICE\tGROSS\tCASH\tPCT\tDESCRIPTION")
+	for _, p := range rows {
+		pct := "-"
+		if p.NegotiatedPercentageBps != nil {
+			pct = fmt.Sprintf("%.2f%%", float64(*p.NegotiatedPercentageBps)/100)
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			p.CodeType, p.Code, p.HospitalName, dash(p.State), dash(p.Setting), dash(p.Payer), dash(p.Plan),
+			dollars(p.PriceCents), dollars(p.GrossChargeCents), dollars(p.DiscountedCashCents), pct, p.Description)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d rows\n", len(rows))
+	return err
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func dash(s *string) string {
+	if s == nil || *s == "" {
+		return "-"
+	}
+	return *s
+}
+
+func num(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(*n, 10)
+}
+
+func num32(n *int32) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(int64(*n), 10)
+}
+
+// dollars formats cents as a dollar amount.
+func dollars(c *int64) string {
+	if c == nil {
+		return "-"
+	}
+	n, sign := *c, ""
+	if n < 0 {
+		n, sign = -n, "-"
+	}
+	return fmt.Sprintf("%s$%d.%02d", sign, n/100, n%100)
+}
@@ -0,0 +1,119 @@
+// Package rejects exports quarantined rows from ingest.rejected_rows so data
+// stewards can return them to the publishing hospital.
+package rejects
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+// Format is an export file format.
+type Format string
+
+const (
+	FormatParquet Format = "parquet"
+	FormatCSV     Format = "csv"
+)
+
+// FormatForPath picks the export format from an explicit name, falling back
+// to the file extension.
+func FormatForPath(path, name string) (Format, error) {
+	if name == "" {
+		name = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch Format(strings.ToLower(name)) {
+	case FormatParquet:
+		return FormatParquet, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unsupported export format %q (want parquet or csv)", name)
+}
+
+// Row is a rejected source row with its reject metadata. The source columns
+// keep their original Parquet names so the file matches the hospital's own
+// layout; reject metadata columns are prefixed with "reject_".
+type Row struct {
+	SourceRowNumber int64  `parquet:"reject_source_row_number"`
+	ReasonCode      string `parquet:"reject_reason_code"`
+	FieldName       string `parquet:"reject_field_name"`
+	Detail          string `parquet:"reject_detail"`
+	model.HospitalChargeRow
+}
+
+// rejectColumns are the leading CSV columns, matching Row's parquet tags.
+var rejectColumns = []string{"reject_source_row_number", "reject_reason_code", "reject_field_name", "reject_detail"}
+
+// FromDB converts quarantined rows into export rows by decoding raw_row.
+func FromDB(rows []*sqlcgen.ListRejectedRowsRow) ([]Row, error) {
+	out := make([]Row, 0, len(rows))
+	for _, r := range rows {
+		charge, err := model.ParseRawJSON(r.RawRow)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", r.SourceRowNumber, err)
+		}
+		out = append(out, Row{
+			SourceRowNumber:   r.SourceRowNumber,
+			ReasonCode:        r.ReasonCode,
+			FieldName:         deref(r.FieldName),
+			Detail:            deref(r.Detail),
+			HospitalChargeRow: charge,
+		})
+	}
+	return out, nil
+}
+
+// Write encodes rows to w in the given format.
+func Write(w io.Writer, format Format, rows []Row) error {
+	switch format {
+	case FormatParquet:
+		return writeParquet(w, rows)
+	case FormatCSV:
+		return writeCSV(w, rows)
+	}
+	return fmt.Errorf("unsupported export format %q", format)
+}
+
+func writeParquet(w io.Writer, rows []Row) error {
+	pw := parquet.NewGenericWriter[Row](w)
+	if _, err := pw.Write(rows); err != nil {
+		return fmt.Errorf("write parquet rows: %w", err)
+	}
+	if err := pw.Close(); err != nil {
+		return fmt.Errorf("close parquet writer: %w", err)
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, rows []Row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append(append([]string{}, rejectColumns...), model.ChargeColumnNames()...)); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+	for i := range rows {
+		r := &rows[i]
+		rec := append([]string{strconv.FormatInt(r.SourceRowNumber, 10), r.ReasonCode, r.FieldName, r.Detail},
+			r.ChargeColumnStrings()...)
+		if err := cw.Write(rec); err != nil {
+			return fmt.Errorf("write csv row %d: %w", r.SourceRowNumber, err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,127 @@
+package rejects
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gyeh/pricestats/internal/model"
+	"github.com/gyeh/pricestats/internal/parquetread"
+	"github.com/gyeh/pricestats/internal/sqlcgen"
+)
+
+func strPtr(s string) *string { return &s }
+
+// quarantined builds a DB row the way Stage stores it.
+func quarantined(t *testing.T) []*sqlcgen.ListRejectedRowsRow {
+	t.Helper()
+	nan := math.NaN()
+	src := model.HospitalChargeRow{
+		Description:  "Office visit",
+		Setting:      "outpatient",
+		CPTCode:      strPtr("99213"),
+		GrossCharge:  &nan,
+		HospitalName: "General Hospital",
+		Affirmation:  true,
+	}
+	raw, err := src.RawJSON()
+	if err != nil {
+		t.Fatalf("RawJSON: %v", err)
+	}
+	return []*sqlcgen.ListRejectedRowsRow{{
+		SourceRowNumber: 7,
+		ReasonCode:      "invalid_amount",
+		FieldName:       strPtr("gross_charge"),
+		RawRow:          raw,
+	}}
+}
+
+func TestExport_ParquetRoundTrip(t *testing.T) {
+	rows, err := FromDB(quarantined(t))
+	if err != nil {
+		t.Fatalf("FromDB: %v", err)
+	}
+	if rows[0].GrossCharge == nil || !math.IsNaN(*rows[0].GrossCharge) {
+		t.Fatalf("NaN gross charge should survive raw_row: %v", rows[0].GrossCharge)
+	}
+
+	path := filepath.Join(t.TempDir(), "rejects.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(f, FormatParquet, rows); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	f.Close()
+
+	// The export must be re-readable with the source schema
+	r, err := parquetread.Open(path)
+	if err != nil {
+		t.Fatalf("open export: %v", err)
+	}
+	defer r.Close()
+	if err := r.Validate(); err != nil {
+		t.Fatalf("export should pass source schema validation: %v", err)
+	}
+	buf := make([]model.HospitalChargeRow, 2)
+	n, err := r.Read(buf)
+	if err != nil && err != io.EOF {
+		t.Fatalf("read export: %v", err)
+	}
+	if n != 1 || buf[0].CPTCode == nil || *buf[0].CPTCode != "99213" || buf[0].HospitalName != "General Hospital" {
+		t.Errorf("unexpected exported row: n=%d %+v", n, buf[0])
+	}
+}
+
+func TestExport_CSV(t *testing.T) {
+	rows, err := FromDB(quarantined(t))
+	if err != nil {
+		t.Fatalf("FromDB: %v", err)
+	}
+	var b bytes.Buffer
+	if err := Write(&b, FormatCSV, rows); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	recs, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("expected header + 1 row, got %d", len(recs))
+	}
+	got := make(map[string]string)
+	for i, h := range recs[0] {
+		got[h] = recs[1][i]
+	}
+	want := map[string]string{
+		"reject_source_row_number": "7",
+		"reject_reason_code":       "invalid_amount",
+		"reject_field_name":        "gross_charge",
+		"cpt_code":                 "99213",
+		"gross_charge":             "NaN",
+		"hcpcs_code":               "",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: got %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	if f, err := FormatForPath("out.CSV", ""); err != nil || f != FormatCSV {
+		t.Errorf("extension: got %q, %v", f, err)
+	}
+	if f, err := FormatForPath("out.dat", "parquet"); err != nil || f != FormatParquet {
+		t.Errorf("explicit: got %q, %v", f, err)
+	}
+	if _, err := FormatForPath("out.xlsx", ""); err == nil {
+		t.Error("expected error for unsupported extension")
+	}
+}
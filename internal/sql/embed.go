@@ -4,3 +4,8 @@ import "embed"
 
 //go:embed migrations/*.sql
 var Migrations embed.FS
+
+// Templates holds SQL rendered at runtime from the code type registry.
+//
+//go:embed templates/*.sql
+var Templates embed.FS
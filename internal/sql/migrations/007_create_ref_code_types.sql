@@ -0,0 +1,10 @@
+-- Persisted copy of the model.AllCodeTypes registry. Rows, the matching
+-- staging columns, and the serving partitions/indexes are reconciled by
+-- db.SyncCodeTypes after the migration files run.
+CREATE TABLE IF NOT EXISTS ref.code_types (
+  code_type        text    PRIMARY KEY,
+  staging_column   text    NOT NULL UNIQUE,
+  partition_suffix text    NOT NULL UNIQUE,
+  sort_order       integer NOT NULL,
+  created_at       timestamptz NOT NULL DEFAULT now()
+);
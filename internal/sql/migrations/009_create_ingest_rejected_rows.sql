@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS ingest.rejected_rows (
+  rejected_row_id    bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  ingest_batch_id    uuid   NOT NULL,
+  mrf_file_id        bigint NOT NULL REFERENCES ingest.mrf_files(mrf_file_id),
+
+  source_row_number  bigint NOT NULL,
+  reason_code        text   NOT NULL,
+  field_name         text,
+  detail             text,
+  raw_row            jsonb  NOT NULL,
+
+  rejected_at        timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS rejected_rows_file_idx
+  ON ingest.rejected_rows (mrf_file_id, source_row_number);
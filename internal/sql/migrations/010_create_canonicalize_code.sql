@@ -0,0 +1,49 @@
+-- Type-aware code canonicalization used by the wide→long transform to fill
+-- code_norm. Mirrors normalize.CanonicalizeCode in Go; invalid codes fall
+-- back to the generic upper-case/strip form.
+CREATE OR REPLACE FUNCTION mrf.canonicalize_code(code_type text, code_raw text)
+RETURNS text
+LANGUAGE plpgsql IMMUTABLE PARALLEL SAFE
+AS $$
+DECLARE
+  norm  text := upper(regexp_replace(code_raw, '[^A-Za-z0-9]', '', 'g'));
+  raw   text := btrim(code_raw, E' \t\n\r\f\v');
+  parts text[];
+BEGIN
+  CASE code_type
+    WHEN 'CPT', 'HCPCS' THEN
+      RETURN norm;
+    WHEN 'NDC' THEN
+      IF strpos(raw, '-') = 0 THEN
+        IF raw ~ '^[0-9]{11}$' THEN
+          RETURN raw;
+        END IF;
+        RETURN norm;
+      END IF;
+      parts := regexp_split_to_array(raw, '\s*-\s*');
+      IF array_length(parts, 1) <> 3
+         OR parts[1] !~ '^[0-9]+$' OR parts[2] !~ '^[0-9]+$' OR parts[3] !~ '^[0-9]+$' THEN
+        RETURN norm;
+      END IF;
+      CASE format('%s-%s-%s', length(parts[1]), length(parts[2]), length(parts[3]))
+        WHEN '5-4-2' THEN RETURN parts[1] || parts[2] || parts[3];
+        WHEN '4-4-2' THEN RETURN '0' || parts[1] || parts[2] || parts[3];
+        WHEN '5-3-2' THEN RETURN parts[1] || '0' || parts[2] || parts[3];
+        WHEN '5-4-1' THEN RETURN parts[1] || parts[2] || '0' || parts[3];
+        ELSE RETURN norm;
+      END CASE;
+    WHEN 'MS-DRG', 'DRG' THEN
+      IF norm ~ '^[0-9]{1,3}$' THEN
+        RETURN lpad(norm, 3, '0');
+      END IF;
+      RETURN norm;
+    WHEN 'CDT' THEN
+      IF norm ~ '^D?[0-9]{4}$' THEN
+        RETURN 'D' || right(norm, 4);
+      END IF;
+      RETURN norm;
+    ELSE
+      RETURN norm;
+  END CASE;
+END
+$$;
@@ -0,0 +1,4 @@
+-- Money rule flags (normalize.MoneyPolicy) recorded per row as "rule:field".
+-- NULL means the row passed every rule.
+ALTER TABLE ingest.stage_charge_rows ADD COLUMN IF NOT EXISTS quality_flags text[];
+ALTER TABLE mrf.prices_by_code ADD COLUMN IF NOT EXISTS quality_flags text[];
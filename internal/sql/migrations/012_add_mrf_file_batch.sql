@@ -0,0 +1,7 @@
+-- The batch that staged the file and its staging counts, so an interrupted
+-- ingest can be resumed (mrfload ingest --resume). The counts are NULL
+-- until staging completes.
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS ingest_batch_id uuid;
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS rows_read bigint;
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS rows_staged bigint;
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS rows_rejected bigint;
@@ -0,0 +1,3 @@
+-- Staging commits in chunks; staged_through_row is the last source_row_number
+-- of the last committed chunk. An interrupted COPY continues after it.
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS staged_through_row bigint;
@@ -0,0 +1,3 @@
+-- Refreshed by a running ingest (every status change and periodically), so
+-- mrfload gc can tell a stuck file from one still being loaded.
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS heartbeat_at timestamptz;
@@ -0,0 +1,40 @@
+-- Price timeline of each item across a hospital's successive files. An item
+-- is keyed by (code_type, code_norm, setting, payer_id, plan_id, modifiers),
+-- hashed into item_key; the row number plays no part, so an item is linked
+-- across versions wherever it sits in the file. A row holds the item's
+-- prices over [valid_from, valid_to), dated by the files' last_updated_on
+-- (import date when absent); valid_to is NULL while the prices are current.
+-- first/last_mrf_file_id are the versions that published the prices and
+-- are kept after those versions are pruned.
+CREATE TABLE IF NOT EXISTS mrf.price_history (
+  price_history_id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  hospital_id      bigint NOT NULL REFERENCES ref.hospitals(hospital_id),
+  item_key         bytea  NOT NULL,
+
+  code_type        text   NOT NULL,
+  code_norm        text   NOT NULL,
+  setting          text,
+  payer_id         bigint,
+  plan_id          bigint,
+  modifiers        text,
+  description      text   NOT NULL,
+
+  gross_charge_cents        bigint,
+  discounted_cash_cents     bigint,
+  negotiated_dollar_cents   bigint,
+  negotiated_percentage_bps integer,
+  estimated_amount_cents    bigint,
+
+  valid_from        date   NOT NULL,
+  valid_to          date,
+  first_mrf_file_id bigint NOT NULL,
+  last_mrf_file_id  bigint NOT NULL,
+
+  UNIQUE (hospital_id, item_key, valid_from)
+);
+
+CREATE INDEX IF NOT EXISTS price_history_code_idx
+  ON mrf.price_history (code_norm, hospital_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS price_history_open_idx
+  ON mrf.price_history (hospital_id, item_key) WHERE valid_to IS NULL;
@@ -0,0 +1,113 @@
+-- Price statistics of each code per hospital and payer over the hospital's
+-- active version: row counts, the distribution (min, p10, p25, median, p75,
+-- p90, max) of each price column and its dispersion (IQR = p75 - p25, and
+-- the coefficient of variation = population stddev / mean). payer_id is
+-- NULL for the rows without a payer (the hospital's own charges). A
+-- hospital's rows are replaced whenever its active version changes.
+CREATE TABLE IF NOT EXISTS mrf.price_stats (
+  code_type     text   NOT NULL,
+  code_norm     text   NOT NULL,
+  setting       text,
+  hospital_id   bigint NOT NULL REFERENCES ref.hospitals(hospital_id),
+  payer_id      bigint,
+  license_state text,
+  mrf_file_id   bigint NOT NULL,
+
+  row_count bigint NOT NULL,
+
+  -- negotiated dollar amounts
+  negotiated_count        bigint NOT NULL,
+  negotiated_min_cents    bigint,
+  negotiated_p10_cents    bigint,
+  negotiated_p25_cents    bigint,
+  negotiated_median_cents bigint,
+  negotiated_p75_cents    bigint,
+  negotiated_p90_cents    bigint,
+  negotiated_max_cents    bigint,
+  negotiated_iqr_cents    bigint,
+  negotiated_cv           double precision,
+
+  -- gross charges
+  gross_count        bigint NOT NULL,
+  gross_min_cents    bigint,
+  gross_p10_cents    bigint,
+  gross_p25_cents    bigint,
+  gross_median_cents bigint,
+  gross_p75_cents    bigint,
+  gross_p90_cents    bigint,
+  gross_max_cents    bigint,
+  gross_iqr_cents    bigint,
+  gross_cv           double precision,
+
+  -- discounted cash prices
+  cash_count        bigint NOT NULL,
+  cash_min_cents    bigint,
+  cash_p10_cents    bigint,
+  cash_p25_cents    bigint,
+  cash_median_cents bigint,
+  cash_p75_cents    bigint,
+  cash_p90_cents    bigint,
+  cash_max_cents    bigint,
+  cash_iqr_cents    bigint,
+  cash_cv           double precision,
+
+  refreshed_at timestamptz NOT NULL DEFAULT now(),
+
+  UNIQUE NULLS NOT DISTINCT (code_type, code_norm, setting, hospital_id, payer_id)
+);
+
+CREATE INDEX IF NOT EXISTS price_stats_hospital_idx
+  ON mrf.price_stats (hospital_id);
+
+-- The same statistics across the active versions of every hospital
+-- licensed in a state, over all payers. When a hospital's rows are
+-- refreshed, so are its state's rows for the codes it has or had.
+CREATE TABLE IF NOT EXISTS mrf.price_stats_by_state (
+  code_type     text NOT NULL,
+  code_norm     text NOT NULL,
+  setting       text,
+  license_state text NOT NULL,
+
+  hospital_count bigint NOT NULL,
+  row_count      bigint NOT NULL,
+
+  -- negotiated dollar amounts
+  negotiated_count        bigint NOT NULL,
+  negotiated_min_cents    bigint,
+  negotiated_p10_cents    bigint,
+  negotiated_p25_cents    bigint,
+  negotiated_median_cents bigint,
+  negotiated_p75_cents    bigint,
+  negotiated_p90_cents    bigint,
+  negotiated_max_cents    bigint,
+  negotiated_iqr_cents    bigint,
+  negotiated_cv           double precision,
+
+  -- gross charges
+  gross_count        bigint NOT NULL,
+  gross_min_cents    bigint,
+  gross_p10_cents    bigint,
+  gross_p25_cents    bigint,
+  gross_median_cents bigint,
+  gross_p75_cents    bigint,
+  gross_p90_cents    bigint,
+  gross_max_cents    bigint,
+  gross_iqr_cents    bigint,
+  gross_cv           double precision,
+
+  -- discounted cash prices
+  cash_count        bigint NOT NULL,
+  cash_min_cents    bigint,
+  cash_p10_cents    bigint,
+  cash_p25_cents    bigint,
+  cash_median_cents bigint,
+  cash_p75_cents    bigint,
+  cash_p90_cents    bigint,
+  cash_max_cents    bigint,
+  cash_iqr_cents    bigint,
+  cash_cv           double precision,
+
+  refreshed_at timestamptz NOT NULL DEFAULT now(),
+
+  UNIQUE NULLS NOT DISTINCT (code_type, code_norm, setting, license_state)
+);
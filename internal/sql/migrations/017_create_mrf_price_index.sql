@@ -0,0 +1,42 @@
+-- Market-basket price index of each hospital and payer, per file version
+-- and basket. index_value is the weighted mean, over the basket items the
+-- group prices, of price / reference price * 100, so 100 is the market
+-- median and 120 is 20% above it; NULL when no item is priced. payer_id is
+-- NULL for the hospital's own gross charges. Rows are kept when the version
+-- is superseded or pruned so a hospital's trend can be followed.
+CREATE TABLE IF NOT EXISTS mrf.price_index (
+  price_index_id  bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  basket_name     text   NOT NULL,
+  hospital_id     bigint NOT NULL REFERENCES ref.hospitals(hospital_id),
+  mrf_file_id     bigint NOT NULL,
+  last_updated_on date,
+  payer_id        bigint,
+
+  index_value     double precision,
+  items_total     integer NOT NULL,
+  items_priced    integer NOT NULL,
+  weight_coverage double precision NOT NULL, -- priced share of the basket weight, 0-1
+
+  computed_at timestamptz NOT NULL DEFAULT now(),
+
+  UNIQUE NULLS NOT DISTINCT (basket_name, mrf_file_id, payer_id)
+);
+
+CREATE INDEX IF NOT EXISTS price_index_hospital_idx
+  ON mrf.price_index (basket_name, hospital_id);
+
+-- One row per basket item of an index row, priced or not: price_cents is
+-- the median of the group's prices of the code (negotiated dollar amount,
+-- or gross charge for the hospital's own charges) and NULL, with missing
+-- set, when the group has none.
+CREATE TABLE IF NOT EXISTS mrf.price_index_items (
+  price_index_id  bigint NOT NULL REFERENCES mrf.price_index(price_index_id) ON DELETE CASCADE,
+  code_type       text   NOT NULL,
+  code_norm       text   NOT NULL,
+  weight          double precision NOT NULL,
+  price_cents     bigint,
+  reference_cents bigint,
+  missing         boolean NOT NULL,
+
+  PRIMARY KEY (price_index_id, code_type, code_norm)
+);
@@ -0,0 +1,3 @@
+-- Replaced versions used to keep status 'active'; deactivation now returns
+-- them to 'transformed', so bring the files replaced before that in line.
+UPDATE ingest.mrf_files SET status = 'transformed' WHERE status = 'active' AND NOT is_active;
@@ -0,0 +1,24 @@
+-- Work table for RecordHistory: the items of the file being recorded, keyed
+-- and priced once by FillHistoryItems and read by every statement that
+-- matches them against mrf.price_history. Rows only live inside the
+-- publishing transaction, so the table is unlogged.
+CREATE UNLOGGED TABLE IF NOT EXISTS ingest.history_items (
+  mrf_file_id bigint NOT NULL,
+  item_key    bytea  NOT NULL,
+
+  code_type   text   NOT NULL,
+  code_norm   text   NOT NULL,
+  setting     text   NOT NULL,
+  payer_id    bigint NOT NULL,
+  plan_id     bigint NOT NULL,
+  modifiers   text   NOT NULL,
+  description text   NOT NULL,
+
+  gross_charge_cents        bigint,
+  discounted_cash_cents     bigint,
+  negotiated_dollar_cents   bigint,
+  negotiated_percentage_bps integer,
+  estimated_amount_cents    bigint,
+
+  PRIMARY KEY (mrf_file_id, item_key)
+);
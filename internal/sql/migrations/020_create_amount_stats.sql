@@ -0,0 +1,24 @@
+-- The statistics mrf.price_stats and mrf.price_stats_by_state keep for each
+-- price column, over one group's amounts (NULLs ignored): count, min,
+-- interpolated percentiles rounded to the cent, max, IQR (p75 - p25) and
+-- coefficient of variation (population stddev / mean). The OUT parameters
+-- follow the tables' per-column order, so the stats inserts select them
+-- positionally.
+CREATE OR REPLACE FUNCTION mrf.amount_stats(
+  amounts bigint[],
+  OUT count bigint, OUT min_cents bigint, OUT p10_cents bigint, OUT p25_cents bigint,
+  OUT median_cents bigint, OUT p75_cents bigint, OUT p90_cents bigint, OUT max_cents bigint,
+  OUT iqr_cents bigint, OUT cv double precision
+)
+LANGUAGE sql IMMUTABLE PARALLEL SAFE
+AS $$
+  SELECT s.n, s.lo, round(s.pct[1])::bigint, round(s.pct[2])::bigint, round(s.pct[3])::bigint,
+         round(s.pct[4])::bigint, round(s.pct[5])::bigint, s.hi,
+         (round(s.pct[4]) - round(s.pct[2]))::bigint, s.cv
+  FROM (
+    SELECT count(a) AS n, min(a) AS lo, max(a) AS hi,
+           percentile_cont(ARRAY[0.1, 0.25, 0.5, 0.75, 0.9]) WITHIN GROUP (ORDER BY a) AS pct,
+           stddev_pop(a) / NULLIF(avg(a), 0) AS cv
+    FROM unnest(amounts) AS a
+  ) s
+$$;
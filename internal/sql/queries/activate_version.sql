@@ -1,4 +1,7 @@
 -- name: ActivateVersion :exec
+-- A partial file keeps its status: activation does not clear the marker
+-- that the reject policy let some rows through.
 UPDATE ingest.mrf_files
-SET is_active = true, status = 'active'
+SET is_active = true,
+    status = CASE WHEN status = 'partial' THEN status ELSE 'active' END
 WHERE mrf_file_id = sqlc.arg(mrf_file_id);
@@ -0,0 +1,12 @@
+-- name: AdvisoryLockHolder :one
+-- Session holding a two-key advisory lock. pg_locks reports the int4 keys as
+-- unsigned oids, hence the modulo.
+SELECT a.pid::int4 AS pid, COALESCE(a.application_name, '')::text AS application_name
+FROM pg_catalog.pg_locks l
+JOIN pg_catalog.pg_stat_activity a ON a.pid = l.pid
+WHERE l.locktype = 'advisory'
+  AND l.granted
+  AND l.objsubid = 2
+  AND l.classid = ((sqlc.arg(class_id)::int4::int8 + 4294967296) % 4294967296)::oid
+  AND l.objid = ((sqlc.arg(obj_id)::int4::int8 + 4294967296) % 4294967296)::oid
+LIMIT 1;
@@ -0,0 +1,2 @@
+-- name: AdvisoryUnlock :one
+SELECT pg_advisory_unlock(sqlc.arg(class_id)::int4, sqlc.arg(obj_id)::int4)::boolean AS unlocked;
@@ -0,0 +1,2 @@
+-- name: AdvisoryXactLock :exec
+SELECT pg_advisory_xact_lock(sqlc.arg(class_id)::int4, sqlc.arg(obj_id)::int4);
@@ -0,0 +1,3 @@
+-- name: ClearHistoryItems :exec
+DELETE FROM ingest.history_items
+WHERE mrf_file_id = sqlc.arg(mrf_file_id);
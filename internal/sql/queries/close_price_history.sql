@@ -0,0 +1,18 @@
+-- name: ClosePriceHistory :execrows
+-- Ends the current prices of the hospital's items that the file (see
+-- FillHistoryItems) dropped or repriced, as of the file's valid_from.
+UPDATE mrf.price_history h
+SET valid_to = sqlc.arg(valid_from)::date
+WHERE h.hospital_id = sqlc.arg(hospital_id)
+  AND h.valid_to IS NULL
+  AND h.valid_from < sqlc.arg(valid_from)::date
+  AND NOT EXISTS (
+    SELECT 1 FROM ingest.history_items i
+    WHERE i.mrf_file_id = sqlc.arg(mrf_file_id)
+      AND i.item_key = h.item_key
+      AND i.gross_charge_cents IS NOT DISTINCT FROM h.gross_charge_cents
+      AND i.discounted_cash_cents IS NOT DISTINCT FROM h.discounted_cash_cents
+      AND i.negotiated_dollar_cents IS NOT DISTINCT FROM h.negotiated_dollar_cents
+      AND i.negotiated_percentage_bps IS NOT DISTINCT FROM h.negotiated_percentage_bps
+      AND i.estimated_amount_cents IS NOT DISTINCT FROM h.estimated_amount_cents
+  );
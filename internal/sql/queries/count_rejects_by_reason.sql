@@ -0,0 +1,6 @@
+-- name: CountRejectsByReason :many
+SELECT reason_code, coalesce(field_name, '')::text AS field_name, count(*) AS row_count
+FROM ingest.rejected_rows
+WHERE mrf_file_id = sqlc.arg(mrf_file_id)
+GROUP BY reason_code, field_name
+ORDER BY row_count DESC, reason_code, field_name;
@@ -0,0 +1,6 @@
+-- name: CountServingByCodeType :many
+SELECT mrf_file_id, code_type, count(*)::bigint AS row_count
+FROM mrf.prices_by_code
+WHERE mrf_file_id = ANY(sqlc.arg(mrf_file_ids)::bigint[])
+GROUP BY mrf_file_id, code_type
+ORDER BY mrf_file_id, code_type;
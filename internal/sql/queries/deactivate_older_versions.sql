@@ -1,6 +1,9 @@
 -- name: DeactivateOlderVersions :execresult
+-- A replaced 'active' version goes back to 'transformed', so status
+-- 'active' only ever names a hospital's current version.
 UPDATE ingest.mrf_files
-SET is_active = false
+SET is_active = false,
+    status = CASE WHEN status = 'active' THEN 'transformed' ELSE status END
 WHERE hospital_id = sqlc.arg(hospital_id)
   AND mrf_file_id <> sqlc.arg(mrf_file_id)
   AND is_active = true;
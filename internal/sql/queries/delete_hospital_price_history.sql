@@ -0,0 +1,2 @@
+-- name: DeleteHospitalPriceHistory :execrows
+DELETE FROM mrf.price_history WHERE hospital_id = sqlc.arg(hospital_id);
@@ -0,0 +1,2 @@
+-- name: DeleteHospitalStats :execrows
+DELETE FROM mrf.price_stats WHERE hospital_id = sqlc.arg(hospital_id);
@@ -0,0 +1,6 @@
+-- name: DeleteLaterPriceHistory :execrows
+-- Drops the hospital's periods that start after valid_from: the versions
+-- that opened them are being rolled back.
+DELETE FROM mrf.price_history
+WHERE hospital_id = sqlc.arg(hospital_id)
+  AND valid_from > sqlc.arg(valid_from)::date;
@@ -0,0 +1,3 @@
+-- name: DeleteMRFFile :execrows
+DELETE FROM ingest.mrf_files
+WHERE mrf_file_id = sqlc.arg(mrf_file_id) AND NOT is_active;
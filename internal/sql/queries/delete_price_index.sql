@@ -0,0 +1,4 @@
+-- name: DeletePriceIndex :execrows
+-- Deletes a basket's index rows of the given versions, with their items.
+DELETE FROM mrf.price_index
+WHERE basket_name = sqlc.arg(basket_name) AND mrf_file_id = ANY(sqlc.arg(mrf_file_ids)::bigint[]);
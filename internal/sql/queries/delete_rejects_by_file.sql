@@ -0,0 +1,2 @@
+-- name: DeleteRejectsByFile :exec
+DELETE FROM ingest.rejected_rows WHERE mrf_file_id = sqlc.arg(mrf_file_id);
@@ -0,0 +1,18 @@
+-- name: DeleteReplacedPriceHistory :execrows
+-- Drops current prices recorded on the file's own valid_from that the file
+-- (see FillHistoryItems) drops or reprices: a later file with the same
+-- date replaces them.
+DELETE FROM mrf.price_history h
+WHERE h.hospital_id = sqlc.arg(hospital_id)
+  AND h.valid_to IS NULL
+  AND h.valid_from = sqlc.arg(valid_from)::date
+  AND NOT EXISTS (
+    SELECT 1 FROM ingest.history_items i
+    WHERE i.mrf_file_id = sqlc.arg(mrf_file_id)
+      AND i.item_key = h.item_key
+      AND i.gross_charge_cents IS NOT DISTINCT FROM h.gross_charge_cents
+      AND i.discounted_cash_cents IS NOT DISTINCT FROM h.discounted_cash_cents
+      AND i.negotiated_dollar_cents IS NOT DISTINCT FROM h.negotiated_dollar_cents
+      AND i.negotiated_percentage_bps IS NOT DISTINCT FROM h.negotiated_percentage_bps
+      AND i.estimated_amount_cents IS NOT DISTINCT FROM h.estimated_amount_cents
+  );
@@ -0,0 +1,9 @@
+-- name: DeleteServingBatch :execrows
+-- Deletes up to batch_rows serving rows of a file, keeping each statement's
+-- locks and WAL short.
+DELETE FROM mrf.prices_by_code p
+WHERE (p.price_row_id, p.code_type) IN (
+  SELECT b.price_row_id, b.code_type FROM mrf.prices_by_code b
+  WHERE b.mrf_file_id = sqlc.arg(mrf_file_id)
+  LIMIT sqlc.arg(batch_rows)::int
+);
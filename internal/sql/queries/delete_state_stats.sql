@@ -0,0 +1,11 @@
+-- name: DeleteStateStats :execrows
+-- Deletes the mrf.price_stats_by_state rows of the given keys, as passed to
+-- InsertStateStats.
+WITH k AS (
+  SELECT unnest(sqlc.arg(code_types)::text[]) AS code_type, unnest(sqlc.arg(code_norms)::text[]) AS code_norm,
+         unnest(sqlc.arg(settings)::text[]) AS setting, unnest(sqlc.arg(states)::text[]) AS license_state
+)
+DELETE FROM mrf.price_stats_by_state s
+USING k
+WHERE s.code_type = k.code_type AND s.code_norm = k.code_norm
+  AND COALESCE(s.setting, '') = k.setting AND s.license_state = k.license_state;
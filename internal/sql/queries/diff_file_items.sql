@@ -0,0 +1,52 @@
+-- name: DiffFileItems :many
+-- Items added, removed or repriced between two files. An item is keyed by
+-- (code_type, code_norm, setting, payer_id, plan_id, modifiers), with NULL
+-- key parts comparing equal (setting and modifiers come back as '' for
+-- NULL). Its gross charge, negotiated dollar amount and negotiated
+-- percentage are each the lowest among its rows and compared separately;
+-- a change to any of them reprices the item. Keep in step with
+-- DiffFileSummary.
+WITH a AS (
+  SELECT p.code_type, p.code_norm, COALESCE(p.setting, '')::text AS setting,
+         COALESCE(p.payer_id, 0)::bigint AS payer_id, COALESCE(p.plan_id, 0)::bigint AS plan_id,
+         COALESCE(p.modifiers, '')::text AS modifiers,
+         min(p.description)::text AS description,
+         min(p.gross_charge_cents)::bigint AS gross_cents,
+         min(p.negotiated_dollar_cents)::bigint AS negotiated_cents,
+         min(p.negotiated_percentage_bps)::integer AS pct_bps
+  FROM mrf.prices_by_code p
+  WHERE p.mrf_file_id = sqlc.arg(from_file_id)
+  GROUP BY 1, 2, 3, 4, 5, 6
+), b AS (
+  SELECT p.code_type, p.code_norm, COALESCE(p.setting, '')::text AS setting,
+         COALESCE(p.payer_id, 0)::bigint AS payer_id, COALESCE(p.plan_id, 0)::bigint AS plan_id,
+         COALESCE(p.modifiers, '')::text AS modifiers,
+         min(p.description)::text AS description,
+         min(p.gross_charge_cents)::bigint AS gross_cents,
+         min(p.negotiated_dollar_cents)::bigint AS negotiated_cents,
+         min(p.negotiated_percentage_bps)::integer AS pct_bps
+  FROM mrf.prices_by_code p
+  WHERE p.mrf_file_id = sqlc.arg(to_file_id)
+  GROUP BY 1, 2, 3, 4, 5, 6
+)
+SELECT a.code_type AS from_code_type, b.code_type AS to_code_type,
+       COALESCE(a.code_norm, b.code_norm)::text AS code_norm,
+       COALESCE(b.description, a.description)::text AS description,
+       COALESCE(a.setting, b.setting)::text AS setting,
+       py.payer_name, pl.plan_name,
+       COALESCE(a.modifiers, b.modifiers)::text AS modifiers,
+       a.gross_cents AS from_gross_cents, b.gross_cents AS to_gross_cents,
+       a.negotiated_cents AS from_negotiated_cents, b.negotiated_cents AS to_negotiated_cents,
+       a.pct_bps AS from_pct_bps, b.pct_bps AS to_pct_bps
+FROM a
+FULL JOIN b ON b.code_type = a.code_type AND b.code_norm = a.code_norm
+  AND b.setting = a.setting AND b.payer_id = a.payer_id
+  AND b.plan_id = a.plan_id AND b.modifiers = a.modifiers
+LEFT JOIN ref.payers py ON py.payer_id = COALESCE(a.payer_id, b.payer_id)
+LEFT JOIN ref.plans pl ON pl.plan_id = COALESCE(a.plan_id, b.plan_id)
+WHERE a.code_type IS NULL OR b.code_type IS NULL
+   OR a.gross_cents IS DISTINCT FROM b.gross_cents
+   OR a.negotiated_cents IS DISTINCT FROM b.negotiated_cents
+   OR a.pct_bps IS DISTINCT FROM b.pct_bps
+ORDER BY COALESCE(a.code_type, b.code_type), 3, 5, py.payer_name NULLS FIRST, pl.plan_name NULLS FIRST, 8
+LIMIT sqlc.narg(item_limit)::integer;
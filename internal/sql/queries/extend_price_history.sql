@@ -0,0 +1,7 @@
+-- name: ExtendPriceHistory :exec
+-- Credits the hospital's still-current prices to the file that republished
+-- them. Run after ClosePriceHistory and DeleteReplacedPriceHistory.
+UPDATE mrf.price_history
+SET last_mrf_file_id = sqlc.arg(mrf_file_id)
+WHERE hospital_id = sqlc.arg(hospital_id)
+  AND valid_to IS NULL;
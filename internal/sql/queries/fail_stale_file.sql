@@ -0,0 +1,7 @@
+-- name: FailStaleFile :execrows
+-- Re-checks staleness so a file whose ingest resumed heartbeating is left alone.
+UPDATE ingest.mrf_files
+SET status = 'failed', heartbeat_at = now()
+WHERE mrf_file_id = sqlc.arg(mrf_file_id)
+  AND status IN ('pending', 'staging', 'staged', 'transforming')
+  AND coalesce(heartbeat_at, imported_at) < now() - make_interval(secs => sqlc.arg(stale_after_secs)::float8);
@@ -0,0 +1,9 @@
+-- name: GetHistoryDates :one
+-- The date a file's prices take effect in mrf.price_history, and the latest
+-- date already recorded for its hospital (0001-01-01 when none).
+SELECT f.hospital_id,
+       COALESCE(f.last_updated_on, f.imported_at::date)::date AS valid_from,
+       COALESCE((SELECT max(h.valid_from) FROM mrf.price_history h
+                 WHERE h.hospital_id = f.hospital_id), '0001-01-01')::date AS latest_valid_from
+FROM ingest.mrf_files f
+WHERE f.mrf_file_id = sqlc.arg(mrf_file_id);
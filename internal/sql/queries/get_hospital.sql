@@ -0,0 +1,2 @@
+-- name: GetHospital :one
+SELECT hospital_id, hospital_name FROM ref.hospitals WHERE hospital_id = sqlc.arg(hospital_id);
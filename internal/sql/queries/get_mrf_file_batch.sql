@@ -0,0 +1,11 @@
+-- name: GetMRFFileBatch :one
+SELECT f.status, f.ingest_batch_id, f.rows_read, f.rows_staged, f.rows_rejected,
+       f.staged_through_row,
+       (SELECT count(*) FROM ingest.stage_charge_rows s
+        WHERE s.mrf_file_id = f.mrf_file_id
+          AND s.ingest_batch_id = f.ingest_batch_id)::bigint AS staged_now,
+       (SELECT count(*) FROM ingest.rejected_rows r
+        WHERE r.mrf_file_id = f.mrf_file_id
+          AND r.ingest_batch_id = f.ingest_batch_id)::bigint AS rejected_now
+FROM ingest.mrf_files f
+WHERE f.mrf_file_id = sqlc.arg(mrf_file_id);
@@ -0,0 +1,20 @@
+-- name: InsertHospitalStats :execrows
+-- Computes a hospital's mrf.price_stats rows from its active version (see
+-- mrf.amount_stats for the per-column statistics).
+WITH g AS (
+  SELECT p.code_type, p.code_norm, p.setting, p.payer_id, p.mrf_file_id, count(*) AS row_count,
+         array_agg(p.negotiated_dollar_cents) AS negotiated, array_agg(p.gross_charge_cents) AS gross,
+         array_agg(p.discounted_cash_cents) AS cash
+  FROM mrf.prices_by_code p
+  JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+  WHERE p.hospital_id = sqlc.arg(hospital_id)
+  GROUP BY p.code_type, p.code_norm, p.setting, p.payer_id, p.mrf_file_id
+)
+INSERT INTO mrf.price_stats
+SELECT g.code_type, g.code_norm, g.setting, h.hospital_id, g.payer_id, h.license_state, g.mrf_file_id, g.row_count,
+       n.*, gr.*, c.*
+FROM g
+JOIN ref.hospitals h ON h.hospital_id = sqlc.arg(hospital_id)
+CROSS JOIN LATERAL mrf.amount_stats(g.negotiated) n
+CROSS JOIN LATERAL mrf.amount_stats(g.gross) gr
+CROSS JOIN LATERAL mrf.amount_stats(g.cash) c;
@@ -0,0 +1,21 @@
+-- name: InsertPriceHistory :execrows
+-- Opens a history row for each item of the file (see FillHistoryItems)
+-- without current prices.
+INSERT INTO mrf.price_history (
+  hospital_id, item_key, code_type, code_norm, setting, payer_id, plan_id, modifiers, description,
+  gross_charge_cents, discounted_cash_cents, negotiated_dollar_cents, negotiated_percentage_bps,
+  estimated_amount_cents, valid_from, first_mrf_file_id, last_mrf_file_id
+)
+SELECT sqlc.arg(hospital_id)::bigint, i.item_key, i.code_type, i.code_norm, NULLIF(i.setting, ''),
+       NULLIF(i.payer_id, 0), NULLIF(i.plan_id, 0), NULLIF(i.modifiers, ''), i.description,
+       i.gross_charge_cents, i.discounted_cash_cents, i.negotiated_dollar_cents,
+       i.negotiated_percentage_bps, i.estimated_amount_cents,
+       sqlc.arg(valid_from)::date, i.mrf_file_id, i.mrf_file_id
+FROM ingest.history_items i
+WHERE i.mrf_file_id = sqlc.arg(mrf_file_id)
+  AND NOT EXISTS (
+    SELECT 1 FROM mrf.price_history h
+    WHERE h.hospital_id = sqlc.arg(hospital_id)
+      AND h.item_key = i.item_key
+      AND h.valid_to IS NULL
+  );
@@ -0,0 +1,7 @@
+-- name: InsertPriceIndex :one
+INSERT INTO mrf.price_index (basket_name, hospital_id, mrf_file_id, last_updated_on, payer_id,
+                             index_value, items_total, items_priced, weight_coverage)
+VALUES (sqlc.arg(basket_name), sqlc.arg(hospital_id), sqlc.arg(mrf_file_id), sqlc.narg(last_updated_on),
+        sqlc.narg(payer_id), sqlc.narg(index_value), sqlc.arg(items_total), sqlc.arg(items_priced),
+        sqlc.arg(weight_coverage))
+RETURNING price_index_id;
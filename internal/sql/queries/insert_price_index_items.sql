@@ -0,0 +1,8 @@
+-- name: InsertPriceIndexItems :exec
+-- Inserts an index row's items from a JSON array of
+-- {code_type, code_norm, weight, price_cents, reference_cents} objects.
+INSERT INTO mrf.price_index_items (price_index_id, code_type, code_norm, weight, price_cents, reference_cents, missing)
+SELECT sqlc.arg(price_index_id), i.code_type, i.code_norm, i.weight, i.price_cents, i.reference_cents,
+       i.price_cents IS NULL
+FROM jsonb_to_recordset(sqlc.arg(items)::jsonb)
+  AS i(code_type text, code_norm text, weight double precision, price_cents bigint, reference_cents bigint);
@@ -0,0 +1,26 @@
+-- name: InsertStateStats :execrows
+-- Computes the mrf.price_stats_by_state rows of the given (code_type,
+-- code_norm, setting, license_state) keys; a setting of '' stands for none.
+-- See mrf.amount_stats for the per-column statistics.
+WITH k AS (
+  SELECT DISTINCT unnest(sqlc.arg(code_types)::text[]) AS code_type, unnest(sqlc.arg(code_norms)::text[]) AS code_norm,
+         unnest(sqlc.arg(settings)::text[]) AS setting, unnest(sqlc.arg(states)::text[]) AS license_state
+), g AS (
+  SELECT p.code_type, p.code_norm, p.setting, h.license_state,
+         count(DISTINCT p.hospital_id) AS hospital_count, count(*) AS row_count,
+         array_agg(p.negotiated_dollar_cents) AS negotiated, array_agg(p.gross_charge_cents) AS gross,
+         array_agg(p.discounted_cash_cents) AS cash
+  FROM k
+  JOIN mrf.prices_by_code p ON p.code_type = k.code_type AND p.code_norm = k.code_norm
+                           AND COALESCE(p.setting, '') = k.setting
+  JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+  JOIN ref.hospitals h ON h.hospital_id = p.hospital_id AND h.license_state = k.license_state
+  GROUP BY p.code_type, p.code_norm, p.setting, h.license_state
+)
+INSERT INTO mrf.price_stats_by_state
+SELECT g.code_type, g.code_norm, g.setting, g.license_state, g.hospital_count, g.row_count,
+       n.*, gr.*, c.*
+FROM g
+CROSS JOIN LATERAL mrf.amount_stats(g.negotiated) n
+CROSS JOIN LATERAL mrf.amount_stats(g.gross) gr
+CROSS JOIN LATERAL mrf.amount_stats(g.cash) c;
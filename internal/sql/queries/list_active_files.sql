@@ -0,0 +1,6 @@
+-- name: ListActiveFiles :many
+SELECT f.mrf_file_id, f.hospital_id, h.hospital_name, f.last_updated_on
+FROM ingest.mrf_files f
+JOIN ref.hospitals h ON h.hospital_id = f.hospital_id
+WHERE f.is_active
+ORDER BY f.hospital_id;
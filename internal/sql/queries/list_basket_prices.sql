@@ -0,0 +1,20 @@
+-- name: ListBasketPrices :many
+-- The median price of each basket code per active version and payer: the
+-- negotiated dollar amount for payer rows, the gross charge for the
+-- hospital's own (payer-less) rows.
+WITH b AS (
+  SELECT unnest(sqlc.arg(code_types)::text[]) AS code_type, unnest(sqlc.arg(code_norms)::text[]) AS code_norm
+), r AS (
+  SELECT p.mrf_file_id, p.payer_id, p.code_type, p.code_norm,
+         CASE WHEN p.payer_id IS NULL THEN p.gross_charge_cents ELSE p.negotiated_dollar_cents END AS price
+  FROM b
+  JOIN mrf.prices_by_code p ON p.code_type = b.code_type AND p.code_norm = b.code_norm
+  JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+)
+SELECT r.mrf_file_id, r.payer_id, py.payer_name, r.code_type, r.code_norm,
+       round(percentile_cont(0.5) WITHIN GROUP (ORDER BY r.price))::bigint AS price_cents
+FROM r
+LEFT JOIN ref.payers py ON py.payer_id = r.payer_id
+WHERE r.price IS NOT NULL
+GROUP BY r.mrf_file_id, r.payer_id, py.payer_name, r.code_type, r.code_norm
+ORDER BY r.mrf_file_id, r.payer_id NULLS FIRST;
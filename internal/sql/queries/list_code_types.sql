@@ -0,0 +1,4 @@
+-- name: ListCodeTypes :many
+SELECT code_type, staging_column, partition_suffix, sort_order
+FROM ref.code_types
+ORDER BY sort_order;
@@ -0,0 +1,5 @@
+-- name: ListHospitalStatsKeys :many
+-- The state rollup keys a hospital's mrf.price_stats rows feed.
+SELECT DISTINCT code_type, code_norm, COALESCE(setting, '')::text AS setting, license_state::text AS license_state
+FROM mrf.price_stats
+WHERE hospital_id = sqlc.arg(hospital_id) AND license_state IS NOT NULL;
@@ -0,0 +1,11 @@
+-- name: ListMRFFiles :many
+SELECT f.mrf_file_id, f.hospital_id, h.hospital_name, f.source_file_name, f.source_file_sha256,
+       f.version, f.last_updated_on, f.status, f.is_active, f.imported_at,
+       f.ingest_batch_id, f.rows_read, f.rows_staged, f.rows_rejected
+FROM ingest.mrf_files f
+JOIN ref.hospitals h ON h.hospital_id = f.hospital_id
+WHERE (sqlc.narg(mrf_file_id)::bigint IS NULL OR f.mrf_file_id = sqlc.narg(mrf_file_id)::bigint)
+  AND (sqlc.narg(hospital)::text IS NULL OR h.hospital_name ILIKE '%' || sqlc.narg(hospital)::text || '%')
+  AND (sqlc.narg(status)::text IS NULL OR f.status = sqlc.narg(status)::text)
+  AND (sqlc.narg(is_active)::boolean IS NULL OR f.is_active = sqlc.narg(is_active)::boolean)
+ORDER BY h.hospital_name, f.mrf_file_id;
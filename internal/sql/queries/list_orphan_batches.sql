@@ -0,0 +1,13 @@
+-- name: ListOrphanBatches :many
+-- Staging batches no ingest will use again: those of failed or cancelled
+-- files, and batches superseded by a newer run of the file.
+SELECT s.mrf_file_id, s.ingest_batch_id, f.status,
+       coalesce(f.heartbeat_at, f.imported_at)::timestamptz AS last_seen,
+       count(*)::bigint AS staging_rows
+FROM ingest.stage_charge_rows s
+JOIN ingest.mrf_files f ON f.mrf_file_id = s.mrf_file_id
+WHERE (f.status IN ('failed', 'cancelled') OR f.ingest_batch_id IS DISTINCT FROM s.ingest_batch_id)
+  AND f.status NOT IN ('pending', 'staging', 'staged', 'transforming')
+  AND coalesce(f.heartbeat_at, f.imported_at) < now() - make_interval(secs => sqlc.arg(orphan_after_secs)::float8)
+GROUP BY s.mrf_file_id, s.ingest_batch_id, f.status, f.heartbeat_at, f.imported_at
+ORDER BY s.mrf_file_id, s.ingest_batch_id;
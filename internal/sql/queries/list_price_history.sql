@@ -0,0 +1,18 @@
+-- name: ListPriceHistory :many
+-- A code's price timeline at the matching hospitals, one row per item and
+-- period, oldest first.
+SELECT ho.hospital_name, h.code_type, h.code_norm, h.description, h.setting,
+       py.payer_name, pl.plan_name, h.modifiers,
+       h.gross_charge_cents, h.discounted_cash_cents, h.negotiated_dollar_cents,
+       h.negotiated_percentage_bps, h.estimated_amount_cents,
+       h.valid_from, h.valid_to, h.first_mrf_file_id, h.last_mrf_file_id
+FROM mrf.price_history h
+JOIN ref.hospitals ho ON ho.hospital_id = h.hospital_id
+LEFT JOIN ref.payers py ON py.payer_id = h.payer_id
+LEFT JOIN ref.plans pl ON pl.plan_id = h.plan_id
+WHERE ho.hospital_name ILIKE '%' || sqlc.arg(hospital)::text || '%'
+  AND h.code_norm = ANY(sqlc.arg(code_norms)::text[])
+  AND (sqlc.narg(code_type)::text IS NULL OR h.code_type = sqlc.narg(code_type)::text)
+  AND (sqlc.narg(payer)::text IS NULL OR py.payer_name ILIKE '%' || sqlc.narg(payer)::text || '%')
+ORDER BY ho.hospital_name, h.code_type, h.code_norm, h.setting NULLS FIRST, py.payer_name NULLS FIRST,
+         pl.plan_name NULLS FIRST, h.modifiers NULLS FIRST, h.valid_from;
@@ -0,0 +1,14 @@
+-- name: ListPriceIndexTrend :many
+-- A basket's stored index rows of the hospitals matching hospital, oldest
+-- version first.
+SELECT pi.hospital_id, h.hospital_name, pi.mrf_file_id, pi.last_updated_on, pi.payer_id, py.payer_name,
+       pi.index_value, pi.items_total, pi.items_priced, pi.weight_coverage, pi.computed_at,
+       COALESCE(f.is_active, false)::boolean AS is_active
+FROM mrf.price_index pi
+JOIN ref.hospitals h ON h.hospital_id = pi.hospital_id
+LEFT JOIN ref.payers py ON py.payer_id = pi.payer_id
+LEFT JOIN ingest.mrf_files f ON f.mrf_file_id = pi.mrf_file_id
+WHERE pi.basket_name = sqlc.arg(basket_name)
+  AND h.hospital_name ILIKE '%' || sqlc.arg(hospital)::text || '%'
+  AND (sqlc.narg(payer)::text IS NULL OR py.payer_name ILIKE '%' || sqlc.narg(payer)::text || '%')
+ORDER BY h.hospital_name, pi.hospital_id, pi.payer_id NULLS FIRST, pi.last_updated_on, pi.mrf_file_id;
@@ -0,0 +1,34 @@
+-- name: ListPrunableFiles :many
+-- Files outside the retention policy. Superseded versions are the inactive
+-- files that could be activated and were imported before their hospital's
+-- active version; version_rank counts them from the newest (1). Inactive
+-- loads newer than the active version were never superseded, so neither
+-- rule selects them. Failed and cancelled attempts are not versions: they
+-- are not ranked (version_rank 0) and only failed_max_age_days selects them.
+WITH superseded AS (
+  SELECT f.mrf_file_id, f.hospital_id, f.source_file_name, f.status, f.imported_at,
+         row_number() OVER (PARTITION BY f.hospital_id ORDER BY f.imported_at DESC, f.mrf_file_id DESC) AS version_rank
+  FROM ingest.mrf_files f
+  JOIN ingest.mrf_files a ON a.hospital_id = f.hospital_id AND a.is_active
+  WHERE NOT f.is_active
+    AND f.status IN ('transformed', 'partial', 'active')
+    AND (f.imported_at, f.mrf_file_id) < (a.imported_at, a.mrf_file_id)
+),
+candidates AS (
+  SELECT s.mrf_file_id, s.hospital_id, s.source_file_name, s.status, s.imported_at, s.version_rank
+  FROM superseded s
+  WHERE (sqlc.arg(keep_versions)::int > 0 AND s.version_rank > sqlc.arg(keep_versions)::int)
+     OR (sqlc.arg(max_age_days)::int > 0 AND s.imported_at < now() - make_interval(days => sqlc.arg(max_age_days)::int))
+  UNION ALL
+  SELECT f.mrf_file_id, f.hospital_id, f.source_file_name, f.status, f.imported_at, 0
+  FROM ingest.mrf_files f
+  WHERE NOT f.is_active
+    AND f.status IN ('failed', 'cancelled')
+    AND sqlc.arg(failed_max_age_days)::int > 0
+    AND f.imported_at < now() - make_interval(days => sqlc.arg(failed_max_age_days)::int)
+)
+SELECT c.mrf_file_id, c.hospital_id, h.hospital_name, c.source_file_name, c.status, c.imported_at,
+       c.version_rank::bigint AS version_rank
+FROM candidates c
+JOIN ref.hospitals h ON h.hospital_id = c.hospital_id
+ORDER BY h.hospital_name, c.imported_at;
@@ -0,0 +1,7 @@
+-- name: ListRejectedRows :many
+SELECT rejected_row_id, ingest_batch_id, source_row_number, reason_code, field_name, detail, raw_row, rejected_at
+FROM ingest.rejected_rows
+WHERE mrf_file_id = sqlc.arg(mrf_file_id)
+  AND (sqlc.narg(reason_code)::text IS NULL OR reason_code = sqlc.narg(reason_code)::text)
+ORDER BY source_row_number
+LIMIT sqlc.narg(row_limit)::integer;
@@ -0,0 +1,10 @@
+-- name: ListStaleFiles :many
+-- Files left in a running status whose ingest stopped heartbeating.
+SELECT f.mrf_file_id, f.hospital_id, f.source_file_name, f.status, f.is_active,
+       coalesce(f.heartbeat_at, f.imported_at)::timestamptz AS last_seen,
+       (SELECT count(*) FROM ingest.stage_charge_rows s
+        WHERE s.mrf_file_id = f.mrf_file_id)::bigint AS staging_rows
+FROM ingest.mrf_files f
+WHERE f.status IN ('pending', 'staging', 'staged', 'transforming')
+  AND coalesce(f.heartbeat_at, f.imported_at) < now() - make_interval(secs => sqlc.arg(stale_after_secs)::float8)
+ORDER BY f.mrf_file_id;
@@ -0,0 +1,9 @@
+-- name: ListStatsHospitals :many
+-- Hospitals whose statistics a full refresh recomputes: those with an
+-- active version or with statistics rows left from one.
+SELECT h.hospital_id, h.hospital_name
+FROM ref.hospitals h
+WHERE (sqlc.narg(hospital)::text IS NULL OR h.hospital_name ILIKE '%' || sqlc.narg(hospital)::text || '%')
+  AND (EXISTS (SELECT 1 FROM ingest.mrf_files f WHERE f.hospital_id = h.hospital_id AND f.is_active)
+       OR EXISTS (SELECT 1 FROM mrf.price_stats s WHERE s.hospital_id = h.hospital_id))
+ORDER BY h.hospital_id;
@@ -0,0 +1,40 @@
+-- name: LookupPrices :many
+-- Active-version serving rows of one code type and a set of canonical codes,
+-- cheapest first per code and at most code_limit rows per code (NULL for
+-- all); code_rows is the code's row count before the limit. A row's price
+-- is its negotiated dollar amount, or its gross charge when it has none. A
+-- setting filter also matches rows published for both settings.
+WITH matched AS (
+  SELECT p.price_row_id, p.mrf_file_id, p.code_type, p.code_norm, p.code_raw, p.description,
+         h.hospital_id, h.hospital_name, h.license_state,
+         p.setting, p.billing_class, py.payer_name, pl.plan_name, p.modifiers,
+         p.gross_charge_cents, p.discounted_cash_cents, p.negotiated_dollar_cents,
+         p.negotiated_percentage_bps, p.estimated_amount_cents,
+         p.min_charge_cents, p.max_charge_cents, p.methodology,
+         row_number() OVER (PARTITION BY p.code_norm
+                            ORDER BY COALESCE(p.negotiated_dollar_cents, p.gross_charge_cents) NULLS LAST,
+                                     h.hospital_name, p.price_row_id) AS code_rank,
+         count(*) OVER (PARTITION BY p.code_norm) AS code_rows
+  FROM mrf.prices_by_code p
+  JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+  JOIN ref.hospitals h ON h.hospital_id = p.hospital_id
+  LEFT JOIN ref.payers py ON py.payer_id = p.payer_id
+  LEFT JOIN ref.plans pl ON pl.plan_id = p.plan_id
+  WHERE p.code_type = sqlc.arg(code_type)
+    AND p.code_norm = ANY(sqlc.arg(code_norms)::text[])
+    AND (sqlc.narg(hospital)::text IS NULL OR h.hospital_name ILIKE '%' || sqlc.narg(hospital)::text || '%')
+    AND (sqlc.narg(state)::text IS NULL OR upper(h.license_state) = upper(sqlc.narg(state)::text))
+    AND (sqlc.narg(payer)::text IS NULL OR py.payer_name ILIKE '%' || sqlc.narg(payer)::text || '%')
+    AND (sqlc.narg(plan)::text IS NULL OR pl.plan_name ILIKE '%' || sqlc.narg(plan)::text || '%')
+    AND (sqlc.narg(setting)::text IS NULL OR lower(p.setting) IN (lower(sqlc.narg(setting)::text), 'both'))
+)
+SELECT m.price_row_id, m.mrf_file_id, m.code_type, m.code_norm, m.code_raw, m.description,
+       m.hospital_id, m.hospital_name, m.license_state,
+       m.setting, m.billing_class, m.payer_name, m.plan_name, m.modifiers,
+       m.gross_charge_cents, m.discounted_cash_cents, m.negotiated_dollar_cents,
+       m.negotiated_percentage_bps, m.estimated_amount_cents,
+       m.min_charge_cents, m.max_charge_cents, m.methodology,
+       m.code_rows::bigint AS code_rows
+FROM matched m
+WHERE sqlc.narg(code_limit)::integer IS NULL OR m.code_rank <= sqlc.narg(code_limit)::integer
+ORDER BY m.code_norm, m.code_rank;
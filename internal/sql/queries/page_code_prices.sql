@@ -0,0 +1,29 @@
+-- name: PageCodePrices :many
+-- One page of a code's active-version rows in (sort_cents, price_row_id)
+-- order. sort_cents is the negotiated dollar amount, else the gross charge,
+-- else the bigint maximum so unpriced rows come last. after_cents/after_id
+-- are the last row of the previous page.
+SELECT p.price_row_id, p.mrf_file_id, p.code_type, p.code_norm, p.code_raw, p.description,
+       h.hospital_id, h.hospital_name, h.license_state,
+       p.setting, p.billing_class, py.payer_id, py.payer_name, pl.plan_id, pl.plan_name, p.modifiers,
+       p.gross_charge_cents, p.discounted_cash_cents, p.negotiated_dollar_cents,
+       p.negotiated_percentage_bps, p.estimated_amount_cents,
+       p.min_charge_cents, p.max_charge_cents, p.methodology,
+       COALESCE(p.negotiated_dollar_cents, p.gross_charge_cents, 9223372036854775807)::bigint AS sort_cents
+FROM mrf.prices_by_code p
+JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+JOIN ref.hospitals h ON h.hospital_id = p.hospital_id
+LEFT JOIN ref.payers py ON py.payer_id = p.payer_id
+LEFT JOIN ref.plans pl ON pl.plan_id = p.plan_id
+WHERE p.code_type = sqlc.arg(code_type)
+  AND p.code_norm = sqlc.arg(code_norm)
+  AND (sqlc.narg(hospital_id)::bigint IS NULL OR p.hospital_id = sqlc.narg(hospital_id)::bigint)
+  AND (sqlc.narg(state)::text IS NULL OR upper(h.license_state) = upper(sqlc.narg(state)::text))
+  AND (sqlc.narg(payer_id)::bigint IS NULL OR p.payer_id = sqlc.narg(payer_id)::bigint)
+  AND (sqlc.narg(plan)::text IS NULL OR pl.plan_name ILIKE '%' || sqlc.narg(plan)::text || '%')
+  AND (sqlc.narg(setting)::text IS NULL OR lower(p.setting) IN (lower(sqlc.narg(setting)::text), 'both'))
+  AND (sqlc.narg(after_cents)::bigint IS NULL
+       OR (COALESCE(p.negotiated_dollar_cents, p.gross_charge_cents, 9223372036854775807), p.price_row_id)
+          > (sqlc.narg(after_cents)::bigint, sqlc.narg(after_id)::bigint))
+ORDER BY sort_cents, p.price_row_id
+LIMIT sqlc.arg(row_limit)::integer;
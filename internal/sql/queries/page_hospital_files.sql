@@ -0,0 +1,9 @@
+-- name: PageHospitalFiles :many
+-- One page of a hospital's file versions, newest first.
+SELECT f.mrf_file_id, f.source_file_name, f.version, f.last_updated_on, f.status,
+       f.is_active, f.imported_at, f.rows_read, f.rows_staged, f.rows_rejected
+FROM ingest.mrf_files f
+WHERE f.hospital_id = sqlc.arg(hospital_id)
+  AND (sqlc.narg(before_id)::bigint IS NULL OR f.mrf_file_id < sqlc.narg(before_id)::bigint)
+ORDER BY f.mrf_file_id DESC
+LIMIT sqlc.arg(row_limit)::integer;
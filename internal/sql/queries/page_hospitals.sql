@@ -0,0 +1,12 @@
+-- name: PageHospitals :many
+-- One page of the hospitals with an active version, by hospital_id.
+SELECT h.hospital_id, h.hospital_name, h.hospital_location, h.hospital_address,
+       h.license_number, h.license_state,
+       f.mrf_file_id AS active_mrf_file_id, f.last_updated_on AS active_last_updated_on
+FROM ref.hospitals h
+JOIN ingest.mrf_files f ON f.hospital_id = h.hospital_id AND f.is_active
+WHERE (sqlc.narg(name)::text IS NULL OR h.hospital_name ILIKE '%' || sqlc.narg(name)::text || '%')
+  AND (sqlc.narg(state)::text IS NULL OR upper(h.license_state) = upper(sqlc.narg(state)::text))
+  AND (sqlc.narg(after_id)::bigint IS NULL OR h.hospital_id > sqlc.narg(after_id)::bigint)
+ORDER BY h.hospital_id
+LIMIT sqlc.arg(row_limit)::integer;
@@ -0,0 +1,13 @@
+-- name: PagePayers :many
+-- One page of the payers with rows in an active version, by payer_id.
+SELECT py.payer_id, py.payer_name
+FROM ref.payers py
+WHERE (sqlc.narg(name)::text IS NULL OR py.payer_name ILIKE '%' || sqlc.narg(name)::text || '%')
+  AND (sqlc.narg(after_id)::bigint IS NULL OR py.payer_id > sqlc.narg(after_id)::bigint)
+  AND EXISTS (
+    SELECT 1 FROM mrf.prices_by_code p
+    JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+    WHERE p.payer_id = py.payer_id
+  )
+ORDER BY py.payer_id
+LIMIT sqlc.arg(row_limit)::integer;
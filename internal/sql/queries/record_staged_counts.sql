@@ -0,0 +1,7 @@
+-- name: RecordStagedCounts :exec
+UPDATE ingest.mrf_files
+SET status = 'staged',
+    rows_read = sqlc.arg(rows_read),
+    rows_staged = sqlc.arg(rows_staged),
+    rows_rejected = sqlc.arg(rows_rejected)
+WHERE mrf_file_id = sqlc.arg(mrf_file_id);
@@ -0,0 +1,7 @@
+-- name: ReopenPriceHistory :execrows
+-- Makes current again the hospital's periods that a rolled-back version
+-- closed after valid_from. Run after DeleteLaterPriceHistory.
+UPDATE mrf.price_history
+SET valid_to = NULL
+WHERE hospital_id = sqlc.arg(hospital_id)
+  AND valid_to > sqlc.arg(valid_from)::date;
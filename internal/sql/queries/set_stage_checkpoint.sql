@@ -0,0 +1,4 @@
+-- name: SetStageCheckpoint :exec
+UPDATE ingest.mrf_files
+SET staged_through_row = sqlc.arg(staged_through_row)
+WHERE mrf_file_id = sqlc.arg(mrf_file_id);
@@ -0,0 +1,6 @@
+-- name: StartMRFBatch :exec
+UPDATE ingest.mrf_files
+SET ingest_batch_id = sqlc.arg(ingest_batch_id),
+    rows_read = NULL, rows_staged = NULL, rows_rejected = NULL,
+    staged_through_row = NULL
+WHERE mrf_file_id = sqlc.arg(mrf_file_id);
@@ -0,0 +1,3 @@
+-- name: TouchMRFFile :exec
+UPDATE ingest.mrf_files SET heartbeat_at = now()
+WHERE mrf_file_id = sqlc.arg(mrf_file_id);
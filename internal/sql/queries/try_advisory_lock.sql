@@ -0,0 +1,2 @@
+-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock(sqlc.arg(class_id)::int4, sqlc.arg(obj_id)::int4)::boolean AS locked;
@@ -1,4 +1,4 @@
 -- name: UpdateMRFStatus :exec
 UPDATE ingest.mrf_files
-SET status = sqlc.arg(status)
+SET status = sqlc.arg(status), heartbeat_at = now()
 WHERE mrf_file_id = sqlc.arg(mrf_file_id);
@@ -0,0 +1,7 @@
+-- name: UpsertCodeType :exec
+INSERT INTO ref.code_types (code_type, staging_column, partition_suffix, sort_order)
+VALUES (sqlc.arg(code_type), sqlc.arg(staging_column), sqlc.arg(partition_suffix), sqlc.arg(sort_order))
+ON CONFLICT (code_type) DO UPDATE
+SET staging_column   = EXCLUDED.staging_column,
+    partition_suffix = EXCLUDED.partition_suffix,
+    sort_order       = EXCLUDED.sort_order;
@@ -1,6 +1,8 @@
 -- schema.sql
 -- Composite schema for sqlc type-checking.
--- Concatenates migrations 001-007 + 009 (skipping 008 which uses PL/pgSQL).
+-- Concatenates migrations 001-020. Code columns beyond the original five
+-- and the per-code-type partitions are created at migrate time from the
+-- model.AllCodeTypes registry (see db.SyncCodeTypes).
 
 -- 001_create_schemas.sql
 CREATE SCHEMA IF NOT EXISTS ref;
@@ -143,19 +145,19 @@ CREATE TABLE IF NOT EXISTS mrf.prices_by_code (
   PRIMARY KEY (price_row_id, code_type)
 ) PARTITION BY LIST (code_type);
 
--- 007_create_partitions.sql
-CREATE TABLE IF NOT EXISTS mrf.prices_by_code_cpt
-  PARTITION OF mrf.prices_by_code FOR VALUES IN ('CPT');
-CREATE TABLE IF NOT EXISTS mrf.prices_by_code_hcpcs
-  PARTITION OF mrf.prices_by_code FOR VALUES IN ('HCPCS');
-CREATE TABLE IF NOT EXISTS mrf.prices_by_code_ms_drg
-  PARTITION OF mrf.prices_by_code FOR VALUES IN ('MS-DRG');
-CREATE TABLE IF NOT EXISTS mrf.prices_by_code_ndc
-  PARTITION OF mrf.prices_by_code FOR VALUES IN ('NDC');
-CREATE TABLE IF NOT EXISTS mrf.prices_by_code_cdt
-  PARTITION OF mrf.prices_by_code FOR VALUES IN ('CDT');
-
--- 009_create_staging_indexes.sql
+-- 007_create_ref_code_types.sql
+-- Persisted copy of the model.AllCodeTypes registry. Rows, the matching
+-- staging columns, and the serving partitions/indexes are reconciled by
+-- db.SyncCodeTypes after the migration files run.
+CREATE TABLE IF NOT EXISTS ref.code_types (
+  code_type        text    PRIMARY KEY,
+  staging_column   text    NOT NULL UNIQUE,
+  partition_suffix text    NOT NULL UNIQUE,
+  sort_order       integer NOT NULL,
+  created_at       timestamptz NOT NULL DEFAULT now()
+);
+
+-- 008_create_staging_indexes.sql
 CREATE INDEX IF NOT EXISTS stage_charge_rows_batch_idx
   ON ingest.stage_charge_rows (ingest_batch_id);
 
@@ -164,3 +166,355 @@ CREATE INDEX IF NOT EXISTS stage_charge_rows_file_idx
 
 CREATE UNIQUE INDEX IF NOT EXISTS stage_charge_rows_batch_rowhash_uq
   ON ingest.stage_charge_rows (ingest_batch_id, source_row_hash);
+
+-- 009_create_ingest_rejected_rows.sql
+CREATE TABLE IF NOT EXISTS ingest.rejected_rows (
+  rejected_row_id    bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  ingest_batch_id    uuid   NOT NULL,
+  mrf_file_id        bigint NOT NULL REFERENCES ingest.mrf_files(mrf_file_id),
+
+  source_row_number  bigint NOT NULL,
+  reason_code        text   NOT NULL,
+  field_name         text,
+  detail             text,
+  raw_row            jsonb  NOT NULL,
+
+  rejected_at        timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS rejected_rows_file_idx
+  ON ingest.rejected_rows (mrf_file_id, source_row_number);
+
+-- 010_create_canonicalize_code.sql
+-- Type-aware code canonicalization used by the wide→long transform to fill
+-- code_norm. Mirrors normalize.CanonicalizeCode in Go; invalid codes fall
+-- back to the generic upper-case/strip form.
+CREATE OR REPLACE FUNCTION mrf.canonicalize_code(code_type text, code_raw text)
+RETURNS text
+LANGUAGE plpgsql IMMUTABLE PARALLEL SAFE
+AS $$
+DECLARE
+  norm  text := upper(regexp_replace(code_raw, '[^A-Za-z0-9]', '', 'g'));
+  raw   text := btrim(code_raw, E' \t\n\r\f\v');
+  parts text[];
+BEGIN
+  CASE code_type
+    WHEN 'CPT', 'HCPCS' THEN
+      RETURN norm;
+    WHEN 'NDC' THEN
+      IF strpos(raw, '-') = 0 THEN
+        IF raw ~ '^[0-9]{11}$' THEN
+          RETURN raw;
+        END IF;
+        RETURN norm;
+      END IF;
+      parts := regexp_split_to_array(raw, '\s*-\s*');
+      IF array_length(parts, 1) <> 3
+         OR parts[1] !~ '^[0-9]+$' OR parts[2] !~ '^[0-9]+$' OR parts[3] !~ '^[0-9]+$' THEN
+        RETURN norm;
+      END IF;
+      CASE format('%s-%s-%s', length(parts[1]), length(parts[2]), length(parts[3]))
+        WHEN '5-4-2' THEN RETURN parts[1] || parts[2] || parts[3];
+        WHEN '4-4-2' THEN RETURN '0' || parts[1] || parts[2] || parts[3];
+        WHEN '5-3-2' THEN RETURN parts[1] || '0' || parts[2] || parts[3];
+        WHEN '5-4-1' THEN RETURN parts[1] || parts[2] || '0' || parts[3];
+        ELSE RETURN norm;
+      END CASE;
+    WHEN 'MS-DRG', 'DRG' THEN
+      IF norm ~ '^[0-9]{1,3}$' THEN
+        RETURN lpad(norm, 3, '0');
+      END IF;
+      RETURN norm;
+    WHEN 'CDT' THEN
+      IF norm ~ '^D?[0-9]{4}$' THEN
+        RETURN 'D' || right(norm, 4);
+      END IF;
+      RETURN norm;
+    ELSE
+      RETURN norm;
+  END CASE;
+END
+$$;
+
+-- 011_add_quality_flags.sql
+-- Money rule flags (normalize.MoneyPolicy) recorded per row as "rule:field".
+-- NULL means the row passed every rule.
+ALTER TABLE ingest.stage_charge_rows ADD COLUMN IF NOT EXISTS quality_flags text[];
+ALTER TABLE mrf.prices_by_code ADD COLUMN IF NOT EXISTS quality_flags text[];
+
+-- 012_add_mrf_file_batch.sql
+-- The batch that staged the file and its staging counts, so an interrupted
+-- ingest can be resumed (mrfload ingest --resume). The counts are NULL
+-- until staging completes.
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS ingest_batch_id uuid;
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS rows_read bigint;
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS rows_staged bigint;
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS rows_rejected bigint;
+
+-- 013_add_stage_checkpoint.sql
+-- Staging commits in chunks; staged_through_row is the last source_row_number
+-- of the last committed chunk. An interrupted COPY continues after it.
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS staged_through_row bigint;
+
+-- 014_add_mrf_file_heartbeat.sql
+-- Refreshed by a running ingest (every status change and periodically), so
+-- mrfload gc can tell a stuck file from one still being loaded.
+ALTER TABLE ingest.mrf_files ADD COLUMN IF NOT EXISTS heartbeat_at timestamptz;
+
+-- 015_create_mrf_price_history.sql
+-- Price timeline of each item across a hospital's successive files. An item
+-- is keyed by (code_type, code_norm, setting, payer_id, plan_id, modifiers),
+-- hashed into item_key; the row number plays no part, so an item is linked
+-- across versions wherever it sits in the file. A row holds the item's
+-- prices over [valid_from, valid_to), dated by the files' last_updated_on
+-- (import date when absent); valid_to is NULL while the prices are current.
+-- first/last_mrf_file_id are the versions that published the prices and
+-- are kept after those versions are pruned.
+CREATE TABLE IF NOT EXISTS mrf.price_history (
+  price_history_id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  hospital_id      bigint NOT NULL REFERENCES ref.hospitals(hospital_id),
+  item_key         bytea  NOT NULL,
+
+  code_type        text   NOT NULL,
+  code_norm        text   NOT NULL,
+  setting          text,
+  payer_id         bigint,
+  plan_id          bigint,
+  modifiers        text,
+  description      text   NOT NULL,
+
+  gross_charge_cents        bigint,
+  discounted_cash_cents     bigint,
+  negotiated_dollar_cents   bigint,
+  negotiated_percentage_bps integer,
+  estimated_amount_cents    bigint,
+
+  valid_from        date   NOT NULL,
+  valid_to          date,
+  first_mrf_file_id bigint NOT NULL,
+  last_mrf_file_id  bigint NOT NULL,
+
+  UNIQUE (hospital_id, item_key, valid_from)
+);
+
+CREATE INDEX IF NOT EXISTS price_history_code_idx
+  ON mrf.price_history (code_norm, hospital_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS price_history_open_idx
+  ON mrf.price_history (hospital_id, item_key) WHERE valid_to IS NULL;
+
+-- 016_create_mrf_price_stats.sql
+-- Price statistics of each code per hospital and payer over the hospital's
+-- active version: row counts, the distribution (min, p10, p25, median, p75,
+-- p90, max) of each price column and its dispersion (IQR = p75 - p25, and
+-- the coefficient of variation = population stddev / mean). payer_id is
+-- NULL for the rows without a payer (the hospital's own charges). A
+-- hospital's rows are replaced whenever its active version changes.
+CREATE TABLE IF NOT EXISTS mrf.price_stats (
+  code_type     text   NOT NULL,
+  code_norm     text   NOT NULL,
+  setting       text,
+  hospital_id   bigint NOT NULL REFERENCES ref.hospitals(hospital_id),
+  payer_id      bigint,
+  license_state text,
+  mrf_file_id   bigint NOT NULL,
+
+  row_count bigint NOT NULL,
+
+  -- negotiated dollar amounts
+  negotiated_count        bigint NOT NULL,
+  negotiated_min_cents    bigint,
+  negotiated_p10_cents    bigint,
+  negotiated_p25_cents    bigint,
+  negotiated_median_cents bigint,
+  negotiated_p75_cents    bigint,
+  negotiated_p90_cents    bigint,
+  negotiated_max_cents    bigint,
+  negotiated_iqr_cents    bigint,
+  negotiated_cv           double precision,
+
+  -- gross charges
+  gross_count        bigint NOT NULL,
+  gross_min_cents    bigint,
+  gross_p10_cents    bigint,
+  gross_p25_cents    bigint,
+  gross_median_cents bigint,
+  gross_p75_cents    bigint,
+  gross_p90_cents    bigint,
+  gross_max_cents    bigint,
+  gross_iqr_cents    bigint,
+  gross_cv           double precision,
+
+  -- discounted cash prices
+  cash_count        bigint NOT NULL,
+  cash_min_cents    bigint,
+  cash_p10_cents    bigint,
+  cash_p25_cents    bigint,
+  cash_median_cents bigint,
+  cash_p75_cents    bigint,
+  cash_p90_cents    bigint,
+  cash_max_cents    bigint,
+  cash_iqr_cents    bigint,
+  cash_cv           double precision,
+
+  refreshed_at timestamptz NOT NULL DEFAULT now(),
+
+  UNIQUE NULLS NOT DISTINCT (code_type, code_norm, setting, hospital_id, payer_id)
+);
+
+CREATE INDEX IF NOT EXISTS price_stats_hospital_idx
+  ON mrf.price_stats (hospital_id);
+
+-- The same statistics across the active versions of every hospital
+-- licensed in a state, over all payers. When a hospital's rows are
+-- refreshed, so are its state's rows for the codes it has or had.
+CREATE TABLE IF NOT EXISTS mrf.price_stats_by_state (
+  code_type     text NOT NULL,
+  code_norm     text NOT NULL,
+  setting       text,
+  license_state text NOT NULL,
+
+  hospital_count bigint NOT NULL,
+  row_count      bigint NOT NULL,
+
+  -- negotiated dollar amounts
+  negotiated_count        bigint NOT NULL,
+  negotiated_min_cents    bigint,
+  negotiated_p10_cents    bigint,
+  negotiated_p25_cents    bigint,
+  negotiated_median_cents bigint,
+  negotiated_p75_cents    bigint,
+  negotiated_p90_cents    bigint,
+  negotiated_max_cents    bigint,
+  negotiated_iqr_cents    bigint,
+  negotiated_cv           double precision,
+
+  -- gross charges
+  gross_count        bigint NOT NULL,
+  gross_min_cents    bigint,
+  gross_p10_cents    bigint,
+  gross_p25_cents    bigint,
+  gross_median_cents bigint,
+  gross_p75_cents    bigint,
+  gross_p90_cents    bigint,
+  gross_max_cents    bigint,
+  gross_iqr_cents    bigint,
+  gross_cv           double precision,
+
+  -- discounted cash prices
+  cash_count        bigint NOT NULL,
+  cash_min_cents    bigint,
+  cash_p10_cents    bigint,
+  cash_p25_cents    bigint,
+  cash_median_cents bigint,
+  cash_p75_cents    bigint,
+  cash_p90_cents    bigint,
+  cash_max_cents    bigint,
+  cash_iqr_cents    bigint,
+  cash_cv           double precision,
+
+  refreshed_at timestamptz NOT NULL DEFAULT now(),
+
+  UNIQUE NULLS NOT DISTINCT (code_type, code_norm, setting, license_state)
+);
+
+-- 017_create_mrf_price_index.sql
+-- Market-basket price index of each hospital and payer, per file version
+-- and basket. index_value is the weighted mean, over the basket items the
+-- group prices, of price / reference price * 100, so 100 is the market
+-- median and 120 is 20% above it; NULL when no item is priced. payer_id is
+-- NULL for the hospital's own gross charges. Rows are kept when the version
+-- is superseded or pruned so a hospital's trend can be followed.
+CREATE TABLE IF NOT EXISTS mrf.price_index (
+  price_index_id  bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  basket_name     text   NOT NULL,
+  hospital_id     bigint NOT NULL REFERENCES ref.hospitals(hospital_id),
+  mrf_file_id     bigint NOT NULL,
+  last_updated_on date,
+  payer_id        bigint,
+
+  index_value     double precision,
+  items_total     integer NOT NULL,
+  items_priced    integer NOT NULL,
+  weight_coverage double precision NOT NULL, -- priced share of the basket weight, 0-1
+
+  computed_at timestamptz NOT NULL DEFAULT now(),
+
+  UNIQUE NULLS NOT DISTINCT (basket_name, mrf_file_id, payer_id)
+);
+
+CREATE INDEX IF NOT EXISTS price_index_hospital_idx
+  ON mrf.price_index (basket_name, hospital_id);
+
+-- One row per basket item of an index row, priced or not: price_cents is
+-- the median of the group's prices of the code (negotiated dollar amount,
+-- or gross charge for the hospital's own charges) and NULL, with missing
+-- set, when the group has none.
+CREATE TABLE IF NOT EXISTS mrf.price_index_items (
+  price_index_id  bigint NOT NULL REFERENCES mrf.price_index(price_index_id) ON DELETE CASCADE,
+  code_type       text   NOT NULL,
+  code_norm       text   NOT NULL,
+  weight          double precision NOT NULL,
+  price_cents     bigint,
+  reference_cents bigint,
+  missing         boolean NOT NULL,
+
+  PRIMARY KEY (price_index_id, code_type, code_norm)
+);
+
+-- 018_fix_inactive_status.sql
+-- Replaced versions used to keep status 'active'; deactivation now returns
+-- them to 'transformed', so bring the files replaced before that in line.
+UPDATE ingest.mrf_files SET status = 'transformed' WHERE status = 'active' AND NOT is_active;
+
+-- 019_create_history_items.sql
+-- Work table for RecordHistory: the items of the file being recorded, keyed
+-- and priced once by FillHistoryItems and read by every statement that
+-- matches them against mrf.price_history. Rows only live inside the
+-- publishing transaction, so the table is unlogged.
+CREATE UNLOGGED TABLE IF NOT EXISTS ingest.history_items (
+  mrf_file_id bigint NOT NULL,
+  item_key    bytea  NOT NULL,
+
+  code_type   text   NOT NULL,
+  code_norm   text   NOT NULL,
+  setting     text   NOT NULL,
+  payer_id    bigint NOT NULL,
+  plan_id     bigint NOT NULL,
+  modifiers   text   NOT NULL,
+  description text   NOT NULL,
+
+  gross_charge_cents        bigint,
+  discounted_cash_cents     bigint,
+  negotiated_dollar_cents   bigint,
+  negotiated_percentage_bps integer,
+  estimated_amount_cents    bigint,
+
+  PRIMARY KEY (mrf_file_id, item_key)
+);
+
+-- 020_create_amount_stats.sql
+-- The statistics mrf.price_stats and mrf.price_stats_by_state keep for each
+-- price column, over one group's amounts (NULLs ignored): count, min,
+-- interpolated percentiles rounded to the cent, max, IQR (p75 - p25) and
+-- coefficient of variation (population stddev / mean). The OUT parameters
+-- follow the tables' per-column order, so the stats inserts select them
+-- positionally.
+CREATE OR REPLACE FUNCTION mrf.amount_stats(
+  amounts bigint[],
+  OUT count bigint, OUT min_cents bigint, OUT p10_cents bigint, OUT p25_cents bigint,
+  OUT median_cents bigint, OUT p75_cents bigint, OUT p90_cents bigint, OUT max_cents bigint,
+  OUT iqr_cents bigint, OUT cv double precision
+)
+LANGUAGE sql IMMUTABLE PARALLEL SAFE
+AS $$
+  SELECT s.n, s.lo, round(s.pct[1])::bigint, round(s.pct[2])::bigint, round(s.pct[3])::bigint,
+         round(s.pct[4])::bigint, round(s.pct[5])::bigint, s.hi,
+         (round(s.pct[4]) - round(s.pct[2]))::bigint, s.cv
+  FROM (
+    SELECT count(a) AS n, min(a) AS lo, max(a) AS hi,
+           percentile_cont(ARRAY[0.1, 0.25, 0.5, 0.75, 0.9]) WITHIN GROUP (ORDER BY a) AS pct,
+           stddev_pop(a) / NULLIF(avg(a), 0) AS cv
+    FROM unnest(amounts) AS a
+  ) s
+$$;
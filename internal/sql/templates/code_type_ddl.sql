@@ -0,0 +1,19 @@
+-- Per-code-type DDL, rendered with text/template for one model.CodeType.
+-- Identifiers and names are validated by the model registry at init.
+ALTER TABLE ingest.stage_charge_rows
+  ADD COLUMN IF NOT EXISTS {{ .Column }} text;
+
+CREATE TABLE IF NOT EXISTS mrf.prices_by_code_{{ .Partition }}
+  PARTITION OF mrf.prices_by_code FOR VALUES IN ('{{ .Name }}');
+
+CREATE INDEX IF NOT EXISTS prices_by_code_{{ .Partition }}_code_idx
+  ON mrf.prices_by_code_{{ .Partition }} (code_norm);
+
+CREATE INDEX IF NOT EXISTS prices_by_code_{{ .Partition }}_code_hospital_idx
+  ON mrf.prices_by_code_{{ .Partition }} (code_norm, hospital_id);
+
+CREATE INDEX IF NOT EXISTS prices_by_code_{{ .Partition }}_file_idx
+  ON mrf.prices_by_code_{{ .Partition }} (mrf_file_id);
+
+CREATE INDEX IF NOT EXISTS prices_by_code_{{ .Partition }}_payer_idx
+  ON mrf.prices_by_code_{{ .Partition }} (payer_id);
@@ -0,0 +1,20 @@
+-- Counts the staged rows the wide→long transform drops: those with no code
+-- of any registered type (codeless), and those whose codes are all of types
+-- outside the code_types filter (filtered out). Rendered like
+-- transform_wide_to_long.sql. $1 = ingest_batch_id, $2 = code_types filter.
+SELECT count(*) FILTER (WHERE c.codes = 0),
+       count(*) FILTER (WHERE c.codes > 0 AND c.selected = 0)
+FROM ingest.stage_charge_rows s
+CROSS JOIN LATERAL (
+  SELECT count(*) AS codes,
+         count(*) FILTER (WHERE $2::text[] IS NULL OR c.code_type = ANY($2::text[])) AS selected
+  FROM (
+    VALUES
+{{- range $i, $ct := . }}{{ if $i }},{{ end }}
+      ('{{ $ct.Name }}', s.{{ $ct.Column }})
+{{- end }}
+  ) AS c(code_type, code_raw)
+  WHERE c.code_raw IS NOT NULL
+    AND c.code_raw <> ''
+) c
+WHERE s.ingest_batch_id = $1;
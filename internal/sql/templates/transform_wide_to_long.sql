@@ -1,4 +1,6 @@
--- name: TransformWideToLong :execresult
+-- Wide→long transform from staging into mrf.prices_by_code.
+-- Rendered with text/template over model.AllCodeTypes: one lateral VALUES
+-- entry per registered code type. $1 = ingest_batch_id, $2 = code_types filter.
 INSERT INTO mrf.prices_by_code (
   mrf_file_id,
   hospital_id,
@@ -26,6 +28,7 @@ INSERT INTO mrf.prices_by_code (
   modifiers,
   additional_generic_notes,
   additional_payer_notes,
+  quality_flags,
   source_row_hash
 )
 SELECT
@@ -33,7 +36,7 @@ SELECT
   f.hospital_id,
   c.code_type,
   c.code_raw,
-  upper(regexp_replace(c.code_raw, '[^A-Za-z0-9]', '', 'g')) AS code_norm,
+  mrf.canonicalize_code(c.code_type, c.code_raw) AS code_norm,
   s.description,
   s.setting,
   s.billing_class,
@@ -55,6 +58,7 @@ SELECT
   s.modifiers,
   s.additional_generic_notes,
   s.additional_payer_notes,
+  s.quality_flags,
   s.source_row_hash
 FROM ingest.stage_charge_rows s
 JOIN ingest.mrf_files f ON f.mrf_file_id = s.mrf_file_id
@@ -64,14 +68,12 @@ LEFT JOIN ref.plans pl
  AND pl.plan_name_norm = s.plan_name_norm
 CROSS JOIN LATERAL (
   VALUES
-    ('CPT',    s.cpt_code),
-    ('HCPCS',  s.hcpcs_code),
-    ('MS-DRG', s.ms_drg_code),
-    ('NDC',    s.ndc_code),
-    ('CDT',    s.cdt_code)
+{{- range $i, $ct := . }}{{ if $i }},{{ end }}
+    ('{{ $ct.Name }}', s.{{ $ct.Column }})
+{{- end }}
 ) AS c(code_type, code_raw)
-WHERE s.ingest_batch_id = sqlc.arg(ingest_batch_id)
+WHERE s.ingest_batch_id = $1
   AND c.code_raw IS NOT NULL
   AND c.code_raw <> ''
-  AND (sqlc.arg(code_types)::text[] IS NULL
-       OR c.code_type = ANY(sqlc.arg(code_types)::text[]));
+  AND ($2::text[] IS NULL
+       OR c.code_type = ANY($2::text[]));
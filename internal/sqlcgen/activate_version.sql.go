@@ -11,10 +11,13 @@ import (
 
 const activateVersion = `-- name: ActivateVersion :exec
 UPDATE ingest.mrf_files
-SET is_active = true, status = 'active'
+SET is_active = true,
+    status = CASE WHEN status = 'partial' THEN status ELSE 'active' END
 WHERE mrf_file_id = $1
 `
 
+// A partial file keeps its status: activation does not clear the marker
+// that the reject policy let some rows through.
 func (q *Queries) ActivateVersion(ctx context.Context, mrfFileID int64) error {
 	_, err := q.db.Exec(ctx, activateVersion, mrfFileID)
 	return err
@@ -0,0 +1,41 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: advisory_lock_holder.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const advisoryLockHolder = `-- name: AdvisoryLockHolder :one
+SELECT a.pid::int4 AS pid, COALESCE(a.application_name, '')::text AS application_name
+FROM pg_catalog.pg_locks l
+JOIN pg_catalog.pg_stat_activity a ON a.pid = l.pid
+WHERE l.locktype = 'advisory'
+  AND l.granted
+  AND l.objsubid = 2
+  AND l.classid = (($1::int4::int8 + 4294967296) % 4294967296)::oid
+  AND l.objid = (($2::int4::int8 + 4294967296) % 4294967296)::oid
+LIMIT 1
+`
+
+type AdvisoryLockHolderParams struct {
+	ClassID int32
+	ObjID   int32
+}
+
+type AdvisoryLockHolderRow struct {
+	Pid             int32
+	ApplicationName string
+}
+
+// Session holding a two-key advisory lock. pg_locks reports the int4 keys as
+// unsigned oids, hence the modulo.
+func (q *Queries) AdvisoryLockHolder(ctx context.Context, arg AdvisoryLockHolderParams) (*AdvisoryLockHolderRow, error) {
+	row := q.db.QueryRow(ctx, advisoryLockHolder, arg.ClassID, arg.ObjID)
+	var i AdvisoryLockHolderRow
+	err := row.Scan(&i.Pid, &i.ApplicationName)
+	return &i, err
+}
@@ -0,0 +1,26 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: advisory_unlock.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const advisoryUnlock = `-- name: AdvisoryUnlock :one
+SELECT pg_advisory_unlock($1::int4, $2::int4)::boolean AS unlocked
+`
+
+type AdvisoryUnlockParams struct {
+	ClassID int32
+	ObjID   int32
+}
+
+func (q *Queries) AdvisoryUnlock(ctx context.Context, arg AdvisoryUnlockParams) (bool, error) {
+	row := q.db.QueryRow(ctx, advisoryUnlock, arg.ClassID, arg.ObjID)
+	var unlocked bool
+	err := row.Scan(&unlocked)
+	return unlocked, err
+}
@@ -0,0 +1,24 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: advisory_xact_lock.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const advisoryXactLock = `-- name: AdvisoryXactLock :exec
+SELECT pg_advisory_xact_lock($1::int4, $2::int4)
+`
+
+type AdvisoryXactLockParams struct {
+	ClassID int32
+	ObjID   int32
+}
+
+func (q *Queries) AdvisoryXactLock(ctx context.Context, arg AdvisoryXactLockParams) error {
+	_, err := q.db.Exec(ctx, advisoryXactLock, arg.ClassID, arg.ObjID)
+	return err
+}
@@ -0,0 +1,20 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: clear_history_items.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const clearHistoryItems = `-- name: ClearHistoryItems :exec
+DELETE FROM ingest.history_items
+WHERE mrf_file_id = $1
+`
+
+func (q *Queries) ClearHistoryItems(ctx context.Context, mrfFileID int64) error {
+	_, err := q.db.Exec(ctx, clearHistoryItems, mrfFileID)
+	return err
+}
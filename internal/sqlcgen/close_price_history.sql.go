@@ -0,0 +1,46 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: close_price_history.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const closePriceHistory = `-- name: ClosePriceHistory :execrows
+UPDATE mrf.price_history h
+SET valid_to = $1::date
+WHERE h.hospital_id = $2
+  AND h.valid_to IS NULL
+  AND h.valid_from < $1::date
+  AND NOT EXISTS (
+    SELECT 1 FROM ingest.history_items i
+    WHERE i.mrf_file_id = $3
+      AND i.item_key = h.item_key
+      AND i.gross_charge_cents IS NOT DISTINCT FROM h.gross_charge_cents
+      AND i.discounted_cash_cents IS NOT DISTINCT FROM h.discounted_cash_cents
+      AND i.negotiated_dollar_cents IS NOT DISTINCT FROM h.negotiated_dollar_cents
+      AND i.negotiated_percentage_bps IS NOT DISTINCT FROM h.negotiated_percentage_bps
+      AND i.estimated_amount_cents IS NOT DISTINCT FROM h.estimated_amount_cents
+  )
+`
+
+type ClosePriceHistoryParams struct {
+	ValidFrom  pgtype.Date
+	HospitalID int64
+	MrfFileID  int64
+}
+
+// Ends the current prices of the hospital's items that the file (see
+// FillHistoryItems) dropped or repriced, as of the file's valid_from.
+func (q *Queries) ClosePriceHistory(ctx context.Context, arg ClosePriceHistoryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, closePriceHistory, arg.ValidFrom, arg.HospitalID, arg.MrfFileID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,44 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: count_rejects_by_reason.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const countRejectsByReason = `-- name: CountRejectsByReason :many
+SELECT reason_code, coalesce(field_name, '')::text AS field_name, count(*) AS row_count
+FROM ingest.rejected_rows
+WHERE mrf_file_id = $1
+GROUP BY reason_code, field_name
+ORDER BY row_count DESC, reason_code, field_name
+`
+
+type CountRejectsByReasonRow struct {
+	ReasonCode string
+	FieldName  string
+	RowCount   int64
+}
+
+func (q *Queries) CountRejectsByReason(ctx context.Context, mrfFileID int64) ([]*CountRejectsByReasonRow, error) {
+	rows, err := q.db.Query(ctx, countRejectsByReason, mrfFileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*CountRejectsByReasonRow
+	for rows.Next() {
+		var i CountRejectsByReasonRow
+		if err := rows.Scan(&i.ReasonCode, &i.FieldName, &i.RowCount); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,44 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: count_serving_by_code_type.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const countServingByCodeType = `-- name: CountServingByCodeType :many
+SELECT mrf_file_id, code_type, count(*)::bigint AS row_count
+FROM mrf.prices_by_code
+WHERE mrf_file_id = ANY($1::bigint[])
+GROUP BY mrf_file_id, code_type
+ORDER BY mrf_file_id, code_type
+`
+
+type CountServingByCodeTypeRow struct {
+	MrfFileID int64
+	CodeType  string
+	RowCount  int64
+}
+
+func (q *Queries) CountServingByCodeType(ctx context.Context, mrfFileIds []int64) ([]*CountServingByCodeTypeRow, error) {
+	rows, err := q.db.Query(ctx, countServingByCodeType, mrfFileIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*CountServingByCodeTypeRow
+	for rows.Next() {
+		var i CountServingByCodeTypeRow
+		if err := rows.Scan(&i.MrfFileID, &i.CodeType, &i.RowCount); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
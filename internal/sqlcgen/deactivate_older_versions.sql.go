@@ -13,7 +13,8 @@ import (
 
 const deactivateOlderVersions = `-- name: DeactivateOlderVersions :execresult
 UPDATE ingest.mrf_files
-SET is_active = false
+SET is_active = false,
+    status = CASE WHEN status = 'active' THEN 'transformed' ELSE status END
 WHERE hospital_id = $1
   AND mrf_file_id <> $2
   AND is_active = true
@@ -24,6 +25,8 @@ type DeactivateOlderVersionsParams struct {
 	MrfFileID  int64
 }
 
+// A replaced 'active' version goes back to 'transformed', so status
+// 'active' only ever names a hospital's current version.
 func (q *Queries) DeactivateOlderVersions(ctx context.Context, arg DeactivateOlderVersionsParams) (pgconn.CommandTag, error) {
 	return q.db.Exec(ctx, deactivateOlderVersions, arg.HospitalID, arg.MrfFileID)
 }
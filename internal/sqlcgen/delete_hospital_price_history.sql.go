@@ -0,0 +1,22 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: delete_hospital_price_history.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const deleteHospitalPriceHistory = `-- name: DeleteHospitalPriceHistory :execrows
+DELETE FROM mrf.price_history WHERE hospital_id = $1
+`
+
+func (q *Queries) DeleteHospitalPriceHistory(ctx context.Context, hospitalID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteHospitalPriceHistory, hospitalID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
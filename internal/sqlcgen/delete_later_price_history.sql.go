@@ -0,0 +1,33 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: delete_later_price_history.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const deleteLaterPriceHistory = `-- name: DeleteLaterPriceHistory :execrows
+DELETE FROM mrf.price_history
+WHERE hospital_id = $1
+  AND valid_from > $2::date
+`
+
+type DeleteLaterPriceHistoryParams struct {
+	HospitalID int64
+	ValidFrom  pgtype.Date
+}
+
+// Drops the hospital's periods that start after valid_from: the versions
+// that opened them are being rolled back.
+func (q *Queries) DeleteLaterPriceHistory(ctx context.Context, arg DeleteLaterPriceHistoryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteLaterPriceHistory, arg.HospitalID, arg.ValidFrom)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,23 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: delete_mrf_file.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const deleteMRFFile = `-- name: DeleteMRFFile :execrows
+DELETE FROM ingest.mrf_files
+WHERE mrf_file_id = $1 AND NOT is_active
+`
+
+func (q *Queries) DeleteMRFFile(ctx context.Context, mrfFileID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteMRFFile, mrfFileID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
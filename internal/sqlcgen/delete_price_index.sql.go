@@ -0,0 +1,29 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: delete_price_index.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const deletePriceIndex = `-- name: DeletePriceIndex :execrows
+DELETE FROM mrf.price_index
+WHERE basket_name = $1 AND mrf_file_id = ANY($2::bigint[])
+`
+
+type DeletePriceIndexParams struct {
+	BasketName string
+	MrfFileIds []int64
+}
+
+// Deletes a basket's index rows of the given versions, with their items.
+func (q *Queries) DeletePriceIndex(ctx context.Context, arg DeletePriceIndexParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePriceIndex, arg.BasketName, arg.MrfFileIds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
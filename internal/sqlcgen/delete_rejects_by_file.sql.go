@@ -0,0 +1,19 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: delete_rejects_by_file.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const deleteRejectsByFile = `-- name: DeleteRejectsByFile :exec
+DELETE FROM ingest.rejected_rows WHERE mrf_file_id = $1
+`
+
+func (q *Queries) DeleteRejectsByFile(ctx context.Context, mrfFileID int64) error {
+	_, err := q.db.Exec(ctx, deleteRejectsByFile, mrfFileID)
+	return err
+}
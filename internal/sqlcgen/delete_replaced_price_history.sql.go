@@ -0,0 +1,46 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: delete_replaced_price_history.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const deleteReplacedPriceHistory = `-- name: DeleteReplacedPriceHistory :execrows
+DELETE FROM mrf.price_history h
+WHERE h.hospital_id = $1
+  AND h.valid_to IS NULL
+  AND h.valid_from = $2::date
+  AND NOT EXISTS (
+    SELECT 1 FROM ingest.history_items i
+    WHERE i.mrf_file_id = $3
+      AND i.item_key = h.item_key
+      AND i.gross_charge_cents IS NOT DISTINCT FROM h.gross_charge_cents
+      AND i.discounted_cash_cents IS NOT DISTINCT FROM h.discounted_cash_cents
+      AND i.negotiated_dollar_cents IS NOT DISTINCT FROM h.negotiated_dollar_cents
+      AND i.negotiated_percentage_bps IS NOT DISTINCT FROM h.negotiated_percentage_bps
+      AND i.estimated_amount_cents IS NOT DISTINCT FROM h.estimated_amount_cents
+  )
+`
+
+type DeleteReplacedPriceHistoryParams struct {
+	HospitalID int64
+	ValidFrom  pgtype.Date
+	MrfFileID  int64
+}
+
+// Drops current prices recorded on the file's own valid_from that the file
+// (see FillHistoryItems) drops or reprices: a later file with the same
+// date replaces them.
+func (q *Queries) DeleteReplacedPriceHistory(ctx context.Context, arg DeleteReplacedPriceHistoryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteReplacedPriceHistory, arg.HospitalID, arg.ValidFrom, arg.MrfFileID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
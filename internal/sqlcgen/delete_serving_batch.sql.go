@@ -0,0 +1,34 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: delete_serving_batch.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const deleteServingBatch = `-- name: DeleteServingBatch :execrows
+DELETE FROM mrf.prices_by_code p
+WHERE (p.price_row_id, p.code_type) IN (
+  SELECT b.price_row_id, b.code_type FROM mrf.prices_by_code b
+  WHERE b.mrf_file_id = $1
+  LIMIT $2::int
+)
+`
+
+type DeleteServingBatchParams struct {
+	MrfFileID int64
+	BatchRows int32
+}
+
+// Deletes up to batch_rows serving rows of a file, keeping each statement's
+// locks and WAL short.
+func (q *Queries) DeleteServingBatch(ctx context.Context, arg DeleteServingBatchParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteServingBatch, arg.MrfFileID, arg.BatchRows)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,43 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: delete_state_stats.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const deleteStateStats = `-- name: DeleteStateStats :execrows
+WITH k AS (
+  SELECT unnest($1::text[]) AS code_type, unnest($2::text[]) AS code_norm,
+         unnest($3::text[]) AS setting, unnest($4::text[]) AS license_state
+)
+DELETE FROM mrf.price_stats_by_state s
+USING k
+WHERE s.code_type = k.code_type AND s.code_norm = k.code_norm
+  AND COALESCE(s.setting, '') = k.setting AND s.license_state = k.license_state
+`
+
+type DeleteStateStatsParams struct {
+	CodeTypes []string
+	CodeNorms []string
+	Settings  []string
+	States    []string
+}
+
+// Deletes the mrf.price_stats_by_state rows of the given keys, as passed to
+// InsertStateStats.
+func (q *Queries) DeleteStateStats(ctx context.Context, arg DeleteStateStatsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteStateStats,
+		arg.CodeTypes,
+		arg.CodeNorms,
+		arg.Settings,
+		arg.States,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
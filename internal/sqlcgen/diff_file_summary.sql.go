@@ -0,0 +1,136 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: diff_file_summary.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const diffFileSummary = `-- name: DiffFileSummary :many
+WITH a AS (
+  SELECT p.code_type, p.code_norm, COALESCE(p.setting, '')::text AS setting,
+         COALESCE(p.payer_id, 0)::bigint AS payer_id, COALESCE(p.plan_id, 0)::bigint AS plan_id,
+         COALESCE(p.modifiers, '')::text AS modifiers,
+         min(p.gross_charge_cents)::bigint AS gross_cents,
+         min(p.negotiated_dollar_cents)::bigint AS negotiated_cents,
+         min(p.negotiated_percentage_bps)::integer AS pct_bps
+  FROM mrf.prices_by_code p
+  WHERE p.mrf_file_id = $1
+  GROUP BY 1, 2, 3, 4, 5, 6
+), b AS (
+  SELECT p.code_type, p.code_norm, COALESCE(p.setting, '')::text AS setting,
+         COALESCE(p.payer_id, 0)::bigint AS payer_id, COALESCE(p.plan_id, 0)::bigint AS plan_id,
+         COALESCE(p.modifiers, '')::text AS modifiers,
+         min(p.gross_charge_cents)::bigint AS gross_cents,
+         min(p.negotiated_dollar_cents)::bigint AS negotiated_cents,
+         min(p.negotiated_percentage_bps)::integer AS pct_bps
+  FROM mrf.prices_by_code p
+  WHERE p.mrf_file_id = $2
+  GROUP BY 1, 2, 3, 4, 5, 6
+), j AS (
+  SELECT COALESCE(a.code_type, b.code_type)::text AS code_type,
+         COALESCE(a.payer_id, b.payer_id)::bigint AS payer_id,
+         (a.code_type IS NOT NULL)::boolean AS in_from,
+         (b.code_type IS NOT NULL)::boolean AS in_to,
+         (a.gross_cents IS DISTINCT FROM b.gross_cents
+          OR a.negotiated_cents IS DISTINCT FROM b.negotiated_cents
+          OR a.pct_bps IS DISTINCT FROM b.pct_bps)::boolean AS differs,
+         (a.pct_bps IS DISTINCT FROM b.pct_bps)::boolean AS pct_rate_differs,
+         CASE WHEN COALESCE(a.payer_id, b.payer_id) = 0 THEN a.gross_cents ELSE a.negotiated_cents END AS from_cents,
+         CASE WHEN COALESCE(a.payer_id, b.payer_id) = 0 THEN b.gross_cents ELSE b.negotiated_cents END AS to_cents
+  FROM a
+  FULL JOIN b ON b.code_type = a.code_type AND b.code_norm = a.code_norm
+    AND b.setting = a.setting AND b.payer_id = a.payer_id
+    AND b.plan_id = a.plan_id AND b.modifiers = a.modifiers
+), d AS (
+  SELECT j.code_type, j.payer_id, j.in_from, j.in_to, j.differs, j.pct_rate_differs, j.from_cents, j.to_cents,
+         CASE WHEN j.from_cents > 0
+              THEN (j.to_cents - j.from_cents) * 100.0 / j.from_cents
+         END AS pct_delta
+  FROM j
+)
+SELECT d.code_type, py.payer_name,
+       count(*) FILTER (WHERE NOT d.in_from)::bigint AS added,
+       count(*) FILTER (WHERE NOT d.in_to)::bigint AS removed,
+       count(*) FILTER (WHERE d.in_from AND d.in_to AND d.differs)::bigint AS changed,
+       count(*) FILTER (WHERE d.in_from AND d.in_to AND NOT d.differs)::bigint AS unchanged,
+       count(*) FILTER (WHERE d.to_cents > d.from_cents)::bigint AS increased,
+       count(*) FILTER (WHERE d.to_cents < d.from_cents)::bigint AS decreased,
+       count(*) FILTER (WHERE d.in_from AND d.in_to AND d.pct_rate_differs)::bigint AS negotiated_pct_changed,
+       count(d.pct_delta) FILTER (WHERE d.from_cents <> d.to_cents)::bigint AS pct_items,
+       COALESCE(round(avg(d.pct_delta) FILTER (WHERE d.from_cents <> d.to_cents), 2), 0)::float8 AS avg_pct_delta,
+       COALESCE(round((percentile_cont(0.5) WITHIN GROUP (ORDER BY d.pct_delta)
+                FILTER (WHERE d.from_cents <> d.to_cents))::numeric, 2), 0)::float8 AS median_pct_delta,
+       COALESCE(round(min(d.pct_delta) FILTER (WHERE d.from_cents <> d.to_cents), 2), 0)::float8 AS min_pct_delta,
+       COALESCE(round(max(d.pct_delta) FILTER (WHERE d.from_cents <> d.to_cents), 2), 0)::float8 AS max_pct_delta
+FROM d
+LEFT JOIN ref.payers py ON py.payer_id = d.payer_id
+GROUP BY d.code_type, d.payer_id, py.payer_name
+ORDER BY d.code_type, py.payer_name NULLS FIRST
+`
+
+type DiffFileSummaryParams struct {
+	FromFileID int64
+	ToFileID   int64
+}
+
+type DiffFileSummaryRow struct {
+	CodeType             string
+	PayerName            *string
+	Added                int64
+	Removed              int64
+	Changed              int64
+	Unchanged            int64
+	Increased            int64
+	Decreased            int64
+	NegotiatedPctChanged int64
+	PctItems             int64
+	AvgPctDelta          float64
+	MedianPctDelta       float64
+	MinPctDelta          float64
+	MaxPctDelta          float64
+}
+
+// DiffFileItems counted per code type and payer (NULL payer: the
+// hospital's own charges), with unchanged items, items whose negotiated
+// percentage changed, and the spread of percent price changes over the
+// pct_items repriced items with a positive old price (0 when there are
+// none). An item's price is its negotiated dollar amount for a payer and
+// its gross charge otherwise, with no fallback between the two.
+func (q *Queries) DiffFileSummary(ctx context.Context, arg DiffFileSummaryParams) ([]*DiffFileSummaryRow, error) {
+	rows, err := q.db.Query(ctx, diffFileSummary, arg.FromFileID, arg.ToFileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*DiffFileSummaryRow
+	for rows.Next() {
+		var i DiffFileSummaryRow
+		if err := rows.Scan(
+			&i.CodeType,
+			&i.PayerName,
+			&i.Added,
+			&i.Removed,
+			&i.Changed,
+			&i.Unchanged,
+			&i.Increased,
+			&i.Decreased,
+			&i.NegotiatedPctChanged,
+			&i.PctItems,
+			&i.AvgPctDelta,
+			&i.MedianPctDelta,
+			&i.MinPctDelta,
+			&i.MaxPctDelta,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,29 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: extend_price_history.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const extendPriceHistory = `-- name: ExtendPriceHistory :exec
+UPDATE mrf.price_history
+SET last_mrf_file_id = $1
+WHERE hospital_id = $2
+  AND valid_to IS NULL
+`
+
+type ExtendPriceHistoryParams struct {
+	MrfFileID  int64
+	HospitalID int64
+}
+
+// Credits the hospital's still-current prices to the file that republished
+// them. Run after ClosePriceHistory and DeleteReplacedPriceHistory.
+func (q *Queries) ExtendPriceHistory(ctx context.Context, arg ExtendPriceHistoryParams) error {
+	_, err := q.db.Exec(ctx, extendPriceHistory, arg.MrfFileID, arg.HospitalID)
+	return err
+}
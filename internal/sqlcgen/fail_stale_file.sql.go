@@ -0,0 +1,32 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: fail_stale_file.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const failStaleFile = `-- name: FailStaleFile :execrows
+UPDATE ingest.mrf_files
+SET status = 'failed', heartbeat_at = now()
+WHERE mrf_file_id = $1
+  AND status IN ('pending', 'staging', 'staged', 'transforming')
+  AND coalesce(heartbeat_at, imported_at) < now() - make_interval(secs => $2::float8)
+`
+
+type FailStaleFileParams struct {
+	MrfFileID      int64
+	StaleAfterSecs float64
+}
+
+// Re-checks staleness so a file whose ingest resumed heartbeating is left alone.
+func (q *Queries) FailStaleFile(ctx context.Context, arg FailStaleFileParams) (int64, error) {
+	result, err := q.db.Exec(ctx, failStaleFile, arg.MrfFileID, arg.StaleAfterSecs)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,41 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: fill_history_items.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const fillHistoryItems = `-- name: FillHistoryItems :execrows
+INSERT INTO ingest.history_items (
+  mrf_file_id, item_key, code_type, code_norm, setting, payer_id, plan_id, modifiers, description,
+  gross_charge_cents, discounted_cash_cents, negotiated_dollar_cents, negotiated_percentage_bps,
+  estimated_amount_cents
+)
+SELECT $1::bigint,
+       decode(md5(concat_ws(chr(31), p.code_type, p.code_norm, COALESCE(p.setting, ''),
+         COALESCE(p.payer_id, 0)::text, COALESCE(p.plan_id, 0)::text, COALESCE(p.modifiers, ''))), 'hex'),
+       p.code_type, p.code_norm, COALESCE(p.setting, ''), COALESCE(p.payer_id, 0), COALESCE(p.plan_id, 0),
+       COALESCE(p.modifiers, ''),
+       min(p.description),
+       min(p.gross_charge_cents), min(p.discounted_cash_cents), min(p.negotiated_dollar_cents),
+       min(p.negotiated_percentage_bps), min(p.estimated_amount_cents)
+FROM mrf.prices_by_code p
+WHERE p.mrf_file_id = $1
+GROUP BY 2, 3, 4, 5, 6, 7, 8
+`
+
+// Builds the file's items in ingest.history_items. An item is keyed by
+// (code_type, code_norm, setting, payer_id, plan_id, modifiers) with NULL
+// key parts comparing equal, and priced by the lowest of each amount among
+// its rows.
+func (q *Queries) FillHistoryItems(ctx context.Context, mrfFileID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, fillHistoryItems, mrfFileID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
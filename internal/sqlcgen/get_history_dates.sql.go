@@ -0,0 +1,36 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: get_history_dates.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const getHistoryDates = `-- name: GetHistoryDates :one
+SELECT f.hospital_id,
+       COALESCE(f.last_updated_on, f.imported_at::date)::date AS valid_from,
+       COALESCE((SELECT max(h.valid_from) FROM mrf.price_history h
+                 WHERE h.hospital_id = f.hospital_id), '0001-01-01')::date AS latest_valid_from
+FROM ingest.mrf_files f
+WHERE f.mrf_file_id = $1
+`
+
+type GetHistoryDatesRow struct {
+	HospitalID      int64
+	ValidFrom       pgtype.Date
+	LatestValidFrom pgtype.Date
+}
+
+// The date a file's prices take effect in mrf.price_history, and the latest
+// date already recorded for its hospital (0001-01-01 when none).
+func (q *Queries) GetHistoryDates(ctx context.Context, mrfFileID int64) (*GetHistoryDatesRow, error) {
+	row := q.db.QueryRow(ctx, getHistoryDates, mrfFileID)
+	var i GetHistoryDatesRow
+	err := row.Scan(&i.HospitalID, &i.ValidFrom, &i.LatestValidFrom)
+	return &i, err
+}
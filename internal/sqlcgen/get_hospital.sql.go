@@ -0,0 +1,26 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: get_hospital.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const getHospital = `-- name: GetHospital :one
+SELECT hospital_id, hospital_name FROM ref.hospitals WHERE hospital_id = $1
+`
+
+type GetHospitalRow struct {
+	HospitalID   int64
+	HospitalName string
+}
+
+func (q *Queries) GetHospital(ctx context.Context, hospitalID int64) (*GetHospitalRow, error) {
+	row := q.db.QueryRow(ctx, getHospital, hospitalID)
+	var i GetHospitalRow
+	err := row.Scan(&i.HospitalID, &i.HospitalName)
+	return &i, err
+}
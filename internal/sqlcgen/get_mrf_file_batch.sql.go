@@ -0,0 +1,52 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: get_mrf_file_batch.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const getMRFFileBatch = `-- name: GetMRFFileBatch :one
+SELECT f.status, f.ingest_batch_id, f.rows_read, f.rows_staged, f.rows_rejected,
+       f.staged_through_row,
+       (SELECT count(*) FROM ingest.stage_charge_rows s
+        WHERE s.mrf_file_id = f.mrf_file_id
+          AND s.ingest_batch_id = f.ingest_batch_id)::bigint AS staged_now,
+       (SELECT count(*) FROM ingest.rejected_rows r
+        WHERE r.mrf_file_id = f.mrf_file_id
+          AND r.ingest_batch_id = f.ingest_batch_id)::bigint AS rejected_now
+FROM ingest.mrf_files f
+WHERE f.mrf_file_id = $1
+`
+
+type GetMRFFileBatchRow struct {
+	Status           string
+	IngestBatchID    *uuid.UUID
+	RowsRead         *int64
+	RowsStaged       *int64
+	RowsRejected     *int64
+	StagedThroughRow *int64
+	StagedNow        int64
+	RejectedNow      int64
+}
+
+func (q *Queries) GetMRFFileBatch(ctx context.Context, mrfFileID int64) (*GetMRFFileBatchRow, error) {
+	row := q.db.QueryRow(ctx, getMRFFileBatch, mrfFileID)
+	var i GetMRFFileBatchRow
+	err := row.Scan(
+		&i.Status,
+		&i.IngestBatchID,
+		&i.RowsRead,
+		&i.RowsStaged,
+		&i.RowsRejected,
+		&i.StagedThroughRow,
+		&i.StagedNow,
+		&i.RejectedNow,
+	)
+	return &i, err
+}
@@ -0,0 +1,40 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: insert_hospital_stats.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const insertHospitalStats = `-- name: InsertHospitalStats :execrows
+WITH g AS (
+  SELECT p.code_type, p.code_norm, p.setting, p.payer_id, p.mrf_file_id, count(*) AS row_count,
+         array_agg(p.negotiated_dollar_cents) AS negotiated, array_agg(p.gross_charge_cents) AS gross,
+         array_agg(p.discounted_cash_cents) AS cash
+  FROM mrf.prices_by_code p
+  JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+  WHERE p.hospital_id = $1
+  GROUP BY p.code_type, p.code_norm, p.setting, p.payer_id, p.mrf_file_id
+)
+INSERT INTO mrf.price_stats
+SELECT g.code_type, g.code_norm, g.setting, h.hospital_id, g.payer_id, h.license_state, g.mrf_file_id, g.row_count,
+       n.count, n.min_cents, n.p10_cents, n.p25_cents, n.median_cents, n.p75_cents, n.p90_cents, n.max_cents, n.iqr_cents, n.cv, gr.count, gr.min_cents, gr.p10_cents, gr.p25_cents, gr.median_cents, gr.p75_cents, gr.p90_cents, gr.max_cents, gr.iqr_cents, gr.cv, c.count, c.min_cents, c.p10_cents, c.p25_cents, c.median_cents, c.p75_cents, c.p90_cents, c.max_cents, c.iqr_cents, c.cv
+FROM g
+JOIN ref.hospitals h ON h.hospital_id = $1
+CROSS JOIN LATERAL mrf.amount_stats(g.negotiated) n
+CROSS JOIN LATERAL mrf.amount_stats(g.gross) gr
+CROSS JOIN LATERAL mrf.amount_stats(g.cash) c
+`
+
+// Computes a hospital's mrf.price_stats rows from its active version (see
+// mrf.amount_stats for the per-column statistics).
+func (q *Queries) InsertHospitalStats(ctx context.Context, hospitalID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, insertHospitalStats, hospitalID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,49 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: insert_price_history.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const insertPriceHistory = `-- name: InsertPriceHistory :execrows
+INSERT INTO mrf.price_history (
+  hospital_id, item_key, code_type, code_norm, setting, payer_id, plan_id, modifiers, description,
+  gross_charge_cents, discounted_cash_cents, negotiated_dollar_cents, negotiated_percentage_bps,
+  estimated_amount_cents, valid_from, first_mrf_file_id, last_mrf_file_id
+)
+SELECT $1::bigint, i.item_key, i.code_type, i.code_norm, NULLIF(i.setting, ''),
+       NULLIF(i.payer_id, 0), NULLIF(i.plan_id, 0), NULLIF(i.modifiers, ''), i.description,
+       i.gross_charge_cents, i.discounted_cash_cents, i.negotiated_dollar_cents,
+       i.negotiated_percentage_bps, i.estimated_amount_cents,
+       $2::date, i.mrf_file_id, i.mrf_file_id
+FROM ingest.history_items i
+WHERE i.mrf_file_id = $3
+  AND NOT EXISTS (
+    SELECT 1 FROM mrf.price_history h
+    WHERE h.hospital_id = $1
+      AND h.item_key = i.item_key
+      AND h.valid_to IS NULL
+  )
+`
+
+type InsertPriceHistoryParams struct {
+	HospitalID int64
+	ValidFrom  pgtype.Date
+	MrfFileID  int64
+}
+
+// Opens a history row for each item of the file (see FillHistoryItems)
+// without current prices.
+func (q *Queries) InsertPriceHistory(ctx context.Context, arg InsertPriceHistoryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertPriceHistory, arg.HospitalID, arg.ValidFrom, arg.MrfFileID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
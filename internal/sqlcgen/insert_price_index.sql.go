@@ -0,0 +1,49 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: insert_price_index.sql
+
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+const insertPriceIndex = `-- name: InsertPriceIndex :one
+INSERT INTO mrf.price_index (basket_name, hospital_id, mrf_file_id, last_updated_on, payer_id,
+                             index_value, items_total, items_priced, weight_coverage)
+VALUES ($1, $2, $3, $4,
+        $5, $6, $7, $8,
+        $9)
+RETURNING price_index_id
+`
+
+type InsertPriceIndexParams struct {
+	BasketName     string
+	HospitalID     int64
+	MrfFileID      int64
+	LastUpdatedOn  *time.Time
+	PayerID        *int64
+	IndexValue     *float64
+	ItemsTotal     int32
+	ItemsPriced    int32
+	WeightCoverage float64
+}
+
+func (q *Queries) InsertPriceIndex(ctx context.Context, arg InsertPriceIndexParams) (int64, error) {
+	row := q.db.QueryRow(ctx, insertPriceIndex,
+		arg.BasketName,
+		arg.HospitalID,
+		arg.MrfFileID,
+		arg.LastUpdatedOn,
+		arg.PayerID,
+		arg.IndexValue,
+		arg.ItemsTotal,
+		arg.ItemsPriced,
+		arg.WeightCoverage,
+	)
+	var price_index_id int64
+	err := row.Scan(&price_index_id)
+	return price_index_id, err
+}
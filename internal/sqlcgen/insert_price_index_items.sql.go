@@ -0,0 +1,30 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: insert_price_index_items.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const insertPriceIndexItems = `-- name: InsertPriceIndexItems :exec
+INSERT INTO mrf.price_index_items (price_index_id, code_type, code_norm, weight, price_cents, reference_cents, missing)
+SELECT $1, i.code_type, i.code_norm, i.weight, i.price_cents, i.reference_cents,
+       i.price_cents IS NULL
+FROM jsonb_to_recordset($2::jsonb)
+  AS i(code_type text, code_norm text, weight double precision, price_cents bigint, reference_cents bigint)
+`
+
+type InsertPriceIndexItemsParams struct {
+	PriceIndexID int64
+	Items        []byte
+}
+
+// Inserts an index row's items from a JSON array of
+// {code_type, code_norm, weight, price_cents, reference_cents} objects.
+func (q *Queries) InsertPriceIndexItems(ctx context.Context, arg InsertPriceIndexItemsParams) error {
+	_, err := q.db.Exec(ctx, insertPriceIndexItems, arg.PriceIndexID, arg.Items)
+	return err
+}
@@ -0,0 +1,58 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: insert_state_stats.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const insertStateStats = `-- name: InsertStateStats :execrows
+WITH k AS (
+  SELECT DISTINCT unnest($1::text[]) AS code_type, unnest($2::text[]) AS code_norm,
+         unnest($3::text[]) AS setting, unnest($4::text[]) AS license_state
+), g AS (
+  SELECT p.code_type, p.code_norm, p.setting, h.license_state,
+         count(DISTINCT p.hospital_id) AS hospital_count, count(*) AS row_count,
+         array_agg(p.negotiated_dollar_cents) AS negotiated, array_agg(p.gross_charge_cents) AS gross,
+         array_agg(p.discounted_cash_cents) AS cash
+  FROM k
+  JOIN mrf.prices_by_code p ON p.code_type = k.code_type AND p.code_norm = k.code_norm
+                           AND COALESCE(p.setting, '') = k.setting
+  JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+  JOIN ref.hospitals h ON h.hospital_id = p.hospital_id AND h.license_state = k.license_state
+  GROUP BY p.code_type, p.code_norm, p.setting, h.license_state
+)
+INSERT INTO mrf.price_stats_by_state
+SELECT g.code_type, g.code_norm, g.setting, g.license_state, g.hospital_count, g.row_count,
+       n.count, n.min_cents, n.p10_cents, n.p25_cents, n.median_cents, n.p75_cents, n.p90_cents, n.max_cents, n.iqr_cents, n.cv, gr.count, gr.min_cents, gr.p10_cents, gr.p25_cents, gr.median_cents, gr.p75_cents, gr.p90_cents, gr.max_cents, gr.iqr_cents, gr.cv, c.count, c.min_cents, c.p10_cents, c.p25_cents, c.median_cents, c.p75_cents, c.p90_cents, c.max_cents, c.iqr_cents, c.cv
+FROM g
+CROSS JOIN LATERAL mrf.amount_stats(g.negotiated) n
+CROSS JOIN LATERAL mrf.amount_stats(g.gross) gr
+CROSS JOIN LATERAL mrf.amount_stats(g.cash) c
+`
+
+type InsertStateStatsParams struct {
+	CodeTypes []string
+	CodeNorms []string
+	Settings  []string
+	States    []string
+}
+
+// Computes the mrf.price_stats_by_state rows of the given (code_type,
+// code_norm, setting, license_state) keys; a setting of ” stands for none.
+// See mrf.amount_stats for the per-column statistics.
+func (q *Queries) InsertStateStats(ctx context.Context, arg InsertStateStatsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertStateStats,
+		arg.CodeTypes,
+		arg.CodeNorms,
+		arg.Settings,
+		arg.States,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
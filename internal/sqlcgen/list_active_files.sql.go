@@ -0,0 +1,51 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_active_files.sql
+
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+const listActiveFiles = `-- name: ListActiveFiles :many
+SELECT f.mrf_file_id, f.hospital_id, h.hospital_name, f.last_updated_on
+FROM ingest.mrf_files f
+JOIN ref.hospitals h ON h.hospital_id = f.hospital_id
+WHERE f.is_active
+ORDER BY f.hospital_id
+`
+
+type ListActiveFilesRow struct {
+	MrfFileID     int64
+	HospitalID    int64
+	HospitalName  string
+	LastUpdatedOn *time.Time
+}
+
+func (q *Queries) ListActiveFiles(ctx context.Context) ([]*ListActiveFilesRow, error) {
+	rows, err := q.db.Query(ctx, listActiveFiles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListActiveFilesRow
+	for rows.Next() {
+		var i ListActiveFilesRow
+		if err := rows.Scan(
+			&i.MrfFileID,
+			&i.HospitalID,
+			&i.HospitalName,
+			&i.LastUpdatedOn,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
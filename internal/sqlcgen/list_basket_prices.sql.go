@@ -0,0 +1,73 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_basket_prices.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const listBasketPrices = `-- name: ListBasketPrices :many
+WITH b AS (
+  SELECT unnest($1::text[]) AS code_type, unnest($2::text[]) AS code_norm
+), r AS (
+  SELECT p.mrf_file_id, p.payer_id, p.code_type, p.code_norm,
+         CASE WHEN p.payer_id IS NULL THEN p.gross_charge_cents ELSE p.negotiated_dollar_cents END AS price
+  FROM b
+  JOIN mrf.prices_by_code p ON p.code_type = b.code_type AND p.code_norm = b.code_norm
+  JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+)
+SELECT r.mrf_file_id, r.payer_id, py.payer_name, r.code_type, r.code_norm,
+       round(percentile_cont(0.5) WITHIN GROUP (ORDER BY r.price))::bigint AS price_cents
+FROM r
+LEFT JOIN ref.payers py ON py.payer_id = r.payer_id
+WHERE r.price IS NOT NULL
+GROUP BY r.mrf_file_id, r.payer_id, py.payer_name, r.code_type, r.code_norm
+ORDER BY r.mrf_file_id, r.payer_id NULLS FIRST
+`
+
+type ListBasketPricesParams struct {
+	CodeTypes []string
+	CodeNorms []string
+}
+
+type ListBasketPricesRow struct {
+	MrfFileID  int64
+	PayerID    *int64
+	PayerName  *string
+	CodeType   string
+	CodeNorm   string
+	PriceCents int64
+}
+
+// The median price of each basket code per active version and payer: the
+// negotiated dollar amount for payer rows, the gross charge for the
+// hospital's own (payer-less) rows.
+func (q *Queries) ListBasketPrices(ctx context.Context, arg ListBasketPricesParams) ([]*ListBasketPricesRow, error) {
+	rows, err := q.db.Query(ctx, listBasketPrices, arg.CodeTypes, arg.CodeNorms)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListBasketPricesRow
+	for rows.Next() {
+		var i ListBasketPricesRow
+		if err := rows.Scan(
+			&i.MrfFileID,
+			&i.PayerID,
+			&i.PayerName,
+			&i.CodeType,
+			&i.CodeNorm,
+			&i.PriceCents,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
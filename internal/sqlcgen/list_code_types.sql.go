@@ -0,0 +1,48 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_code_types.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const listCodeTypes = `-- name: ListCodeTypes :many
+SELECT code_type, staging_column, partition_suffix, sort_order
+FROM ref.code_types
+ORDER BY sort_order
+`
+
+type ListCodeTypesRow struct {
+	CodeType        string
+	StagingColumn   string
+	PartitionSuffix string
+	SortOrder       int32
+}
+
+func (q *Queries) ListCodeTypes(ctx context.Context) ([]*ListCodeTypesRow, error) {
+	rows, err := q.db.Query(ctx, listCodeTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListCodeTypesRow
+	for rows.Next() {
+		var i ListCodeTypesRow
+		if err := rows.Scan(
+			&i.CodeType,
+			&i.StagingColumn,
+			&i.PartitionSuffix,
+			&i.SortOrder,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,49 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_hospital_stats_keys.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const listHospitalStatsKeys = `-- name: ListHospitalStatsKeys :many
+SELECT DISTINCT code_type, code_norm, COALESCE(setting, '')::text AS setting, license_state::text AS license_state
+FROM mrf.price_stats
+WHERE hospital_id = $1 AND license_state IS NOT NULL
+`
+
+type ListHospitalStatsKeysRow struct {
+	CodeType     string
+	CodeNorm     string
+	Setting      string
+	LicenseState string
+}
+
+// The state rollup keys a hospital's mrf.price_stats rows feed.
+func (q *Queries) ListHospitalStatsKeys(ctx context.Context, hospitalID int64) ([]*ListHospitalStatsKeysRow, error) {
+	rows, err := q.db.Query(ctx, listHospitalStatsKeys, hospitalID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListHospitalStatsKeysRow
+	for rows.Next() {
+		var i ListHospitalStatsKeysRow
+		if err := rows.Scan(
+			&i.CodeType,
+			&i.CodeNorm,
+			&i.Setting,
+			&i.LicenseState,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
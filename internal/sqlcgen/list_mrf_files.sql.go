@@ -0,0 +1,91 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_mrf_files.sql
+
+package sqlcgen
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const listMRFFiles = `-- name: ListMRFFiles :many
+SELECT f.mrf_file_id, f.hospital_id, h.hospital_name, f.source_file_name, f.source_file_sha256,
+       f.version, f.last_updated_on, f.status, f.is_active, f.imported_at,
+       f.ingest_batch_id, f.rows_read, f.rows_staged, f.rows_rejected
+FROM ingest.mrf_files f
+JOIN ref.hospitals h ON h.hospital_id = f.hospital_id
+WHERE ($1::bigint IS NULL OR f.mrf_file_id = $1::bigint)
+  AND ($2::text IS NULL OR h.hospital_name ILIKE '%' || $2::text || '%')
+  AND ($3::text IS NULL OR f.status = $3::text)
+  AND ($4::boolean IS NULL OR f.is_active = $4::boolean)
+ORDER BY h.hospital_name, f.mrf_file_id
+`
+
+type ListMRFFilesParams struct {
+	MrfFileID *int64
+	Hospital  *string
+	Status    *string
+	IsActive  *bool
+}
+
+type ListMRFFilesRow struct {
+	MrfFileID        int64
+	HospitalID       int64
+	HospitalName     string
+	SourceFileName   string
+	SourceFileSha256 string
+	Version          *string
+	LastUpdatedOn    *time.Time
+	Status           string
+	IsActive         bool
+	ImportedAt       pgtype.Timestamptz
+	IngestBatchID    *uuid.UUID
+	RowsRead         *int64
+	RowsStaged       *int64
+	RowsRejected     *int64
+}
+
+func (q *Queries) ListMRFFiles(ctx context.Context, arg ListMRFFilesParams) ([]*ListMRFFilesRow, error) {
+	rows, err := q.db.Query(ctx, listMRFFiles,
+		arg.MrfFileID,
+		arg.Hospital,
+		arg.Status,
+		arg.IsActive,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListMRFFilesRow
+	for rows.Next() {
+		var i ListMRFFilesRow
+		if err := rows.Scan(
+			&i.MrfFileID,
+			&i.HospitalID,
+			&i.HospitalName,
+			&i.SourceFileName,
+			&i.SourceFileSha256,
+			&i.Version,
+			&i.LastUpdatedOn,
+			&i.Status,
+			&i.IsActive,
+			&i.ImportedAt,
+			&i.IngestBatchID,
+			&i.RowsRead,
+			&i.RowsStaged,
+			&i.RowsRejected,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,62 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_orphan_batches.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const listOrphanBatches = `-- name: ListOrphanBatches :many
+SELECT s.mrf_file_id, s.ingest_batch_id, f.status,
+       coalesce(f.heartbeat_at, f.imported_at)::timestamptz AS last_seen,
+       count(*)::bigint AS staging_rows
+FROM ingest.stage_charge_rows s
+JOIN ingest.mrf_files f ON f.mrf_file_id = s.mrf_file_id
+WHERE (f.status IN ('failed', 'cancelled') OR f.ingest_batch_id IS DISTINCT FROM s.ingest_batch_id)
+  AND f.status NOT IN ('pending', 'staging', 'staged', 'transforming')
+  AND coalesce(f.heartbeat_at, f.imported_at) < now() - make_interval(secs => $1::float8)
+GROUP BY s.mrf_file_id, s.ingest_batch_id, f.status, f.heartbeat_at, f.imported_at
+ORDER BY s.mrf_file_id, s.ingest_batch_id
+`
+
+type ListOrphanBatchesRow struct {
+	MrfFileID     int64
+	IngestBatchID uuid.UUID
+	Status        string
+	LastSeen      pgtype.Timestamptz
+	StagingRows   int64
+}
+
+// Staging batches no ingest will use again: those of failed or cancelled
+// files, and batches superseded by a newer run of the file.
+func (q *Queries) ListOrphanBatches(ctx context.Context, orphanAfterSecs float64) ([]*ListOrphanBatchesRow, error) {
+	rows, err := q.db.Query(ctx, listOrphanBatches, orphanAfterSecs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListOrphanBatchesRow
+	for rows.Next() {
+		var i ListOrphanBatchesRow
+		if err := rows.Scan(
+			&i.MrfFileID,
+			&i.IngestBatchID,
+			&i.Status,
+			&i.LastSeen,
+			&i.StagingRows,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
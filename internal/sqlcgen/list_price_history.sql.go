@@ -0,0 +1,103 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_price_history.sql
+
+package sqlcgen
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const listPriceHistory = `-- name: ListPriceHistory :many
+SELECT ho.hospital_name, h.code_type, h.code_norm, h.description, h.setting,
+       py.payer_name, pl.plan_name, h.modifiers,
+       h.gross_charge_cents, h.discounted_cash_cents, h.negotiated_dollar_cents,
+       h.negotiated_percentage_bps, h.estimated_amount_cents,
+       h.valid_from, h.valid_to, h.first_mrf_file_id, h.last_mrf_file_id
+FROM mrf.price_history h
+JOIN ref.hospitals ho ON ho.hospital_id = h.hospital_id
+LEFT JOIN ref.payers py ON py.payer_id = h.payer_id
+LEFT JOIN ref.plans pl ON pl.plan_id = h.plan_id
+WHERE ho.hospital_name ILIKE '%' || $1::text || '%'
+  AND h.code_norm = ANY($2::text[])
+  AND ($3::text IS NULL OR h.code_type = $3::text)
+  AND ($4::text IS NULL OR py.payer_name ILIKE '%' || $4::text || '%')
+ORDER BY ho.hospital_name, h.code_type, h.code_norm, h.setting NULLS FIRST, py.payer_name NULLS FIRST,
+         pl.plan_name NULLS FIRST, h.modifiers NULLS FIRST, h.valid_from
+`
+
+type ListPriceHistoryParams struct {
+	Hospital  string
+	CodeNorms []string
+	CodeType  *string
+	Payer     *string
+}
+
+type ListPriceHistoryRow struct {
+	HospitalName            string
+	CodeType                string
+	CodeNorm                string
+	Description             string
+	Setting                 *string
+	PayerName               *string
+	PlanName                *string
+	Modifiers               *string
+	GrossChargeCents        *int64
+	DiscountedCashCents     *int64
+	NegotiatedDollarCents   *int64
+	NegotiatedPercentageBps *int32
+	EstimatedAmountCents    *int64
+	ValidFrom               pgtype.Date
+	ValidTo                 *time.Time
+	FirstMrfFileID          int64
+	LastMrfFileID           int64
+}
+
+// A code's price timeline at the matching hospitals, one row per item and
+// period, oldest first.
+func (q *Queries) ListPriceHistory(ctx context.Context, arg ListPriceHistoryParams) ([]*ListPriceHistoryRow, error) {
+	rows, err := q.db.Query(ctx, listPriceHistory,
+		arg.Hospital,
+		arg.CodeNorms,
+		arg.CodeType,
+		arg.Payer,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListPriceHistoryRow
+	for rows.Next() {
+		var i ListPriceHistoryRow
+		if err := rows.Scan(
+			&i.HospitalName,
+			&i.CodeType,
+			&i.CodeNorm,
+			&i.Description,
+			&i.Setting,
+			&i.PayerName,
+			&i.PlanName,
+			&i.Modifiers,
+			&i.GrossChargeCents,
+			&i.DiscountedCashCents,
+			&i.NegotiatedDollarCents,
+			&i.NegotiatedPercentageBps,
+			&i.EstimatedAmountCents,
+			&i.ValidFrom,
+			&i.ValidTo,
+			&i.FirstMrfFileID,
+			&i.LastMrfFileID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,83 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_price_index_trend.sql
+
+package sqlcgen
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const listPriceIndexTrend = `-- name: ListPriceIndexTrend :many
+SELECT pi.hospital_id, h.hospital_name, pi.mrf_file_id, pi.last_updated_on, pi.payer_id, py.payer_name,
+       pi.index_value, pi.items_total, pi.items_priced, pi.weight_coverage, pi.computed_at,
+       COALESCE(f.is_active, false)::boolean AS is_active
+FROM mrf.price_index pi
+JOIN ref.hospitals h ON h.hospital_id = pi.hospital_id
+LEFT JOIN ref.payers py ON py.payer_id = pi.payer_id
+LEFT JOIN ingest.mrf_files f ON f.mrf_file_id = pi.mrf_file_id
+WHERE pi.basket_name = $1
+  AND h.hospital_name ILIKE '%' || $2::text || '%'
+  AND ($3::text IS NULL OR py.payer_name ILIKE '%' || $3::text || '%')
+ORDER BY h.hospital_name, pi.hospital_id, pi.payer_id NULLS FIRST, pi.last_updated_on, pi.mrf_file_id
+`
+
+type ListPriceIndexTrendParams struct {
+	BasketName string
+	Hospital   string
+	Payer      *string
+}
+
+type ListPriceIndexTrendRow struct {
+	HospitalID     int64
+	HospitalName   string
+	MrfFileID      int64
+	LastUpdatedOn  *time.Time
+	PayerID        *int64
+	PayerName      *string
+	IndexValue     *float64
+	ItemsTotal     int32
+	ItemsPriced    int32
+	WeightCoverage float64
+	ComputedAt     pgtype.Timestamptz
+	IsActive       bool
+}
+
+// A basket's stored index rows of the hospitals matching hospital, oldest
+// version first.
+func (q *Queries) ListPriceIndexTrend(ctx context.Context, arg ListPriceIndexTrendParams) ([]*ListPriceIndexTrendRow, error) {
+	rows, err := q.db.Query(ctx, listPriceIndexTrend, arg.BasketName, arg.Hospital, arg.Payer)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListPriceIndexTrendRow
+	for rows.Next() {
+		var i ListPriceIndexTrendRow
+		if err := rows.Scan(
+			&i.HospitalID,
+			&i.HospitalName,
+			&i.MrfFileID,
+			&i.LastUpdatedOn,
+			&i.PayerID,
+			&i.PayerName,
+			&i.IndexValue,
+			&i.ItemsTotal,
+			&i.ItemsPriced,
+			&i.WeightCoverage,
+			&i.ComputedAt,
+			&i.IsActive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,92 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_prunable_files.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const listPrunableFiles = `-- name: ListPrunableFiles :many
+WITH superseded AS (
+  SELECT f.mrf_file_id, f.hospital_id, f.source_file_name, f.status, f.imported_at,
+         row_number() OVER (PARTITION BY f.hospital_id ORDER BY f.imported_at DESC, f.mrf_file_id DESC) AS version_rank
+  FROM ingest.mrf_files f
+  JOIN ingest.mrf_files a ON a.hospital_id = f.hospital_id AND a.is_active
+  WHERE NOT f.is_active
+    AND f.status IN ('transformed', 'partial', 'active')
+    AND (f.imported_at, f.mrf_file_id) < (a.imported_at, a.mrf_file_id)
+),
+candidates AS (
+  SELECT s.mrf_file_id, s.hospital_id, s.source_file_name, s.status, s.imported_at, s.version_rank
+  FROM superseded s
+  WHERE ($1::int > 0 AND s.version_rank > $1::int)
+     OR ($2::int > 0 AND s.imported_at < now() - make_interval(days => $2::int))
+  UNION ALL
+  SELECT f.mrf_file_id, f.hospital_id, f.source_file_name, f.status, f.imported_at, 0
+  FROM ingest.mrf_files f
+  WHERE NOT f.is_active
+    AND f.status IN ('failed', 'cancelled')
+    AND $3::int > 0
+    AND f.imported_at < now() - make_interval(days => $3::int)
+)
+SELECT c.mrf_file_id, c.hospital_id, h.hospital_name, c.source_file_name, c.status, c.imported_at,
+       c.version_rank::bigint AS version_rank
+FROM candidates c
+JOIN ref.hospitals h ON h.hospital_id = c.hospital_id
+ORDER BY h.hospital_name, c.imported_at
+`
+
+type ListPrunableFilesParams struct {
+	KeepVersions     int32
+	MaxAgeDays       int32
+	FailedMaxAgeDays int32
+}
+
+type ListPrunableFilesRow struct {
+	MrfFileID      int64
+	HospitalID     int64
+	HospitalName   string
+	SourceFileName string
+	Status         string
+	ImportedAt     pgtype.Timestamptz
+	VersionRank    int64
+}
+
+// Files outside the retention policy. Superseded versions are the inactive
+// files that could be activated and were imported before their hospital's
+// active version; version_rank counts them from the newest (1). Inactive
+// loads newer than the active version were never superseded, so neither
+// rule selects them. Failed and cancelled attempts are not versions: they
+// are not ranked (version_rank 0) and only failed_max_age_days selects them.
+func (q *Queries) ListPrunableFiles(ctx context.Context, arg ListPrunableFilesParams) ([]*ListPrunableFilesRow, error) {
+	rows, err := q.db.Query(ctx, listPrunableFiles, arg.KeepVersions, arg.MaxAgeDays, arg.FailedMaxAgeDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListPrunableFilesRow
+	for rows.Next() {
+		var i ListPrunableFilesRow
+		if err := rows.Scan(
+			&i.MrfFileID,
+			&i.HospitalID,
+			&i.HospitalName,
+			&i.SourceFileName,
+			&i.Status,
+			&i.ImportedAt,
+			&i.VersionRank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
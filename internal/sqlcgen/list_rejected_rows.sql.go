@@ -0,0 +1,68 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_rejected_rows.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const listRejectedRows = `-- name: ListRejectedRows :many
+SELECT rejected_row_id, ingest_batch_id, source_row_number, reason_code, field_name, detail, raw_row, rejected_at
+FROM ingest.rejected_rows
+WHERE mrf_file_id = $1
+  AND ($2::text IS NULL OR reason_code = $2::text)
+ORDER BY source_row_number
+LIMIT $3::integer
+`
+
+type ListRejectedRowsParams struct {
+	MrfFileID  int64
+	ReasonCode *string
+	RowLimit   *int32
+}
+
+type ListRejectedRowsRow struct {
+	RejectedRowID   int64
+	IngestBatchID   uuid.UUID
+	SourceRowNumber int64
+	ReasonCode      string
+	FieldName       *string
+	Detail          *string
+	RawRow          []byte
+	RejectedAt      pgtype.Timestamptz
+}
+
+func (q *Queries) ListRejectedRows(ctx context.Context, arg ListRejectedRowsParams) ([]*ListRejectedRowsRow, error) {
+	rows, err := q.db.Query(ctx, listRejectedRows, arg.MrfFileID, arg.ReasonCode, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListRejectedRowsRow
+	for rows.Next() {
+		var i ListRejectedRowsRow
+		if err := rows.Scan(
+			&i.RejectedRowID,
+			&i.IngestBatchID,
+			&i.SourceRowNumber,
+			&i.ReasonCode,
+			&i.FieldName,
+			&i.Detail,
+			&i.RawRow,
+			&i.RejectedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,62 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_stale_files.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const listStaleFiles = `-- name: ListStaleFiles :many
+SELECT f.mrf_file_id, f.hospital_id, f.source_file_name, f.status, f.is_active,
+       coalesce(f.heartbeat_at, f.imported_at)::timestamptz AS last_seen,
+       (SELECT count(*) FROM ingest.stage_charge_rows s
+        WHERE s.mrf_file_id = f.mrf_file_id)::bigint AS staging_rows
+FROM ingest.mrf_files f
+WHERE f.status IN ('pending', 'staging', 'staged', 'transforming')
+  AND coalesce(f.heartbeat_at, f.imported_at) < now() - make_interval(secs => $1::float8)
+ORDER BY f.mrf_file_id
+`
+
+type ListStaleFilesRow struct {
+	MrfFileID      int64
+	HospitalID     int64
+	SourceFileName string
+	Status         string
+	IsActive       bool
+	LastSeen       pgtype.Timestamptz
+	StagingRows    int64
+}
+
+// Files left in a running status whose ingest stopped heartbeating.
+func (q *Queries) ListStaleFiles(ctx context.Context, staleAfterSecs float64) ([]*ListStaleFilesRow, error) {
+	rows, err := q.db.Query(ctx, listStaleFiles, staleAfterSecs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListStaleFilesRow
+	for rows.Next() {
+		var i ListStaleFilesRow
+		if err := rows.Scan(
+			&i.MrfFileID,
+			&i.HospitalID,
+			&i.SourceFileName,
+			&i.Status,
+			&i.IsActive,
+			&i.LastSeen,
+			&i.StagingRows,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,46 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: list_stats_hospitals.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const listStatsHospitals = `-- name: ListStatsHospitals :many
+SELECT h.hospital_id, h.hospital_name
+FROM ref.hospitals h
+WHERE ($1::text IS NULL OR h.hospital_name ILIKE '%' || $1::text || '%')
+  AND (EXISTS (SELECT 1 FROM ingest.mrf_files f WHERE f.hospital_id = h.hospital_id AND f.is_active)
+       OR EXISTS (SELECT 1 FROM mrf.price_stats s WHERE s.hospital_id = h.hospital_id))
+ORDER BY h.hospital_id
+`
+
+type ListStatsHospitalsRow struct {
+	HospitalID   int64
+	HospitalName string
+}
+
+// Hospitals whose statistics a full refresh recomputes: those with an
+// active version or with statistics rows left from one.
+func (q *Queries) ListStatsHospitals(ctx context.Context, hospital *string) ([]*ListStatsHospitalsRow, error) {
+	rows, err := q.db.Query(ctx, listStatsHospitals, hospital)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListStatsHospitalsRow
+	for rows.Next() {
+		var i ListStatsHospitalsRow
+		if err := rows.Scan(&i.HospitalID, &i.HospitalName); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
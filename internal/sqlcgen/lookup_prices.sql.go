@@ -0,0 +1,142 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: lookup_prices.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const lookupPrices = `-- name: LookupPrices :many
+WITH matched AS (
+  SELECT p.price_row_id, p.mrf_file_id, p.code_type, p.code_norm, p.code_raw, p.description,
+         h.hospital_id, h.hospital_name, h.license_state,
+         p.setting, p.billing_class, py.payer_name, pl.plan_name, p.modifiers,
+         p.gross_charge_cents, p.discounted_cash_cents, p.negotiated_dollar_cents,
+         p.negotiated_percentage_bps, p.estimated_amount_cents,
+         p.min_charge_cents, p.max_charge_cents, p.methodology,
+         row_number() OVER (PARTITION BY p.code_norm
+                            ORDER BY COALESCE(p.negotiated_dollar_cents, p.gross_charge_cents) NULLS LAST,
+                                     h.hospital_name, p.price_row_id) AS code_rank,
+         count(*) OVER (PARTITION BY p.code_norm) AS code_rows
+  FROM mrf.prices_by_code p
+  JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+  JOIN ref.hospitals h ON h.hospital_id = p.hospital_id
+  LEFT JOIN ref.payers py ON py.payer_id = p.payer_id
+  LEFT JOIN ref.plans pl ON pl.plan_id = p.plan_id
+  WHERE p.code_type = $2
+    AND p.code_norm = ANY($3::text[])
+    AND ($4::text IS NULL OR h.hospital_name ILIKE '%' || $4::text || '%')
+    AND ($5::text IS NULL OR upper(h.license_state) = upper($5::text))
+    AND ($6::text IS NULL OR py.payer_name ILIKE '%' || $6::text || '%')
+    AND ($7::text IS NULL OR pl.plan_name ILIKE '%' || $7::text || '%')
+    AND ($8::text IS NULL OR lower(p.setting) IN (lower($8::text), 'both'))
+)
+SELECT m.price_row_id, m.mrf_file_id, m.code_type, m.code_norm, m.code_raw, m.description,
+       m.hospital_id, m.hospital_name, m.license_state,
+       m.setting, m.billing_class, m.payer_name, m.plan_name, m.modifiers,
+       m.gross_charge_cents, m.discounted_cash_cents, m.negotiated_dollar_cents,
+       m.negotiated_percentage_bps, m.estimated_amount_cents,
+       m.min_charge_cents, m.max_charge_cents, m.methodology,
+       m.code_rows::bigint AS code_rows
+FROM matched m
+WHERE $1::integer IS NULL OR m.code_rank <= $1::integer
+ORDER BY m.code_norm, m.code_rank
+`
+
+type LookupPricesParams struct {
+	CodeLimit *int32
+	CodeType  string
+	CodeNorms []string
+	Hospital  *string
+	State     *string
+	Payer     *string
+	Plan      *string
+	Setting   *string
+}
+
+type LookupPricesRow struct {
+	PriceRowID              int64
+	MrfFileID               int64
+	CodeType                string
+	CodeNorm                string
+	CodeRaw                 string
+	Description             string
+	HospitalID              int64
+	HospitalName            string
+	LicenseState            *string
+	Setting                 *string
+	BillingClass            *string
+	PayerName               *string
+	PlanName                *string
+	Modifiers               *string
+	GrossChargeCents        *int64
+	DiscountedCashCents     *int64
+	NegotiatedDollarCents   *int64
+	NegotiatedPercentageBps *int32
+	EstimatedAmountCents    *int64
+	MinChargeCents          *int64
+	MaxChargeCents          *int64
+	Methodology             *string
+	CodeRows                int64
+}
+
+// Active-version serving rows of one code type and a set of canonical codes,
+// cheapest first per code and at most code_limit rows per code (NULL for
+// all); code_rows is the code's row count before the limit. A row's price
+// is its negotiated dollar amount, or its gross charge when it has none. A
+// setting filter also matches rows published for both settings.
+func (q *Queries) LookupPrices(ctx context.Context, arg LookupPricesParams) ([]*LookupPricesRow, error) {
+	rows, err := q.db.Query(ctx, lookupPrices,
+		arg.CodeLimit,
+		arg.CodeType,
+		arg.CodeNorms,
+		arg.Hospital,
+		arg.State,
+		arg.Payer,
+		arg.Plan,
+		arg.Setting,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*LookupPricesRow
+	for rows.Next() {
+		var i LookupPricesRow
+		if err := rows.Scan(
+			&i.PriceRowID,
+			&i.MrfFileID,
+			&i.CodeType,
+			&i.CodeNorm,
+			&i.CodeRaw,
+			&i.Description,
+			&i.HospitalID,
+			&i.HospitalName,
+			&i.LicenseState,
+			&i.Setting,
+			&i.BillingClass,
+			&i.PayerName,
+			&i.PlanName,
+			&i.Modifiers,
+			&i.GrossChargeCents,
+			&i.DiscountedCashCents,
+			&i.NegotiatedDollarCents,
+			&i.NegotiatedPercentageBps,
+			&i.EstimatedAmountCents,
+			&i.MinChargeCents,
+			&i.MaxChargeCents,
+			&i.Methodology,
+			&i.CodeRows,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
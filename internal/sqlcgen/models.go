@@ -11,6 +11,23 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type IngestHistoryItem struct {
+	MrfFileID               int64
+	ItemKey                 []byte
+	CodeType                string
+	CodeNorm                string
+	Setting                 string
+	PayerID                 int64
+	PlanID                  int64
+	Modifiers               string
+	Description             string
+	GrossChargeCents        *int64
+	DiscountedCashCents     *int64
+	NegotiatedDollarCents   *int64
+	NegotiatedPercentageBps *int32
+	EstimatedAmountCents    *int64
+}
+
 type IngestMrfFile struct {
 	MrfFileID        int64
 	HospitalID       int64
@@ -23,6 +40,24 @@ type IngestMrfFile struct {
 	Status           string
 	ImportedAt       pgtype.Timestamptz
 	IsActive         bool
+	IngestBatchID    *uuid.UUID
+	RowsRead         *int64
+	RowsStaged       *int64
+	RowsRejected     *int64
+	StagedThroughRow *int64
+	HeartbeatAt      *time.Time
+}
+
+type IngestRejectedRow struct {
+	RejectedRowID   int64
+	IngestBatchID   uuid.UUID
+	MrfFileID       int64
+	SourceRowNumber int64
+	ReasonCode      string
+	FieldName       *string
+	Detail          *string
+	RawRow          []byte
+	RejectedAt      pgtype.Timestamptz
 }
 
 type IngestStageChargeRow struct {
@@ -64,169 +99,138 @@ type IngestStageChargeRow struct {
 	Modifiers               *string
 	AdditionalGenericNotes  *string
 	AdditionalPayerNotes    *string
+	QualityFlags            []string
 }
 
-type MrfPricesByCode struct {
-	PriceRowID              int64
-	MrfFileID               int64
+type MrfPriceHistory struct {
+	PriceHistoryID          int64
 	HospitalID              int64
+	ItemKey                 []byte
 	CodeType                string
-	CodeRaw                 string
 	CodeNorm                string
-	Description             string
 	Setting                 *string
-	BillingClass            *string
 	PayerID                 *int64
 	PlanID                  *int64
-	PayerNameRaw            *string
-	PlanNameRaw             *string
-	GrossChargeCents        *int64
-	DiscountedCashCents     *int64
-	NegotiatedDollarCents   *int64
-	NegotiatedPercentageBps *int32
-	EstimatedAmountCents    *int64
-	MinChargeCents          *int64
-	MaxChargeCents          *int64
-	Methodology             *string
-	NegotiatedAlgorithm     *string
-	DrugUnit                *string
-	DrugUnitType            *string
 	Modifiers               *string
-	AdditionalGenericNotes  *string
-	AdditionalPayerNotes    *string
-	SourceRowHash           []byte
-	ImportedAt              pgtype.Timestamptz
-}
-
-type MrfPricesByCodeCdt struct {
-	PriceRowID              int64
-	MrfFileID               int64
-	HospitalID              int64
-	CodeType                string
-	CodeRaw                 string
-	CodeNorm                string
 	Description             string
-	Setting                 *string
-	BillingClass            *string
-	PayerID                 *int64
-	PlanID                  *int64
-	PayerNameRaw            *string
-	PlanNameRaw             *string
 	GrossChargeCents        *int64
 	DiscountedCashCents     *int64
 	NegotiatedDollarCents   *int64
 	NegotiatedPercentageBps *int32
 	EstimatedAmountCents    *int64
-	MinChargeCents          *int64
-	MaxChargeCents          *int64
-	Methodology             *string
-	NegotiatedAlgorithm     *string
-	DrugUnit                *string
-	DrugUnitType            *string
-	Modifiers               *string
-	AdditionalGenericNotes  *string
-	AdditionalPayerNotes    *string
-	SourceRowHash           []byte
-	ImportedAt              pgtype.Timestamptz
+	ValidFrom               pgtype.Date
+	ValidTo                 *time.Time
+	FirstMrfFileID          int64
+	LastMrfFileID           int64
 }
 
-type MrfPricesByCodeCpt struct {
-	PriceRowID              int64
-	MrfFileID               int64
-	HospitalID              int64
-	CodeType                string
-	CodeRaw                 string
-	CodeNorm                string
-	Description             string
-	Setting                 *string
-	BillingClass            *string
-	PayerID                 *int64
-	PlanID                  *int64
-	PayerNameRaw            *string
-	PlanNameRaw             *string
-	GrossChargeCents        *int64
-	DiscountedCashCents     *int64
-	NegotiatedDollarCents   *int64
-	NegotiatedPercentageBps *int32
-	EstimatedAmountCents    *int64
-	MinChargeCents          *int64
-	MaxChargeCents          *int64
-	Methodology             *string
-	NegotiatedAlgorithm     *string
-	DrugUnit                *string
-	DrugUnitType            *string
-	Modifiers               *string
-	AdditionalGenericNotes  *string
-	AdditionalPayerNotes    *string
-	SourceRowHash           []byte
-	ImportedAt              pgtype.Timestamptz
+type MrfPriceIndex struct {
+	PriceIndexID   int64
+	BasketName     string
+	HospitalID     int64
+	MrfFileID      int64
+	LastUpdatedOn  *time.Time
+	PayerID        *int64
+	IndexValue     *float64
+	ItemsTotal     int32
+	ItemsPriced    int32
+	WeightCoverage float64
+	ComputedAt     pgtype.Timestamptz
 }
 
-type MrfPricesByCodeHcpc struct {
-	PriceRowID              int64
-	MrfFileID               int64
-	HospitalID              int64
-	CodeType                string
-	CodeRaw                 string
-	CodeNorm                string
-	Description             string
-	Setting                 *string
-	BillingClass            *string
-	PayerID                 *int64
-	PlanID                  *int64
-	PayerNameRaw            *string
-	PlanNameRaw             *string
-	GrossChargeCents        *int64
-	DiscountedCashCents     *int64
-	NegotiatedDollarCents   *int64
-	NegotiatedPercentageBps *int32
-	EstimatedAmountCents    *int64
-	MinChargeCents          *int64
-	MaxChargeCents          *int64
-	Methodology             *string
-	NegotiatedAlgorithm     *string
-	DrugUnit                *string
-	DrugUnitType            *string
-	Modifiers               *string
-	AdditionalGenericNotes  *string
-	AdditionalPayerNotes    *string
-	SourceRowHash           []byte
-	ImportedAt              pgtype.Timestamptz
+type MrfPriceIndexItem struct {
+	PriceIndexID   int64
+	CodeType       string
+	CodeNorm       string
+	Weight         float64
+	PriceCents     *int64
+	ReferenceCents *int64
+	Missing        bool
 }
 
-type MrfPricesByCodeMsDrg struct {
-	PriceRowID              int64
-	MrfFileID               int64
-	HospitalID              int64
-	CodeType                string
-	CodeRaw                 string
-	CodeNorm                string
-	Description             string
-	Setting                 *string
-	BillingClass            *string
-	PayerID                 *int64
-	PlanID                  *int64
-	PayerNameRaw            *string
-	PlanNameRaw             *string
-	GrossChargeCents        *int64
-	DiscountedCashCents     *int64
-	NegotiatedDollarCents   *int64
-	NegotiatedPercentageBps *int32
-	EstimatedAmountCents    *int64
-	MinChargeCents          *int64
-	MaxChargeCents          *int64
-	Methodology             *string
-	NegotiatedAlgorithm     *string
-	DrugUnit                *string
-	DrugUnitType            *string
-	Modifiers               *string
-	AdditionalGenericNotes  *string
-	AdditionalPayerNotes    *string
-	SourceRowHash           []byte
-	ImportedAt              pgtype.Timestamptz
+type MrfPriceStat struct {
+	CodeType              string
+	CodeNorm              string
+	Setting               *string
+	HospitalID            int64
+	PayerID               *int64
+	LicenseState          *string
+	MrfFileID             int64
+	RowCount              int64
+	NegotiatedCount       int64
+	NegotiatedMinCents    *int64
+	NegotiatedP10Cents    *int64
+	NegotiatedP25Cents    *int64
+	NegotiatedMedianCents *int64
+	NegotiatedP75Cents    *int64
+	NegotiatedP90Cents    *int64
+	NegotiatedMaxCents    *int64
+	NegotiatedIqrCents    *int64
+	NegotiatedCv          *float64
+	GrossCount            int64
+	GrossMinCents         *int64
+	GrossP10Cents         *int64
+	GrossP25Cents         *int64
+	GrossMedianCents      *int64
+	GrossP75Cents         *int64
+	GrossP90Cents         *int64
+	GrossMaxCents         *int64
+	GrossIqrCents         *int64
+	GrossCv               *float64
+	CashCount             int64
+	CashMinCents          *int64
+	CashP10Cents          *int64
+	CashP25Cents          *int64
+	CashMedianCents       *int64
+	CashP75Cents          *int64
+	CashP90Cents          *int64
+	CashMaxCents          *int64
+	CashIqrCents          *int64
+	CashCv                *float64
+	RefreshedAt           pgtype.Timestamptz
 }
 
-type MrfPricesByCodeNdc struct {
+type MrfPriceStatsByState struct {
+	CodeType              string
+	CodeNorm              string
+	Setting               *string
+	LicenseState          string
+	HospitalCount         int64
+	RowCount              int64
+	NegotiatedCount       int64
+	NegotiatedMinCents    *int64
+	NegotiatedP10Cents    *int64
+	NegotiatedP25Cents    *int64
+	NegotiatedMedianCents *int64
+	NegotiatedP75Cents    *int64
+	NegotiatedP90Cents    *int64
+	NegotiatedMaxCents    *int64
+	NegotiatedIqrCents    *int64
+	NegotiatedCv          *float64
+	GrossCount            int64
+	GrossMinCents         *int64
+	GrossP10Cents         *int64
+	GrossP25Cents         *int64
+	GrossMedianCents      *int64
+	GrossP75Cents         *int64
+	GrossP90Cents         *int64
+	GrossMaxCents         *int64
+	GrossIqrCents         *int64
+	GrossCv               *float64
+	CashCount             int64
+	CashMinCents          *int64
+	CashP10Cents          *int64
+	CashP25Cents          *int64
+	CashMedianCents       *int64
+	CashP75Cents          *int64
+	CashP90Cents          *int64
+	CashMaxCents          *int64
+	CashIqrCents          *int64
+	CashCv                *float64
+	RefreshedAt           pgtype.Timestamptz
+}
+
+type MrfPricesByCode struct {
 	PriceRowID              int64
 	MrfFileID               int64
 	HospitalID              int64
@@ -256,6 +260,15 @@ type MrfPricesByCodeNdc struct {
 	AdditionalPayerNotes    *string
 	SourceRowHash           []byte
 	ImportedAt              pgtype.Timestamptz
+	QualityFlags            []string
+}
+
+type RefCodeType struct {
+	CodeType        string
+	StagingColumn   string
+	PartitionSuffix string
+	SortOrder       int32
+	CreatedAt       pgtype.Timestamptz
 }
 
 type RefHospital struct {
@@ -0,0 +1,139 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: page_code_prices.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const pageCodePrices = `-- name: PageCodePrices :many
+SELECT p.price_row_id, p.mrf_file_id, p.code_type, p.code_norm, p.code_raw, p.description,
+       h.hospital_id, h.hospital_name, h.license_state,
+       p.setting, p.billing_class, py.payer_id, py.payer_name, pl.plan_id, pl.plan_name, p.modifiers,
+       p.gross_charge_cents, p.discounted_cash_cents, p.negotiated_dollar_cents,
+       p.negotiated_percentage_bps, p.estimated_amount_cents,
+       p.min_charge_cents, p.max_charge_cents, p.methodology,
+       COALESCE(p.negotiated_dollar_cents, p.gross_charge_cents, 9223372036854775807)::bigint AS sort_cents
+FROM mrf.prices_by_code p
+JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+JOIN ref.hospitals h ON h.hospital_id = p.hospital_id
+LEFT JOIN ref.payers py ON py.payer_id = p.payer_id
+LEFT JOIN ref.plans pl ON pl.plan_id = p.plan_id
+WHERE p.code_type = $1
+  AND p.code_norm = $2
+  AND ($3::bigint IS NULL OR p.hospital_id = $3::bigint)
+  AND ($4::text IS NULL OR upper(h.license_state) = upper($4::text))
+  AND ($5::bigint IS NULL OR p.payer_id = $5::bigint)
+  AND ($6::text IS NULL OR pl.plan_name ILIKE '%' || $6::text || '%')
+  AND ($7::text IS NULL OR lower(p.setting) IN (lower($7::text), 'both'))
+  AND ($8::bigint IS NULL
+       OR (COALESCE(p.negotiated_dollar_cents, p.gross_charge_cents, 9223372036854775807), p.price_row_id)
+          > ($8::bigint, $9::bigint))
+ORDER BY sort_cents, p.price_row_id
+LIMIT $10::integer
+`
+
+type PageCodePricesParams struct {
+	CodeType   string
+	CodeNorm   string
+	HospitalID *int64
+	State      *string
+	PayerID    *int64
+	Plan       *string
+	Setting    *string
+	AfterCents *int64
+	AfterID    *int64
+	RowLimit   int32
+}
+
+type PageCodePricesRow struct {
+	PriceRowID              int64
+	MrfFileID               int64
+	CodeType                string
+	CodeNorm                string
+	CodeRaw                 string
+	Description             string
+	HospitalID              int64
+	HospitalName            string
+	LicenseState            *string
+	Setting                 *string
+	BillingClass            *string
+	PayerID                 *int64
+	PayerName               *string
+	PlanID                  *int64
+	PlanName                *string
+	Modifiers               *string
+	GrossChargeCents        *int64
+	DiscountedCashCents     *int64
+	NegotiatedDollarCents   *int64
+	NegotiatedPercentageBps *int32
+	EstimatedAmountCents    *int64
+	MinChargeCents          *int64
+	MaxChargeCents          *int64
+	Methodology             *string
+	SortCents               int64
+}
+
+// One page of a code's active-version rows in (sort_cents, price_row_id)
+// order. sort_cents is the negotiated dollar amount, else the gross charge,
+// else the bigint maximum so unpriced rows come last. after_cents/after_id
+// are the last row of the previous page.
+func (q *Queries) PageCodePrices(ctx context.Context, arg PageCodePricesParams) ([]*PageCodePricesRow, error) {
+	rows, err := q.db.Query(ctx, pageCodePrices,
+		arg.CodeType,
+		arg.CodeNorm,
+		arg.HospitalID,
+		arg.State,
+		arg.PayerID,
+		arg.Plan,
+		arg.Setting,
+		arg.AfterCents,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*PageCodePricesRow
+	for rows.Next() {
+		var i PageCodePricesRow
+		if err := rows.Scan(
+			&i.PriceRowID,
+			&i.MrfFileID,
+			&i.CodeType,
+			&i.CodeNorm,
+			&i.CodeRaw,
+			&i.Description,
+			&i.HospitalID,
+			&i.HospitalName,
+			&i.LicenseState,
+			&i.Setting,
+			&i.BillingClass,
+			&i.PayerID,
+			&i.PayerName,
+			&i.PlanID,
+			&i.PlanName,
+			&i.Modifiers,
+			&i.GrossChargeCents,
+			&i.DiscountedCashCents,
+			&i.NegotiatedDollarCents,
+			&i.NegotiatedPercentageBps,
+			&i.EstimatedAmountCents,
+			&i.MinChargeCents,
+			&i.MaxChargeCents,
+			&i.Methodology,
+			&i.SortCents,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,74 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: page_hospital_files.sql
+
+package sqlcgen
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const pageHospitalFiles = `-- name: PageHospitalFiles :many
+SELECT f.mrf_file_id, f.source_file_name, f.version, f.last_updated_on, f.status,
+       f.is_active, f.imported_at, f.rows_read, f.rows_staged, f.rows_rejected
+FROM ingest.mrf_files f
+WHERE f.hospital_id = $1
+  AND ($2::bigint IS NULL OR f.mrf_file_id < $2::bigint)
+ORDER BY f.mrf_file_id DESC
+LIMIT $3::integer
+`
+
+type PageHospitalFilesParams struct {
+	HospitalID int64
+	BeforeID   *int64
+	RowLimit   int32
+}
+
+type PageHospitalFilesRow struct {
+	MrfFileID      int64
+	SourceFileName string
+	Version        *string
+	LastUpdatedOn  *time.Time
+	Status         string
+	IsActive       bool
+	ImportedAt     pgtype.Timestamptz
+	RowsRead       *int64
+	RowsStaged     *int64
+	RowsRejected   *int64
+}
+
+// One page of a hospital's file versions, newest first.
+func (q *Queries) PageHospitalFiles(ctx context.Context, arg PageHospitalFilesParams) ([]*PageHospitalFilesRow, error) {
+	rows, err := q.db.Query(ctx, pageHospitalFiles, arg.HospitalID, arg.BeforeID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*PageHospitalFilesRow
+	for rows.Next() {
+		var i PageHospitalFilesRow
+		if err := rows.Scan(
+			&i.MrfFileID,
+			&i.SourceFileName,
+			&i.Version,
+			&i.LastUpdatedOn,
+			&i.Status,
+			&i.IsActive,
+			&i.ImportedAt,
+			&i.RowsRead,
+			&i.RowsStaged,
+			&i.RowsRejected,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,77 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: page_hospitals.sql
+
+package sqlcgen
+
+import (
+	"context"
+	"time"
+)
+
+const pageHospitals = `-- name: PageHospitals :many
+SELECT h.hospital_id, h.hospital_name, h.hospital_location, h.hospital_address,
+       h.license_number, h.license_state,
+       f.mrf_file_id AS active_mrf_file_id, f.last_updated_on AS active_last_updated_on
+FROM ref.hospitals h
+JOIN ingest.mrf_files f ON f.hospital_id = h.hospital_id AND f.is_active
+WHERE ($1::text IS NULL OR h.hospital_name ILIKE '%' || $1::text || '%')
+  AND ($2::text IS NULL OR upper(h.license_state) = upper($2::text))
+  AND ($3::bigint IS NULL OR h.hospital_id > $3::bigint)
+ORDER BY h.hospital_id
+LIMIT $4::integer
+`
+
+type PageHospitalsParams struct {
+	Name     *string
+	State    *string
+	AfterID  *int64
+	RowLimit int32
+}
+
+type PageHospitalsRow struct {
+	HospitalID          int64
+	HospitalName        string
+	HospitalLocation    *string
+	HospitalAddress     *string
+	LicenseNumber       *string
+	LicenseState        *string
+	ActiveMrfFileID     int64
+	ActiveLastUpdatedOn *time.Time
+}
+
+// One page of the hospitals with an active version, by hospital_id.
+func (q *Queries) PageHospitals(ctx context.Context, arg PageHospitalsParams) ([]*PageHospitalsRow, error) {
+	rows, err := q.db.Query(ctx, pageHospitals,
+		arg.Name,
+		arg.State,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*PageHospitalsRow
+	for rows.Next() {
+		var i PageHospitalsRow
+		if err := rows.Scan(
+			&i.HospitalID,
+			&i.HospitalName,
+			&i.HospitalLocation,
+			&i.HospitalAddress,
+			&i.LicenseNumber,
+			&i.LicenseState,
+			&i.ActiveMrfFileID,
+			&i.ActiveLastUpdatedOn,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
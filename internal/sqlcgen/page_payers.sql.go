@@ -0,0 +1,56 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: page_payers.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const pagePayers = `-- name: PagePayers :many
+SELECT py.payer_id, py.payer_name
+FROM ref.payers py
+WHERE ($1::text IS NULL OR py.payer_name ILIKE '%' || $1::text || '%')
+  AND ($2::bigint IS NULL OR py.payer_id > $2::bigint)
+  AND EXISTS (
+    SELECT 1 FROM mrf.prices_by_code p
+    JOIN ingest.mrf_files f ON f.mrf_file_id = p.mrf_file_id AND f.is_active
+    WHERE p.payer_id = py.payer_id
+  )
+ORDER BY py.payer_id
+LIMIT $3::integer
+`
+
+type PagePayersParams struct {
+	Name     *string
+	AfterID  *int64
+	RowLimit int32
+}
+
+type PagePayersRow struct {
+	PayerID   int64
+	PayerName string
+}
+
+// One page of the payers with rows in an active version, by payer_id.
+func (q *Queries) PagePayers(ctx context.Context, arg PagePayersParams) ([]*PagePayersRow, error) {
+	rows, err := q.db.Query(ctx, pagePayers, arg.Name, arg.AfterID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*PagePayersRow
+	for rows.Next() {
+		var i PagePayersRow
+		if err := rows.Scan(&i.PayerID, &i.PayerName); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,36 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: record_staged_counts.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const recordStagedCounts = `-- name: RecordStagedCounts :exec
+UPDATE ingest.mrf_files
+SET status = 'staged',
+    rows_read = $1,
+    rows_staged = $2,
+    rows_rejected = $3
+WHERE mrf_file_id = $4
+`
+
+type RecordStagedCountsParams struct {
+	RowsRead     *int64
+	RowsStaged   *int64
+	RowsRejected *int64
+	MrfFileID    int64
+}
+
+func (q *Queries) RecordStagedCounts(ctx context.Context, arg RecordStagedCountsParams) error {
+	_, err := q.db.Exec(ctx, recordStagedCounts,
+		arg.RowsRead,
+		arg.RowsStaged,
+		arg.RowsRejected,
+		arg.MrfFileID,
+	)
+	return err
+}
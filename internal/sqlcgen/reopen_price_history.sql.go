@@ -0,0 +1,34 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: reopen_price_history.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const reopenPriceHistory = `-- name: ReopenPriceHistory :execrows
+UPDATE mrf.price_history
+SET valid_to = NULL
+WHERE hospital_id = $1
+  AND valid_to > $2::date
+`
+
+type ReopenPriceHistoryParams struct {
+	HospitalID int64
+	ValidFrom  pgtype.Date
+}
+
+// Makes current again the hospital's periods that a rolled-back version
+// closed after valid_from. Run after DeleteLaterPriceHistory.
+func (q *Queries) ReopenPriceHistory(ctx context.Context, arg ReopenPriceHistoryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reopenPriceHistory, arg.HospitalID, arg.ValidFrom)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,26 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: set_stage_checkpoint.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const setStageCheckpoint = `-- name: SetStageCheckpoint :exec
+UPDATE ingest.mrf_files
+SET staged_through_row = $1
+WHERE mrf_file_id = $2
+`
+
+type SetStageCheckpointParams struct {
+	StagedThroughRow *int64
+	MrfFileID        int64
+}
+
+func (q *Queries) SetStageCheckpoint(ctx context.Context, arg SetStageCheckpointParams) error {
+	_, err := q.db.Exec(ctx, setStageCheckpoint, arg.StagedThroughRow, arg.MrfFileID)
+	return err
+}
@@ -0,0 +1,30 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: start_mrf_batch.sql
+
+package sqlcgen
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const startMRFBatch = `-- name: StartMRFBatch :exec
+UPDATE ingest.mrf_files
+SET ingest_batch_id = $1,
+    rows_read = NULL, rows_staged = NULL, rows_rejected = NULL,
+    staged_through_row = NULL
+WHERE mrf_file_id = $2
+`
+
+type StartMRFBatchParams struct {
+	IngestBatchID *uuid.UUID
+	MrfFileID     int64
+}
+
+func (q *Queries) StartMRFBatch(ctx context.Context, arg StartMRFBatchParams) error {
+	_, err := q.db.Exec(ctx, startMRFBatch, arg.IngestBatchID, arg.MrfFileID)
+	return err
+}
@@ -0,0 +1,20 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: touch_mrf_file.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const touchMRFFile = `-- name: TouchMRFFile :exec
+UPDATE ingest.mrf_files SET heartbeat_at = now()
+WHERE mrf_file_id = $1
+`
+
+func (q *Queries) TouchMRFFile(ctx context.Context, mrfFileID int64) error {
+	_, err := q.db.Exec(ctx, touchMRFFile, mrfFileID)
+	return err
+}
@@ -0,0 +1,26 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: try_advisory_lock.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const tryAdvisoryLock = `-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock($1::int4, $2::int4)::boolean AS locked
+`
+
+type TryAdvisoryLockParams struct {
+	ClassID int32
+	ObjID   int32
+}
+
+func (q *Queries) TryAdvisoryLock(ctx context.Context, arg TryAdvisoryLockParams) (bool, error) {
+	row := q.db.QueryRow(ctx, tryAdvisoryLock, arg.ClassID, arg.ObjID)
+	var locked bool
+	err := row.Scan(&locked)
+	return locked, err
+}
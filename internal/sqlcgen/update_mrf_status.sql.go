@@ -11,7 +11,7 @@ import (
 
 const updateMRFStatus = `-- name: UpdateMRFStatus :exec
 UPDATE ingest.mrf_files
-SET status = $1
+SET status = $1, heartbeat_at = now()
 WHERE mrf_file_id = $2
 `
 
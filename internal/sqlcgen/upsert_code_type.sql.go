@@ -0,0 +1,36 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: upsert_code_type.sql
+
+package sqlcgen
+
+import (
+	"context"
+)
+
+const upsertCodeType = `-- name: UpsertCodeType :exec
+INSERT INTO ref.code_types (code_type, staging_column, partition_suffix, sort_order)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (code_type) DO UPDATE
+SET staging_column   = EXCLUDED.staging_column,
+    partition_suffix = EXCLUDED.partition_suffix,
+    sort_order       = EXCLUDED.sort_order
+`
+
+type UpsertCodeTypeParams struct {
+	CodeType        string
+	StagingColumn   string
+	PartitionSuffix string
+	SortOrder       int32
+}
+
+func (q *Queries) UpsertCodeType(ctx context.Context, arg UpsertCodeTypeParams) error {
+	_, err := q.db.Exec(ctx, upsertCodeType,
+		arg.CodeType,
+		arg.StagingColumn,
+		arg.PartitionSuffix,
+		arg.SortOrder,
+	)
+	return err
+}